@@ -3,12 +3,18 @@ package main
 import (
 	"github.com/zuchzub/Go/pkg"
 	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/eventlog"
 	"github.com/zuchzub/Go/pkg/lang"
 	"github.com/zuchzub/Go/pkg/vc"
 
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_ "net/http"
@@ -29,6 +35,9 @@ func handleFlood(err error) bool {
 	return false
 }
 
+// version is the build version, set with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
 //go:generate go run setup_ntgcalls.go static
 
 // main serves as the entry point for the application.
@@ -41,6 +50,7 @@ func main() {
 	if err := config.LoadConfig(); err != nil {
 		gologging.Fatal(err.Error())
 	}
+	config.Conf.Version = version
 
 	go func() {
 		gologging.InfoF("[pprof] running on :%s", config.Conf.Port)
@@ -88,8 +98,33 @@ func main() {
 	gologging.InfoF("The bot is running as @%s.", client.Me().Username)
 	_, _ = client.SendMessage(config.Conf.LoggerId, "The bot has started!")
 
-	client.Idle()
+	// The signal is handled here rather than by client.Idle, which would stop the bot client before chats are drained.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-sigCtx.Done()
+	stop()
+
 	gologging.InfoF("The bot is shutting down...")
+	cache.SetMaintenance(true, "")
+	drainCtx, drainCancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	vc.Calls.Drain(drainCtx, config.Conf.ShutdownDrain, config.Conf.ShutdownDrainTimeout)
+	drainCancel()
+
+	vc.Calls.SaveSessions()
+	flushCtx, flushCancel := db.Ctx()
+	if err := db.Instance.FlushQueueStats(flushCtx); err != nil {
+		gologging.WarnF("Failed to flush the queue stats: %v", err)
+	}
+	if err := db.Instance.FlushCommandStats(flushCtx); err != nil {
+		gologging.WarnF("Failed to flush the command stats: %v", err)
+	}
+	if err := db.Instance.FlushQueues(flushCtx); err != nil {
+		gologging.WarnF("Failed to flush the queues: %v", err)
+	}
+	flushCancel()
+	if err := vc.Calls.SaveCaches(); err != nil {
+		gologging.WarnF("Failed to save the caches: %v", err)
+	}
 	vc.Calls.StopAllClients()
+	eventlog.Close()
 	_ = client.Stop()
 }
\ No newline at end of file
@@ -3,8 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Laky-64/gologging"
 	"github.com/joho/godotenv"
@@ -33,6 +34,64 @@ type BotConfig struct {
 	CookiesPath    []string // CookiesPath is a list of paths to cookies files.
 	cookiesUrl     []string // cookiesUrl is a list of URLs to cookies files.
 	Port           string
+
+	platformCookieURLs map[string][]string // platformCookieURLs maps a platform key to its COOKIES_URL_<KEY> URLs.
+	platformCookies    map[string][]string // platformCookies maps a platform key to its saved cookie file paths.
+	cookiesMu          sync.RWMutex
+
+	ValidateDownloads bool // ValidateDownloads enables the size and ffprobe check run after each download.
+	DownloadRetries   int  // DownloadRetries is how many times a corrupt download is retried before giving up.
+	VideoMaxHeight    int  // VideoMaxHeight is the preferred height of YouTube videos; lower qualities are used when it is unavailable.
+
+	FFmpegPath      string   // FFmpegPath is the ffmpeg binary, as a name looked up in PATH or a path.
+	FFprobePath     string   // FFprobePath is the ffprobe binary, as a name looked up in PATH or a path.
+	FFmpegExtraArgs []string // FFmpegExtraArgs are global flags, such as -hwaccel, given to the ffmpeg that streams to voice chats.
+
+	SilenceCheck          bool     // SilenceCheck enables the post-download check for silent audio.
+	SilenceCheckPlatforms []string // SilenceCheckPlatforms lists the platforms whose downloads are checked for silence.
+
+	RetryStatusCodes []int // RetryStatusCodes lists the HTTP status codes that API requests are retried on.
+
+	DownloadStrategies map[string][]string // DownloadStrategies maps a platform to the download strategies tried for it in order; other platforms use the defaults.
+
+	IncomingCallMode  string // IncomingCallMode is how assistants react to private calls: off, message, or play.
+	IncomingCallMedia string // IncomingCallMedia is the t.me link or local file streamed when IncomingCallMode is play.
+
+	AutoReconnect    bool // AutoReconnect rejoins the voice chat and resumes the track when the call connection drops.
+	ReconnectRetries int  // ReconnectRetries is how many rejoin attempts are made before giving up.
+
+	AdaptiveAudio   bool // AdaptiveAudio lowers the audio sample rate for voice chats with few listeners.
+	AudioSampleRate int  // AudioSampleRate is the sample rate used when adaptation is off or the voice chat is busy.
+
+	EventLogPath    string // EventLogPath is the JSONL file queue and playback events are written to; empty disables it.
+	EventLogMaxSize int64  // EventLogMaxSize is the size in bytes at which the event log is rotated.
+	EventLogKeep    int    // EventLogKeep is how many rotated event log files are kept.
+
+	MaxDuration        int // MaxDuration is the longest music track, in seconds, that can be played; 0 disables the limit.
+	MaxPodcastDuration int // MaxPodcastDuration is the longest podcast episode, in seconds, that can be played; 0 disables the limit.
+	PodcastEpisodes    int // PodcastEpisodes is how many of the latest episodes are queued for a podcast show link.
+	MaxPlaylistTracks  int // MaxPlaylistTracks is the most tracks a batch of links sent to /play queues at once.
+
+	ShutdownDrain        string        // ShutdownDrain is how active chats are wound down on shutdown: off, notify, or finish.
+	ShutdownDrainTimeout time.Duration // ShutdownDrainTimeout bounds how long the finish mode waits for current tracks to end.
+
+	AssistantRejoinGrace time.Duration // AssistantRejoinGrace is how long a chat's queue is kept after the assistant is removed; 0 clears it at once.
+
+	NetworkFamily string // NetworkFamily is the IP family downloads use: auto, ipv4, or ipv6.
+
+	PlaybackStartTimeout time.Duration // PlaybackStartTimeout bounds the wait for a stream's first frame before "now playing" is shown; 0 disables the wait.
+
+	MaxVideoSessions int // MaxVideoSessions is how many chats can stream video at once across the bot; 0 disables the limit.
+
+	StaleCommandAge time.Duration // StaleCommandAge is how old a command sent while the bot was down can be before it is held back; 0 disables the check.
+
+	PublicURL  string // PublicURL is the address the bot's HTTP server is reachable at from outside, used for gateway redirects.
+	SessionKey string // SessionKey is the secret the encryption key of stored gateway user tokens is derived from; empty disables account linking.
+
+	CommandStatsFlush bool // CommandStatsFlush writes daily per-command usage totals to the stats collection, for trends beyond a restart.
+
+	TelemetryURL string // TelemetryURL is where the daily anonymous usage ping is sent; empty disables it.
+	Version      string // Version is the build version, set by main from its ldflags variable.
 }
 
 // Conf is the global configuration for the bot.
@@ -63,32 +122,81 @@ func LoadConfig() error {
 		SupportChannel: getEnvStr("SUPPORT_CHANNEL", "https://t.me/FallenProjects"),
 		cookiesUrl:     processCookieURLs(os.Getenv("COOKIES_URL")),
 		Port:           getEnvStr("PORT", "5068"),
-	}
 
-	// Parse DEVS list
-	devsEnv := os.Getenv("DEVS")
-	if devsEnv != "" {
-		for _, idStr := range strings.Fields(devsEnv) {
-			if id, err := strconv.ParseInt(idStr, 10, 64); err == nil {
-				Conf.DEVS = append(Conf.DEVS, id)
-			}
-		}
+		platformCookieURLs: getPlatformCookieURLs("COOKIES_URL_"),
+		platformCookies:    make(map[string][]string),
+
+		ValidateDownloads: getEnvBool("VALIDATE_DOWNLOADS", true),
+		DownloadRetries:   int(getEnvInt64("DOWNLOAD_RETRIES", 1)),
+		VideoMaxHeight:    int(getEnvInt64("VIDEO_MAX_HEIGHT", 1080)),
+
+		FFmpegPath:      getEnvStr("FFMPEG_PATH", "ffmpeg"),
+		FFprobePath:     getEnvStr("FFPROBE_PATH", "ffprobe"),
+		FFmpegExtraArgs: strings.Fields(os.Getenv("FFMPEG_EXTRA_ARGS")),
+
+		SilenceCheck:          getEnvBool("SILENCE_CHECK", true),
+		SilenceCheckPlatforms: strings.Fields(strings.ToLower(strings.ReplaceAll(getEnvStr("SILENCE_CHECK_PLATFORMS", "spotify"), ",", " "))),
+
+		RetryStatusCodes: getEnvInts("RETRY_STATUS_CODES", []int{429, 500, 502, 503, 504}),
+
+		DownloadStrategies: getDownloadStrategies(),
+
+		IncomingCallMode:  strings.ToLower(getEnvStr("INCOMING_CALL_MODE", "message")),
+		IncomingCallMedia: os.Getenv("INCOMING_CALL_MEDIA"),
+
+		AutoReconnect:    getEnvBool("AUTO_RECONNECT", true),
+		ReconnectRetries: int(getEnvInt64("RECONNECT_RETRIES", 3)),
+
+		AdaptiveAudio:   getEnvBool("ADAPTIVE_AUDIO", true),
+		AudioSampleRate: int(getEnvInt64("AUDIO_SAMPLE_RATE", 96000)),
+
+		EventLogPath:    getEnvStr("EVENT_LOG_PATH", "logs/events.jsonl"),
+		EventLogMaxSize: getEnvInt64("EVENT_LOG_MAX_SIZE", 10*1024*1024),
+		EventLogKeep:    int(getEnvInt64("EVENT_LOG_KEEP", 3)),
+
+		MaxDuration:        int(getEnvInt64("MAX_DURATION", 3*60*60)),
+		MaxPodcastDuration: int(getEnvInt64("MAX_PODCAST_DURATION", 8*60*60)),
+		PodcastEpisodes:    int(getEnvInt64("PODCAST_EPISODES", 10)),
+		MaxPlaylistTracks:  int(getEnvInt64("MAX_PLAYLIST_TRACKS", 20)),
+
+		ShutdownDrain:        strings.ToLower(getEnvStr("SHUTDOWN_DRAIN", "notify")),
+		ShutdownDrainTimeout: time.Duration(getEnvInt64("SHUTDOWN_DRAIN_TIMEOUT", 25)) * time.Second,
+
+		AssistantRejoinGrace: time.Duration(getEnvInt64("ASSISTANT_REJOIN_GRACE", 60)) * time.Second,
+
+		NetworkFamily: strings.ToLower(getEnvStr("NETWORK_FAMILY", "auto")),
+
+		PlaybackStartTimeout: time.Duration(getEnvInt64("PLAYBACK_START_TIMEOUT", 3)) * time.Second,
+
+		MaxVideoSessions: int(getEnvInt64("MAX_VIDEO_SESSIONS", 2)),
+
+		StaleCommandAge: time.Duration(getEnvInt64("STALE_COMMAND_AGE", 180)) * time.Second,
+
+		PublicURL:  strings.TrimRight(os.Getenv("PUBLIC_URL"), "/"),
+		SessionKey: os.Getenv("SESSION_KEY"),
+
+		CommandStatsFlush: getEnvBool("COMMAND_STATS_FLUSH", false),
+
+		TelemetryURL: os.Getenv("TELEMETRY_URL"),
 	}
-	if Conf.OwnerId != 0 && !containsInt(Conf.DEVS, Conf.OwnerId) {
-		Conf.DEVS = append(Conf.DEVS, Conf.OwnerId)
+
+	var malformed []string
+	Conf.DEVS, malformed = parseDevs(os.Getenv("DEVS"), Conf.OwnerId)
+	for _, entry := range malformed {
+		gologging.WarnF("Ignoring the malformed DEVS entry %q: expected a user ID.", entry)
 	}
 
 	if err := Conf.validate(); err != nil {
 		return err
 	}
 
-	if len(Conf.cookiesUrl) > 0 {
+	if len(Conf.cookiesUrl) > 0 || len(Conf.platformCookieURLs) > 0 {
 		if err := os.MkdirAll(tmpDir, 0750); err != nil {
 			return fmt.Errorf("failed to create temp dir: %w", err)
 		}
 
 		gologging.InfoF("Saving cookies...")
-		go saveAllCookies(Conf.cookiesUrl)
+		go saveAllCookies(Conf.cookiesUrl, Conf.platformCookieURLs)
 	}
 	return nil
 }
@@ -2,7 +2,12 @@ package config
 
 import (
 	"fmt"
+	"github.com/zuchzub/Go/pkg/core/storagehealth"
+	"maps"
 	"os"
+	"os/exec"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -59,6 +64,24 @@ func getEnvBool(key string, def bool) bool {
 	return val == "true"
 }
 
+// getEnvInts retrieves a list of integers from a space- or comma-separated environment variable or returns a default value.
+// It takes the environment variable key and a default slice as input.
+// It returns the parsed integers, skipping invalid entries, or the default value if the variable is unset.
+func getEnvInts(key string, def []int) []int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+
+	var ints []int
+	for _, part := range strings.Fields(strings.ReplaceAll(val, ",", " ")) {
+		if i, err := strconv.Atoi(part); err == nil {
+			ints = append(ints, i)
+		}
+	}
+	return ints
+}
+
 // getSessionStrings retrieves a list of session strings from environment variables.
 // It takes a prefix and a count as input.
 // It returns a slice of strings containing the session strings.
@@ -89,6 +112,92 @@ func processCookieURLs(value string) []string {
 	return urls
 }
 
+// getPlatformCookieURLs collects the cookie URLs configured per platform, such as COOKIES_URL_YT or COOKIES_URL_SC.
+// It takes the environment variable prefix as input.
+// It returns a map from the lowercased platform key (e.g. "yt", "sc") to its cookie URLs.
+func getPlatformCookieURLs(prefix string) map[string][]string {
+	urls := make(map[string][]string)
+	for _, env := range os.Environ() {
+		key, value, ok := strings.Cut(env, "=")
+		if !ok || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		platform := strings.ToLower(strings.TrimPrefix(key, prefix))
+		if platform == "" {
+			continue
+		}
+
+		if parsed := processCookieURLs(value); len(parsed) > 0 {
+			urls[platform] = parsed
+		}
+	}
+	return urls
+}
+
+// strategyEnvKeys maps the environment variables that set a platform's download strategies to the platform.
+var strategyEnvKeys = map[string]string{
+	"YT_STRATEGY":          "youtube",
+	"SPOTIFY_STRATEGY":     "spotify",
+	"JIOSAAVN_STRATEGY":    "jiosaavn",
+	"APPLE_MUSIC_STRATEGY": "apple_music",
+	"SOUNDCLOUD_STRATEGY":  "soundcloud",
+}
+
+// downloadStrategyNames lists the download strategies a platform can use; they are implemented by the dl package.
+var downloadStrategyNames = []string{"api", "cdn-direct", "ytdlp", "youtube-search"}
+
+// getDownloadStrategies reads the comma- or space-separated strategy lists set in strategyEnvKeys.
+// It returns a map from the platform to its strategies, without the platforms left unset.
+func getDownloadStrategies() map[string][]string {
+	strategies := make(map[string][]string)
+	for key, platform := range strategyEnvKeys {
+		if val := os.Getenv(key); val != "" {
+			strategies[platform] = strings.Fields(strings.ToLower(strings.ReplaceAll(val, ",", " ")))
+		}
+	}
+	return strategies
+}
+
+// validateDownloadStrategies checks that every configured strategy list is non-empty and names known strategies only.
+func validateDownloadStrategies(strategies map[string][]string) error {
+	for _, key := range slices.Sorted(maps.Keys(strategyEnvKeys)) {
+		chain, ok := strategies[strategyEnvKeys[key]]
+		if !ok {
+			continue
+		}
+		if len(chain) == 0 {
+			return fmt.Errorf("invalid %s: expected at least one of %s", key, strings.Join(downloadStrategyNames, ", "))
+		}
+		for _, name := range chain {
+			if !slices.Contains(downloadStrategyNames, name) {
+				return fmt.Errorf("invalid %s strategy %q: expected %s", key, name, strings.Join(downloadStrategyNames, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// parseDevs parses the whitespace-separated user IDs of DEVS, and adds the owner if it is not listed.
+// Duplicates are listed once, in the order they first appear. Entries that are not positive integers are skipped and
+// returned as malformed.
+func parseDevs(value string, ownerID int64) (devs []int64, malformed []string) {
+	for _, entry := range strings.Fields(value) {
+		id, err := strconv.ParseInt(entry, 10, 64)
+		if err != nil || id <= 0 {
+			malformed = append(malformed, entry)
+			continue
+		}
+		if !containsInt(devs, id) {
+			devs = append(devs, id)
+		}
+	}
+	if ownerID != 0 && !containsInt(devs, ownerID) {
+		devs = append(devs, ownerID)
+	}
+	return devs, malformed
+}
+
 // containsInt checks if a slice of int64 contains a specific value.
 // It takes a slice of int64 and an int64 as input.
 // It returns true if the slice contains the value, otherwise it returns false.
@@ -101,6 +210,40 @@ func containsInt(list []int64, x int64) bool {
 	return false
 }
 
+// validateIncomingCallMedia checks that the incoming-call media is a t.me message link or an existing local file.
+// It returns an error describing the problem, or nil if the media is usable.
+func validateIncomingCallMedia(media string) error {
+	if media == "" {
+		return fmt.Errorf("INCOMING_CALL_MEDIA is required when INCOMING_CALL_MODE is play")
+	}
+	if regexp.MustCompile(`^https?://t\.me/\w+/\d+$`).MatchString(media) {
+		return nil
+	}
+	if info, err := os.Stat(media); err != nil || info.IsDir() {
+		return fmt.Errorf("INCOMING_CALL_MEDIA must be a t.me message link or an existing file: %s", media)
+	}
+	return nil
+}
+
+var (
+	// shellSafePath matches binary paths that can be put in a shell command without quoting.
+	shellSafePath = regexp.MustCompile(`^[\w./+-]+$`)
+	// shellSafeArg matches the FFMPEG_EXTRA_ARGS allowed: flags and plain values, with no quotes, spaces or shell operators.
+	shellSafeArg = regexp.MustCompile(`^-?[\w.,:=/+-]+$`)
+)
+
+// validateBinary checks that a binary named by key is a path safe to put in a shell command,
+// and that it exists and is executable. Bare names are looked up in PATH.
+func validateBinary(key, path string) error {
+	if !shellSafePath.MatchString(path) {
+		return fmt.Errorf("invalid %s %q: only letters, digits, and ./_+- are allowed", key, path)
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		return fmt.Errorf("%s %q is not an executable: %w", key, path, err)
+	}
+	return nil
+}
+
 // validate checks if the bot configuration is valid.
 // It returns an error if the configuration is invalid, otherwise it returns nil.
 func (c *BotConfig) validate() error {
@@ -128,6 +271,72 @@ func (c *BotConfig) validate() error {
 		return fmt.Errorf("missing required config: %s", strings.Join(missing, ", "))
 	}
 
+	if c.DownloadRetries < 0 {
+		c.DownloadRetries = 0
+	}
+
+	if c.VideoMaxHeight <= 0 {
+		c.VideoMaxHeight = 1080
+	}
+
+	if c.ReconnectRetries < 1 {
+		c.ReconnectRetries = 1
+	}
+
+	if c.AudioSampleRate <= 0 {
+		c.AudioSampleRate = 96000
+	}
+
+	if c.EventLogKeep < 0 {
+		c.EventLogKeep = 0
+	}
+
+	if c.PodcastEpisodes < 1 {
+		c.PodcastEpisodes = 1
+	}
+
+	if c.MaxPlaylistTracks < 1 {
+		c.MaxPlaylistTracks = 1
+	}
+
+	switch c.IncomingCallMode {
+	case "off", "message":
+	case "play":
+		if err := validateIncomingCallMedia(c.IncomingCallMedia); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid INCOMING_CALL_MODE %q: expected off, message, or play", c.IncomingCallMode)
+	}
+
+	switch c.ShutdownDrain {
+	case "off", "notify", "finish":
+	default:
+		return fmt.Errorf("invalid SHUTDOWN_DRAIN %q: expected off, notify, or finish", c.ShutdownDrain)
+	}
+
+	if err := validateBinary("FFMPEG_PATH", c.FFmpegPath); err != nil {
+		return err
+	}
+	if err := validateBinary("FFPROBE_PATH", c.FFprobePath); err != nil {
+		return err
+	}
+	for _, arg := range c.FFmpegExtraArgs {
+		if !shellSafeArg.MatchString(arg) {
+			return fmt.Errorf("invalid FFMPEG_EXTRA_ARGS value %q: only flags and plain values are allowed", arg)
+		}
+	}
+
+	if err := validateDownloadStrategies(c.DownloadStrategies); err != nil {
+		return err
+	}
+
+	switch c.NetworkFamily {
+	case "auto", "ipv4", "ipv6":
+	default:
+		return fmt.Errorf("invalid NETWORK_FAMILY %q: expected auto, ipv4, or ipv6", c.NetworkFamily)
+	}
+
 	if len(c.SessionStrings) == 0 {
 		return fmt.Errorf("at least one session string (STRING1–10) is required")
 	}
@@ -135,6 +344,9 @@ func (c *BotConfig) validate() error {
 	if err := os.MkdirAll(c.DownloadsDir, 0750); err != nil {
 		return fmt.Errorf("failed to create downloads dir: %v", err)
 	}
+	if err := storagehealth.Probe(c.DownloadsDir); err != nil {
+		return fmt.Errorf("the downloads dir cannot be used: %v", err)
+	}
 
 	return nil
 }
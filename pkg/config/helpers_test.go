@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateBinary(t *testing.T) {
+	dir := t.TempDir()
+	notExecutable := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(notExecutable, []byte("#!/bin/sh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"sh", false},
+		{"/bin/sh", false},
+		{"no-such-binary-here", true},
+		{"/nonexistent/ffmpeg", true},
+		{notExecutable, true},
+		{"ffmpeg; rm -rf /", true},
+		{"$(id)", true},
+		{"/opt/my ffmpeg/ffmpeg", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if err := validateBinary("FFMPEG_PATH", tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("validateBinary(%q) = %v, want error %t", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShellSafeArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		{"-hwaccel", true},
+		{"vaapi", true},
+		{"-hwaccel_device", true},
+		{"/dev/dri/renderD128", true},
+		{"-init_hw_device", true},
+		{"vaapi=va:/dev/dri/renderD128", true},
+		{"-threads", true},
+		{"4", true},
+		{"a,b", true},
+		{"-vf;id", false},
+		{"$(id)", false},
+		{"`id`", false},
+		{"'quoted'", false},
+		{"a|b", false},
+		{"a&b", false},
+		{">out", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := shellSafeArg.MatchString(tt.arg); got != tt.want {
+			t.Errorf("shellSafeArg.MatchString(%q) = %t, want %t", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestGetDownloadStrategies(t *testing.T) {
+	for key := range strategyEnvKeys {
+		t.Setenv(key, "")
+	}
+	t.Setenv("YT_STRATEGY", "API, ytdlp")
+	t.Setenv("SPOTIFY_STRATEGY", "youtube-search cdn-direct")
+	t.Setenv("SOUNDCLOUD_STRATEGY", " , ")
+
+	want := map[string][]string{
+		"youtube":    {"api", "ytdlp"},
+		"spotify":    {"youtube-search", "cdn-direct"},
+		"soundcloud": {},
+	}
+	if got := getDownloadStrategies(); !reflect.DeepEqual(got, want) {
+		t.Errorf("getDownloadStrategies() = %v, want %v", got, want)
+	}
+}
+
+func TestValidateDownloadStrategies(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategies map[string][]string
+		wantErr    bool
+	}{
+		{"none set", map[string][]string{}, false},
+		{"known strategies", map[string][]string{"youtube": {"ytdlp", "api"}, "spotify": {"youtube-search"}}, false},
+		{"empty list", map[string][]string{"soundcloud": {}}, true},
+		{"unknown strategy", map[string][]string{"youtube": {"api", "torrent"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateDownloadStrategies(tt.strategies); (err != nil) != tt.wantErr {
+				t.Errorf("validateDownloadStrategies(%v) = %v, want error %t", tt.strategies, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseDevs(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		owner         int64
+		wantDevs      []int64
+		wantMalformed []string
+	}{
+		{"empty", "", 0, nil, nil},
+		{"owner only", "", 5, []int64{5}, nil},
+		{"owner listed", "5 6", 5, []int64{5, 6}, nil},
+		{"owner appended", "6 7", 5, []int64{6, 7, 5}, nil},
+		{"duplicates", "7 6 7  6\t7", 0, []int64{7, 6}, nil},
+		{"duplicate owner", "5 5", 5, []int64{5}, nil},
+		{"malformed", "6 abc 7,8 -9 0 1e3 7", 0, []int64{6, 7}, []string{"abc", "7,8", "-9", "0", "1e3"}},
+		{"out of range", "99999999999999999999 6", 0, []int64{6}, []string{"99999999999999999999"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devs, malformed := parseDevs(tt.value, tt.owner)
+			if !reflect.DeepEqual(devs, tt.wantDevs) || !reflect.DeepEqual(malformed, tt.wantMalformed) {
+				t.Errorf("parseDevs(%q, %d) = %v, %q, want %v, %q", tt.value, tt.owner, devs, malformed, tt.wantDevs, tt.wantMalformed)
+			}
+		})
+	}
+}
@@ -69,9 +69,11 @@ func saveContent(url, content string) (string, error) {
 	return filePath, nil
 }
 
-// saveAllCookies downloads all URLs and stores paths in Conf.CookiesPath.
+// saveCookies downloads all URLs and saves them to files.
 // It takes a slice of URLs as input.
-func saveAllCookies(urls []string) {
+// It returns the paths of the files that were saved successfully.
+func saveCookies(urls []string) []string {
+	var paths []string
 	for _, url := range urls {
 		content, err := fetchContent(url)
 		if err != nil {
@@ -85,6 +87,38 @@ func saveAllCookies(urls []string) {
 			continue
 		}
 
-		Conf.CookiesPath = append(Conf.CookiesPath, path)
+		paths = append(paths, path)
 	}
+	return paths
+}
+
+// saveAllCookies downloads the default and per-platform cookie URLs.
+// The default cookies are stored in Conf.CookiesPath, and per-platform cookies are keyed by platform.
+func saveAllCookies(urls []string, platformURLs map[string][]string) {
+	paths := saveCookies(urls)
+	Conf.cookiesMu.Lock()
+	Conf.CookiesPath = append(Conf.CookiesPath, paths...)
+	Conf.cookiesMu.Unlock()
+
+	for platform, pURLs := range platformURLs {
+		paths := saveCookies(pURLs)
+		Conf.cookiesMu.Lock()
+		Conf.platformCookies[platform] = append(Conf.platformCookies[platform], paths...)
+		Conf.cookiesMu.Unlock()
+	}
+}
+
+// CookieFiles returns the cookie file paths configured for a platform key such as "yt" or "sc".
+// YouTube falls back to the files from COOKIES_URL, and any other platform falls back to COOKIES_URL_GENERIC.
+func (c *BotConfig) CookieFiles(platform string) []string {
+	c.cookiesMu.RLock()
+	defer c.cookiesMu.RUnlock()
+
+	if files := c.platformCookies[platform]; len(files) > 0 {
+		return files
+	}
+	if platform == "yt" {
+		return c.CookiesPath
+	}
+	return c.platformCookies["generic"]
 }
@@ -13,7 +13,7 @@ import (
 )
 
 // CloseBtn is a button that closes the current view.
-var CloseBtn = telegram.Button.Data("Cʟᴏsᴇ", "vcplay_close")
+var CloseBtn = telegram.Button.Data("Cʟᴏsᴇ", mustEncodeCallback("vcplay", "close"))
 
 // HomeBtn is a button that returns to the home screen.
 var HomeBtn = telegram.Button.Data("Hᴏᴍᴇ", "help_back")
@@ -52,20 +52,20 @@ func SupportKeyboard() *telegram.ReplyInlineMarkup {
 }
 
 // SettingsKeyboard creates an inline keyboard for bot settings
-func SettingsKeyboard(playMode, adminMode string) *telegram.ReplyInlineMarkup {
+func SettingsKeyboard(playMode, adminMode, autoplay string) *telegram.ReplyInlineMarkup {
 	// Helper function to create a button with a checkmark if active
 	createButton := func(label, settingType, settingValue, currentValue string) *telegram.KeyboardButtonCallback {
 		text := label
 		if settingValue == currentValue {
 			text += " ✅"
 		}
-		return telegram.Button.Data(text, fmt.Sprintf("settings_%s_%s", settingType, settingValue))
+		return telegram.Button.Data(text, mustEncodeCallback("settings", settingType, settingValue))
 	}
 
 	keyboard := telegram.NewKeyboard()
 
 	// Play Mode Section
-	keyboard.AddRow(telegram.Button.Data("🎵 Play Mode", "settings_xxx_noop"))
+	keyboard.AddRow(telegram.Button.Data("🎵 Play Mode", mustEncodeCallback("settings", "none")))
 	keyboard.AddRow(
 		createButton("Admins", "play", cache.Admins, playMode),
 		createButton("Auth", "play", cache.Auth, playMode),
@@ -73,13 +73,20 @@ func SettingsKeyboard(playMode, adminMode string) *telegram.ReplyInlineMarkup {
 	)
 
 	// Admin Mode Section
-	keyboard.AddRow(telegram.Button.Data("🛡️ Admin Mode", "settings_xxx_none"))
+	keyboard.AddRow(telegram.Button.Data("🛡️ Admin Mode", mustEncodeCallback("settings", "none")))
 	keyboard.AddRow(
 		createButton("Admins", "admin", cache.Admins, adminMode),
 		createButton("Auth", "admin", cache.Auth, adminMode),
 		createButton("Everyone", "admin", cache.Everyone, adminMode),
 	)
 
+	// Autoplay Section
+	keyboard.AddRow(telegram.Button.Data("📻 Autoplay", mustEncodeCallback("settings", "none")))
+	keyboard.AddRow(
+		createButton("On", "autoplay", "on", autoplay),
+		createButton("Off", "autoplay", "off", autoplay),
+	)
+
 	// Close button
 	keyboard.AddRow(CloseBtn)
 
@@ -108,12 +115,12 @@ func BackHelpMenuKeyboard() *telegram.ReplyInlineMarkup {
 // ControlButtons creates and returns an inline keyboard with playback control buttons, customized based on the current mode.
 // The 'mode' parameter can be "play", "pause", "resume", "mute", or "unmute" to display the relevant controls.
 func ControlButtons(mode string) *telegram.ReplyInlineMarkup {
-	skipBtn := telegram.Button.Data("‣‣I", "play_skip")
-	stopBtn := telegram.Button.Data("▢", "play_stop")
-	pauseBtn := telegram.Button.Data("II", "play_pause")
-	resumeBtn := telegram.Button.Data("▷", "play_resume")
-	muteBtn := telegram.Button.Data("🔇", "play_mute")
-	unmuteBtn := telegram.Button.Data("🔊", "play_unmute")
+	skipBtn := telegram.Button.Data("‣‣I", mustEncodeCallback("play", "skip"))
+	stopBtn := telegram.Button.Data("▢", mustEncodeCallback("play", "stop"))
+	pauseBtn := telegram.Button.Data("II", mustEncodeCallback("play", "pause"))
+	resumeBtn := telegram.Button.Data("▷", mustEncodeCallback("play", "resume"))
+	muteBtn := telegram.Button.Data("🔇", mustEncodeCallback("play", "mute"))
+	unmuteBtn := telegram.Button.Data("🔊", mustEncodeCallback("play", "unmute"))
 
 	var keyboard *telegram.KeyboardBuilder
 
@@ -135,17 +142,27 @@ func ControlButtons(mode string) *telegram.ReplyInlineMarkup {
 	return keyboard.Build()
 }
 
+// AudioOnlyButtons creates and returns an inline keyboard offered when a track cannot get a video slot,
+// with buttons to play it as audio only or to skip it.
+func AudioOnlyButtons() *telegram.ReplyInlineMarkup {
+	keyboard := telegram.NewKeyboard().
+		AddRow(telegram.Button.Data("🎧 Audio only", mustEncodeCallback("play", "audioonly")), telegram.Button.Data("‣‣I", mustEncodeCallback("play", "skip"))).
+		AddRow(CloseBtn)
+
+	return keyboard.Build()
+}
+
 func LanguageKeyboard() *telegram.ReplyInlineMarkup {
 	keyboard := telegram.NewKeyboard()
 	langs := lang.GetAvailableLangs()
 	for i := 0; i < len(langs); i += 2 {
 		if i+1 < len(langs) {
 			keyboard.AddRow(
-				telegram.Button.Data(lang.GetLangDisplayName(langs[i]), fmt.Sprintf("setlang_%s", langs[i])),
-				telegram.Button.Data(lang.GetLangDisplayName(langs[i+1]), fmt.Sprintf("setlang_%s", langs[i+1])),
+				telegram.Button.Data(lang.GetLangDisplayName(langs[i]), mustEncodeCallback("setlang", langs[i])),
+				telegram.Button.Data(lang.GetLangDisplayName(langs[i+1]), mustEncodeCallback("setlang", langs[i+1])),
 			)
 		} else {
-			keyboard.AddRow(telegram.Button.Data(lang.GetLangDisplayName(langs[i]), fmt.Sprintf("setlang_%s", langs[i])))
+			keyboard.AddRow(telegram.Button.Data(lang.GetLangDisplayName(langs[i]), mustEncodeCallback("setlang", langs[i])))
 		}
 	}
 	keyboard.AddRow(CloseBtn)
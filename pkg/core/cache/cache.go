@@ -7,8 +7,8 @@ import (
 
 // CacheItem represents an item stored in the cache, containing a value and its expiration time.
 type CacheItem[T any] struct {
-	Value      T
-	Expiration time.Time
+	Value      T         `json:"v"`
+	Expiration time.Time `json:"exp"`
 }
 
 // Cache is a generic, thread-safe TTL cache that stores values with string keys.
@@ -1,27 +1,41 @@
 package cache
 
 import (
+	"github.com/zuchzub/Go/pkg/core/eventlog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"sync"
 )
 
 // ChatData holds the state of a chat's music queue, including whether it is active and the list of tracks.
 type ChatData struct {
-	IsActive bool
-	Queue    []*CachedTrack
+	IsActive        bool
+	Queue           []*CachedTrack
+	NowPlayingMsgID int32
+	JoinedAs        int64 // JoinedAs is the ID of the assistant known to be in the chat for this session, or 0.
+	LoopQueue       bool  // LoopQueue is true if the tracks played are queued again once the queue ends.
+	looped          []CachedTrack
+	skipVotes       map[string]map[int64]struct{} // skipVotes holds the users who voted to skip the current track, by track ID.
 }
 
 // ChatCacher is a thread-safe cache that manages music queues for multiple chats.
 type ChatCacher struct {
 	mu        sync.RWMutex
 	chatCache map[int64]*ChatData
+	locks     map[int64]*QueueLock
+	undo      map[int64]undoSnapshot
+	dirty     map[int64]struct{} // dirty holds the chats whose queue changed since it was last persisted.
 }
 
 // NewChatCacher initializes and returns a new ChatCacher.
 func NewChatCacher() *ChatCacher {
 	return &ChatCacher{
 		chatCache: make(map[int64]*ChatData),
+		locks:     make(map[int64]*QueueLock),
+		undo:      make(map[int64]undoSnapshot),
+		dirty:     make(map[int64]struct{}),
 	}
 }
 
@@ -38,9 +52,37 @@ func (c *ChatCacher) AddSong(chatID int64, song *CachedTrack) *CachedTrack {
 	}
 
 	data.Queue = append(data.Queue, song)
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "add", song.TrackID, strconv.Itoa(len(data.Queue)))
+	RecordQueueStat(chatID, QueueEnqueued, 1)
 	return song
 }
 
+// StartIfIdle atomically marks an idle chat as active and makes song its current track.
+// It returns false and leaves the queue untouched if the chat is already active, so that of several concurrent
+// requests only one starts playback.
+func (c *ChatCacher) StartIfIdle(chatID int64, song *CachedTrack) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if ok && data.IsActive {
+		return false
+	}
+	if !ok {
+		data = &ChatData{}
+		c.chatCache[chatID] = data
+	}
+
+	data.IsActive = true
+	data.Queue = append(data.Queue, song)
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "set_active", "", "true")
+	eventlog.Emit(chatID, "add", song.TrackID, strconv.Itoa(len(data.Queue)))
+	RecordQueueStat(chatID, QueueEnqueued, 1)
+	return true
+}
+
 // GetUpcomingTrack retrieves the next song in the queue for a given chat.
 // It returns the upcoming track or nil if the queue is empty or has only one song.
 func (c *ChatCacher) GetUpcomingTrack(chatID int64) *CachedTrack {
@@ -81,6 +123,10 @@ func (c *ChatCacher) RemoveCurrentSong(chatID int64, diskClear bool) *CachedTrac
 
 	removed := data.Queue[0]
 	data.Queue = data.Queue[1:]
+	c.dirty[chatID] = struct{}{}
+	c.keepLooped(data, removed)
+	data.skipVotes = nil
+	eventlog.Emit(chatID, "remove_current", removed.TrackID, strconv.Itoa(len(data.Queue)))
 
 	if diskClear && removed.FilePath != "" {
 		_ = os.Remove(removed.FilePath)
@@ -111,13 +157,16 @@ func (c *ChatCacher) SetActive(chatID int64, active bool) {
 		c.chatCache[chatID] = data
 	}
 	data.IsActive = active
+	eventlog.Emit(chatID, "set_active", "", strconv.FormatBool(active))
 }
 
 // ClearChat removes all tracks from a chat's queue and optionally deletes the files from disk.
+// The session is over, so it also lifts any lock on the queue.
 func (c *ChatCacher) ClearChat(chatID int64, diskClear bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.stopLock(chatID)
 	data, ok := c.chatCache[chatID]
 	if !ok {
 		return
@@ -131,6 +180,111 @@ func (c *ChatCacher) ClearChat(chatID int64, diskClear bool) {
 		}
 	}
 	delete(c.chatCache, chatID)
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "clear", "", strconv.Itoa(len(data.Queue)))
+}
+
+// ClearPending removes every track of a chat's queue except the current one, deleting their downloaded files.
+// A file still used by the current track, which was queued more than once, is kept.
+// It returns the number of tracks removed.
+func (c *ChatCacher) ClearPending(chatID int64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || len(data.Queue) < 2 {
+		return 0
+	}
+
+	current := data.Queue[0]
+	pending := data.Queue[1:]
+	for _, track := range pending {
+		if track.FilePath != "" && track.FilePath != current.FilePath {
+			_ = os.Remove(track.FilePath)
+		}
+	}
+	data.Queue = []*CachedTrack{current}
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "clear_pending", current.TrackID, strconv.Itoa(len(pending)))
+	return len(pending)
+}
+
+// DropUpTo removes the upcoming tracks before position n of a chat's queue, so that the track at position n is the
+// next one; position 0 is the current track, which is kept. The files of the removed tracks are deleted unless a
+// track left in the queue uses them. It returns the number of tracks removed, or -1 if n is not an upcoming position.
+func (c *ChatCacher) DropUpTo(chatID int64, n int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || n < 1 || n >= len(data.Queue) {
+		return -1
+	}
+
+	dropped := slices.Clone(data.Queue[1:n])
+	data.Queue = slices.Delete(data.Queue, 1, n)
+	for _, track := range dropped {
+		if track.FilePath == "" {
+			continue
+		}
+		inUse := slices.ContainsFunc(data.Queue, func(t *CachedTrack) bool { return t.FilePath == track.FilePath })
+		if !inUse {
+			_ = os.Remove(track.FilePath)
+		}
+	}
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "drop_up_to", data.Queue[0].TrackID, strconv.Itoa(len(dropped)))
+	return len(dropped)
+}
+
+// SetNowPlayingMessage records the ID of the message showing the current track for a chat.
+func (c *ChatCacher) SetNowPlayingMessage(chatID int64, msgID int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok {
+		return
+	}
+	data.NowPlayingMsgID = msgID
+}
+
+// GetNowPlayingMessage returns the ID of the message showing the current track for a chat.
+// It returns 0 if no such message has been recorded.
+func (c *ChatCacher) GetNowPlayingMessage(chatID int64) int32 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok {
+		return 0
+	}
+	return data.NowPlayingMsgID
+}
+
+// SetJoinedAs records that an assistant is in a chat, so that it is not checked again for every track of the session.
+// An ID of 0 clears the record. It has no effect on a chat without a queue.
+func (c *ChatCacher) SetJoinedAs(chatID, assistantID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok {
+		return
+	}
+	data.JoinedAs = assistantID
+}
+
+// GetJoinedAs returns the ID of the assistant recorded as being in a chat, or 0 if none is.
+func (c *ChatCacher) GetJoinedAs(chatID int64) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok {
+		return 0
+	}
+	return data.JoinedAs
 }
 
 // GetQueueLength returns the total number of songs in a chat's queue.
@@ -168,9 +322,79 @@ func (c *ChatCacher) SetLoopCount(chatID int64, loop int) bool {
 		return false
 	}
 	data.Queue[0].Loop = loop
+	eventlog.Emit(chatID, "loop", data.Queue[0].TrackID, strconv.Itoa(loop))
 	return true
 }
 
+// SetStartAt sets the offset in seconds that a queued track starts playing from.
+// It returns true if the offset was set, otherwise false. The current track (index 0) cannot be changed.
+func (c *ChatCacher) SetStartAt(chatID int64, index, startAt int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || index <= 0 || index >= len(data.Queue) {
+		return false
+	}
+	data.Queue[index].StartAt = startAt
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "start_at", data.Queue[index].TrackID, strconv.Itoa(startAt))
+	return true
+}
+
+// RecordFailure notes on a queued track that its download failed, so the queue view can show it.
+// The track is matched by identity; it returns false if the track is no longer in the chat's queue.
+func (c *ChatCacher) RecordFailure(chatID int64, track *CachedTrack, reason error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || !slices.Contains(data.Queue, track) {
+		return false
+	}
+	track.FailCount++
+	track.LastError = reason.Error()
+	eventlog.Emit(chatID, "track_failed", track.TrackID, strconv.Itoa(track.FailCount))
+	RecordQueueStat(chatID, QueueFailed, 1)
+	return true
+}
+
+// SetDownload records the file a queued track was downloaded to, and what the download learned about it, and clears
+// its failure notes. The track is matched by identity; it returns false, leaving the track untouched, if the track is
+// no longer in the chat's queue, for instance because it was removed while a prefetch downloaded it.
+func (c *ChatCacher) SetDownload(chatID int64, track *CachedTrack, filePath string, info *TrackInfo) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || !slices.Contains(data.Queue, track) {
+		return false
+	}
+	track.FilePath = filePath
+	if info != nil && info.Duration > 0 {
+		track.Duration = info.Duration
+	}
+	if info != nil && info.IsLive {
+		track.IsLive = true
+		track.Duration = 0
+	}
+	if info != nil && info.Lyrics != "" {
+		track.Lyrics = info.Lyrics
+	}
+	track.FailCount = 0
+	track.LastError = ""
+	c.dirty[chatID] = struct{}{}
+	return true
+}
+
+// TrackState returns a copy of a track taken under the lock, so that fields written by SetDownload or RecordFailure
+// from another goroutine can be read safely.
+func (c *ChatCacher) TrackState(track *CachedTrack) CachedTrack {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return *track
+}
+
 // RemoveTrack removes a specific song from the queue by its index.
 // It returns true if the track was successfully removed, otherwise false.
 func (c *ChatCacher) RemoveTrack(chatID int64, index int) bool {
@@ -182,10 +406,66 @@ func (c *ChatCacher) RemoveTrack(chatID int64, index int) bool {
 		return false
 	}
 
+	removed := data.Queue[index]
 	data.Queue = append(data.Queue[:index], data.Queue[index+1:]...)
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "remove", removed.TrackID, strconv.Itoa(index))
+	return true
+}
+
+// MoveTrack moves the track at index from to index to, shifting the tracks in between.
+// It returns false if either index is out of range or is 0, as the current track cannot be moved.
+func (c *ChatCacher) MoveTrack(chatID int64, from, to int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || from <= 0 || to <= 0 || from >= len(data.Queue) || to >= len(data.Queue) {
+		return false
+	}
+	if from == to {
+		return true
+	}
+
+	track := data.Queue[from]
+	data.Queue = slices.Delete(data.Queue, from, from+1)
+	data.Queue = slices.Insert(data.Queue, to, track)
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "move", track.TrackID, strconv.Itoa(from)+">"+strconv.Itoa(to))
 	return true
 }
 
+// RemoveTracks removes the given tracks from a chat's upcoming queue, leaving the current track in place.
+// Tracks are matched by identity, so the removal is safe even if the queue changed since they were read.
+// It returns the number of tracks removed.
+func (c *ChatCacher) RemoveTracks(chatID int64, tracks []*CachedTrack) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || len(data.Queue) < 2 {
+		return 0
+	}
+
+	drop := make(map[*CachedTrack]struct{}, len(tracks))
+	for _, track := range tracks {
+		drop[track] = struct{}{}
+	}
+
+	kept := data.Queue[:1]
+	for _, track := range data.Queue[1:] {
+		if _, ok := drop[track]; !ok {
+			kept = append(kept, track)
+		}
+	}
+
+	removed := len(data.Queue) - len(kept)
+	data.Queue = kept
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "remove_many", "", strconv.Itoa(removed))
+	return removed
+}
+
 // GetQueue returns a copy of the current song queue for a chat.
 func (c *ChatCacher) GetQueue(chatID int64) []*CachedTrack {
 	c.mu.RLock()
@@ -198,6 +478,35 @@ func (c *ChatCacher) GetQueue(chatID int64) []*CachedTrack {
 	return append([]*CachedTrack(nil), data.Queue...)
 }
 
+// TakeChangedQueues returns the queues of the chats that changed since the last call, so that they can be persisted.
+// A chat whose queue was cleared is returned with an empty queue. The tracks are copies, so that they can be read while
+// downloads update the queued ones.
+func (c *ChatCacher) TakeChangedQueues() map[int64][]*CachedTrack {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := make(map[int64][]*CachedTrack, len(c.dirty))
+	for chatID := range c.dirty {
+		var queue []*CachedTrack
+		if data, ok := c.chatCache[chatID]; ok {
+			for _, track := range data.Queue {
+				copied := *track
+				queue = append(queue, &copied)
+			}
+		}
+		changed[chatID] = queue
+	}
+	clear(c.dirty)
+	return changed
+}
+
+// MarkChanged marks a chat's queue as changed, so that it is persisted again; it is used when persisting it failed.
+func (c *ChatCacher) MarkChanged(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dirty[chatID] = struct{}{}
+}
+
 // GetActiveChats returns a list of all chat IDs where the music player is currently active.
 func (c *ChatCacher) GetActiveChats() []int64 {
 	c.mu.RLock()
@@ -212,6 +521,25 @@ func (c *ChatCacher) GetActiveChats() []int64 {
 	return active
 }
 
+// GetLastTrackRequestedBy returns the upcoming track most recently queued by a user, along with its queue index.
+// It returns nil and -1 if the user has no upcoming track in the chat's queue.
+func (c *ChatCacher) GetLastTrackRequestedBy(chatID, userID int64) (*CachedTrack, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok {
+		return nil, -1
+	}
+
+	for i := len(data.Queue) - 1; i > 0; i-- {
+		if data.Queue[i].UserID == userID {
+			return data.Queue[i], i
+		}
+	}
+	return nil, -1
+}
+
 // GetTrackIfExists searches for a track in the queue by its ID and returns it if found.
 // It returns the track or nil if it does not exist in the queue.
 func (c *ChatCacher) GetTrackIfExists(chatID int64, trackID string) *CachedTrack {
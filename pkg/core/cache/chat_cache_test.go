@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// newTestQueue returns a chat cache holding the given tracks in the queue of chatID.
+func newTestQueue(chatID int64, tracks ...*CachedTrack) *ChatCacher {
+	c := NewChatCacher()
+	for _, track := range tracks {
+		c.AddSong(chatID, track)
+	}
+	return c
+}
+
+func TestSetDownload(t *testing.T) {
+	current, next := &CachedTrack{TrackID: "a"}, &CachedTrack{TrackID: "b", FailCount: 1, LastError: "timeout"}
+	c := newTestQueue(1, current, next)
+
+	info := &TrackInfo{Duration: 200, Lyrics: "la la"}
+	if !c.SetDownload(1, next, "/tmp/b.mp3", info) {
+		t.Fatal("SetDownload() = false for a queued track")
+	}
+	got := c.TrackState(next)
+	if got.FilePath != "/tmp/b.mp3" || got.Duration != 200 || got.Lyrics != "la la" {
+		t.Errorf("SetDownload() left %+v", got)
+	}
+	if got.FailCount != 0 || got.LastError != "" {
+		t.Errorf("SetDownload() kept the failure notes: %d, %q", got.FailCount, got.LastError)
+	}
+
+	c.SetDownload(1, next, "/tmp/live", &TrackInfo{IsLive: true, Duration: 50})
+	if got := c.TrackState(next); !got.IsLive || got.Duration != 0 {
+		t.Errorf("SetDownload() of a livestream left IsLive %t, Duration %d", got.IsLive, got.Duration)
+	}
+}
+
+func TestSetDownloadOfDequeuedTrack(t *testing.T) {
+	current, next := &CachedTrack{TrackID: "a"}, &CachedTrack{TrackID: "b"}
+	c := newTestQueue(1, current, next)
+	c.RemoveTrack(1, 1)
+
+	if c.SetDownload(1, next, "/tmp/b.mp3", nil) {
+		t.Error("SetDownload() = true for a track that left the queue")
+	}
+	if next.FilePath != "" {
+		t.Errorf("SetDownload() wrote %q to a track that left the queue", next.FilePath)
+	}
+	if c.SetDownload(2, current, "/tmp/a.mp3", nil) {
+		t.Error("SetDownload() = true for a track of another chat")
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	track := &CachedTrack{TrackID: "a"}
+	c := newTestQueue(1, track)
+
+	c.RecordFailure(1, track, errors.New("first"))
+	c.RecordFailure(1, track, errors.New("second"))
+	if got := c.TrackState(track); got.FailCount != 2 || got.LastError != "second" {
+		t.Errorf("after two failures: FailCount %d, LastError %q", got.FailCount, got.LastError)
+	}
+	if c.RecordFailure(1, &CachedTrack{TrackID: "a"}, errors.New("x")) {
+		t.Error("RecordFailure() = true for an equal track that is not queued")
+	}
+}
+
+func TestTakeChangedQueuesCopiesTracks(t *testing.T) {
+	track := &CachedTrack{TrackID: "a"}
+	c := newTestQueue(1, track)
+
+	changed := c.TakeChangedQueues()
+	if len(changed[1]) != 1 || changed[1][0] == track {
+		t.Fatalf("TakeChangedQueues() = %v, want a copy of the queued track", changed[1])
+	}
+	c.SetDownload(1, track, "/tmp/a.mp3", nil)
+	if changed[1][0].FilePath != "" {
+		t.Error("a download changed the copy returned by TakeChangedQueues()")
+	}
+	if again := c.TakeChangedQueues(); len(again[1]) != 1 || again[1][0].FilePath != "/tmp/a.mp3" {
+		t.Errorf("SetDownload() did not mark the queue as changed: %v", again)
+	}
+}
+
+// TestSetDownloadConcurrentReads is meant for the race detector: downloads update a track while the queue is
+// persisted and snapshotted.
+func TestSetDownloadConcurrentReads(t *testing.T) {
+	track := &CachedTrack{TrackID: "a"}
+	c := newTestQueue(1, &CachedTrack{TrackID: "current"}, track)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			c.SetDownload(1, track, "/tmp/a.mp3", &TrackInfo{Duration: 10})
+			c.RecordFailure(1, track, errors.New("failed"))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			for _, queue := range c.TakeChangedQueues() {
+				for _, t := range queue {
+					_ = t.FilePath + t.LastError
+				}
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			c.SnapshotQueue(1)
+			_ = c.TrackState(track).FailCount
+		}
+	}()
+	wg.Wait()
+}
+
+func TestSetStartAt(t *testing.T) {
+	current, next := &CachedTrack{TrackID: "a"}, &CachedTrack{TrackID: "b"}
+	c := newTestQueue(1, current, next)
+
+	if !c.SetStartAt(1, 1, 90) || next.StartAt != 90 {
+		t.Errorf("SetStartAt() of a pending track left StartAt %d, want 90", next.StartAt)
+	}
+	if !c.SetStartAt(1, 1, 0) || next.StartAt != 0 {
+		t.Errorf("SetStartAt(0) left StartAt %d, want it cleared", next.StartAt)
+	}
+	for _, index := range []int{0, 2, -1} {
+		if c.SetStartAt(1, index, 30) {
+			t.Errorf("SetStartAt() at index %d = true", index)
+		}
+	}
+	if current.StartAt != 0 {
+		t.Errorf("SetStartAt() changed the current track to %d", current.StartAt)
+	}
+	if c.SetStartAt(2, 1, 30) {
+		t.Error("SetStartAt() in an unknown chat = true")
+	}
+}
+
+func TestMoveTrack(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+		wantOK   bool
+		want     []string
+	}{
+		{"to the front", 3, 1, true, []string{"a", "d", "b", "c"}},
+		{"to the back", 1, 3, true, []string{"a", "c", "d", "b"}},
+		{"same position", 2, 2, true, []string{"a", "b", "c", "d"}},
+		{"the current track", 0, 2, false, []string{"a", "b", "c", "d"}},
+		{"before the current track", 2, 0, false, []string{"a", "b", "c", "d"}},
+		{"past the end", 1, 4, false, []string{"a", "b", "c", "d"}},
+		{"from past the end", 4, 1, false, []string{"a", "b", "c", "d"}},
+		{"negative", -1, 1, false, []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestQueue(1, &CachedTrack{TrackID: "a"}, &CachedTrack{TrackID: "b"}, &CachedTrack{TrackID: "c"}, &CachedTrack{TrackID: "d"})
+			if ok := c.MoveTrack(1, tt.from, tt.to); ok != tt.wantOK {
+				t.Errorf("MoveTrack(%d, %d) = %t, want %t", tt.from, tt.to, ok, tt.wantOK)
+			}
+			if got := queueIDs(c, 1); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if NewChatCacher().MoveTrack(2, 1, 2) {
+		t.Error("MoveTrack() in a chat without a queue = true")
+	}
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"cmp"
+	"github.com/zuchzub/Go/pkg/config"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CommandStat holds the counters of a command since the bot started.
+type CommandStat struct {
+	Command     string
+	Invocations int64         // Invocations counts the runs of the command's handler.
+	Errors      int64         // Errors counts the runs that returned an error.
+	Rejected    int64         // Rejected counts the uses refused by the command's permission check, which are not runs.
+	Latency     time.Duration // Latency is the total time the runs took.
+	LastError   string
+	LastErrorAt time.Time // LastErrorAt is when LastError was returned, or zero if the command never failed.
+}
+
+// AverageLatency returns the average time a run of the command took.
+func (s CommandStat) AverageLatency() time.Duration {
+	if s.Invocations == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Invocations)
+}
+
+// ErrorRate returns the share of the runs that returned an error, from 0 to 1.
+func (s CommandStat) ErrorRate() float64 {
+	if s.Invocations == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Invocations)
+}
+
+// commandCounters are the in-memory counters of a command. The counts are atomics, so recording a run never waits;
+// only the last error, a string and a time, needs the mutex.
+type commandCounters struct {
+	invocations atomic.Int64
+	errors      atomic.Int64
+	rejected    atomic.Int64
+	latency     atomic.Int64 // latency is the total time of the runs in nanoseconds.
+
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// commandDayStat is a count kept per command and day for the stats collection.
+type commandDayStat int
+
+const (
+	commandDayInvocations commandDayStat = iota
+	commandDayErrors
+	commandDayRejected
+
+	commandDayStatCount
+)
+
+// commandDayStatNames are the names of the daily counts, used as field names in the database.
+var commandDayStatNames = [commandDayStatCount]string{"invocations", "errors", "rejected"}
+
+// CommandDayStatNames returns the names of the counts of a CommandStatsDelta, in order.
+func CommandDayStatNames() []string {
+	return commandDayStatNames[:]
+}
+
+// CommandStatsDelta holds the counts of a command for a day that are not yet written to the database, in the order of
+// CommandDayStatNames.
+type CommandStatsDelta struct {
+	Command string
+	Day     string
+	Counts  [commandDayStatCount]int64
+}
+
+// commandDayKey identifies the daily counters of a command.
+type commandDayKey struct {
+	command string
+	day     string
+}
+
+var (
+	// commandStats maps a command to its *commandCounters.
+	commandStats sync.Map
+	// commandDays maps a commandDayKey to its *[commandDayStatCount]atomic.Int64, the counts not yet flushed.
+	commandDays sync.Map
+)
+
+// commandCountersFor returns the counters of a command, creating them if needed.
+func commandCountersFor(command string) *commandCounters {
+	if v, ok := commandStats.Load(command); ok {
+		return v.(*commandCounters)
+	}
+	v, _ := commandStats.LoadOrStore(command, new(commandCounters))
+	return v.(*commandCounters)
+}
+
+// commandDayCounters returns the daily counters of a command, creating them if needed.
+func commandDayCounters(command, day string) *[commandDayStatCount]atomic.Int64 {
+	key := commandDayKey{command: command, day: day}
+	if v, ok := commandDays.Load(key); ok {
+		return v.(*[commandDayStatCount]atomic.Int64)
+	}
+	v, _ := commandDays.LoadOrStore(key, new([commandDayStatCount]atomic.Int64))
+	return v.(*[commandDayStatCount]atomic.Int64)
+}
+
+// RecordCommand records a run of a command's handler that took latency and returned err.
+func RecordCommand(command string, latency time.Duration, err error) {
+	c := commandCountersFor(command)
+	c.invocations.Add(1)
+	c.latency.Add(int64(latency))
+	recordCommandDay(command, commandDayInvocations)
+	if err == nil {
+		return
+	}
+
+	c.errors.Add(1)
+	recordCommandDay(command, commandDayErrors)
+	c.mu.Lock()
+	c.lastError = err.Error()
+	c.lastErrorAt = time.Now()
+	c.mu.Unlock()
+}
+
+// RecordCommandRejected records a use of a command refused by its permission check.
+func RecordCommandRejected(command string) {
+	commandCountersFor(command).rejected.Add(1)
+	recordCommandDay(command, commandDayRejected)
+}
+
+// recordCommandDay adds one to today's count of stat for a command. Daily counts are only kept when they are flushed
+// to the database, as nothing else reads them.
+func recordCommandDay(command string, stat commandDayStat) {
+	if config.Conf == nil || !config.Conf.CommandStatsFlush {
+		return
+	}
+	commandDayCounters(command, StatsDay(time.Now()))[stat].Add(1)
+}
+
+// CommandStats returns the counters of every command used since the bot started, the most used first.
+func CommandStats() []CommandStat {
+	var stats []CommandStat
+	commandStats.Range(func(k, v any) bool {
+		c := v.(*commandCounters)
+		stat := CommandStat{
+			Command:     k.(string),
+			Invocations: c.invocations.Load(),
+			Errors:      c.errors.Load(),
+			Rejected:    c.rejected.Load(),
+			Latency:     time.Duration(c.latency.Load()),
+		}
+		c.mu.Lock()
+		stat.LastError, stat.LastErrorAt = c.lastError, c.lastErrorAt
+		c.mu.Unlock()
+		stats = append(stats, stat)
+		return true
+	})
+
+	slices.SortFunc(stats, func(a, b CommandStat) int {
+		if n := cmp.Compare(b.Invocations+b.Rejected, a.Invocations+a.Rejected); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Command, b.Command)
+	})
+	return stats
+}
+
+// CommandTotals returns the counters of all commands added up. The last error is the most recent of any command.
+func CommandTotals() CommandStat {
+	var total CommandStat
+	for _, stat := range CommandStats() {
+		total.Invocations += stat.Invocations
+		total.Errors += stat.Errors
+		total.Rejected += stat.Rejected
+		total.Latency += stat.Latency
+		if stat.LastErrorAt.After(total.LastErrorAt) {
+			total.LastError, total.LastErrorAt = stat.Command+": "+stat.LastError, stat.LastErrorAt
+		}
+	}
+	return total
+}
+
+// TakeCommandStats returns the daily counts recorded since the last call and resets them.
+// Counters of past days are dropped once taken.
+func TakeCommandStats() []CommandStatsDelta {
+	today := StatsDay(time.Now())
+	var deltas []CommandStatsDelta
+	commandDays.Range(func(k, v any) bool {
+		key := k.(commandDayKey)
+		if key.day != today {
+			commandDays.Delete(key)
+		}
+
+		delta := CommandStatsDelta{Command: key.command, Day: key.day}
+		empty := true
+		for i := range v.(*[commandDayStatCount]atomic.Int64) {
+			delta.Counts[i] = v.(*[commandDayStatCount]atomic.Int64)[i].Swap(0)
+			empty = empty && delta.Counts[i] == 0
+		}
+		if !empty {
+			deltas = append(deltas, delta)
+		}
+		return true
+	})
+	return deltas
+}
+
+// RestoreCommandStats puts back counts returned by TakeCommandStats that could not be written, so the next flush
+// retries them.
+func RestoreCommandStats(delta CommandStatsDelta) {
+	c := commandDayCounters(delta.Command, delta.Day)
+	for i, n := range delta.Counts {
+		c[i].Add(n)
+	}
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"errors"
+	"github.com/zuchzub/Go/pkg/config"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// resetCommandStats forgets every recorded command and enables the daily flush, restoring both after the test.
+func resetCommandStats(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		commandStats.Range(func(k, _ any) bool { commandStats.Delete(k); return true })
+		commandDays.Range(func(k, _ any) bool { commandDays.Delete(k); return true })
+	}
+	saved := config.Conf
+	config.Conf = &config.BotConfig{CommandStatsFlush: true}
+	reset()
+	t.Cleanup(func() {
+		config.Conf = saved
+		reset()
+	})
+}
+
+func TestCommandStats(t *testing.T) {
+	resetCommandStats(t)
+	RecordCommand("play", 30*time.Millisecond, nil)
+	RecordCommand("play", 10*time.Millisecond, errors.New("no results"))
+	RecordCommand("play", 20*time.Millisecond, nil)
+	RecordCommand("skip", time.Millisecond, nil)
+	RecordCommandRejected("skip")
+	RecordCommandRejected("skip")
+	RecordCommandRejected("auth")
+
+	stats := CommandStats()
+	var order []string
+	for _, s := range stats {
+		order = append(order, s.Command)
+	}
+	if want := []string{"play", "skip", "auth"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("CommandStats() order = %v, want %v", order, want)
+	}
+
+	play := stats[0]
+	if play.Invocations != 3 || play.Errors != 1 || play.Rejected != 0 {
+		t.Errorf("play = %d runs, %d errors, %d rejected, want 3, 1, 0", play.Invocations, play.Errors, play.Rejected)
+	}
+	if play.AverageLatency() != 20*time.Millisecond {
+		t.Errorf("AverageLatency() = %v, want 20ms", play.AverageLatency())
+	}
+	if rate := play.ErrorRate(); rate < 0.33 || rate > 0.34 {
+		t.Errorf("ErrorRate() = %v, want 1/3", rate)
+	}
+	if play.LastError != "no results" {
+		t.Errorf("LastError = %q, want no results", play.LastError)
+	}
+
+	auth := stats[2]
+	if auth.Invocations != 0 || auth.Rejected != 1 || auth.ErrorRate() != 0 || auth.AverageLatency() != 0 {
+		t.Errorf("auth = %+v, want only one rejection", auth)
+	}
+
+	total := CommandTotals()
+	if total.Invocations != 4 || total.Errors != 1 || total.Rejected != 3 || total.LastError != "play: no results" {
+		t.Errorf("CommandTotals() = %+v, want 4 runs, 1 error, 3 rejected, last error of play", total)
+	}
+}
+
+func TestTakeCommandStats(t *testing.T) {
+	resetCommandStats(t)
+	RecordCommand("play", time.Millisecond, nil)
+	RecordCommand("play", time.Millisecond, errors.New("failed"))
+	RecordCommandRejected("play")
+
+	today := StatsDay(time.Now())
+	deltas := TakeCommandStats()
+	want := []CommandStatsDelta{{Command: "play", Day: today, Counts: [commandDayStatCount]int64{2, 1, 1}}}
+	if !reflect.DeepEqual(deltas, want) {
+		t.Fatalf("TakeCommandStats() = %+v, want %+v", deltas, want)
+	}
+	if again := TakeCommandStats(); len(again) != 0 {
+		t.Errorf("TakeCommandStats() again = %+v, want nothing new", again)
+	}
+
+	// A failed write puts the counts back for the next flush, added to what was recorded since.
+	RestoreCommandStats(deltas[0])
+	RecordCommand("play", time.Millisecond, nil)
+	want[0].Counts = [commandDayStatCount]int64{3, 1, 1}
+	if got := TakeCommandStats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("TakeCommandStats() after a restore = %+v, want %+v", got, want)
+	}
+}
+
+func TestTakeCommandStatsDropsPastDays(t *testing.T) {
+	resetCommandStats(t)
+	RestoreCommandStats(CommandStatsDelta{Command: "play", Day: "2000-01-01", Counts: [commandDayStatCount]int64{5, 0, 0}})
+
+	if got := TakeCommandStats(); len(got) != 1 || got[0].Day != "2000-01-01" {
+		t.Fatalf("TakeCommandStats() = %+v, want the past day's counts", got)
+	}
+	if _, ok := commandDays.Load(commandDayKey{command: "play", day: "2000-01-01"}); ok {
+		t.Error("the counters of a past day were kept once taken")
+	}
+}
+
+func TestCommandStatsWithoutFlush(t *testing.T) {
+	resetCommandStats(t)
+	config.Conf.CommandStatsFlush = false
+	RecordCommand("play", time.Millisecond, nil)
+	RecordCommandRejected("play")
+
+	if got := TakeCommandStats(); len(got) != 0 {
+		t.Errorf("TakeCommandStats() = %+v, want no daily counts when they are not flushed", got)
+	}
+	if stat := CommandStats(); len(stat) != 1 || stat[0].Invocations != 1 {
+		t.Errorf("CommandStats() = %+v, want the run counted", stat)
+	}
+}
+
+func TestRecordCommandConcurrently(t *testing.T) {
+	resetCommandStats(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			if i%5 == 0 {
+				err = errors.New("failed")
+			}
+			RecordCommand("play", time.Millisecond, err)
+			RecordCommandRejected("play")
+		}(i)
+	}
+	wg.Wait()
+
+	stat := CommandStats()[0]
+	if stat.Invocations != 50 || stat.Errors != 10 || stat.Rejected != 50 {
+		t.Errorf("play = %d runs, %d errors, %d rejected, want 50, 10, 50", stat.Invocations, stat.Errors, stat.Rejected)
+	}
+}
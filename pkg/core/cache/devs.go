@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"github.com/zuchzub/Go/pkg/config"
+	"slices"
+	"sync"
+)
+
+// devs is the set of users who may use the developer commands, set by SetDevs.
+// Until it is set, the developers of the config are used.
+var devs struct {
+	sync.RWMutex
+	ids map[int64]struct{}
+}
+
+// MergeDevs returns the developers from the config followed by those added at runtime, each listed once.
+func MergeDevs(configured, added []int64) []int64 {
+	merged := make([]int64, 0, len(configured)+len(added))
+	for _, id := range slices.Concat(configured, added) {
+		if id != 0 && !slices.Contains(merged, id) {
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
+
+// SetDevs replaces the set of developers with those of the config and those added at runtime.
+func SetDevs(configured, added []int64) {
+	ids := make(map[int64]struct{})
+	for _, id := range MergeDevs(configured, added) {
+		ids[id] = struct{}{}
+	}
+	devs.Lock()
+	devs.ids = ids
+	devs.Unlock()
+}
+
+// IsDev reports whether a user may use the developer commands.
+func IsDev(userID int64) bool {
+	devs.RLock()
+	defer devs.RUnlock()
+	if devs.ids == nil {
+		return config.Conf != nil && slices.Contains(config.Conf.DEVS, userID)
+	}
+	_, ok := devs.ids[userID]
+	return ok
+}
+
+// Devs returns the users who may use the developer commands, sorted.
+func Devs() []int64 {
+	devs.RLock()
+	defer devs.RUnlock()
+	if devs.ids == nil {
+		if config.Conf == nil {
+			return nil
+		}
+		return slices.Sorted(slices.Values(config.Conf.DEVS))
+	}
+	ids := make([]int64, 0, len(devs.ids))
+	for id := range devs.ids {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
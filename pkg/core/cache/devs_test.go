@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"github.com/zuchzub/Go/pkg/config"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// useDevs sets the developers of the config and forgets those set by SetDevs, restoring both after the test.
+func useDevs(t *testing.T, configured ...int64) {
+	t.Helper()
+	saved := config.Conf
+	config.Conf = &config.BotConfig{DEVS: configured}
+	devs.Lock()
+	savedIDs := devs.ids
+	devs.ids = nil
+	devs.Unlock()
+	t.Cleanup(func() {
+		config.Conf = saved
+		devs.Lock()
+		devs.ids = savedIDs
+		devs.Unlock()
+	})
+}
+
+func TestMergeDevs(t *testing.T) {
+	tests := []struct {
+		name              string
+		configured, added []int64
+		want              []int64
+	}{
+		{"none", nil, nil, []int64{}},
+		{"config only", []int64{1, 2}, nil, []int64{1, 2}},
+		{"added only", nil, []int64{3}, []int64{3}},
+		{"config first", []int64{2, 1}, []int64{4, 3}, []int64{2, 1, 4, 3}},
+		{"added already configured", []int64{1, 2}, []int64{2, 3}, []int64{1, 2, 3}},
+		{"duplicates in each", []int64{1, 1}, []int64{3, 3, 1}, []int64{1, 3}},
+		{"zero skipped", []int64{0, 1}, []int64{0}, []int64{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeDevs(tt.configured, tt.added); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeDevs(%v, %v) = %v, want %v", tt.configured, tt.added, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDevBeforeSetDevs(t *testing.T) {
+	useDevs(t, 1, 2)
+	if !IsDev(1) || IsDev(3) {
+		t.Errorf("IsDev(1), IsDev(3) = %v, %v, want the config's developers until SetDevs", IsDev(1), IsDev(3))
+	}
+	if got := Devs(); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Errorf("Devs() = %v, want [1 2]", got)
+	}
+}
+
+func TestSetDevs(t *testing.T) {
+	useDevs(t, 5, 1)
+	SetDevs(config.Conf.DEVS, []int64{9, 1})
+	for id, want := range map[int64]bool{1: true, 5: true, 9: true, 2: false, 0: false} {
+		if got := IsDev(id); got != want {
+			t.Errorf("IsDev(%d) = %v, want %v", id, got, want)
+		}
+	}
+	if got := Devs(); !reflect.DeepEqual(got, []int64{1, 5, 9}) {
+		t.Errorf("Devs() = %v, want [1 5 9]", got)
+	}
+
+	// Removing an added developer replaces the whole set.
+	SetDevs(config.Conf.DEVS, nil)
+	if IsDev(9) {
+		t.Error("IsDev(9) = true after the added developer was removed")
+	}
+	if got := Devs(); !reflect.DeepEqual(got, []int64{1, 5}) {
+		t.Errorf("Devs() = %v, want [1 5]", got)
+	}
+}
+
+func TestSetDevsConcurrently(t *testing.T) {
+	useDevs(t, 1)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			SetDevs([]int64{1}, []int64{int64(i + 2)})
+		}(i)
+		go func() {
+			defer wg.Done()
+			if !IsDev(1) {
+				t.Error("a configured developer was missing while the set was replaced")
+			}
+			_ = Devs()
+		}()
+	}
+	wg.Wait()
+}
@@ -3,14 +3,20 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/Laky-64/gologging"
 	tg "github.com/amarnathcjd/gogram/telegram"
 )
 
+var meanVolumeRegex = regexp.MustCompile(`mean_volume:\s*(-?inf|-?[\d.]+) dB`)
+
 // FFProbeFormat defines the structure for parsing the format information from ffprobe's JSON output.
 type FFProbeFormat struct {
 	Format struct {
@@ -72,7 +78,7 @@ func GetFileDuration(filePath string) int {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "ffprobe",
+	cmd := exec.CommandContext(ctx, config.Conf.FFprobePath,
 		"-v", "quiet",
 		"-print_format", "json",
 		"-show_format",
@@ -102,3 +108,31 @@ func GetFileDuration(filePath string) int {
 
 	return int(duration)
 }
+
+// GetMeanVolume runs ffmpeg's volumedetect filter over the first seconds of a media file.
+// It returns the mean volume in dB, which is about -91 dB for pure digital silence, or an error if ffmpeg fails.
+func GetMeanVolume(ctx context.Context, filePath string, seconds int) (float64, error) {
+	cmd := exec.CommandContext(ctx, config.Conf.FFmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-t", strconv.Itoa(seconds),
+		"-i", filePath,
+		"-vn",
+		"-af", "volumedetect",
+		"-f", "null",
+		"-",
+	)
+
+	// volumedetect reports on stderr.
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("ffmpeg volumedetect failed: %w", err)
+	}
+
+	match := meanVolumeRegex.FindSubmatch(output)
+	if match == nil {
+		return 0, errors.New("ffmpeg did not report a mean volume")
+	}
+
+	return strconv.ParseFloat(string(match[1]), 64)
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"github.com/zuchzub/Go/pkg/config"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestMeanVolumeRegex(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   string
+	}{
+		{"[Parsed_volumedetect_0 @ 0x1] mean_volume: -23.4 dB\n[Parsed_volumedetect_0 @ 0x1] max_volume: -4.0 dB", "-23.4"},
+		{"[Parsed_volumedetect_0 @ 0x1] mean_volume: -inf dB", "-inf"},
+		{"[Parsed_volumedetect_0 @ 0x1] mean_volume: 0.0 dB", "0.0"},
+		{"[Parsed_volumedetect_0 @ 0x1] n_samples: 0", ""},
+	}
+	for _, tt := range tests {
+		match := meanVolumeRegex.FindStringSubmatch(tt.stderr)
+		got := ""
+		if match != nil {
+			got = match[1]
+		}
+		if got != tt.want {
+			t.Errorf("mean volume of %q = %q, want %q", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+// generateWav writes a wav file from an ffmpeg lavfi source, skipping the test when ffmpeg is not installed.
+func generateWav(t *testing.T, source string) string {
+	t.Helper()
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+	config.Conf = &config.BotConfig{FFmpegPath: ffmpeg}
+
+	path := filepath.Join(t.TempDir(), "probe.wav")
+	// #nosec G204 - The source is a constant of the test.
+	if out, err := exec.Command(ffmpeg, "-hide_banner", "-f", "lavfi", "-i", source, "-t", "3", path).CombinedOutput(); err != nil {
+		t.Fatalf("ffmpeg could not generate %s: %v\n%s", source, err, out)
+	}
+	return path
+}
+
+func TestGetMeanVolume(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		silent bool
+	}{
+		{"silence", "anullsrc=r=48000:cl=stereo", true},
+		{"tone", "sine=frequency=440:sample_rate=48000", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := generateWav(t, tt.source)
+
+			volume, err := GetMeanVolume(context.Background(), path, 30)
+			if err != nil {
+				t.Fatalf("GetMeanVolume() = %v", err)
+			}
+			if silent := volume < -70; silent != tt.silent {
+				t.Errorf("GetMeanVolume() = %.1f dB, want silent %t", volume, tt.silent)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"fmt"
 	"log"
+	"strings"
 )
 
 // SecToMin converts a duration in seconds to a formatted string (MM:SS or HH:MM:SS).
@@ -22,3 +23,14 @@ func SecToMin(seconds int) string {
 	}
 	return fmt.Sprintf("%d:%02d", minutes, secs)
 }
+
+// ProgressBar draws how far played is into a track of duration seconds as a bar of width segments, such as
+// "▬▬▬🔘▬▬▬", with the knob at the position. The knob is at the start if the duration is unknown, as for a live stream.
+func ProgressBar(played, duration, width int) string {
+	width = max(width, 1)
+	pos := 0
+	if duration > 0 && played > 0 {
+		pos = min(played*width/duration, width-1)
+	}
+	return strings.Repeat("▬", pos) + "🔘" + strings.Repeat("▬", width-1-pos)
+}
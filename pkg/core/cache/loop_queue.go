@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"github.com/zuchzub/Go/pkg/core/eventlog"
+	"strconv"
+)
+
+// SetLoopQueue turns looping of the whole queue on or off for a chat.
+// While it is on, every track that leaves the queue after playing is kept, and once the last one finishes they are
+// all queued again in the same order. Turning it on or off forgets the tracks kept so far.
+// It returns false if the chat has no queue.
+func (c *ChatCacher) SetLoopQueue(chatID int64, on bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || len(data.Queue) == 0 {
+		return false
+	}
+	data.LoopQueue = on
+	data.looped = nil
+	eventlog.Emit(chatID, "loop_queue", "", strconv.FormatBool(on))
+	return true
+}
+
+// GetLoopQueue reports whether the whole queue of a chat is looped.
+func (c *ChatCacher) GetLoopQueue(chatID int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.chatCache[chatID]
+	return ok && data.LoopQueue
+}
+
+// RequeueLooped appends the tracks kept since the queue loop started, or since the last pass, to a chat's queue.
+// The copies have no file, so they are downloaded again when reached, and start from the beginning.
+// It returns the number of tracks queued.
+func (c *ChatCacher) RequeueLooped(chatID int64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || !data.LoopQueue || len(data.looped) == 0 {
+		return 0
+	}
+	for _, track := range data.looped {
+		data.Queue = append(data.Queue, &track)
+	}
+	n := len(data.looped)
+	data.looped = nil
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "loop_queue_requeue", "", strconv.Itoa(n))
+	return n
+}
+
+// keepLooped keeps a copy of a track that left the queue, if the queue is looped. Tracks that failed to play are not
+// kept, so that a queue of broken tracks does not loop forever. The caller must hold c.mu.
+func (c *ChatCacher) keepLooped(data *ChatData, track *CachedTrack) {
+	if !data.LoopQueue || track.FailCount > 0 {
+		return
+	}
+	kept := *track
+	kept.FilePath = ""
+	kept.Loop = 0
+	kept.StartAt = 0
+	data.looped = append(data.looped, kept)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// lrcTimestamp matches an LRC time tag such as [01:23.45] or [01:23].
+var lrcTimestamp = regexp.MustCompile(`\[(\d{1,3}):(\d{1,2})(?:[.:](\d{1,3}))?]`)
+
+// LyricLine is a single line of synced lyrics and the time, in milliseconds from the start of the track, at which it is sung.
+type LyricLine struct {
+	At   int
+	Text string
+}
+
+// ParseSyncedLyrics parses lyrics in the LRC format into lines sorted by time.
+// A line with several time tags is repeated at each of them; metadata tags such as [ar:...] are ignored.
+// It returns nil if the lyrics contain no time tags, i.e. when only plain lyrics are available.
+func ParseSyncedLyrics(lyrics string) []LyricLine {
+	var lines []LyricLine
+	for _, raw := range strings.Split(lyrics, "\n") {
+		tags := lrcTimestamp.FindAllStringSubmatchIndex(raw, -1)
+		if len(tags) == 0 || tags[0][0] != 0 {
+			continue
+		}
+
+		// The text follows the last of the leading time tags.
+		end := 0
+		var times []int
+		for _, tag := range tags {
+			if tag[0] != end {
+				break
+			}
+			times = append(times, lrcMillis(raw, tag))
+			end = tag[1]
+		}
+
+		text := strings.TrimSpace(raw[end:])
+		for _, at := range times {
+			lines = append(lines, LyricLine{At: at, Text: text})
+		}
+	}
+
+	slices.SortStableFunc(lines, func(a, b LyricLine) int { return a.At - b.At })
+	return lines
+}
+
+// lrcMillis converts the submatches of an LRC time tag to milliseconds.
+// The fraction is read as hundredths for two digits and thousandths for three, as LRC files use both.
+func lrcMillis(raw string, tag []int) int {
+	minutes, _ := strconv.Atoi(raw[tag[2]:tag[3]])
+	seconds, _ := strconv.Atoi(raw[tag[4]:tag[5]])
+	millis := (minutes*60 + seconds) * 1000
+	if tag[6] >= 0 {
+		fraction := raw[tag[6]:tag[7]]
+		value, _ := strconv.Atoi(fraction)
+		switch len(fraction) {
+		case 1:
+			value *= 100
+		case 2:
+			value *= 10
+		}
+		millis += value
+	}
+	return millis
+}
+
+// CurrentLyricLine returns the index of the line being sung at the given position in milliseconds,
+// or -1 if the first line has not been reached yet.
+func CurrentLyricLine(lines []LyricLine, position int) int {
+	index, found := slices.BinarySearchFunc(lines, position, func(line LyricLine, target int) int { return line.At - target })
+	if found {
+		// Several lines can share a time; the last of them is the current one.
+		for index+1 < len(lines) && lines[index+1].At == position {
+			index++
+		}
+		return index
+	}
+	return index - 1
+}
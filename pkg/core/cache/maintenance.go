@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	maintenanceOn  atomic.Bool
+	maintenanceMu  sync.RWMutex
+	maintenanceETA string
+)
+
+// SetMaintenance updates the in-memory maintenance flag and the optional ETA shown to users.
+func SetMaintenance(enabled bool, eta string) {
+	maintenanceMu.Lock()
+	maintenanceETA = eta
+	maintenanceMu.Unlock()
+	maintenanceOn.Store(enabled)
+}
+
+// IsMaintenance reports whether maintenance mode is on.
+// It is a single atomic load so it can be checked on every request.
+func IsMaintenance() bool {
+	return maintenanceOn.Load()
+}
+
+// MaintenanceETA returns the ETA text set when maintenance mode was turned on.
+func MaintenanceETA() string {
+	maintenanceMu.RLock()
+	defer maintenanceMu.RUnlock()
+	return maintenanceETA
+}
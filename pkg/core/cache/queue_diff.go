@@ -0,0 +1,60 @@
+package cache
+
+const (
+	// DisplacedMinPositions is how many positions a track must be pushed back by for its requester to be told.
+	DisplacedMinPositions = 2
+	// DisplacedMinDelay is how much later, in seconds, a track must be expected to start for its requester to be told.
+	DisplacedMinDelay = 10 * 60
+)
+
+// Displacement describes a queued track that a reorder pushed back.
+type Displacement struct {
+	Track *CachedTrack
+	From  int // From is the position of the track before the reorder.
+	To    int // To is the position of the track after the reorder.
+	Delay int // Delay is how much later, in seconds, the track is expected to start.
+}
+
+// startOffsets returns, for each track of a queue, the number of seconds of queue before it.
+// Index 0 is the playing track; as its remaining time is the same before and after a reorder, its full duration is used.
+func startOffsets(queue []*CachedTrack) map[*CachedTrack]int {
+	offsets := make(map[*CachedTrack]int, len(queue))
+	total := 0
+	for _, track := range queue {
+		if _, ok := offsets[track]; !ok {
+			offsets[track] = total
+		}
+		total += track.Duration
+	}
+	return offsets
+}
+
+// DisplacedTracks compares a queue before and after a reorder and returns the tracks pushed back by more than
+// DisplacedMinPositions positions, or expected to start more than DisplacedMinDelay seconds later.
+// Tracks are matched by identity, so both queues must hold the same pointers; tracks without a requester,
+// and tracks not in both queues, are left out.
+func DisplacedTracks(before, after []*CachedTrack) []Displacement {
+	positions := make(map[*CachedTrack]int, len(before))
+	for i, track := range before {
+		if _, ok := positions[track]; !ok {
+			positions[track] = i
+		}
+	}
+	offsetsBefore := startOffsets(before)
+	offsetsAfter := startOffsets(after)
+
+	var displaced []Displacement
+	seen := make(map[*CachedTrack]bool, len(after))
+	for to, track := range after {
+		from, ok := positions[track]
+		if !ok || track.UserID == 0 || seen[track] {
+			continue
+		}
+		seen[track] = true
+		delay := offsetsAfter[track] - offsetsBefore[track]
+		if to-from > DisplacedMinPositions || delay > DisplacedMinDelay {
+			displaced = append(displaced, Displacement{Track: track, From: from, To: to, Delay: max(delay, 0)})
+		}
+	}
+	return displaced
+}
@@ -0,0 +1,59 @@
+package cache
+
+import "testing"
+
+// track returns a queued track requested by user, lasting duration seconds.
+func track(id string, user int64, duration int) *CachedTrack {
+	return &CachedTrack{TrackID: id, UserID: user, Duration: duration}
+}
+
+func TestDisplacedTracks(t *testing.T) {
+	a, b, c, d, e, f := track("a", 1, 60), track("b", 2, 60), track("c", 3, 60), track("d", 4, 60), track("e", 5, 60), track("f", 6, 60)
+	long1, long2, long3 := track("l1", 7, 400), track("l2", 8, 400), track("l3", 9, 400)
+	anonymous := track("anon", 0, 60)
+	added := track("new", 10, 700)
+	tenMinutes := track("ten", 11, DisplacedMinDelay)
+
+	type want struct {
+		id             string
+		from, to, wait int
+	}
+	tests := []struct {
+		name          string
+		before, after []*CachedTrack
+		want          []want
+	}{
+		{"unchanged", []*CachedTrack{a, b, c}, []*CachedTrack{a, b, c}, nil},
+		{"one place back", []*CachedTrack{a, b, c, d, e}, []*CachedTrack{a, e, b, c, d}, nil},
+		{"exactly two places back", []*CachedTrack{a, b, c, d}, []*CachedTrack{a, c, d, b}, nil},
+		{"three places back", []*CachedTrack{a, b, c, d, e}, []*CachedTrack{a, c, d, e, b}, []want{{"b", 1, 4, 180}}},
+		{
+			"far back and several tracks",
+			[]*CachedTrack{a, b, c, d, e, f},
+			[]*CachedTrack{a, e, f, d, b, c},
+			[]want{{"b", 1, 4, 180}, {"c", 2, 5, 180}},
+		},
+		{"long wait, few places", []*CachedTrack{a, long1, long2, long3}, []*CachedTrack{a, long2, long3, long1}, []want{{"l1", 1, 3, 800}}},
+		{"delay at the limit", []*CachedTrack{a, b, tenMinutes}, []*CachedTrack{a, tenMinutes, b}, nil},
+		{"inserted track delays the queue", []*CachedTrack{a, b, c}, []*CachedTrack{a, added, b, c}, []want{{"b", 1, 2, 700}, {"c", 2, 3, 700}}},
+		{"moved forward", []*CachedTrack{a, b, c, d, e, f}, []*CachedTrack{a, f, b, c, d, e}, nil},
+		{"no requester", []*CachedTrack{a, anonymous, c, d, e}, []*CachedTrack{a, c, d, e, anonymous}, nil},
+		{"removed track", []*CachedTrack{a, b, c, d, e}, []*CachedTrack{a, c}, nil},
+		{"track queued twice is reported once", []*CachedTrack{a, b, b, c, d, e}, []*CachedTrack{a, c, d, e, b, b}, []want{{"b", 1, 4, 180}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DisplacedTracks(tt.before, tt.after)
+			if len(got) != len(tt.want) {
+				t.Fatalf("DisplacedTracks() = %d tracks, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.Track.TrackID != w.id || g.From != w.from || g.To != w.to || g.Delay != w.wait {
+					t.Errorf("DisplacedTracks()[%d] = %s %d→%d +%ds, want %s %d→%d +%ds",
+						i, g.Track.TrackID, g.From, g.To, g.Delay, w.id, w.from, w.to, w.wait)
+				}
+			}
+		})
+	}
+}
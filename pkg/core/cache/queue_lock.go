@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"github.com/zuchzub/Go/pkg/core/eventlog"
+	"strconv"
+	"time"
+)
+
+// QueueLock records that admins locked a chat's queue so that only they can add tracks.
+type QueueLock struct {
+	AllowAuth bool      // AllowAuth lets the chat's authorized users keep adding tracks.
+	Until     time.Time // Until is when the lock lifts by itself, or zero if it lasts until unlocked or the session ends.
+
+	timer *time.Timer
+}
+
+// LockQueue locks a chat's queue, replacing any lock it already has.
+// A positive duration unlocks the queue by itself once it has passed; otherwise the lock lasts until UnlockQueue or
+// ClearChat. The lock lives in memory only and is lost on restart.
+func (c *ChatCacher) LockQueue(chatID int64, allowAuth bool, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopLock(chatID)
+	lock := &QueueLock{AllowAuth: allowAuth}
+	if duration > 0 {
+		lock.Until = time.Now().Add(duration)
+		lock.timer = time.AfterFunc(duration, func() { c.expireLock(chatID, lock) })
+	}
+	c.locks[chatID] = lock
+	eventlog.Emit(chatID, "queue_lock", "", strconv.FormatInt(int64(duration/time.Second), 10))
+}
+
+// UnlockQueue unlocks a chat's queue. It returns false if the queue was not locked.
+func (c *ChatCacher) UnlockQueue(chatID int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.stopLock(chatID) {
+		return false
+	}
+	eventlog.Emit(chatID, "queue_unlock", "", "")
+	return true
+}
+
+// GetQueueLock returns the lock on a chat's queue, and false if the queue is not locked.
+func (c *ChatCacher) GetQueueLock(chatID int64) (QueueLock, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	lock, ok := c.locks[chatID]
+	if !ok {
+		return QueueLock{}, false
+	}
+	return *lock, true
+}
+
+// expireLock removes a lock whose duration has passed, unless it was replaced or removed in the meantime.
+func (c *ChatCacher) expireLock(chatID int64, lock *QueueLock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.locks[chatID] == lock {
+		delete(c.locks, chatID)
+		eventlog.Emit(chatID, "queue_unlock", "", "expired")
+	}
+}
+
+// stopLock removes a chat's lock and stops its timer. It reports whether the chat had a lock.
+// The caller must hold c.mu.
+func (c *ChatCacher) stopLock(chatID int64) bool {
+	lock, ok := c.locks[chatID]
+	if !ok {
+		return false
+	}
+	if lock.timer != nil {
+		lock.timer.Stop()
+	}
+	delete(c.locks, chatID)
+	return true
+}
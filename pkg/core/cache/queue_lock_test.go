@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// waitUnlocked waits up to a second for a chat's queue lock to lift, and reports whether it did.
+func waitUnlocked(c *ChatCacher, chatID int64) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if _, locked := c.GetQueueLock(chatID); !locked {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLockQueue(t *testing.T) {
+	c := NewChatCacher()
+	if _, locked := c.GetQueueLock(1); locked {
+		t.Fatal("a new chat starts locked")
+	}
+
+	c.LockQueue(1, true, 0)
+	lock, locked := c.GetQueueLock(1)
+	if !locked || !lock.AllowAuth || !lock.Until.IsZero() {
+		t.Errorf("GetQueueLock() = %+v, %t; want an auth lock without an end", lock, locked)
+	}
+	if _, locked := c.GetQueueLock(2); locked {
+		t.Error("locking one chat locked another")
+	}
+
+	if !c.UnlockQueue(1) {
+		t.Error("UnlockQueue() of a locked queue = false")
+	}
+	if c.UnlockQueue(1) {
+		t.Error("UnlockQueue() of an unlocked queue = true")
+	}
+}
+
+func TestLockQueueExpires(t *testing.T) {
+	c := NewChatCacher()
+	const duration = 50 * time.Millisecond
+	start := time.Now()
+	c.LockQueue(1, false, duration)
+
+	lock, locked := c.GetQueueLock(1)
+	if !locked || lock.Until.Before(start.Add(duration)) || lock.Until.After(time.Now().Add(duration)) {
+		t.Fatalf("GetQueueLock() = %+v, %t; want a lock until %s from now", lock, locked, duration)
+	}
+	if !waitUnlocked(c, 1) {
+		t.Fatal("the lock did not lift after its duration")
+	}
+	if elapsed := time.Since(start); elapsed < duration {
+		t.Errorf("the lock lifted after %s, before its duration of %s", elapsed, duration)
+	}
+}
+
+func TestReplacedLockOutlivesOldTimer(t *testing.T) {
+	c := NewChatCacher()
+	c.LockQueue(1, false, 20*time.Millisecond)
+	c.LockQueue(1, true, 0)
+
+	time.Sleep(60 * time.Millisecond)
+	if lock, locked := c.GetQueueLock(1); !locked || !lock.AllowAuth {
+		t.Errorf("GetQueueLock() = %+v, %t after the first lock's duration; want the replacement", lock, locked)
+	}
+
+	// A lock removed by hand is not lifted a second time by its timer either.
+	c.LockQueue(2, false, 20*time.Millisecond)
+	c.UnlockQueue(2)
+	c.LockQueue(2, false, 0)
+	time.Sleep(60 * time.Millisecond)
+	if _, locked := c.GetQueueLock(2); !locked {
+		t.Error("the timer of an unlocked queue lifted a later lock")
+	}
+}
+
+func TestClearChatUnlocksQueue(t *testing.T) {
+	c := newTestQueue(1, &CachedTrack{TrackID: "a"})
+	c.LockQueue(1, false, time.Hour)
+	c.LockQueue(2, false, 0) // A chat without a session can be locked too.
+
+	c.ClearChat(1, false)
+	c.ClearChat(2, false)
+	for _, chatID := range []int64{1, 2} {
+		if _, locked := c.GetQueueLock(chatID); locked {
+			t.Errorf("chat %d is still locked after its session ended", chatID)
+		}
+	}
+}
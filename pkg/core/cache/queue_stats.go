@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QueueStat is an outcome counted by the per-chat queue statistics.
+type QueueStat int
+
+const (
+	// QueueEnqueued counts tracks added to a queue.
+	QueueEnqueued QueueStat = iota
+	// QueueCompleted counts tracks that played to the end.
+	QueueCompleted
+	// QueueSkipped counts tracks skipped with /skip or the skip button.
+	QueueSkipped
+	// QueueFailed counts tracks dropped because they could not be downloaded or played.
+	QueueFailed
+	// QueueCleared counts tracks dropped from the queue by /stop, /clearqueue or a reset.
+	QueueCleared
+	// QueueRejected counts requests refused because of the queue or duration limits.
+	QueueRejected
+
+	queueStatCount
+)
+
+// queueStatNames are the names of the stats, used as field names in the database.
+var queueStatNames = [queueStatCount]string{"enqueued", "completed", "skipped", "failed", "cleared", "rejected"}
+
+// String returns the name of the stat.
+func (s QueueStat) String() string {
+	return queueStatNames[s]
+}
+
+// QueueStats lists every QueueStat, in order.
+func QueueStats() []QueueStat {
+	stats := make([]QueueStat, queueStatCount)
+	for i := range stats {
+		stats[i] = QueueStat(i)
+	}
+	return stats
+}
+
+// QueueCounts holds a count for each QueueStat.
+type QueueCounts [queueStatCount]int64
+
+// Add adds other to the counts.
+func (q *QueueCounts) Add(other QueueCounts) {
+	for i, n := range other {
+		q[i] += n
+	}
+}
+
+// QueueStatsDelta holds the counts of a chat for a day that are not yet written to the database.
+type QueueStatsDelta struct {
+	ChatID int64
+	Day    string
+	Counts QueueCounts
+}
+
+// queueStatKey identifies the counters of a chat for a day.
+type queueStatKey struct {
+	chatID int64
+	day    string
+}
+
+// queueCounters are the in-memory counters of a chat for a day.
+type queueCounters [queueStatCount]atomic.Int64
+
+// queueStats maps a queueStatKey to its *queueCounters. Entries are only created once, so recording a stat
+// is a map lookup and an atomic add.
+var queueStats sync.Map
+
+// StatsDay returns the day, in UTC, that stats recorded at t are counted for.
+func StatsDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// counters returns the counters of a chat for a day, creating them if needed.
+func counters(chatID int64, day string) *queueCounters {
+	key := queueStatKey{chatID: chatID, day: day}
+	if v, ok := queueStats.Load(key); ok {
+		return v.(*queueCounters)
+	}
+	v, _ := queueStats.LoadOrStore(key, new(queueCounters))
+	return v.(*queueCounters)
+}
+
+// RecordQueueStat adds n to today's count of stat for a chat.
+func RecordQueueStat(chatID int64, stat QueueStat, n int) {
+	if n <= 0 {
+		return
+	}
+	counters(chatID, StatsDay(time.Now()))[stat].Add(int64(n))
+}
+
+// TakeQueueStats returns the counts recorded since the last call and resets them.
+// Counters of past days are dropped once taken.
+func TakeQueueStats() []QueueStatsDelta {
+	today := StatsDay(time.Now())
+	var deltas []QueueStatsDelta
+	queueStats.Range(func(k, v any) bool {
+		key := k.(queueStatKey)
+		if key.day != today {
+			queueStats.Delete(key)
+		}
+
+		delta := QueueStatsDelta{ChatID: key.chatID, Day: key.day}
+		empty := true
+		for i := range v.(*queueCounters) {
+			delta.Counts[i] = v.(*queueCounters)[i].Swap(0)
+			empty = empty && delta.Counts[i] == 0
+		}
+		if !empty {
+			deltas = append(deltas, delta)
+		}
+		return true
+	})
+	return deltas
+}
+
+// RestoreQueueStats puts back counts returned by TakeQueueStats that could not be written, so the next flush retries them.
+func RestoreQueueStats(delta QueueStatsDelta) {
+	c := counters(delta.ChatID, delta.Day)
+	for i, n := range delta.Counts {
+		c[i].Add(n)
+	}
+}
+
+// PendingQueueStats returns the counts of a chat that are not yet written to the database, by day.
+func PendingQueueStats(chatID int64) map[string]QueueCounts {
+	pending := make(map[string]QueueCounts)
+	queueStats.Range(func(k, v any) bool {
+		key := k.(queueStatKey)
+		if key.chatID != chatID {
+			return true
+		}
+		var counts QueueCounts
+		for i := range v.(*queueCounters) {
+			counts[i] = v.(*queueCounters)[i].Load()
+		}
+		pending[key.day] = counts
+		return true
+	})
+	return pending
+}
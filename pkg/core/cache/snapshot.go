@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is the version of the format Snapshot writes. Restore rejects snapshots of any other version.
+const snapshotVersion = 1
+
+// snapshot is the format a cache is written in by Snapshot.
+type snapshot[T any] struct {
+	Version int                     `json:"version"`
+	Saved   time.Time               `json:"saved"`
+	Items   map[string]CacheItem[T] `json:"items"`
+}
+
+// Snapshot writes the entries of the cache that have not expired, with their expiry, to w as JSON.
+func (c *Cache[T]) Snapshot(w io.Writer) error {
+	now := time.Now()
+	snap := snapshot[T]{Version: snapshotVersion, Saved: now, Items: make(map[string]CacheItem[T])}
+
+	c.mu.RLock()
+	for key, item := range c.data {
+		if item.Expiration.After(now) {
+			snap.Items[key] = item
+		}
+	}
+	c.mu.RUnlock()
+
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// Restore adds the entries of a snapshot written by Snapshot to the cache, keeping their expiry. Entries that have
+// expired since are skipped, and entries already in the cache are replaced.
+// It returns the number of entries restored. A snapshot that cannot be read or is of another version is an error, and
+// leaves the cache as it was.
+func (c *Cache[T]) Restore(r io.Reader) (int, error) {
+	var snap snapshot[T]
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return 0, fmt.Errorf("failed to decode the cache snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported cache snapshot version %d", snap.Version)
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	restored := 0
+	for key, item := range snap.Items {
+		if item.Expiration.After(now) {
+			c.data[key] = item
+			restored++
+		}
+	}
+	return restored, nil
+}
+
+// SaveFile writes a snapshot of the cache to path, creating its directory if needed.
+// The snapshot is written to a temporary file first, so a crash while saving never leaves a partial file behind.
+func (c *Cache[T]) SaveFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = c.Snapshot(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile restores the cache from a snapshot saved to path by SaveFile.
+// A missing file restores nothing and is not an error.
+func (c *Cache[T]) LoadFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return c.Restore(f)
+}
@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	src := NewCache[string](time.Hour)
+	src.Set("a", "alpha")
+	src.SetWithTTL("b", "beta", time.Minute)
+	src.SetWithTTL("gone", "expired", -time.Second)
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	dst := NewCache[string](time.Hour)
+	n, err := dst.Restore(&buf)
+	if err != nil || n != 2 {
+		t.Fatalf("Restore() = %d, %v, want 2, nil", n, err)
+	}
+	for key, want := range map[string]string{"a": "alpha", "b": "beta"} {
+		if got, ok := dst.Get(key); !ok || got != want {
+			t.Errorf("Get(%q) = %q, %v, want %q, true", key, got, ok, want)
+		}
+	}
+	if _, ok := dst.Get("gone"); ok {
+		t.Error("an entry expired before the snapshot was restored")
+	}
+	if got, want := dst.data["b"].Expiration, src.data["b"].Expiration; !got.Equal(want) {
+		t.Errorf("restored expiry = %v, want %v", got, want)
+	}
+}
+
+func TestRestoreSkipsExpired(t *testing.T) {
+	now := time.Now()
+	snap := snapshot[string]{
+		Version: snapshotVersion,
+		Saved:   now.Add(-time.Hour),
+		Items: map[string]CacheItem[string]{
+			"fresh": {Value: "kept", Expiration: now.Add(time.Hour)},
+			"stale": {Value: "dropped", Expiration: now.Add(-time.Minute)},
+		},
+	}
+	raw, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCache[string](time.Hour)
+	n, err := c.Restore(bytes.NewReader(raw))
+	if err != nil || n != 1 {
+		t.Fatalf("Restore() = %d, %v, want 1, nil", n, err)
+	}
+	if _, ok := c.data["stale"]; ok {
+		t.Error("Restore() kept an entry that expired while the bot was down")
+	}
+	if got, ok := c.Get("fresh"); !ok || got != "kept" {
+		t.Errorf("Get(fresh) = %q, %v, want kept, true", got, ok)
+	}
+}
+
+func TestRestoreRejectsBadSnapshots(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"empty", "", "decode"},
+		{"garbage", "not json", "decode"},
+		{"truncated", `{"version":1,"items":{"a":{"v":"x",`, "decode"},
+		{"wrong value type", `{"version":1,"items":{"a":{"v":5,"exp":"2999-01-01T00:00:00Z"}}}`, "decode"},
+		{"other version", `{"version":2,"items":{"a":{"v":"x","exp":"2999-01-01T00:00:00Z"}}}`, "version 2"},
+		{"no version", `{"items":{"a":{"v":"x","exp":"2999-01-01T00:00:00Z"}}}`, "version 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string](time.Hour)
+			c.Set("existing", "kept")
+
+			n, err := c.Restore(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Restore() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+			if n != 0 {
+				t.Errorf("Restore() = %d entries, want 0", n)
+			}
+			if len(c.data) != 1 {
+				t.Errorf("cache has %d entries after a failed restore, want it left as it was", len(c.data))
+			}
+			if got, ok := c.Get("existing"); !ok || got != "kept" {
+				t.Errorf("Get(existing) = %q, %v, want kept, true", got, ok)
+			}
+		})
+	}
+}
+
+func TestRestoreReplacesExisting(t *testing.T) {
+	src := NewCache[string](time.Hour)
+	src.Set("a", "new")
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCache[string](time.Hour)
+	c.Set("a", "old")
+	c.Set("b", "untouched")
+	if _, err := c.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.Get("a"); got != "new" {
+		t.Errorf("Get(a) = %q, want new", got)
+	}
+	if got, _ := c.Get("b"); got != "untouched" {
+		t.Errorf("Get(b) = %q, want untouched", got)
+	}
+}
+
+func TestSaveAndLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "status.json")
+
+	src := NewCache[string](time.Hour)
+	src.Set("a", "alpha")
+	if err := src.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files after SaveFile(), want only the snapshot", len(entries))
+	}
+
+	dst := NewCache[string](time.Hour)
+	if n, err := dst.LoadFile(path); err != nil || n != 1 {
+		t.Fatalf("LoadFile() = %d, %v, want 1, nil", n, err)
+	}
+	if got, ok := dst.Get("a"); !ok || got != "alpha" {
+		t.Errorf("Get(a) = %q, %v, want alpha, true", got, ok)
+	}
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	c := NewCache[string](time.Hour)
+	n, err := c.LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || n != 0 {
+		t.Errorf("LoadFile() of a missing file = %d, %v, want 0, nil", n, err)
+	}
+}
+
+func TestLoadFileCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.json")
+	if err := os.WriteFile(path, []byte(`{"version":1,"items":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCache[string](time.Hour)
+	if _, err := c.LoadFile(path); err == nil {
+		t.Error("LoadFile() of a truncated file returned no error")
+	}
+}
@@ -6,42 +6,56 @@ package cache
 
 // CachedTrack defines the structure for a track that is stored in the queue.
 // It includes metadata such as the track's URL, name, duration, and the user who requested it.
+// Tracks queued from a playlist keep its URL and their index in it, so that /continue can resume the playlist.
 type CachedTrack struct {
-	URL       string `json:"url"`
-	Name      string `json:"name"`
-	Loop      int    `json:"loop"`
-	User      string `json:"user"`
-	FilePath  string `json:"file_path"`
-	Thumbnail string `json:"thumbnail"`
-	TrackID   string `json:"track_id"`
-	Duration  int    `json:"duration"`
-	Lyrics    string `json:"lyrics"`
-	IsVideo   bool   `json:"is_video"`
-	Platform  string `json:"platform"`
+	URL         string `json:"url"`
+	Name        string `json:"name"`
+	Loop        int    `json:"loop"`
+	User        string `json:"user"`
+	UserID      int64  `json:"user_id"`
+	FilePath    string `json:"file_path"`
+	Thumbnail   string `json:"thumbnail"`
+	TrackID     string `json:"track_id"`
+	Duration    int    `json:"duration"`
+	Lyrics      string `json:"lyrics"`
+	IsVideo     bool   `json:"is_video"`
+	Platform    string `json:"platform"`
+	IsLive      bool   `json:"is_live"`
+	ContentType string `json:"content_type"`
+	StartAt     int    `json:"start_at"`
+	LastError   string `json:"last_error"`
+	FailCount   int    `json:"fail_count"`
+	SourceURL   string `json:"source_url"`
+	SourceIndex int    `json:"source_index"`
 }
 
 // TrackInfo holds detailed information about a specific track, including its CDN URL, cover art, and lyrics.
 type TrackInfo struct {
-	URL      string `json:"url"`
-	CdnURL   string `json:"cdnurl"`
-	Key      string `json:"key"`
-	Name     string `json:"name"`
-	TC       string `json:"tc"`
-	Cover    string `json:"cover"`
-	Duration int    `json:"duration"`
-	Lyrics   string `json:"lyrics"`
-	Platform string `json:"platform"`
+	URL         string `json:"url"`
+	CdnURL      string `json:"cdnurl"`
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Artist      string `json:"artist"` // Artist is the artist or uploader of the track, if the platform gives one.
+	TC          string `json:"tc"`
+	Cover       string `json:"cover"`
+	Duration    int    `json:"duration"`
+	Lyrics      string `json:"lyrics"`
+	Platform    string `json:"platform"`
+	IsLive      bool   `json:"is_live"`
+	ContentType string `json:"content_type"`
 }
 
 // MusicTrack represents a single music track returned from a search query.
 // It contains essential details like the track's name, ID, and cover art URL.
 type MusicTrack struct {
-	URL      string `json:"url"`
-	Name     string `json:"name"`
-	ID       string `json:"id"`
-	Cover    string `json:"cover"`
-	Duration int    `json:"duration"`
-	Platform string `json:"platform"`
+	URL         string `json:"url"`
+	Name        string `json:"name"`
+	ID          string `json:"id"`
+	Cover       string `json:"cover"`
+	Duration    int    `json:"duration"`
+	Platform    string `json:"platform"`
+	IsLive      bool   `json:"is_live"`
+	ContentType string `json:"content_type"`
 }
 
 // PlatformTracks is a collection of music tracks, typically returned from a search operation.
@@ -55,6 +69,13 @@ const (
 	Spotify  = "spotify"
 	JioSaavn = "jiosaavn"
 	Apple    = "apple_music"
+	Direct   = "direct"
+)
+
+// Content types a track can have. An empty content type is treated as music.
+const (
+	ContentMusic   = "music"
+	ContentPodcast = "podcast"
 )
 
 const (
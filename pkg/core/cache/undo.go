@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"github.com/zuchzub/Go/pkg/core/eventlog"
+	"strconv"
+	"time"
+)
+
+// UndoWindow is how long a queue snapshot taken before a destructive command can be restored with /undo.
+const UndoWindow = 2 * time.Minute
+
+// undoSnapshot is a copy of a chat's queue taken before a command that removes tracks from it.
+type undoSnapshot struct {
+	tracks []CachedTrack
+	active bool
+	taken  time.Time
+}
+
+// SnapshotQueue saves a copy of a chat's queue so that the next destructive command can be undone.
+// It replaces any earlier snapshot; only one level of undo is kept. A chat without tracks keeps no snapshot.
+// File paths are left out of the copy, as the files may be deleted once the tracks leave the queue.
+func (c *ChatCacher) SnapshotQueue(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || len(data.Queue) == 0 {
+		delete(c.undo, chatID)
+		return
+	}
+
+	tracks := make([]CachedTrack, len(data.Queue))
+	for i, track := range data.Queue {
+		tracks[i] = *track
+		tracks[i].FilePath = ""
+	}
+	c.undo[chatID] = undoSnapshot{tracks: tracks, active: data.IsActive, taken: time.Now()}
+}
+
+// DiscardSnapshot drops a chat's snapshot, for a destructive command that ended up changing nothing.
+func (c *ChatCacher) DiscardSnapshot(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.undo, chatID)
+}
+
+// RestoreSnapshot puts the tracks of a chat's snapshot back and drops the snapshot, so it can be used only once.
+// Tracks queued since the snapshot was taken stay in front, and the restored ones are appended after them; tracks of
+// the snapshot that are still in the queue are not added twice. It returns the number of tracks restored, and reports
+// whether the chat had no queue left, in which case playback has to be started again. It returns 0 and false if the
+// chat has no snapshot or it is older than UndoWindow.
+func (c *ChatCacher) RestoreSnapshot(chatID int64) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snap, ok := c.undo[chatID]
+	delete(c.undo, chatID)
+	if !ok || time.Since(snap.taken) > UndoWindow {
+		return 0, false
+	}
+
+	data, ok := c.chatCache[chatID]
+	idle := !ok || len(data.Queue) == 0
+	if !ok {
+		data = &ChatData{}
+		c.chatCache[chatID] = data
+	}
+
+	// A track can be queued more than once, so the copies still present are counted rather than just noted.
+	present := make(map[string]int, len(data.Queue))
+	for _, track := range data.Queue {
+		present[track.TrackID]++
+	}
+
+	restored := 0
+	for _, track := range snap.tracks {
+		if present[track.TrackID] > 0 {
+			present[track.TrackID]--
+			continue
+		}
+		if idle && restored == 0 {
+			// The track that was playing starts again from the beginning.
+			track.StartAt = 0
+		}
+		data.Queue = append(data.Queue, &track)
+		restored++
+	}
+	if idle {
+		data.IsActive = snap.active
+	}
+	c.dirty[chatID] = struct{}{}
+	eventlog.Emit(chatID, "undo", "", strconv.Itoa(restored))
+	RecordQueueStat(chatID, QueueEnqueued, restored)
+	return restored, idle && restored > 0
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// queueIDs returns the track IDs of a chat's queue, in order.
+func queueIDs(c *ChatCacher, chatID int64) []string {
+	var ids []string
+	for _, track := range c.GetQueue(chatID) {
+		ids = append(ids, track.TrackID)
+	}
+	return ids
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	c := newTestQueue(1,
+		&CachedTrack{TrackID: "a", FilePath: "/tmp/a.mp3", StartAt: 42},
+		&CachedTrack{TrackID: "b", FilePath: "/tmp/b.mp3"},
+	)
+	c.SetActive(1, true)
+	c.SnapshotQueue(1)
+	c.ClearChat(1, false)
+
+	restored, restart := c.RestoreSnapshot(1)
+	if restored != 2 || !restart {
+		t.Fatalf("RestoreSnapshot() = %d, %t; want 2, true", restored, restart)
+	}
+	if got := queueIDs(c, 1); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("queue = %v, want [a b]", got)
+	}
+	for _, track := range c.GetQueue(1) {
+		if track.FilePath != "" {
+			t.Errorf("track %s was restored with the file %q, want it downloaded again", track.TrackID, track.FilePath)
+		}
+	}
+	if head := c.GetPlayingTrack(1); head.StartAt != 0 {
+		t.Errorf("the head track restarts at %d, want from the beginning", head.StartAt)
+	}
+	if !c.IsActive(1) {
+		t.Error("the active flag was not restored")
+	}
+}
+
+func TestRestoreSnapshotOnce(t *testing.T) {
+	c := newTestQueue(1, &CachedTrack{TrackID: "a"})
+	c.SnapshotQueue(1)
+	c.ClearChat(1, false)
+
+	if restored, _ := c.RestoreSnapshot(1); restored != 1 {
+		t.Fatalf("first RestoreSnapshot() = %d, want 1", restored)
+	}
+	if restored, restart := c.RestoreSnapshot(1); restored != 0 || restart {
+		t.Errorf("second RestoreSnapshot() = %d, %t; want nothing to undo", restored, restart)
+	}
+	if got := queueIDs(c, 1); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("queue after undoing twice = %v, want [a]", got)
+	}
+}
+
+func TestRestoreSnapshotExpires(t *testing.T) {
+	c := newTestQueue(1, &CachedTrack{TrackID: "a"})
+	c.SnapshotQueue(1)
+	c.ClearChat(1, false)
+
+	c.mu.Lock()
+	snap := c.undo[1]
+	snap.taken = time.Now().Add(-UndoWindow - time.Second)
+	c.undo[1] = snap
+	c.mu.Unlock()
+
+	if restored, restart := c.RestoreSnapshot(1); restored != 0 || restart {
+		t.Errorf("RestoreSnapshot() after %s = %d, %t; want nothing to undo", UndoWindow, restored, restart)
+	}
+	if c.GetQueueLength(1) != 0 {
+		t.Error("an expired snapshot was restored")
+	}
+}
+
+func TestRestoreSnapshotMerges(t *testing.T) {
+	c := newTestQueue(1, &CachedTrack{TrackID: "a"}, &CachedTrack{TrackID: "b"}, &CachedTrack{TrackID: "c"})
+	c.SnapshotQueue(1)
+	c.ClearChat(1, false)
+
+	// A track was queued after the stop, and it is one of the snapshot's tracks.
+	c.AddSong(1, &CachedTrack{TrackID: "new"})
+	c.AddSong(1, &CachedTrack{TrackID: "b", FilePath: "/tmp/b.mp3"})
+
+	restored, restart := c.RestoreSnapshot(1)
+	if restored != 2 || restart {
+		t.Errorf("RestoreSnapshot() = %d, %t; want 2, false as playback is running", restored, restart)
+	}
+	if got, want := queueIDs(c, 1), []string{"new", "b", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+}
+
+func TestRestoreSnapshotKeepsRepeats(t *testing.T) {
+	c := newTestQueue(1, &CachedTrack{TrackID: "a"}, &CachedTrack{TrackID: "a"}, &CachedTrack{TrackID: "b"})
+	c.SnapshotQueue(1)
+	c.RemoveTracks(1, c.GetQueue(1)[1:])
+
+	if restored, _ := c.RestoreSnapshot(1); restored != 2 {
+		t.Errorf("RestoreSnapshot() = %d, want the second a and b", restored)
+	}
+	if got, want := queueIDs(c, 1), []string{"a", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+}
+
+func TestSnapshotQueueCopiesTracks(t *testing.T) {
+	track := &CachedTrack{TrackID: "a", Name: "Before"}
+	c := newTestQueue(1, track)
+	c.SnapshotQueue(1)
+	track.Name = "After"
+	c.ClearChat(1, false)
+
+	c.RestoreSnapshot(1)
+	if got := c.GetPlayingTrack(1); got == track || got.Name != "Before" {
+		t.Errorf("restored track = %p named %q, want a copy named Before", got, got.Name)
+	}
+}
+
+func TestSnapshotWithoutTracks(t *testing.T) {
+	c := newTestQueue(1, &CachedTrack{TrackID: "a"})
+	c.SnapshotQueue(1)
+	c.ClearChat(1, false)
+
+	// A destructive command on an empty queue leaves nothing to undo, not the older snapshot.
+	c.SnapshotQueue(1)
+	if restored, _ := c.RestoreSnapshot(1); restored != 0 {
+		t.Errorf("RestoreSnapshot() after snapshotting an empty queue = %d, want 0", restored)
+	}
+
+	c.AddSong(2, &CachedTrack{TrackID: "b"})
+	c.SnapshotQueue(2)
+	c.DiscardSnapshot(2)
+	if restored, _ := c.RestoreSnapshot(2); restored != 0 {
+		t.Errorf("RestoreSnapshot() after DiscardSnapshot() = %d, want 0", restored)
+	}
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"github.com/zuchzub/Go/pkg/core/eventlog"
+	"strconv"
+)
+
+// AddSkipVote records a user's vote to skip the current track of a chat, which must be the track with trackID.
+// Each user is counted once per track, and the votes are forgotten once the track leaves the queue. Only the votes of
+// listeners count: the votes of users who have left the voice chat since they voted are dropped.
+// It returns the number of votes for the track and whether this vote was new, or -1 if trackID is not the current
+// track, for instance because it changed since the vote was cast.
+func (c *ChatCacher) AddSkipVote(chatID int64, trackID string, userID int64, listeners map[int64]struct{}) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.chatCache[chatID]
+	if !ok || len(data.Queue) == 0 || data.Queue[0].TrackID != trackID {
+		return -1, false
+	}
+	if data.skipVotes == nil {
+		data.skipVotes = make(map[string]map[int64]struct{})
+	}
+	voters, ok := data.skipVotes[trackID]
+	if !ok {
+		voters = make(map[int64]struct{})
+		data.skipVotes[trackID] = voters
+	}
+	for voter := range voters {
+		if _, listening := listeners[voter]; !listening {
+			delete(voters, voter)
+		}
+	}
+	if _, voted := voters[userID]; voted {
+		return len(voters), false
+	}
+	voters[userID] = struct{}{}
+	eventlog.Emit(chatID, "skip_vote", trackID, strconv.Itoa(len(voters)))
+	return len(voters), true
+}
@@ -0,0 +1,43 @@
+package cache
+
+import "testing"
+
+func TestAddSkipVote(t *testing.T) {
+	c := newTestQueue(1, &CachedTrack{TrackID: "a"}, &CachedTrack{TrackID: "b"})
+	listeners := map[int64]struct{}{10: {}, 11: {}, 12: {}}
+
+	if votes, added := c.AddSkipVote(1, "a", 10, listeners); votes != 1 || !added {
+		t.Errorf("first vote = %d, %t; want 1, true", votes, added)
+	}
+	if votes, added := c.AddSkipVote(1, "a", 10, listeners); votes != 1 || added {
+		t.Errorf("repeated vote = %d, %t; want 1, false", votes, added)
+	}
+	if votes, _ := c.AddSkipVote(1, "a", 11, listeners); votes != 2 {
+		t.Errorf("second voter = %d votes, want 2", votes)
+	}
+	if votes, _ := c.AddSkipVote(1, "b", 12, listeners); votes != -1 {
+		t.Errorf("vote for a track that is not playing = %d, want -1", votes)
+	}
+}
+
+func TestAddSkipVoteDropsLeftListeners(t *testing.T) {
+	c := newTestQueue(1, &CachedTrack{TrackID: "a"})
+	c.AddSkipVote(1, "a", 10, map[int64]struct{}{10: {}, 11: {}})
+	c.AddSkipVote(1, "a", 11, map[int64]struct{}{10: {}, 11: {}})
+
+	// User 10 has left the voice chat since voting.
+	if votes, added := c.AddSkipVote(1, "a", 12, map[int64]struct{}{11: {}, 12: {}}); votes != 2 || !added {
+		t.Errorf("vote after a voter left = %d, %t; want 2, true", votes, added)
+	}
+}
+
+func TestSkipVotesResetOnTrackChange(t *testing.T) {
+	c := newTestQueue(1, &CachedTrack{TrackID: "a"}, &CachedTrack{TrackID: "a"})
+	listeners := map[int64]struct{}{10: {}}
+	c.AddSkipVote(1, "a", 10, listeners)
+	c.RemoveCurrentSong(1, false)
+
+	if votes, added := c.AddSkipVote(1, "a", 10, listeners); votes != 1 || !added {
+		t.Errorf("vote after the track changed = %d, %t; want 1, true", votes, added)
+	}
+}
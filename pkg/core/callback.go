@@ -0,0 +1,113 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxCallbackData is the most bytes Telegram accepts as the data of a callback button.
+const MaxCallbackData = 64
+
+const (
+	callbackSeparator = '|'  // callbackSeparator separates the namespace and fields of callback data.
+	callbackEscape    = '\\' // callbackEscape precedes a separator or escape character that is part of a field.
+)
+
+// ErrCallbackTooLong is returned by EncodeCallback for data longer than MaxCallbackData.
+var ErrCallbackTooLong = errors.New("the callback data is too long")
+
+// legacyCallbackFields is how many fields the legacy data of a namespace, such as "settings_play_admins", splits into.
+// The last field takes the rest of the data. Namespaces not listed split at every underscore.
+var legacyCallbackFields = map[string]int{
+	"setlang":  1,
+	"settings": 2,
+}
+
+// EncodeCallback encodes a namespace and its fields as callback data, such as "settings|play|admins".
+// Separators and escape characters in the fields are escaped, so any field value can be decoded again. It returns
+// ErrCallbackTooLong if the data does not fit in MaxCallbackData bytes, and an error for a namespace that is empty or
+// holds a separator, an escape character or an underscore.
+func EncodeCallback(namespace string, fields ...string) (string, error) {
+	if namespace == "" || strings.ContainsAny(namespace, string([]rune{callbackSeparator, callbackEscape, '_'})) {
+		return "", fmt.Errorf("invalid callback namespace %q", namespace)
+	}
+
+	var b strings.Builder
+	b.WriteString(namespace)
+	for _, field := range fields {
+		b.WriteRune(callbackSeparator)
+		for _, r := range field {
+			if r == callbackSeparator || r == callbackEscape {
+				b.WriteRune(callbackEscape)
+			}
+			b.WriteRune(r)
+		}
+	}
+
+	if b.Len() > MaxCallbackData {
+		return "", fmt.Errorf("%w: %d bytes for %s, at most %d", ErrCallbackTooLong, b.Len(), namespace, MaxCallbackData)
+	}
+	return b.String(), nil
+}
+
+// mustEncodeCallback is EncodeCallback for the buttons of this package, whose data is fixed and known to fit.
+// It panics if the data cannot be encoded.
+func mustEncodeCallback(namespace string, fields ...string) string {
+	data, err := EncodeCallback(namespace, fields...)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// DecodeCallback decodes callback data made by EncodeCallback into its namespace and fields.
+// Buttons sent before EncodeCallback was used, which may still be in chats, carry data such as "play_skip" or
+// "settings_play_admins"; data without a separator is decoded that way, split at underscores.
+func DecodeCallback(data string) (namespace string, fields []string) {
+	if !strings.ContainsRune(data, callbackSeparator) {
+		return decodeLegacyCallback(data)
+	}
+
+	var (
+		parts   []string
+		cur     strings.Builder
+		escaped bool
+	)
+	for _, r := range data {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == callbackEscape:
+			escaped = true
+		case r == callbackSeparator:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	parts = append(parts, cur.String())
+	return parts[0], parts[1:]
+}
+
+// decodeLegacyCallback decodes callback data of the underscore-separated format used before EncodeCallback.
+func decodeLegacyCallback(data string) (string, []string) {
+	namespace, rest, ok := strings.Cut(data, "_")
+	if !ok {
+		return data, nil
+	}
+	if n, ok := legacyCallbackFields[namespace]; ok {
+		return namespace, strings.SplitN(rest, "_", n)
+	}
+	return namespace, strings.Split(rest, "_")
+}
+
+// CallbackPattern returns the pattern that routes the callbacks of a namespace to their handler.
+// It matches the data of that namespace only, in both the current and the legacy format: "play" matches "play|skip"
+// and "play_skip", but not "vcplay|close" or "autoplay|skip".
+func CallbackPattern(namespace string) string {
+	return "^" + regexp.QuoteMeta(namespace) + `(\||_|$)`
+}
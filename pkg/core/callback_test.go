@@ -0,0 +1,132 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCallbackRoundTrip(t *testing.T) {
+	tests := []struct {
+		namespace string
+		fields    []string
+		want      string
+	}{
+		{"play", []string{"skip"}, "play|skip"},
+		{"settings", []string{"play", "admins"}, "settings|play|admins"},
+		{"setlang", []string{"pt_BR"}, "setlang|pt_BR"},
+		{"settings", []string{"a|b", "c"}, `settings|a\|b|c`},
+		{"settings", []string{`back\slash`, `trailing\`}, `settings|back\\slash|trailing\\`},
+		{"settings", []string{`\|`, "|"}, `settings|\\\||\|`},
+		{"settings", []string{"", "x", ""}, "settings||x|"},
+		{"setlang", []string{"हिन्दी"}, "setlang|हिन्दी"},
+		{"vcplay", nil, "vcplay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			data, err := EncodeCallback(tt.namespace, tt.fields...)
+			if err != nil {
+				t.Fatalf("EncodeCallback() error = %v", err)
+			}
+			if data != tt.want {
+				t.Errorf("EncodeCallback() = %q, want %q", data, tt.want)
+			}
+
+			namespace, fields := DecodeCallback(data)
+			if namespace != tt.namespace || !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("DecodeCallback(%q) = %q, %q, want %q, %q", data, namespace, fields, tt.namespace, tt.fields)
+			}
+		})
+	}
+}
+
+func TestEncodeCallbackLength(t *testing.T) {
+	// "settings|" takes 9 bytes, so a field of 55 fills the limit exactly.
+	if _, err := EncodeCallback("settings", strings.Repeat("a", MaxCallbackData-9)); err != nil {
+		t.Errorf("EncodeCallback() of exactly %d bytes error = %v", MaxCallbackData, err)
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+	}{
+		{"one byte over", []string{strings.Repeat("a", MaxCallbackData-8)}},
+		{"escapes count", []string{strings.Repeat("|", 28)}},
+		{"multi-byte runes count in bytes", []string{strings.Repeat("я", 28)}},
+		{"many fields", strings.Split(strings.Repeat("x", 30), "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeCallback("settings", tt.fields...)
+			if !errors.Is(err, ErrCallbackTooLong) {
+				t.Errorf("EncodeCallback() = %q, %v, want ErrCallbackTooLong", data, err)
+			}
+		})
+	}
+}
+
+func TestEncodeCallbackInvalidNamespace(t *testing.T) {
+	for _, namespace := range []string{"", "play|skip", `play\`, "play_skip"} {
+		if data, err := EncodeCallback(namespace, "x"); err == nil || errors.Is(err, ErrCallbackTooLong) {
+			t.Errorf("EncodeCallback(%q) = %q, %v, want an invalid namespace error", namespace, data, err)
+		}
+	}
+}
+
+func TestDecodeLegacyCallback(t *testing.T) {
+	tests := []struct {
+		data       string
+		wantNS     string
+		wantFields []string
+	}{
+		{"play_skip", "play", []string{"skip"}},
+		{"play_audioonly", "play", []string{"audioonly"}},
+		{"vcplay_close", "vcplay", []string{"close"}},
+		{"settings_play_admins", "settings", []string{"play", "admins"}},
+		{"settings_admin_mode_everyone", "settings", []string{"admin", "mode_everyone"}},
+		{"settings_none", "settings", []string{"none"}},
+		{"setlang_pt_BR", "setlang", []string{"pt_BR"}},
+		{"help_all", "help", []string{"all"}},
+		{"queue_page_2_next", "queue", []string{"page", "2", "next"}},
+		{"close", "close", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			namespace, fields := DecodeCallback(tt.data)
+			if namespace != tt.wantNS || !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("DecodeCallback(%q) = %q, %q, want %q, %q", tt.data, namespace, fields, tt.wantNS, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestCallbackPattern(t *testing.T) {
+	tests := []struct {
+		namespace string
+		data      string
+		want      bool
+	}{
+		{"play", "play|skip", true},
+		{"play", "play_skip", true},
+		{"play", "play", true},
+		{"play", "autoplay|skip", false},
+		{"play", "autoplay_skip", false},
+		{"play", "vcplay|close", false},
+		{"play", "playlist|1", false},
+		{"play", "player_skip", false},
+		{"settings", "settings|play|admins", true},
+		{"settings", "settings_play_admins", true},
+		{"settings", "setlang|en", false},
+		{"setlang", "setlang_pt_BR", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace+" "+tt.data, func(t *testing.T) {
+			re := regexp.MustCompile(CallbackPattern(tt.namespace))
+			if got := re.MatchString(tt.data); got != tt.want {
+				t.Errorf("CallbackPattern(%q) matches %q = %v, want %v", tt.namespace, tt.data, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LinkStateTTL is how long a link started with /link can be completed on the gateway.
+const LinkStateTTL = 10 * time.Minute
+
+// ErrLinkingDisabled is returned when account linking is used without a SESSION_KEY.
+var ErrLinkingDisabled = errors.New("account linking is disabled: SESSION_KEY is not set")
+
+// ErrInvalidLinkState is returned for a link state that is unknown or has expired.
+var ErrInvalidLinkState = errors.New("the link state is invalid or has expired")
+
+// tokenCipher returns the AEAD that gateway user tokens are encrypted with, keyed by a hash of SESSION_KEY.
+func tokenCipher() (cipher.AEAD, error) {
+	if config.Conf.SessionKey == "" {
+		return nil, ErrLinkingDisabled
+	}
+	key := sha256.Sum256([]byte(config.Conf.SessionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptToken seals a gateway user token, returning the nonce and ciphertext encoded as base64.
+func encryptToken(token string) (string, error) {
+	aead, err := tokenCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(token), nil)), nil
+}
+
+// decryptToken opens a gateway user token sealed by encryptToken.
+func decryptToken(sealed string) (string, error) {
+	aead, err := tokenCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("the sealed token is too short")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the token, was SESSION_KEY changed? %w", err)
+	}
+	return string(plain), nil
+}
+
+// CreateLinkState creates the state token a user's link on the gateway is tied to, replacing any earlier one.
+// The state expires after LinkStateTTL.
+func (db *Database) CreateLinkState(ctx context.Context, userID int64) (string, error) {
+	if config.Conf.SessionKey == "" {
+		return "", ErrLinkingDisabled
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(b[:])
+	_, err := db.UserDB.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{
+		"link_state":         state,
+		"link_state_expires": time.Now().Add(LinkStateTTL),
+	}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// ConsumeLinkState returns the user a link state belongs to and removes it, so it can be used only once.
+// It returns ErrInvalidLinkState if no user has the state or it has expired.
+func (db *Database) ConsumeLinkState(ctx context.Context, state string) (int64, error) {
+	if state == "" {
+		return 0, ErrInvalidLinkState
+	}
+	filter := bson.M{"link_state": state, "link_state_expires": bson.M{"$gt": time.Now()}}
+	update := bson.M{"$unset": bson.M{"link_state": "", "link_state_expires": ""}}
+
+	var user struct {
+		ID int64 `bson:"_id"`
+	}
+	err := db.UserDB.FindOneAndUpdate(ctx, filter, update).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, ErrInvalidLinkState
+	}
+	if err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// SetGatewayToken stores a user's gateway token, encrypted with the key derived from SESSION_KEY.
+func (db *Database) SetGatewayToken(ctx context.Context, userID int64, token string) error {
+	sealed, err := encryptToken(token)
+	if err != nil {
+		return err
+	}
+	_, err = db.UserDB.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{
+		"gateway_token":  sealed,
+		"gateway_linked": time.Now(),
+	}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetGatewayToken retrieves a user's gateway token.
+// It returns an empty token if the user has not linked an account.
+func (db *Database) GetGatewayToken(ctx context.Context, userID int64) (string, error) {
+	var user struct {
+		Token string `bson:"gateway_token"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"gateway_token": 1})
+	err := db.UserDB.FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && user.Token == "") {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return decryptToken(user.Token)
+}
+
+// DeleteGatewayToken removes a user's gateway token.
+// It reports whether the user had one.
+func (db *Database) DeleteGatewayToken(ctx context.Context, userID int64) (bool, error) {
+	res, err := db.UserDB.UpdateOne(ctx,
+		bson.M{"_id": userID, "gateway_token": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"gateway_token": "", "gateway_linked": ""}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetBanner retrieves the path of the banner shown with the start and help messages of a bot.
+// It returns an empty path if no banner is set.
+func (db *Database) GetBanner(ctx context.Context, botID int64) string {
+	key := toKey(botID)
+	if cached, ok := db.BotCache.Get(key); ok {
+		if v, ok := cached["banner"].(string); ok {
+			return v
+		}
+	}
+
+	var data map[string]interface{}
+	_ = db.BotDB.FindOne(ctx, bson.M{"_id": botID}).Decode(&data)
+	path, _ := data["banner"].(string)
+
+	cached, _ := db.BotCache.Get(key)
+	if cached == nil {
+		cached = map[string]interface{}{}
+	}
+	cached["banner"] = path
+	db.BotCache.Set(key, cached)
+	return path
+}
+
+// SetBanner stores the path of the banner shown with the start and help messages of a bot.
+// An empty path removes the banner.
+func (db *Database) SetBanner(ctx context.Context, botID int64, path string) error {
+	update := bson.M{"$set": bson.M{"banner": path}}
+	if path == "" {
+		update = bson.M{"$unset": bson.M{"banner": ""}}
+	}
+	_, err := db.BotDB.UpdateOne(ctx, bson.M{"_id": botID}, update, options.Update().SetUpsert(true))
+	if err == nil {
+		cached, _ := db.BotCache.Get(toKey(botID))
+		if cached == nil {
+			cached = map[string]interface{}{}
+		}
+		cached["banner"] = path
+		db.BotCache.Set(toKey(botID), cached)
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FlushCommandStats writes the daily command counts recorded in memory to the stats collection, one document per
+// command and day. Counts that could not be written are kept for the next flush.
+// Nothing is recorded, and so nothing written, unless COMMAND_STATS_FLUSH is enabled.
+func (db *Database) FlushCommandStats(ctx context.Context) error {
+	names := cache.CommandDayStatNames()
+	var errs []error
+	for _, delta := range cache.TakeCommandStats() {
+		inc := bson.M{}
+		for i, n := range delta.Counts {
+			if n != 0 {
+				inc[names[i]] = n
+			}
+		}
+
+		_, err := db.StatsDB.UpdateOne(ctx,
+			bson.M{"_id": fmt.Sprintf("command:%s:%s", delta.Command, delta.Day)},
+			bson.M{"$inc": inc, "$setOnInsert": bson.M{"command": delta.Command, "day": delta.Day}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			cache.RestoreCommandStats(delta)
+			errs = append(errs, fmt.Errorf("command %s: %w", delta.Command, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StartCommandStatsFlusher writes the daily command counts to the database every queueStatsFlushInterval in the
+// background, if COMMAND_STATS_FLUSH is enabled. The counts recorded since the last flush are written on shutdown by
+// calling FlushCommandStats.
+func (db *Database) StartCommandStatsFlusher() {
+	if !config.Conf.CommandStatsFlush {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(queueStatsFlushInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), queueStatsFlushTimeout)
+			if err := db.FlushCommandStats(ctx); err != nil {
+				gologging.WarnF("[DB] Failed to flush the command stats: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetAddedDevs returns the developers added to a bot with /adddev, on top of those of the config.
+// It is read from the database each time: it is only needed at startup and when the list changes.
+func (db *Database) GetAddedDevs(ctx context.Context, botID int64) ([]int64, error) {
+	var doc struct {
+		Devs []int64 `bson:"devs"`
+	}
+	err := db.BotDB.FindOne(ctx, bson.M{"_id": botID}, options.FindOne().SetProjection(bson.M{"devs": 1})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return doc.Devs, err
+}
+
+// AddDev adds a developer to a bot.
+// It reports false if the user was already added.
+func (db *Database) AddDev(ctx context.Context, botID, userID int64) (bool, error) {
+	res, err := db.BotDB.UpdateOne(ctx, bson.M{"_id": botID}, bson.M{"$addToSet": bson.M{"devs": userID}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0 || res.UpsertedCount > 0, nil
+}
+
+// RemoveDev removes a developer added to a bot.
+// It reports false if the user was not added.
+func (db *Database) RemoveDev(ctx context.Context, botID, userID int64) (bool, error) {
+	res, err := db.BotDB.UpdateOne(ctx, bson.M{"_id": botID}, bson.M{"$pull": bson.M{"devs": userID}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HistoryMaxDays is how long finished tracks are kept in the play history, and so the longest period it can be read for.
+const HistoryMaxDays = 90
+
+// HistoryEntry is a track that finished playing in a chat.
+type HistoryEntry struct {
+	ChatID      int64     `bson:"chat_id"`
+	FinishedAt  time.Time `bson:"finished_at"`
+	Name        string    `bson:"name"`
+	URL         string    `bson:"url"`
+	Platform    string    `bson:"platform"`
+	Duration    int       `bson:"duration"`
+	Requester   string    `bson:"requester"`
+	RequesterID int64     `bson:"requester_id"`
+}
+
+// ensureHistoryIndexes creates the indexes of the history collection: one for reading a chat's history by time, and
+// a TTL index that drops entries older than HistoryMaxDays.
+func (db *Database) ensureHistoryIndexes(ctx context.Context) error {
+	_, err := db.HistoryDB.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "chat_id", Value: 1}, {Key: "finished_at", Value: 1}}},
+		{Keys: bson.D{{Key: "finished_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(HistoryMaxDays * 24 * 60 * 60)},
+	})
+	return err
+}
+
+// AddHistory records a track that finished playing.
+func (db *Database) AddHistory(ctx context.Context, entry HistoryEntry) error {
+	_, err := db.HistoryDB.InsertOne(ctx, entry)
+	return err
+}
+
+// GetHistory returns the tracks that finished playing in a chat since a time, oldest first.
+// If there are more than limit, only the most recent limit are returned.
+func (db *Database) GetHistory(ctx context.Context, chatID int64, since time.Time, limit int64) ([]HistoryEntry, error) {
+	cursor, err := db.HistoryDB.Find(ctx,
+		bson.M{"chat_id": chatID, "finished_at": bson.M{"$gte": since}},
+		options.Find().SetSort(bson.D{{Key: "finished_at", Value: -1}}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []HistoryEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
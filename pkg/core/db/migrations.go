@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// migrationBatchSize is how many document updates are sent per bulk write.
+	migrationBatchSize = 500
+	// migrationTimeout bounds the whole migration run.
+	migrationTimeout = 10 * time.Minute
+	// schemaDocID is the _id of the bot collection document holding the applied migration version.
+	schemaDocID = "schema"
+)
+
+// migration rewrites chat documents stored in a legacy shape to their canonical shape.
+// Each migration must be idempotent: its filter only matches documents it has not rewritten yet.
+type migration struct {
+	version int
+	name    string
+	filter  bson.M
+	rewrite func(doc bson.M) bson.M // rewrite returns the $set update for doc, or nil to leave it untouched.
+}
+
+// migrationStore is the part of a collection that migrations use. *mongo.Collection implements it.
+type migrationStore interface {
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	UpdateOne(ctx context.Context, filter, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+}
+
+// migrations are applied in order, each at most once per database.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "store play_type as int32",
+		filter:  bson.M{"play_type": bson.M{"$type": bson.A{"long", "double"}}},
+		rewrite: func(doc bson.M) bson.M {
+			switch v := doc["play_type"].(type) {
+			case int64:
+				return bson.M{"play_type": int32(v)}
+			case float64:
+				return bson.M{"play_type": int32(v)}
+			}
+			return nil
+		},
+	},
+	{
+		version: 2,
+		name:    "store auth_users as int64",
+		filter:  bson.M{"auth_users": bson.M{"$elemMatch": bson.M{"$type": bson.A{"string", "double"}}}},
+		rewrite: func(doc bson.M) bson.M {
+			users, ok := doc["auth_users"].(bson.A)
+			if !ok {
+				return nil
+			}
+
+			out, dropped := parseAuthUsers(users)
+			for _, v := range dropped {
+				gologging.WarnF("[DB] Migration 2: dropping the auth user %#v of chat %v, which is not a user ID", v, doc["_id"])
+			}
+			return bson.M{"auth_users": out}
+		},
+	},
+	{
+		version: 3,
+		name:    "set a missing language to en",
+		filter:  bson.M{"_id": bson.M{"$lt": 0}, "language": bson.M{"$exists": false}},
+		rewrite: func(bson.M) bson.M {
+			return bson.M{"language": "en"}
+		},
+	},
+}
+
+// parseAuthUsers converts the auth users of a chat document to user IDs. Entries that are not user IDs, such as a
+// string that is not a number, are returned as dropped.
+func parseAuthUsers(users bson.A) (ids []int64, dropped []interface{}) {
+	ids = make([]int64, 0, len(users))
+	for _, u := range users {
+		switch v := u.(type) {
+		case string:
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				dropped = append(dropped, v)
+				continue
+			}
+			ids = append(ids, id)
+		case int32:
+			ids = append(ids, int64(v))
+		case int64:
+			ids = append(ids, v)
+		case float64:
+			ids = append(ids, int64(v))
+		default:
+			dropped = append(dropped, v)
+		}
+	}
+	return ids, dropped
+}
+
+// runMigrations applies the migrations newer than the version recorded in the bot collection.
+// A failing migration is logged and stops the run; startup continues, and the migration is retried on the next start.
+func (db *Database) runMigrations() {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+	migrate(ctx, db.ChatDB, db.BotDB)
+}
+
+// migrate applies the migrations newer than the version recorded in meta to the chat documents in chats.
+func migrate(ctx context.Context, chats, meta migrationStore) {
+	current, err := schemaVersion(ctx, meta)
+	if err != nil {
+		gologging.WarnF("[DB] Failed to read the schema version: %v", err)
+		return
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		gologging.InfoF("[DB] Running migration %d (%s)", m.version, m.name)
+		updated, err := applyMigration(ctx, chats, m)
+		if err != nil {
+			gologging.WarnF("[DB] Migration %d failed after %d documents: %v", m.version, updated, err)
+			return
+		}
+
+		if err := setSchemaVersion(ctx, meta, m.version); err != nil {
+			gologging.WarnF("[DB] Failed to record schema version %d: %v", m.version, err)
+			return
+		}
+		gologging.InfoF("[DB] Migration %d done, %d documents updated", m.version, updated)
+	}
+}
+
+// applyMigration rewrites every chat document matching the migration's filter, in batches.
+// It returns the number of documents updated.
+func applyMigration(ctx context.Context, chats migrationStore, m migration) (int, error) {
+	cursor, err := chats.Find(ctx, m.filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var updated int
+	batch := make([]mongo.WriteModel, 0, migrationBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		res, err := chats.BulkWrite(ctx, batch, options.BulkWrite().SetOrdered(false))
+		if res != nil {
+			updated += int(res.ModifiedCount)
+		}
+		batch = batch[:0]
+		if err == nil {
+			gologging.InfoF("[DB] Migration %d: %d documents updated so far", m.version, updated)
+		}
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return updated, err
+		}
+
+		set := m.rewrite(doc)
+		if set == nil {
+			continue
+		}
+		batch = append(batch, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": doc["_id"]}).SetUpdate(bson.M{"$set": set}))
+		if len(batch) == migrationBatchSize {
+			if err := flush(); err != nil {
+				return updated, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return updated, err
+	}
+	return updated, flush()
+}
+
+// schemaVersion returns the last migration version applied to the database, or 0 if none has been.
+func schemaVersion(ctx context.Context, meta migrationStore) (int, error) {
+	var doc struct {
+		Version int `bson:"version"`
+	}
+	err := meta.FindOne(ctx, bson.M{"_id": schemaDocID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the schema document: %w", err)
+	}
+	return doc.Version, nil
+}
+
+// setSchemaVersion records the last migration version applied to the database.
+func setSchemaVersion(ctx context.Context, meta migrationStore, version int) error {
+	_, err := meta.UpdateOne(ctx,
+		bson.M{"_id": schemaDocID},
+		bson.M{"$set": bson.M{"version": version}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
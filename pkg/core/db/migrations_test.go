@@ -0,0 +1,355 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// memStore is an in-memory migrationStore. Its filters understand the operators the migrations use.
+type memStore struct {
+	docs    []bson.M
+	batches []int // batches holds the size of every bulk write.
+	failErr error // failErr, if set, fails every bulk write.
+}
+
+func newMemStore(t *testing.T, docs ...bson.M) *memStore {
+	t.Helper()
+	s := &memStore{}
+	for _, doc := range docs {
+		s.docs = append(s.docs, normalize(t, doc))
+	}
+	return s
+}
+
+// normalize gives doc the types it has after a round trip through MongoDB.
+func normalize(t *testing.T, doc bson.M) bson.M {
+	t.Helper()
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatalf("bson.Marshal(%v) error = %v", doc, err)
+	}
+	var out bson.M
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("bson.Unmarshal() error = %v", err)
+	}
+	return out
+}
+
+func (s *memStore) byID(id interface{}) bson.M {
+	for _, doc := range s.docs {
+		if reflect.DeepEqual(doc["_id"], id) {
+			return doc
+		}
+	}
+	return nil
+}
+
+func (s *memStore) Find(_ context.Context, filter interface{}, _ ...*options.FindOptions) (*mongo.Cursor, error) {
+	var found []interface{}
+	for _, doc := range s.docs {
+		if matches(doc, filter.(bson.M)) {
+			found = append(found, doc)
+		}
+	}
+	return mongo.NewCursorFromDocuments(found, nil, nil)
+}
+
+func (s *memStore) FindOne(_ context.Context, filter interface{}, _ ...*options.FindOneOptions) *mongo.SingleResult {
+	if doc := s.byID(filter.(bson.M)["_id"]); doc != nil {
+		return mongo.NewSingleResultFromDocument(doc, nil, nil)
+	}
+	return mongo.NewSingleResultFromDocument(bson.M{}, mongo.ErrNoDocuments, nil)
+}
+
+func (s *memStore) UpdateOne(_ context.Context, filter, update interface{}, _ ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	id := filter.(bson.M)["_id"]
+	doc := s.byID(id)
+	if doc == nil {
+		doc = bson.M{"_id": id}
+		s.docs = append(s.docs, doc)
+	}
+	for k, v := range update.(bson.M)["$set"].(bson.M) {
+		doc[k] = v
+	}
+	return &mongo.UpdateResult{ModifiedCount: 1}, nil
+}
+
+func (s *memStore) BulkWrite(_ context.Context, models []mongo.WriteModel, _ ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	s.batches = append(s.batches, len(models))
+	if s.failErr != nil {
+		return nil, s.failErr
+	}
+
+	res := &mongo.BulkWriteResult{}
+	for _, model := range models {
+		m := model.(*mongo.UpdateOneModel)
+		doc := s.byID(m.Filter.(bson.M)["_id"])
+		if doc == nil {
+			continue
+		}
+		res.MatchedCount++
+		set := normalizeSet(m.Update.(bson.M)["$set"].(bson.M))
+		changed := false
+		for k, v := range set {
+			if !reflect.DeepEqual(doc[k], v) {
+				doc[k] = v
+				changed = true
+			}
+		}
+		if changed {
+			res.ModifiedCount++
+		}
+	}
+	return res, nil
+}
+
+func normalizeSet(set bson.M) bson.M {
+	raw, _ := bson.Marshal(set)
+	var out bson.M
+	_ = bson.Unmarshal(raw, &out)
+	return out
+}
+
+// matches reports whether doc matches filter. Only equality, $type, $elemMatch, $lt and $exists are supported.
+func matches(doc bson.M, filter bson.M) bool {
+	for field, cond := range filter {
+		value, present := doc[field]
+		ops, isOps := cond.(bson.M)
+		if !isOps {
+			if !present || !reflect.DeepEqual(value, cond) {
+				return false
+			}
+			continue
+		}
+		for op, arg := range ops {
+			switch op {
+			case "$exists":
+				if present != arg.(bool) {
+					return false
+				}
+			case "$type":
+				if !present || !hasType(value, arg.(bson.A)) {
+					return false
+				}
+			case "$lt":
+				n, ok := number(value)
+				if !ok || n >= float64(arg.(int)) {
+					return false
+				}
+			case "$elemMatch":
+				elems, ok := value.(bson.A)
+				if !ok || !anyMatches(elems, arg.(bson.M)) {
+					return false
+				}
+			default:
+				panic("unsupported filter operator " + op)
+			}
+		}
+	}
+	return true
+}
+
+// anyMatches reports whether one of elems matches an $elemMatch condition on the element itself.
+func anyMatches(elems bson.A, cond bson.M) bool {
+	for _, e := range elems {
+		if matches(bson.M{"v": e}, bson.M{"v": cond}) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasType(v interface{}, types bson.A) bool {
+	var name string
+	switch v.(type) {
+	case int32:
+		name = "int"
+	case int64:
+		name = "long"
+	case float64:
+		name = "double"
+	case string:
+		name = "string"
+	}
+	for _, t := range types {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func TestMigrate(t *testing.T) {
+	chats := newMemStore(t,
+		bson.M{"_id": int64(-1), "play_type": int64(1), "auth_users": bson.A{"5", int64(6), 7.0}, "language": "fr"},
+		bson.M{"_id": int64(-2), "play_type": 2.0},
+		bson.M{"_id": int64(-3), "auth_users": bson.A{"not a number", "11"}, "language": "hi"},
+		bson.M{"_id": int64(-4), "play_type": int32(0), "auth_users": bson.A{int64(9)}, "language": "en"},
+		bson.M{"_id": int64(10)},
+	)
+	meta := newMemStore(t)
+
+	migrate(context.Background(), chats, meta)
+
+	want := []bson.M{
+		{"_id": int64(-1), "play_type": int32(1), "auth_users": bson.A{int64(5), int64(6), int64(7)}, "language": "fr"},
+		{"_id": int64(-2), "play_type": int32(2), "language": "en"},
+		{"_id": int64(-3), "auth_users": bson.A{int64(11)}, "language": "hi"},
+		{"_id": int64(-4), "play_type": int32(0), "auth_users": bson.A{int64(9)}, "language": "en"},
+		{"_id": int64(10)},
+	}
+	for _, w := range want {
+		if got := chats.byID(w["_id"]); !reflect.DeepEqual(got, w) {
+			t.Errorf("chat %v = %v, want %v", w["_id"], got, w)
+		}
+	}
+
+	version, err := schemaVersion(context.Background(), meta)
+	if err != nil || version != migrations[len(migrations)-1].version {
+		t.Errorf("schemaVersion() = %d, %v, want %d", version, err, migrations[len(migrations)-1].version)
+	}
+}
+
+func TestMigrationsAreIdempotent(t *testing.T) {
+	chats := newMemStore(t,
+		bson.M{"_id": int64(-1), "play_type": int64(1), "auth_users": bson.A{"5"}},
+		bson.M{"_id": int64(-2), "play_type": 2.0, "auth_users": bson.A{int64(6), 7.0}},
+	)
+	migrate(context.Background(), chats, newMemStore(t))
+
+	for _, m := range migrations {
+		updated, err := applyMigration(context.Background(), chats, m)
+		if err != nil || updated != 0 {
+			t.Errorf("rerunning migration %d updated %d documents, err = %v, want 0, nil", m.version, updated, err)
+		}
+	}
+}
+
+func TestMigrateSkipsAppliedMigrations(t *testing.T) {
+	chats := newMemStore(t, bson.M{"_id": int64(-1), "play_type": int64(1)})
+	meta := newMemStore(t, bson.M{"_id": schemaDocID, "version": 2})
+
+	migrate(context.Background(), chats, meta)
+
+	want := bson.M{"_id": int64(-1), "play_type": int64(1), "language": "en"}
+	if got := chats.byID(int64(-1)); !reflect.DeepEqual(got, want) {
+		t.Errorf("chat = %v, want %v", got, want)
+	}
+}
+
+func TestMigrateBatches(t *testing.T) {
+	total := 2*migrationBatchSize + 1
+	docs := make([]bson.M, total)
+	for i := range docs {
+		docs[i] = bson.M{"_id": int64(-1 - i), "play_type": int64(1), "language": "en"}
+	}
+	chats := newMemStore(t, docs...)
+
+	updated, err := applyMigration(context.Background(), chats, migrations[0])
+	if err != nil || updated != total {
+		t.Fatalf("applyMigration() = %d, %v, want %d, nil", updated, err, total)
+	}
+	if want := []int{migrationBatchSize, migrationBatchSize, 1}; !reflect.DeepEqual(chats.batches, want) {
+		t.Errorf("bulk writes of %v documents, want %v", chats.batches, want)
+	}
+}
+
+func TestMigrateStopsOnFailure(t *testing.T) {
+	chats := newMemStore(t, bson.M{"_id": int64(-1), "play_type": int64(1)})
+	chats.failErr = errors.New("write failed")
+	meta := newMemStore(t)
+
+	migrate(context.Background(), chats, meta)
+	if version, _ := schemaVersion(context.Background(), meta); version != 0 {
+		t.Errorf("schemaVersion() after a failed migration = %d, want 0", version)
+	}
+	if len(chats.batches) != 1 {
+		t.Errorf("%d bulk writes, want the run to stop after the failing one", len(chats.batches))
+	}
+
+	// The next start retries the migration.
+	chats.failErr = nil
+	migrate(context.Background(), chats, meta)
+	if got := chats.byID(int64(-1))["play_type"]; got != int32(1) {
+		t.Errorf("play_type after the retry = %v (%T), want int32(1)", got, got)
+	}
+	if version, _ := schemaVersion(context.Background(), meta); version != migrations[len(migrations)-1].version {
+		t.Errorf("schemaVersion() after the retry = %d, want %d", version, migrations[len(migrations)-1].version)
+	}
+}
+
+func TestMatchesFilters(t *testing.T) {
+	// Guards the in-memory filter against silently matching everything, which would hide a broken migration filter.
+	tests := []struct {
+		doc    bson.M
+		filter bson.M
+		want   bool
+	}{
+		{bson.M{"play_type": int32(1)}, migrations[0].filter, false},
+		{bson.M{"play_type": int64(1)}, migrations[0].filter, true},
+		{bson.M{"auth_users": bson.A{int64(1)}}, migrations[1].filter, false},
+		{bson.M{"auth_users": bson.A{int64(1), "2"}}, migrations[1].filter, true},
+		{bson.M{"_id": int64(5)}, migrations[2].filter, false},
+		{bson.M{"_id": int64(-5), "language": "en"}, migrations[2].filter, false},
+		{bson.M{"_id": int64(-5)}, migrations[2].filter, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.doc), func(t *testing.T) {
+			if got := matches(tt.doc, tt.filter); got != tt.want {
+				t.Errorf("matches(%v, %v) = %v, want %v", tt.doc, tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAuthUsers(t *testing.T) {
+	tests := []struct {
+		name        string
+		users       bson.A
+		wantIDs     []int64
+		wantDropped []interface{}
+	}{
+		{"empty", bson.A{}, []int64{}, nil},
+		{"mixed types", bson.A{"5", int32(6), int64(7), 8.0}, []int64{5, 6, 7, 8}, nil},
+		{"bad strings", bson.A{"12", "not a number", "", "1e3", "99999999999999999999"}, []int64{12}, []interface{}{"not a number", "", "1e3", "99999999999999999999"}},
+		{"other types", bson.A{int64(1), true, nil}, []int64{1}, []interface{}{true, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, dropped := parseAuthUsers(tt.users)
+			if !reflect.DeepEqual(ids, tt.wantIDs) || !reflect.DeepEqual(dropped, tt.wantDropped) {
+				t.Errorf("parseAuthUsers(%v) = %v, %v, want %v, %v", tt.users, ids, dropped, tt.wantIDs, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestMigrateBadAuthUser(t *testing.T) {
+	// The bad entry is dropped, with a warning, and the valid ones are kept as int64.
+	chats := newMemStore(t, bson.M{"_id": int64(-1), "auth_users": bson.A{"5", "abc", int64(6)}, "language": "en"})
+	migrate(context.Background(), chats, newMemStore(t))
+
+	want := bson.A{int64(5), int64(6)}
+	if got := chats.byID(int64(-1))["auth_users"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("auth_users = %v, want %v", got, want)
+	}
+}
@@ -2,10 +2,13 @@ package db
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
 	"github.com/zuchzub/Go/pkg/config"
 "github.com/zuchzub/Go/pkg/core/cache"
 	"log"
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -20,6 +23,8 @@ type Database struct {
 	ChatDB    *mongo.Collection
 	UserDB    *mongo.Collection
 	BotDB     *mongo.Collection
+	StatsDB   *mongo.Collection
+	HistoryDB *mongo.Collection
 	ChatCache *cache.Cache[map[string]interface{}]
 	BotCache  *cache.Cache[map[string]interface{}]
 	UserCache *cache.Cache[map[string]interface{}]
@@ -44,6 +49,8 @@ func InitDatabase(ctx context.Context) error {
 		ChatDB:    db.Collection("chats"),
 		UserDB:    db.Collection("users"),
 		BotDB:     db.Collection("bot"),
+		StatsDB:   db.Collection("stats"),
+		HistoryDB: db.Collection("history"),
 		ChatCache: cache.NewCache[map[string]interface{}](20 * time.Minute),
 		BotCache:  cache.NewCache[map[string]interface{}](20 * time.Minute),
 		UserCache: cache.NewCache[map[string]interface{}](20 * time.Minute),
@@ -54,6 +61,10 @@ func InitDatabase(ctx context.Context) error {
 	}
 
 	log.Println("[DB] The database connection has been successfully established.")
+	Instance.runMigrations()
+	if err := Instance.ensureHistoryIndexes(ctx); err != nil {
+		log.Printf("[DB] Failed to create the history indexes: %v", err)
+	}
 	return nil
 }
 
@@ -63,6 +74,14 @@ func (db *Database) Ping(ctx context.Context) error {
 	return db.Client.Ping(ctx, nil)
 }
 
+// ClearCaches drops every entry held in the in-memory chat, user, and bot caches,
+// forcing subsequent reads to go back to the database.
+func (db *Database) ClearCaches() {
+	db.ChatCache.Clear()
+	db.UserCache.Clear()
+	db.BotCache.Clear()
+}
+
 // ----------------- CHAT -----------------
 
 // GetChat retrieves a chat's data from the cache or database.
@@ -183,6 +202,299 @@ func (db *Database) SetAdminMode(ctx context.Context, chatID int64, adminMode st
 	return db.updateChatField(ctx, chatID, "admin_mode", adminMode)
 }
 
+// GetNormalize reports whether loudness normalization is enabled for a chat.
+// It returns false by default.
+func (db *Database) GetNormalize(ctx context.Context, chatID int64) bool {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return false
+	}
+	if val, ok := chat["normalize"].(bool); ok {
+		return val
+	}
+	return false
+}
+
+// SetNormalize enables or disables loudness normalization for a given chat.
+func (db *Database) SetNormalize(ctx context.Context, chatID int64, enabled bool) error {
+	return db.updateChatField(ctx, chatID, "normalize", enabled)
+}
+
+// GetStayInVC reports whether the assistant should stay in a chat's voice chat after playback stops.
+// It returns false by default.
+func (db *Database) GetStayInVC(ctx context.Context, chatID int64) bool {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return false
+	}
+	if val, ok := chat["stay_in_vc"].(bool); ok {
+		return val
+	}
+	return false
+}
+
+// SetStayInVC sets whether the assistant stays in a chat's voice chat after playback stops.
+func (db *Database) SetStayInVC(ctx context.Context, chatID int64, enabled bool) error {
+	return db.updateChatField(ctx, chatID, "stay_in_vc", enabled)
+}
+
+// GetStayOnEmpty reports whether the assistant should stay in a chat's voice chat when the queue runs out.
+// Unlike GetStayInVC, it does not apply to /stop. It returns false by default.
+func (db *Database) GetStayOnEmpty(ctx context.Context, chatID int64) bool {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return false
+	}
+	if val, ok := chat["stay_on_empty"].(bool); ok {
+		return val
+	}
+	return false
+}
+
+// SetStayOnEmpty sets whether the assistant stays in a chat's voice chat when the queue runs out.
+func (db *Database) SetStayOnEmpty(ctx context.Context, chatID int64, enabled bool) error {
+	return db.updateChatField(ctx, chatID, "stay_on_empty", enabled)
+}
+
+// GetIdent returns the path of a chat's station ident, the short clip played between tracks.
+// It returns an empty string if the chat has none.
+func (db *Database) GetIdent(ctx context.Context, chatID int64) string {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return ""
+	}
+	if val, ok := chat["ident_path"].(string); ok {
+		return val
+	}
+	return ""
+}
+
+// SetIdent sets the path of a chat's station ident. An empty path removes it.
+func (db *Database) SetIdent(ctx context.Context, chatID int64, path string) error {
+	return db.updateChatField(ctx, chatID, "ident_path", path)
+}
+
+// GetIdentEnabled reports whether a chat's station ident is played between tracks. It returns false by default.
+func (db *Database) GetIdentEnabled(ctx context.Context, chatID int64) bool {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return false
+	}
+	if val, ok := chat["ident_enabled"].(bool); ok {
+		return val
+	}
+	return false
+}
+
+// SetIdentEnabled sets whether a chat's station ident is played between tracks.
+func (db *Database) SetIdentEnabled(ctx context.Context, chatID int64, enabled bool) error {
+	return db.updateChatField(ctx, chatID, "ident_enabled", enabled)
+}
+
+// GetReorderNotify reports whether requesters are told when a reorder of a chat's queue pushes their tracks back.
+// It returns false by default.
+func (db *Database) GetReorderNotify(ctx context.Context, chatID int64) bool {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return false
+	}
+	if val, ok := chat["reorder_notify"].(bool); ok {
+		return val
+	}
+	return false
+}
+
+// SetReorderNotify sets whether requesters are told when a reorder of a chat's queue pushes their tracks back.
+func (db *Database) SetReorderNotify(ctx context.Context, chatID int64, enabled bool) error {
+	return db.updateChatField(ctx, chatID, "reorder_notify", enabled)
+}
+
+// DefaultStopConfirm is how many upcoming tracks a chat's queue can have before /stop asks for confirmation, for
+// chats that have not set their own threshold.
+const DefaultStopConfirm = 5
+
+// GetStopConfirm returns how many upcoming tracks a chat's queue can have before /stop asks for confirmation.
+// It returns DefaultStopConfirm by default; 0 means /stop never asks.
+func (db *Database) GetStopConfirm(ctx context.Context, chatID int64) int {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return DefaultStopConfirm
+	}
+	if val, ok := chat["stop_confirm"].(int32); ok {
+		return int(val)
+	}
+	return DefaultStopConfirm
+}
+
+// SetStopConfirm sets how many upcoming tracks a chat's queue can have before /stop asks for confirmation.
+func (db *Database) SetStopConfirm(ctx context.Context, chatID int64, threshold int) error {
+	return db.updateChatField(ctx, chatID, "stop_confirm", int32(threshold))
+}
+
+// DefaultVoteSkip is the percentage of a voice chat's listeners that must vote with /voteskip to skip a track, for
+// chats that have not set their own threshold.
+const DefaultVoteSkip = 50
+
+// GetVoteSkip returns the percentage of a voice chat's listeners that must vote with /voteskip to skip a track.
+// It returns DefaultVoteSkip by default.
+func (db *Database) GetVoteSkip(ctx context.Context, chatID int64) int {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return DefaultVoteSkip
+	}
+	if val, ok := chat["vote_skip"].(int32); ok {
+		return int(val)
+	}
+	return DefaultVoteSkip
+}
+
+// SetVoteSkip sets the percentage of a voice chat's listeners that must vote with /voteskip to skip a track.
+func (db *Database) SetVoteSkip(ctx context.Context, chatID int64, percent int) error {
+	return db.updateChatField(ctx, chatID, "vote_skip", int32(percent))
+}
+
+// GetAutoplay reports whether related tracks are queued automatically when a chat's queue runs out.
+// It returns false by default.
+func (db *Database) GetAutoplay(ctx context.Context, chatID int64) bool {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return false
+	}
+	if val, ok := chat["autoplay"].(bool); ok {
+		return val
+	}
+	return false
+}
+
+// SetAutoplay enables or disables autoplay for a given chat.
+func (db *Database) SetAutoplay(ctx context.Context, chatID int64, enabled bool) error {
+	return db.updateChatField(ctx, chatID, "autoplay", enabled)
+}
+
+// DefaultVolume is the playback volume, in percent, of chats that have not set their own.
+const DefaultVolume = 100
+
+// GetVolume returns the playback volume of a chat in percent.
+// It returns DefaultVolume by default.
+func (db *Database) GetVolume(ctx context.Context, chatID int64) int {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return DefaultVolume
+	}
+	if val, ok := chat["volume"].(int32); ok {
+		return int(val)
+	}
+	return DefaultVolume
+}
+
+// SetVolume sets the playback volume of a chat in percent.
+func (db *Database) SetVolume(ctx context.Context, chatID int64, volume int) error {
+	return db.updateChatField(ctx, chatID, "volume", int32(volume))
+}
+
+// GetAudioPreset returns the audio preset of a chat, such as a bass boost level.
+// It returns "off" by default.
+func (db *Database) GetAudioPreset(ctx context.Context, chatID int64) string {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return "off"
+	}
+	if val, ok := chat["audio_preset"].(string); ok && val != "" {
+		return val
+	}
+	return "off"
+}
+
+// SetAudioPreset sets the audio preset of a chat.
+func (db *Database) SetAudioPreset(ctx context.Context, chatID int64, preset string) error {
+	return db.updateChatField(ctx, chatID, "audio_preset", preset)
+}
+
+// GetAnnouncements reports whether a chat has opted in to bot update announcements.
+// It returns false by default.
+func (db *Database) GetAnnouncements(ctx context.Context, chatID int64) bool {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		return false
+	}
+	if val, ok := chat["announcements"].(bool); ok {
+		return val
+	}
+	return false
+}
+
+// SetAnnouncements opts a chat in to or out of bot update announcements.
+func (db *Database) SetAnnouncements(ctx context.Context, chatID int64, enabled bool) error {
+	return db.updateChatField(ctx, chatID, "announcements", enabled)
+}
+
+// GetAnnouncementChats returns the chats that opted in to announcements and have not yet received the one with the given hash.
+func (db *Database) GetAnnouncementChats(ctx context.Context, hash string) ([]int64, error) {
+	cursor, err := db.ChatDB.Find(ctx, bson.M{"announcements": true, "last_announcement": bson.M{"$ne": hash}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var chats []int64
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID int64 `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		chats = append(chats, doc.ID)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
+
+// SetLastAnnouncement records the hash of the last announcement delivered to a chat.
+// It bypasses the chat cache because the field is only read by GetAnnouncementChats.
+func (db *Database) SetLastAnnouncement(ctx context.Context, chatID int64, hash string) error {
+	_, err := db.ChatDB.UpdateOne(ctx, bson.M{"_id": chatID}, bson.M{"$set": bson.M{"last_announcement": hash}})
+	return err
+}
+
+// Session is a snapshot of a chat's queue and playback position, saved so that it can be resumed after a restart.
+type Session struct {
+	Queue    []*cache.CachedTrack `bson:"queue"`
+	Position int                  `bson:"position"`
+	SavedAt  time.Time            `bson:"saved_at"`
+}
+
+// SaveSession stores a session snapshot in a chat's document, replacing any earlier one.
+// Sessions bypass the chat cache because they are only read back once.
+func (db *Database) SaveSession(ctx context.Context, chatID int64, session Session) error {
+	_, err := db.ChatDB.UpdateOne(ctx, bson.M{"_id": chatID}, bson.M{"$set": bson.M{"session": session}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetSession retrieves the session snapshot saved for a chat.
+// It returns nil if no session has been saved.
+func (db *Database) GetSession(ctx context.Context, chatID int64) (*Session, error) {
+	var doc struct {
+		Session *Session `bson:"session"`
+	}
+	err := db.ChatDB.FindOne(ctx, bson.M{"_id": chatID}, options.FindOne().SetProjection(bson.M{"session": 1})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.Session, nil
+}
+
+// ClearSession removes the session snapshot saved for a chat.
+func (db *Database) ClearSession(ctx context.Context, chatID int64) error {
+	_, err := db.ChatDB.UpdateOne(ctx, bson.M{"_id": chatID}, bson.M{"$unset": bson.M{"session": ""}})
+	return err
+}
+
 // GetAssistant retrieves the username of the assistant for a chat.
 func (db *Database) GetAssistant(ctx context.Context, chatID int64) (string, error) {
 	chat, _ := db.GetChat(ctx, chatID)
@@ -256,6 +568,62 @@ func (db *Database) GetLang(ctx context.Context, chatID int64) string {
 	return db.getChatLang(ctx, chatID)
 }
 
+// ----------------- DISABLED COMMANDS -----------------
+
+// DisableCommand adds a command to the list of disabled commands for a chat.
+func (db *Database) DisableCommand(ctx context.Context, chatID int64, command string) error {
+	_, err := db.ChatDB.UpdateOne(ctx,
+		bson.M{"_id": chatID},
+		bson.M{"$addToSet": bson.M{"disabled_commands": command}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	commands := db.GetDisabledCommands(ctx, chatID)
+	if !slices.Contains(commands, command) {
+		commands = append(commands, command)
+	}
+	db.setCachedChatField(ctx, chatID, "disabled_commands", commands)
+	return nil
+}
+
+// EnableCommand removes a command from the list of disabled commands for a chat.
+func (db *Database) EnableCommand(ctx context.Context, chatID int64, command string) error {
+	_, err := db.ChatDB.UpdateOne(ctx,
+		bson.M{"_id": chatID},
+		bson.M{"$pull": bson.M{"disabled_commands": command}},
+	)
+	if err != nil {
+		return err
+	}
+	commands := slices.DeleteFunc(db.GetDisabledCommands(ctx, chatID), func(c string) bool { return c == command })
+	db.setCachedChatField(ctx, chatID, "disabled_commands", commands)
+	return nil
+}
+
+// GetDisabledCommands retrieves the list of disabled commands for a chat.
+func (db *Database) GetDisabledCommands(ctx context.Context, chatID int64) []string {
+	chat, _ := db.GetChat(ctx, chatID)
+	commands, _ := getStringSlice(chat["disabled_commands"])
+	return commands
+}
+
+// IsCommandDisabled checks if a command is disabled in a chat.
+func (db *Database) IsCommandDisabled(ctx context.Context, chatID int64, command string) bool {
+	return slices.Contains(db.GetDisabledCommands(ctx, chatID), command)
+}
+
+// setCachedChatField updates a field in the cached copy of a chat's document.
+func (db *Database) setCachedChatField(ctx context.Context, chatID int64, key string, value interface{}) {
+	chat, _ := db.GetChat(ctx, chatID)
+	if chat == nil {
+		chat = make(map[string]interface{})
+	}
+	chat[key] = value
+	db.ChatCache.Set(toKey(chatID), chat)
+}
+
 // ----------------- AUTH USERS -----------------
 
 // AddAuthUser adds a user to the list of authorized users for a chat.
@@ -317,6 +685,58 @@ func (db *Database) IsAuthUser(ctx context.Context, chatID, userID int64) bool {
 	return contains(users, userID)
 }
 
+// ----------------- DJ USERS -----------------
+
+// AddDJ adds a user to the DJs of a chat, who may control playback without being an administrator.
+func (db *Database) AddDJ(ctx context.Context, chatID, userID int64) error {
+	_, err := db.ChatDB.UpdateOne(ctx,
+		bson.M{"_id": chatID},
+		bson.M{"$addToSet": bson.M{"dj_users": userID}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	chat, _ := db.GetChat(ctx, chatID)
+	djs, _ := getIntSlice(chat["dj_users"])
+	if !contains(djs, userID) {
+		djs = append(djs, userID)
+	}
+	chat["dj_users"] = djs
+	db.ChatCache.Set(toKey(chatID), chat)
+	return nil
+}
+
+// RemoveDJ removes a user from the DJs of a chat.
+func (db *Database) RemoveDJ(ctx context.Context, chatID, userID int64) error {
+	_, err := db.ChatDB.UpdateOne(ctx,
+		bson.M{"_id": chatID},
+		bson.M{"$pull": bson.M{"dj_users": userID}},
+	)
+	if err != nil {
+		return err
+	}
+	chat, _ := db.GetChat(ctx, chatID)
+	djs, _ := getIntSlice(chat["dj_users"])
+	djs = remove(djs, userID)
+	chat["dj_users"] = djs
+	db.ChatCache.Set(toKey(chatID), chat)
+	return nil
+}
+
+// GetDJs retrieves the DJs of a chat.
+func (db *Database) GetDJs(ctx context.Context, chatID int64) []int64 {
+	chat, _ := db.GetChat(ctx, chatID)
+	users, _ := getIntSlice(chat["dj_users"])
+	return users
+}
+
+// IsDJ checks if a specific user is a DJ in a chat.
+// Unlike IsAuthUser, administrators are not DJs unless they were added as one.
+func (db *Database) IsDJ(ctx context.Context, chatID, userID int64) bool {
+	return contains(db.GetDJs(ctx, chatID), userID)
+}
+
 // IsAdmin checks if a specific user is an administrator in a chat.
 func (db *Database) IsAdmin(ctx context.Context, chatID, userID int64) bool {
 	admins, err := cache.GetChatAdmins(chatID)
@@ -369,6 +789,87 @@ func (db *Database) SetLoggerStatus(ctx context.Context, botID int64, status boo
 	return err
 }
 
+// GetMaintenance retrieves the maintenance state for a given bot.
+// It returns whether maintenance mode is on and the ETA text set along with it.
+func (db *Database) GetMaintenance(ctx context.Context, botID int64) (bool, string) {
+	key := toKey(botID)
+	if cached, ok := db.BotCache.Get(key); ok {
+		if v, ok := cached["maintenance"].(bool); ok {
+			eta, _ := cached["maintenance_eta"].(string)
+			return v, eta
+		}
+	}
+
+	var data map[string]interface{}
+	_ = db.BotDB.FindOne(ctx, bson.M{"_id": botID}).Decode(&data)
+
+	enabled, _ := data["maintenance"].(bool)
+	eta, _ := data["maintenance_eta"].(string)
+
+	cached, _ := db.BotCache.Get(key)
+	if cached == nil {
+		cached = map[string]interface{}{}
+	}
+	cached["maintenance"] = enabled
+	cached["maintenance_eta"] = eta
+	db.BotCache.Set(key, cached)
+	return enabled, eta
+}
+
+// SetMaintenance turns maintenance mode on or off for a bot, storing the optional ETA text.
+func (db *Database) SetMaintenance(ctx context.Context, botID int64, enabled bool, eta string) error {
+	_, err := db.BotDB.UpdateOne(ctx,
+		bson.M{"_id": botID},
+		bson.M{"$set": bson.M{"maintenance": enabled, "maintenance_eta": eta}},
+		options.Update().SetUpsert(true),
+	)
+	if err == nil {
+		cached, _ := db.BotCache.Get(toKey(botID))
+		if cached == nil {
+			cached = map[string]interface{}{}
+		}
+		cached["maintenance"] = enabled
+		cached["maintenance_eta"] = eta
+		db.BotCache.Set(toKey(botID), cached)
+	}
+	return err
+}
+
+// GetInstanceID returns the random ID that identifies this bot instance in telemetry pings.
+// The ID is generated and stored in the bot collection the first time it is requested.
+func (db *Database) GetInstanceID(ctx context.Context, botID int64) (string, error) {
+	var data map[string]interface{}
+	err := db.BotDB.FindOne(ctx, bson.M{"_id": botID}).Decode(&data)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return "", err
+	}
+	if id, ok := data["instance_id"].(string); ok && id != "" {
+		return id, nil
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.BotDB.UpdateOne(ctx,
+		bson.M{"_id": botID},
+		bson.M{"$set": bson.M{"instance_id": id}},
+		options.Update().SetUpsert(true),
+	)
+	return id, err
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate an instance ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
 // ----------------- USERS -----------------
 
 // AddUser adds a new user to the database if they do not already exist.
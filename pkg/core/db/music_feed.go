@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MusicFeed is where a chat's queue confirmations and now-playing messages are posted instead of the chat itself:
+// a channel, or a topic of the chat.
+type MusicFeed struct {
+	ChatID  int64 `bson:"chat_id"`
+	TopicID int32 `bson:"topic_id,omitempty"` // TopicID is the forum topic to post in, or 0 for the whole chat.
+}
+
+// musicFeeds maps chats to their feeds and feeds back to their chats, so callbacks pressed in a feed act on the chat it
+// belongs to. It mirrors the music_feed field of the chat documents, and is loaded by LoadMusicFeeds.
+var musicFeeds = struct {
+	sync.RWMutex
+	byChat map[int64]MusicFeed
+	byFeed map[int64]int64
+}{byChat: make(map[int64]MusicFeed), byFeed: make(map[int64]int64)}
+
+// LoadMusicFeeds reads the music feeds of all chats from the database.
+// It returns the number of feeds loaded.
+func (db *Database) LoadMusicFeeds(ctx context.Context) (int, error) {
+	cursor, err := db.ChatDB.Find(ctx, bson.M{"music_feed": bson.M{"$exists": true}}, options.Find().SetProjection(bson.M{"music_feed": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var docs []struct {
+		ID   int64     `bson:"_id"`
+		Feed MusicFeed `bson:"music_feed"`
+	}
+	if err = cursor.All(ctx, &docs); err != nil {
+		return 0, err
+	}
+
+	musicFeeds.Lock()
+	defer musicFeeds.Unlock()
+	for _, doc := range docs {
+		musicFeeds.byChat[doc.ID] = doc.Feed
+		if doc.Feed.ChatID != doc.ID {
+			musicFeeds.byFeed[doc.Feed.ChatID] = doc.ID
+		}
+	}
+	return len(docs), nil
+}
+
+// GetMusicFeed returns the music feed of a chat, and false if the chat has none.
+func (db *Database) GetMusicFeed(chatID int64) (MusicFeed, bool) {
+	musicFeeds.RLock()
+	defer musicFeeds.RUnlock()
+	feed, ok := musicFeeds.byChat[chatID]
+	return feed, ok
+}
+
+// MusicFeedOwner returns the chat that a feed belongs to, and false if the chat is not the feed of another chat.
+// A topic of the chat itself is not listed, as callbacks pressed there already come from the chat.
+func (db *Database) MusicFeedOwner(feedChatID int64) (int64, bool) {
+	musicFeeds.RLock()
+	defer musicFeeds.RUnlock()
+	chatID, ok := musicFeeds.byFeed[feedChatID]
+	return chatID, ok
+}
+
+// SetMusicFeed sets the music feed of a chat, replacing any feed it had.
+func (db *Database) SetMusicFeed(ctx context.Context, chatID int64, feed MusicFeed) error {
+	if _, err := db.ChatDB.UpdateOne(ctx, bson.M{"_id": chatID}, bson.M{"$set": bson.M{"music_feed": feed}}, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
+
+	musicFeeds.Lock()
+	defer musicFeeds.Unlock()
+	if old, ok := musicFeeds.byChat[chatID]; ok {
+		delete(musicFeeds.byFeed, old.ChatID)
+	}
+	musicFeeds.byChat[chatID] = feed
+	if feed.ChatID != chatID {
+		musicFeeds.byFeed[feed.ChatID] = chatID
+	}
+	return nil
+}
+
+// DeleteMusicFeed removes the music feed of a chat.
+// It reports whether the chat had one.
+func (db *Database) DeleteMusicFeed(ctx context.Context, chatID int64) (bool, error) {
+	if _, err := db.ChatDB.UpdateOne(ctx, bson.M{"_id": chatID}, bson.M{"$unset": bson.M{"music_feed": ""}}); err != nil {
+		return false, err
+	}
+
+	musicFeeds.Lock()
+	defer musicFeeds.Unlock()
+	old, ok := musicFeeds.byChat[chatID]
+	if ok {
+		delete(musicFeeds.byChat, chatID)
+		delete(musicFeeds.byFeed, old.ChatID)
+	}
+	return ok, nil
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// playlistProgressMaxAge is how long a playlist can be resumed after its last completed track.
+	playlistProgressMaxAge = 14 * 24 * time.Hour
+	// playlistProgressKeep is how many playlists are remembered per chat.
+	playlistProgressKeep = 5
+)
+
+// PlaylistProgress records the last track of a playlist that finished playing in a chat.
+type PlaylistProgress struct {
+	URL       string    `bson:"url"`
+	Index     int       `bson:"index"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// getPlaylistProgress reads the playlist progress saved in a chat's document, most recent first.
+// Like sessions, it bypasses the chat cache.
+func (db *Database) getPlaylistProgress(ctx context.Context, chatID int64) ([]PlaylistProgress, error) {
+	var doc struct {
+		Progress []PlaylistProgress `bson:"playlist_progress"`
+	}
+	err := db.ChatDB.FindOne(ctx, bson.M{"_id": chatID}, options.FindOne().SetProjection(bson.M{"playlist_progress": 1})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return doc.Progress, err
+}
+
+// SavePlaylistProgress records that the track at index of the playlist at url finished playing in a chat.
+// The playlist becomes the most recent one of the chat; only the last playlistProgressKeep are kept.
+func (db *Database) SavePlaylistProgress(ctx context.Context, chatID int64, url string, index int) error {
+	progress, err := db.getPlaylistProgress(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	progress = withPlaylistProgress(progress, PlaylistProgress{URL: url, Index: index, UpdatedAt: time.Now()})
+
+	_, err = db.ChatDB.UpdateOne(ctx, bson.M{"_id": chatID}, bson.M{"$set": bson.M{"playlist_progress": progress}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// withPlaylistProgress puts latest first in the progress of a chat, replacing the entry of the same playlist,
+// and drops the oldest entries beyond playlistProgressKeep.
+func withPlaylistProgress(progress []PlaylistProgress, latest PlaylistProgress) []PlaylistProgress {
+	progress = slices.DeleteFunc(progress, func(p PlaylistProgress) bool { return p.URL == latest.URL })
+	progress = slices.Insert(progress, 0, latest)
+	return progress[:min(len(progress), playlistProgressKeep)]
+}
+
+// GetLatestPlaylistProgress returns the most recent playlist that a chat played, or nil if there is none that can still be resumed.
+func (db *Database) GetLatestPlaylistProgress(ctx context.Context, chatID int64) (*PlaylistProgress, error) {
+	progress, err := db.getPlaylistProgress(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	return latestPlaylistProgress(progress, time.Now()), nil
+}
+
+// latestPlaylistProgress returns the most recently updated entry not older than playlistProgressMaxAge at now, or nil.
+func latestPlaylistProgress(progress []PlaylistProgress, now time.Time) *PlaylistProgress {
+	var latest *PlaylistProgress
+	for i := range progress {
+		p := &progress[i]
+		if now.Sub(p.UpdatedAt) > playlistProgressMaxAge {
+			continue
+		}
+		if latest == nil || p.UpdatedAt.After(latest.UpdatedAt) {
+			latest = p
+		}
+	}
+	return latest
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithPlaylistProgress(t *testing.T) {
+	now := time.Now()
+	entry := func(n, index int) PlaylistProgress {
+		return PlaylistProgress{URL: fmt.Sprintf("https://example.com/list/%d", n), Index: index, UpdatedAt: now.Add(-time.Duration(n) * time.Hour)}
+	}
+
+	got := withPlaylistProgress(nil, entry(1, 0))
+	if want := []PlaylistProgress{entry(1, 0)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first playlist: got %v, want %v", got, want)
+	}
+
+	// A playlist already known moves to the front with its new index, and is not duplicated.
+	got = withPlaylistProgress([]PlaylistProgress{entry(1, 3), entry(2, 7), entry(3, 1)}, entry(2, 8))
+	if want := []PlaylistProgress{entry(2, 8), entry(1, 3), entry(3, 1)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("known playlist: got %v, want %v", got, want)
+	}
+
+	// Only the most recent playlists are kept.
+	var full []PlaylistProgress
+	for n := 1; n <= playlistProgressKeep; n++ {
+		full = append(full, entry(n, n))
+	}
+	got = withPlaylistProgress(full, entry(0, 4))
+	if len(got) != playlistProgressKeep || got[0] != entry(0, 4) || got[len(got)-1] != entry(playlistProgressKeep-1, playlistProgressKeep-1) {
+		t.Errorf("full list: got %v, want the new playlist first and the oldest dropped", got)
+	}
+}
+
+func TestLatestPlaylistProgress(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := PlaylistProgress{URL: "recent", Index: 15, UpdatedAt: now.Add(-time.Hour)}
+	older := PlaylistProgress{URL: "older", Index: 2, UpdatedAt: now.Add(-48 * time.Hour)}
+	limit := PlaylistProgress{URL: "limit", Index: 4, UpdatedAt: now.Add(-playlistProgressMaxAge)}
+	stale := PlaylistProgress{URL: "stale", Index: 9, UpdatedAt: now.Add(-playlistProgressMaxAge - time.Minute)}
+	ancient := PlaylistProgress{URL: "ancient", Index: 20, UpdatedAt: now.Add(-30 * 24 * time.Hour)}
+
+	tests := []struct {
+		name     string
+		progress []PlaylistProgress
+		want     string
+	}{
+		{"none", nil, ""},
+		{"most recent", []PlaylistProgress{older, recent}, "recent"},
+		{"order does not matter", []PlaylistProgress{recent, older}, "recent"},
+		{"stale ignored", []PlaylistProgress{stale, older}, "older"},
+		{"exactly fourteen days", []PlaylistProgress{limit}, "limit"},
+		{"all stale", []PlaylistProgress{stale, ancient}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := latestPlaylistProgress(tt.progress, now)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("latestPlaylistProgress() = %+v, want nil", *got)
+			case tt.want != "" && (got == nil || got.URL != tt.want):
+				t.Errorf("latestPlaylistProgress() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
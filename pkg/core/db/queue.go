@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// queueFlushInterval is how often the queues that changed are written to the database.
+	queueFlushInterval = 3 * time.Second
+	// queueFlushTimeout bounds a single flush.
+	queueFlushTimeout = 30 * time.Second
+)
+
+// SaveQueue stores the tracks of a chat's queue in its document, so that the queue survives a crash or restart.
+// Only metadata is kept: the downloaded files do not outlive the process, so file paths are left out.
+// An empty queue removes the stored one. Queues bypass the chat cache because they are only read back on resume.
+func (db *Database) SaveQueue(ctx context.Context, chatID int64, queue []*cache.CachedTrack) error {
+	if len(queue) == 0 {
+		_, err := db.ChatDB.UpdateOne(ctx, bson.M{"_id": chatID}, bson.M{"$unset": bson.M{"queue": ""}})
+		return err
+	}
+
+	tracks := make([]cache.CachedTrack, len(queue))
+	for i, track := range queue {
+		tracks[i] = *track
+		tracks[i].FilePath = ""
+	}
+	_, err := db.ChatDB.UpdateOne(ctx, bson.M{"_id": chatID}, bson.M{"$set": bson.M{"queue": tracks}}, options.Update().SetUpsert(true))
+	return err
+}
+
+// LoadQueue retrieves the queue stored for a chat, without file paths.
+// It returns nil if no queue is stored.
+func (db *Database) LoadQueue(ctx context.Context, chatID int64) ([]*cache.CachedTrack, error) {
+	var doc struct {
+		Queue []*cache.CachedTrack `bson:"queue"`
+	}
+	err := db.ChatDB.FindOne(ctx, bson.M{"_id": chatID}, options.FindOne().SetProjection(bson.M{"queue": 1})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, track := range doc.Queue {
+		track.FilePath = ""
+	}
+	return doc.Queue, nil
+}
+
+// FlushQueues writes the queues that changed since the last flush to the database.
+// Queues that could not be written are marked as changed again, so that the next flush retries them.
+func (db *Database) FlushQueues(ctx context.Context) error {
+	var errs []error
+	for chatID, queue := range cache.ChatCache.TakeChangedQueues() {
+		if err := db.SaveQueue(ctx, chatID, queue); err != nil {
+			cache.ChatCache.MarkChanged(chatID)
+			errs = append(errs, fmt.Errorf("chat %d: %w", chatID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StartQueueFlusher writes the queues that changed to the database every queueFlushInterval in the background.
+// Changes made since the last flush are written on shutdown by calling FlushQueues.
+func (db *Database) StartQueueFlusher() {
+	go func() {
+		ticker := time.NewTicker(queueFlushInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), queueFlushTimeout)
+			if err := db.FlushQueues(ctx); err != nil {
+				gologging.WarnF("[DB] Failed to flush the queues: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// queueStatsFlushInterval is how often the queue statistics kept in memory are written to the database.
+	queueStatsFlushInterval = 5 * time.Minute
+	// queueStatsFlushTimeout bounds a single flush.
+	queueStatsFlushTimeout = 30 * time.Second
+)
+
+// FlushQueueStats writes the queue statistics recorded in memory to the stats collection,
+// one document per chat and day. Counts that could not be written are kept for the next flush.
+func (db *Database) FlushQueueStats(ctx context.Context) error {
+	var errs []error
+	for _, delta := range cache.TakeQueueStats() {
+		inc := bson.M{}
+		for _, stat := range cache.QueueStats() {
+			if n := delta.Counts[stat]; n != 0 {
+				inc[stat.String()] = n
+			}
+		}
+
+		_, err := db.StatsDB.UpdateOne(ctx,
+			bson.M{"_id": fmt.Sprintf("%d:%s", delta.ChatID, delta.Day)},
+			bson.M{"$inc": inc, "$setOnInsert": bson.M{"chat_id": delta.ChatID, "day": delta.Day}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			cache.RestoreQueueStats(delta)
+			errs = append(errs, fmt.Errorf("chat %d: %w", delta.ChatID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StartQueueStatsFlusher writes the queue statistics to the database every queueStatsFlushInterval in the background.
+// The counts recorded since the last flush are written on shutdown by calling FlushQueueStats.
+func (db *Database) StartQueueStatsFlusher() {
+	go func() {
+		ticker := time.NewTicker(queueStatsFlushInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), queueStatsFlushTimeout)
+			if err := db.FlushQueueStats(ctx); err != nil {
+				gologging.WarnF("[DB] Failed to flush the queue stats: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// GetQueueStats returns a chat's queue statistics by day, for the days from since (as returned by cache.StatsDay) on.
+// Counts not yet flushed are not included; see cache.PendingQueueStats.
+func (db *Database) GetQueueStats(ctx context.Context, chatID int64, since string) (map[string]cache.QueueCounts, error) {
+	cursor, err := db.StatsDB.Find(ctx, bson.M{"chat_id": chatID, "day": bson.M{"$gte": since}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]cache.QueueCounts, len(docs))
+	for _, doc := range docs {
+		day, _ := doc["day"].(string)
+		var counts cache.QueueCounts
+		for _, stat := range cache.QueueStats() {
+			switch n := doc[stat.String()].(type) {
+			case int32:
+				counts[stat] = int64(n)
+			case int64:
+				counts[stat] = n
+			case float64:
+				counts[stat] = int64(n)
+			}
+		}
+		stats[day] = counts
+	}
+	return stats, nil
+}
@@ -34,6 +34,35 @@ func getIntSlice(v interface{}) ([]int64, bool) {
 	}
 }
 
+// getStringSlice safely converts an interface value into a slice of strings.
+// It returns a boolean indicating the success of the conversion.
+func getStringSlice(v interface{}) ([]string, bool) {
+	var arr []interface{}
+	switch val := v.(type) {
+	case nil:
+		return []string{}, false
+	case []string:
+		return val, true
+	case []interface{}:
+		arr = val
+	case primitive.A:
+		arr = val
+	default:
+		gologging.InfoF("Unexpected type encountered in getStringSlice: %T", v)
+		return []string{}, false
+	}
+
+	out := make([]string, 0, len(arr))
+	for _, i := range arr {
+		s, ok := i.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
 // convertInterfaceSlice converts a slice of interfaces to a slice of int64
 func convertInterfaceSlice(arr []interface{}) ([]int64, bool) {
 	var out []int64
@@ -0,0 +1,146 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDownloadCancelled is returned by a download cancelled with CancelDownload.
+var ErrDownloadCancelled = errors.New("the download was cancelled")
+
+// ActiveDownload is a snapshot of a download in progress.
+type ActiveDownload struct {
+	ID       uint64
+	ChatID   int64
+	Name     string
+	Platform string
+	Started  time.Time
+	Deadline time.Time // Deadline is when the download times out, or zero if it has no deadline.
+	Done     int64     // Done is the number of bytes downloaded so far, where the downloader reports it.
+	Total    int64     // Total is the size of the download in bytes, or 0 if it is unknown.
+}
+
+// activeDownload is an entry of the registry of downloads in progress.
+type activeDownload struct {
+	info   ActiveDownload
+	done   atomic.Int64
+	total  atomic.Int64
+	cancel context.CancelCauseFunc
+}
+
+// activeKey is the context key under which a download's registry entry is stored.
+type activeKey struct{}
+
+// progressKey is the context key under which the function told of a download's progress is stored.
+type progressKey struct{}
+
+var (
+	activeMu     sync.Mutex
+	active       = make(map[uint64]*activeDownload)
+	lastActiveID atomic.Uint64
+)
+
+// StartDownload registers a download for a chat in the registry shown by /downloads, and returns a context for it that
+// CancelDownload cancels. Downloaders given the context report their progress to the entry.
+// The returned function removes the entry and must be called when the download ends, however it ends.
+func StartDownload(ctx context.Context, chatID int64, name, platform string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	entry := &activeDownload{
+		info: ActiveDownload{
+			ID:       lastActiveID.Add(1),
+			ChatID:   chatID,
+			Name:     name,
+			Platform: platform,
+			Started:  time.Now(),
+		},
+		cancel: cancel,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		entry.info.Deadline = deadline
+	}
+
+	activeMu.Lock()
+	active[entry.info.ID] = entry
+	activeMu.Unlock()
+
+	return context.WithValue(ctx, activeKey{}, entry), func() {
+		activeMu.Lock()
+		delete(active, entry.info.ID)
+		activeMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// SetDownloadProgress records the progress of the download registered in ctx, if any, and passes it to the function
+// given to WithProgress. total is 0 if the size is unknown.
+func SetDownloadProgress(ctx context.Context, done, total int64) {
+	if entry, ok := ctx.Value(activeKey{}).(*activeDownload); ok {
+		entry.done.Store(done)
+		entry.total.Store(total)
+	}
+	if onProgress, ok := ctx.Value(progressKey{}).(func(done, total int64)); ok {
+		onProgress(done, total)
+	}
+}
+
+// WithProgress returns a context whose download also reports its progress to onProgress, as SetDownloadProgress
+// records it. onProgress is called by the downloader, as often as it reports progress, so it must return quickly.
+func WithProgress(ctx context.Context, onProgress func(done, total int64)) context.Context {
+	return context.WithValue(ctx, progressKey{}, onProgress)
+}
+
+// DownloadCancelled returns ErrDownloadCancelled if the download registered in ctx was cancelled with CancelDownload.
+// It is used after downloads that cannot be interrupted, so their result is discarded instead.
+func DownloadCancelled(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), ErrDownloadCancelled) {
+		return ErrDownloadCancelled
+	}
+	return nil
+}
+
+// ActiveDownloads returns a snapshot of the downloads in progress, oldest first.
+func ActiveDownloads() []ActiveDownload {
+	activeMu.Lock()
+	downloads := make([]ActiveDownload, 0, len(active))
+	for _, entry := range active {
+		info := entry.info
+		info.Done, info.Total = entry.done.Load(), entry.total.Load()
+		downloads = append(downloads, info)
+	}
+	activeMu.Unlock()
+
+	slices.SortFunc(downloads, func(a, b ActiveDownload) int { return a.Started.Compare(b.Started) })
+	return downloads
+}
+
+// CancelDownload cancels a download in progress. It reports false if no download has this ID.
+func CancelDownload(id uint64) bool {
+	activeMu.Lock()
+	entry, ok := active[id]
+	activeMu.Unlock()
+	if ok {
+		entry.cancel(ErrDownloadCancelled)
+	}
+	return ok
+}
+
+// progressReader counts the bytes read from a download body into the download registered in ctx.
+type progressReader struct {
+	io.Reader
+	ctx   context.Context
+	done  int64
+	total int64
+}
+
+// Read implements io.Reader.
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.done += int64(n)
+	SetDownloadProgress(r.ctx, r.done, r.total)
+	return n, err
+}
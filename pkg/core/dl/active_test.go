@@ -0,0 +1,150 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// downloadsOf returns the downloads in progress for a chat, so that tests do not see each other's.
+func downloadsOf(chatID int64) []ActiveDownload {
+	var found []ActiveDownload
+	for _, d := range ActiveDownloads() {
+		if d.ChatID == chatID {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+func TestActiveDownloads(t *testing.T) {
+	const chatID = -5001
+	deadline := time.Now().Add(time.Minute)
+	parent, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	ctx1, done1 := StartDownload(parent, chatID, "first", "youtube")
+	time.Sleep(time.Millisecond)
+	ctx2, done2 := StartDownload(context.Background(), chatID, "second", "telegram")
+	defer done2()
+
+	SetDownloadProgress(ctx1, 10, 100)
+	SetDownloadProgress(context.Background(), 99, 99) // A download that is not registered is ignored.
+
+	got := downloadsOf(chatID)
+	if len(got) != 2 || got[0].Name != "first" || got[1].Name != "second" {
+		t.Fatalf("ActiveDownloads() = %+v, want first and second, oldest first", got)
+	}
+	if got[0].Done != 10 || got[0].Total != 100 || got[0].Platform != "youtube" || !got[0].Deadline.Equal(deadline) {
+		t.Errorf("first download = %+v, want 10 of 100 bytes on youtube with the parent's deadline", got[0])
+	}
+	if !got[1].Deadline.IsZero() || got[1].Done != 0 {
+		t.Errorf("second download = %+v, want no deadline and no progress", got[1])
+	}
+
+	done1()
+	if got := downloadsOf(chatID); len(got) != 1 || got[0].Name != "second" {
+		t.Errorf("ActiveDownloads() after the first ended = %+v, want only second", got)
+	}
+	if ctx1.Err() == nil {
+		t.Error("the context of an ended download is not cancelled")
+	}
+	if err := DownloadCancelled(ctx1); err != nil {
+		t.Errorf("DownloadCancelled() of a download that ended = %v, want nil", err)
+	}
+	if ctx2.Err() != nil {
+		t.Error("ending one download cancelled another")
+	}
+}
+
+func TestCancelDownload(t *testing.T) {
+	const chatID = -5002
+	ctx, done := StartDownload(context.Background(), chatID, "stuck", "direct")
+	defer done()
+
+	if CancelDownload(0) {
+		t.Error("CancelDownload() of an unknown ID = true")
+	}
+	if err := DownloadCancelled(ctx); err != nil {
+		t.Fatalf("DownloadCancelled() before cancelling = %v", err)
+	}
+
+	if !CancelDownload(downloadsOf(chatID)[0].ID) {
+		t.Fatal("CancelDownload() of a download in progress = false")
+	}
+	if !errors.Is(DownloadCancelled(ctx), ErrDownloadCancelled) || ctx.Err() == nil {
+		t.Errorf("after CancelDownload, DownloadCancelled() = %v and ctx.Err() = %v", DownloadCancelled(ctx), ctx.Err())
+	}
+
+	// The entry stays until the download ends, as the downloader may still be winding down.
+	done()
+	if got := downloadsOf(chatID); len(got) != 0 {
+		t.Errorf("ActiveDownloads() after the download ended = %+v", got)
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	ctx, done := StartDownload(context.Background(), -5003, "song", "direct")
+	defer done()
+
+	var reported []int64
+	ctx = WithProgress(ctx, func(done, total int64) { reported = append(reported, done, total) })
+
+	body := &progressReader{Reader: strings.NewReader("abcdefgh"), ctx: ctx, total: 8}
+	buf := make([]byte, 3)
+	for {
+		if _, err := body.Read(buf); err != nil {
+			break
+		}
+	}
+
+	if got := downloadsOf(-5003)[0]; got.Done != 8 || got.Total != 8 {
+		t.Errorf("download progress = %d of %d, want 8 of 8", got.Done, got.Total)
+	}
+	if len(reported) < 2 || reported[len(reported)-2] != 8 || reported[len(reported)-1] != 8 {
+		t.Errorf("reported progress = %v, want it to end with 8 of 8", reported)
+	}
+}
+
+func TestActiveDownloadsConcurrently(t *testing.T) {
+	const chatID, workers, rounds = -5004, 8, 200
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				for _, d := range ActiveDownloads() {
+					if d.ChatID == chatID && d.Done > d.Total {
+						t.Errorf("snapshot of %d has %d of %d bytes", d.ID, d.Done, d.Total)
+					}
+					CancelDownload(d.ID)
+				}
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				ctx, done := StartDownload(context.Background(), chatID, "song", "direct")
+				SetDownloadProgress(ctx, int64(i), int64(rounds))
+				done()
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+
+	if got := downloadsOf(chatID); len(got) != 0 {
+		t.Errorf("%d downloads still registered after all ended", len(got))
+	}
+}
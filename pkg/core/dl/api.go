@@ -15,6 +15,9 @@ import (
 	"github.com/Laky-64/gologging"
 )
 
+// spotifyPodcastRegex matches Spotify episode and show URLs and captures which of the two it is.
+var spotifyPodcastRegex = regexp.MustCompile(`(?i)^(?:https?://)?(?:[a-z0-9-]+\.)*spotify\.com/(episode|show)/`)
+
 // ApiData provides a unified interface for fetching track and playlist information from various music platforms via an API gateway.
 type ApiData struct {
 	Query    string
@@ -31,7 +34,7 @@ func NewApiData(query string) *ApiData {
 		APIKey: config.Conf.ApiKey,
 		Patterns: map[string]*regexp.Regexp{
 			"apple_music": regexp.MustCompile(`(?i)^(https?://)?([a-z0-9-]+\.)*music\.apple\.com/([a-z]{2}/)?(album|playlist|song)/[a-zA-Z0-9\-._]+/(pl\.[a-zA-Z0-9]+|\d+)(\?.*)?$`),
-			"spotify":     regexp.MustCompile(`(?i)^(https?://)?([a-z0-9-]+\.)*spotify\.com/(track|playlist|album|artist)/[a-zA-Z0-9]+(\?.*)?$`),
+			"spotify":     regexp.MustCompile(`(?i)^(https?://)?([a-z0-9-]+\.)*spotify\.com/(track|playlist|album|artist|episode|show)/[a-zA-Z0-9]+(\?.*)?$`),
 			"yt_playlist": regexp.MustCompile(`(?i)^(?:https?://)?(?:www\.)?(?:youtube\.com|music\.youtube\.com)/(?:playlist|watch)\?.*\blist=([\w-]+)`),
 			"yt_music":    regexp.MustCompile(`(?i)^(?:https?://)?music\.youtube\.com/(?:watch|playlist)\?.*v=([\w-]+)`),
 			"jiosaavn":    regexp.MustCompile(`(?i)^(https?://)?(www\.)?jiosaavn\.com/(song|featured)/[\w-]+/[a-zA-Z0-9_-]+$`),
@@ -62,14 +65,32 @@ func (a *ApiData) GetInfo(ctx context.Context) (cache.PlatformTracks, error) {
 	if !a.IsValid() {
 		return cache.PlatformTracks{}, errors.New("the provided URL is invalid or the platform is not supported")
 	}
+	return a.getInfo(ctx, map[string]string{"X-API-Key": a.APIKey})
+}
+
+// GetUserInfo retrieves metadata for a playlist on behalf of the user the gateway token belongs to, so playlists only
+// they can see, such as their liked tracks, can be fetched.
+// The query is a playlist URL listed by UserPlaylists; it is not matched against the known patterns, as the URLs of
+// liked tracks have forms of their own.
+func (a *ApiData) GetUserInfo(ctx context.Context, userToken string) (cache.PlatformTracks, error) {
+	if a.Query == "" || a.ApiUrl == "" || a.APIKey == "" {
+		return cache.PlatformTracks{}, errors.New("the query, API URL, or API key is missing")
+	}
+	return a.getInfo(ctx, map[string]string{"X-API-Key": a.APIKey, "X-User-Token": userToken})
+}
 
+// getInfo retrieves metadata for the query URL, sending headers with the request.
+func (a *ApiData) getInfo(ctx context.Context, headers map[string]string) (cache.PlatformTracks, error) {
 	fullURL := fmt.Sprintf("%s/get_url?%s", a.ApiUrl, url.Values{"url": {a.Query}}.Encode())
-	resp, err := sendRequest(ctx, http.MethodGet, fullURL, nil, map[string]string{"X-API-Key": a.APIKey})
+	resp, err := sendRequest(ctx, http.MethodGet, fullURL, nil, headers)
 	if err != nil {
 		return cache.PlatformTracks{}, fmt.Errorf("the GetInfo request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return cache.PlatformTracks{}, fmt.Errorf("%w: %s", ErrTrackUnavailable, resp.Status)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return cache.PlatformTracks{}, fmt.Errorf("unexpected status code while fetching info: %s", resp.Status)
 	}
@@ -78,9 +99,35 @@ func (a *ApiData) GetInfo(ctx context.Context) (cache.PlatformTracks, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
 		return cache.PlatformTracks{}, fmt.Errorf("failed to decode the GetInfo response: %w", err)
 	}
+
+	contentType := a.contentType()
+	for i := range data.Results {
+		if data.Results[i].ContentType == "" {
+			data.Results[i].ContentType = contentType
+		}
+	}
+
+	// The gateway lists a show's episodes newest first.
+	if a.isPodcastShow() && len(data.Results) > config.Conf.PodcastEpisodes {
+		data.Results = data.Results[:config.Conf.PodcastEpisodes]
+	}
 	return data, nil
 }
 
+// contentType returns the content type implied by the query URL, used when the API response does not set one.
+func (a *ApiData) contentType() string {
+	if spotifyPodcastRegex.MatchString(a.Query) {
+		return cache.ContentPodcast
+	}
+	return cache.ContentMusic
+}
+
+// isPodcastShow reports whether the query is a podcast show URL rather than a single episode.
+func (a *ApiData) isPodcastShow() bool {
+	match := spotifyPodcastRegex.FindStringSubmatch(a.Query)
+	return match != nil && strings.EqualFold(match[1], "show")
+}
+
 // Search queries the API for a track. The context can be used for timeouts or cancellations.
 // If the query is a valid URL, it fetches the information directly.
 // It returns a PlatformTracks object or an error if the search fails.
@@ -94,13 +141,7 @@ func (a *ApiData) Search(ctx context.Context) (cache.PlatformTracks, error) {
 		"limit": {"5"},
 	}.Encode())
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
-	if err != nil {
-		return cache.PlatformTracks{}, fmt.Errorf("failed to create the search request: %w", err)
-	}
-	req.Header.Set("X-API-Key", a.APIKey)
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := sendRequest(ctx, http.MethodGet, fullURL, nil, map[string]string{"X-API-Key": a.APIKey})
 	if err != nil {
 		return cache.PlatformTracks{}, fmt.Errorf("the search request failed: %w", err)
 	}
@@ -135,30 +176,14 @@ func (a *ApiData) GetTrack(ctx context.Context) (cache.TrackInfo, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
 		return cache.TrackInfo{}, fmt.Errorf("failed to decode the GetTrack response: %w", err)
 	}
+	if data.ContentType == "" {
+		data.ContentType = a.contentType()
+	}
 	return data, nil
 }
 
-// downloadTrack downloads a track using the API. If the track is a YouTube video and video format is requested,
-// it delegates the download to the YouTube downloader.
-// It returns the file path of the downloaded track or an error if the download fails.
+// downloadTrack downloads a track with the download strategies of its platform.
+// It returns the file path of the downloaded track or a *StrategyError naming every failed attempt.
 func (a *ApiData) downloadTrack(ctx context.Context, info cache.TrackInfo, video bool) (string, error) {
-	if info.Platform == "youtube" && video {
-		yt := NewYouTubeData(a.Query)
-		return yt.downloadTrack(ctx, info, video)
-	}
-
-	downloader, err := NewDownload(ctx, info)
-	if err != nil {
-		return "", fmt.Errorf("failed to initialize the download: %w", err)
-	}
-
-	filePath, err := downloader.Process()
-	if err != nil {
-		if info.Platform == "youtube" {
-			yt := NewYouTubeData(a.Query)
-			return yt.downloadTrack(ctx, info, video)
-		}
-		return "", fmt.Errorf("the download process failed: %w", err)
-	}
-	return filePath, nil
+	return runStrategies(ctx, info, video)
 }
@@ -0,0 +1,56 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ApiHealth describes the outcome of a single connectivity check against the API gateway.
+type ApiHealth struct {
+	URL         string
+	StatusCode  int
+	Status      string
+	Latency     time.Duration
+	KeyAccepted bool
+	Err         error
+}
+
+// CheckApi performs one trivial authenticated search against the configured API gateway, without retries.
+// Err is set when the gateway could not be reached at all; otherwise StatusCode tells a rejected key (401/403)
+// apart from a failing gateway (5xx).
+func CheckApi(ctx context.Context) ApiHealth {
+	apiUrl := strings.TrimRight(config.Conf.ApiUrl, "/")
+	health := ApiHealth{URL: apiUrl}
+	if apiUrl == "" || config.Conf.ApiKey == "" {
+		health.Err = errors.New("the API URL or API key is not configured")
+		return health
+	}
+
+	fullURL := fmt.Sprintf("%s/search?%s", apiUrl, url.Values{"query": {"test"}, "limit": {"1"}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		health.Err = fmt.Errorf("failed to create the request: %w", err)
+		return health
+	}
+	req.Header.Set("X-API-Key", config.Conf.ApiKey)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Err = err
+		return health
+	}
+	defer resp.Body.Close()
+
+	health.StatusCode = resp.StatusCode
+	health.Status = resp.Status
+	health.KeyAccepted = resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden
+	return health
+}
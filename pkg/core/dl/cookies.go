@@ -0,0 +1,120 @@
+package dl
+
+import (
+	"bufio"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// cookiePlatform returns the key of the cookie files for a track platform, as in COOKIES_URL_<KEY>: "yt" for YouTube,
+// "sc" for SoundCloud, "generic" for direct links, and the platform name otherwise, which falls back to the generic
+// cookies when it has none of its own.
+func cookiePlatform(platform string) string {
+	switch strings.ToLower(platform) {
+	case cache.YouTube:
+		return "yt"
+	case "soundcloud":
+		return "sc"
+	case cache.Direct, "":
+		return "generic"
+	default:
+		return strings.ToLower(platform)
+	}
+}
+
+// readCookieFile reads the cookies of a Netscape cookie file, the format yt-dlp and browser extensions export.
+// Malformed lines are skipped.
+func readCookieFile(path string) ([]*http.Cookie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// domain, include subdomains, path, secure, expiry, name, value
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			continue
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+		cookie := &http.Cookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, scanner.Err()
+}
+
+// cookieMatches reports whether a cookie of a cookie file is to be sent with req.
+// A domain starting with a dot matches its subdomains as well, as in the Netscape format.
+func cookieMatches(cookie *http.Cookie, req *http.Request, now time.Time) bool {
+	host := strings.ToLower(req.URL.Hostname())
+	domain := strings.ToLower(cookie.Domain)
+	switch {
+	case strings.HasPrefix(domain, "."):
+		if host != domain[1:] && !strings.HasSuffix(host, domain) {
+			return false
+		}
+	case host != domain:
+		return false
+	}
+
+	if cookie.Secure && req.URL.Scheme != "https" {
+		return false
+	}
+	if !cookie.Expires.IsZero() && cookie.Expires.Before(now) {
+		return false
+	}
+	reqPath := req.URL.Path
+	if reqPath == "" {
+		reqPath = "/"
+	}
+	return cookie.Path == "" || strings.HasPrefix(reqPath, cookie.Path)
+}
+
+// addCookies adds the cookies of a cookie file that match the request's URL to req.
+// A cookie file that cannot be read is logged and skipped, so the download is tried without cookies.
+func addCookies(req *http.Request, cookieFile string) {
+	if cookieFile == "" {
+		return
+	}
+	cookies, err := readCookieFile(cookieFile)
+	if err != nil {
+		gologging.WarnF("Could not read the cookie file %s: %v", cookieFile, err)
+		return
+	}
+
+	now := time.Now()
+	for _, cookie := range cookies {
+		if cookieMatches(cookie, req, now) {
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+}
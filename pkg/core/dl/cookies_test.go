@@ -0,0 +1,94 @@
+package dl
+
+import (
+	"context"
+	"github.com/zuchzub/Go/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCookiePlatform(t *testing.T) {
+	tests := map[string]string{
+		"youtube":    "yt",
+		"SoundCloud": "sc",
+		"direct":     "generic",
+		"":           "generic",
+		"jiosaavn":   "jiosaavn",
+	}
+	for platform, want := range tests {
+		if got := cookiePlatform(platform); got != want {
+			t.Errorf("cookiePlatform(%q) = %q, want %q", platform, got, want)
+		}
+	}
+}
+
+func writeCookieFile(t *testing.T, lines string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(path, []byte("# Netscape HTTP Cookie File\n"+lines), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCookieMatches(t *testing.T) {
+	path := writeCookieFile(t, ""+
+		".soundcloud.com\tTRUE\t/\tTRUE\t0\tsession\tabc\n"+
+		"#HttpOnly_api.soundcloud.com\tFALSE\t/v2\tFALSE\t0\ttoken\txyz\n"+
+		".soundcloud.com\tTRUE\t/\tFALSE\t1\texpired\told\n"+
+		"malformed line\n")
+	cookies, err := readCookieFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 3 {
+		t.Fatalf("readCookieFile() read %d cookies, want 3", len(cookies))
+	}
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://soundcloud.com/track", []string{"session"}},
+		{"https://cf-media.soundcloud.com/x.mp3", []string{"session"}},
+		{"http://soundcloud.com/track", nil},
+		{"http://api.soundcloud.com/v2/stream", []string{"token"}},
+		{"http://api.soundcloud.com/v1/stream", nil},
+		{"https://notsoundcloud.com/", nil},
+	}
+	now := time.Now()
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		var got []string
+		for _, cookie := range cookies {
+			if cookieMatches(cookie, req, now) {
+				got = append(got, cookie.Name)
+			}
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("cookies for %s = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadFileSendsCookies(t *testing.T) {
+	config.Conf = &config.BotConfig{DownloadsDir: t.TempDir()}
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Cookie")
+		_, _ = w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	cookieFile := writeCookieFile(t, "127.0.0.1\tFALSE\t/\tFALSE\t0\tsid\t42\n")
+	if _, err := downloadFile(context.Background(), server.URL+"/a.mp3", "", true, cookieFile); err != nil {
+		t.Fatal(err)
+	}
+	if got != "sid=42" {
+		t.Errorf("the server got the cookies %q, want %q", got, "sid=42")
+	}
+}
@@ -0,0 +1,208 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+const (
+	// directProbeTimeout bounds the HEAD request used to detect media links without a known extension.
+	directProbeTimeout = 5 * time.Second
+	// directProbeTTL is how long the result of a HEAD request is reused, so that the wrappers created for one
+	// /play request do not probe the same link again.
+	directProbeTTL = 2 * time.Minute
+)
+
+// directProbe is what a HEAD request told about a link.
+type directProbe struct {
+	media bool  // media reports whether the server serves audio or video.
+	size  int64 // size is the Content-Length of the file, or 0 if it is unknown.
+}
+
+// directProbes caches the probe results by URL.
+var directProbes = cache.NewCache[directProbe](directProbeTTL)
+
+// directMediaExtensions are the file extensions treated as direct media links without probing the server.
+var directMediaExtensions = []string{
+	".mp3", ".m4a", ".aac", ".ogg", ".oga", ".opus", ".flac", ".wav",
+	".mp4", ".m4v", ".mkv", ".webm", ".mov",
+}
+
+// DirectURLData handles plain links to audio or video files, such as https://example.com/song.mp3.
+type DirectURLData struct {
+	Query string
+}
+
+// NewDirectURLData creates and initializes a new DirectURLData instance with the provided query.
+func NewDirectURLData(query string) *DirectURLData {
+	return &DirectURLData{Query: strings.TrimSpace(query)}
+}
+
+// parsedURL returns the query as an absolute http(s) URL, or nil if it is not one.
+func (d *DirectURLData) parsedURL() *url.URL {
+	u, err := url.Parse(d.Query)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	return u
+}
+
+// hasMediaExtension reports whether the URL path ends in a known media file extension.
+func (d *DirectURLData) hasMediaExtension() bool {
+	u := d.parsedURL()
+	return u != nil && slices.Contains(directMediaExtensions, strings.ToLower(path.Ext(u.Path)))
+}
+
+// IsValid checks if the query is a direct link to a media file on a public host.
+// URLs without a known extension are probed with a HEAD request and accepted if the server reports an audio or video Content-Type.
+// Links to loopback, private, link-local or unspecified addresses are refused, so that users cannot make the bot fetch
+// from its own network.
+func (d *DirectURLData) IsValid() bool {
+	if !d.isPublic() {
+		return false
+	}
+	if d.hasMediaExtension() {
+		return true
+	}
+	return d.probe().media
+}
+
+// isPublic reports whether the URL is an http(s) link whose host resolves to public addresses only.
+func (d *DirectURLData) isPublic() bool {
+	u := d.parsedURL()
+	if u == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), directProbeTimeout)
+	defer cancel()
+	if err := checkPublicHost(ctx, u.Hostname()); err != nil {
+		gologging.DebugF("Refusing the direct link %s: %v", d.Query, err)
+		return false
+	}
+	return true
+}
+
+// probe sends a HEAD request for the URL, or returns the result of one sent in the last directProbeTTL.
+func (d *DirectURLData) probe() directProbe {
+	return d.probeFrom(withPublicOnly(context.Background()))
+}
+
+// probeFrom is probe with the context the HEAD request is derived from.
+func (d *DirectURLData) probeFrom(parent context.Context) directProbe {
+	if cached, ok := directProbes.Get(d.Query); ok {
+		return cached
+	}
+
+	ctx, cancel := context.WithTimeout(parent, directProbeTimeout)
+	defer cancel()
+
+	var result directProbe
+	resp, err := sendRequest(ctx, http.MethodHead, d.Query, nil, nil)
+	if err != nil {
+		gologging.DebugF("The HEAD request for %s failed: %v", d.Query, err)
+		directProbes.Set(d.Query, result)
+		return result
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+		result.media = strings.HasPrefix(contentType, "audio/") || strings.HasPrefix(contentType, "video/")
+		result.size = max(resp.ContentLength, 0)
+	}
+	directProbes.Set(d.Query, result)
+	return result
+}
+
+// name returns a display name for the link, taken from the last path segment of the URL.
+func (d *DirectURLData) name() string {
+	u := d.parsedURL()
+	if u == nil {
+		return d.Query
+	}
+
+	name := path.Base(u.Path)
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	if name == "" || name == "/" || name == "." {
+		return u.Host
+	}
+	return name
+}
+
+// GetInfo returns the link as a single track. The duration is read with ffprobe and is 0 if it cannot be determined.
+func (d *DirectURLData) GetInfo(_ context.Context) (cache.PlatformTracks, error) {
+	if !d.IsValid() {
+		return cache.PlatformTracks{}, errors.New("the provided URL is not a direct media link")
+	}
+
+	return cache.PlatformTracks{Results: []cache.MusicTrack{{
+		URL:      d.Query,
+		Name:     d.name(),
+		ID:       d.Query,
+		Duration: cache.GetFileDuration(d.Query),
+		Platform: cache.Direct,
+	}}}, nil
+}
+
+// Search returns the link itself, since a direct link cannot be searched.
+func (d *DirectURLData) Search(ctx context.Context) (cache.PlatformTracks, error) {
+	return d.GetInfo(ctx)
+}
+
+// GetTrack returns the track details of the link, with the link itself as the CDN URL.
+func (d *DirectURLData) GetTrack(_ context.Context) (cache.TrackInfo, error) {
+	if !d.IsValid() {
+		return cache.TrackInfo{}, errors.New("the provided URL is not a direct media link")
+	}
+
+	return cache.TrackInfo{
+		URL:      d.Query,
+		CdnURL:   d.Query,
+		Name:     d.name(),
+		TC:       d.Query,
+		Platform: cache.Direct,
+	}, nil
+}
+
+// downloadTrack downloads the linked file. Files that the server reports as larger than MaxFileSize are refused, and
+// neither the link nor its redirects may reach a non-public address.
+// It returns the file path of the downloaded track or an error if the download fails.
+func (d *DirectURLData) downloadTrack(ctx context.Context, info cache.TrackInfo, _ bool) (string, error) {
+	if size := d.probe().size; size > config.Conf.MaxFileSize {
+		return "", fmt.Errorf("the file is %d MB, over the %d MB limit", size/(1024*1024), config.Conf.MaxFileSize/(1024*1024))
+	}
+
+	if !d.isPublic() {
+		return "", errNonPublicAddress
+	}
+
+	downloader, err := NewDownload(withPublicOnly(ctx), info)
+	if err != nil {
+		return "", fmt.Errorf("failed to initialize the download: %w", err)
+	}
+
+	filePath, err := downloader.Process()
+	if err != nil {
+		return "", fmt.Errorf("the download process failed: %w", err)
+	}
+
+	if err := validateDownload(filePath); err != nil {
+		_ = os.Remove(filePath)
+		return "", err
+	}
+	return filePath, nil
+}
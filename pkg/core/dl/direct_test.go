@@ -0,0 +1,163 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mediaServer serves HEAD requests with the Content-Type and Content-Length set for each path, counting them.
+type mediaServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	heads map[string]int
+}
+
+func newMediaServer(t *testing.T) *mediaServer {
+	t.Helper()
+	s := &mediaServer{heads: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.heads[r.URL.Path]++
+		s.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/stream":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Header().Set("Content-Length", "1024")
+		case "/clip":
+			w.Header().Set("Content-Type", "Video/MP4; codecs=avc1")
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case "/disguised":
+			// An audio file served as a download, with a type that says nothing about it.
+			w.Header().Set("Content-Type", "application/octet-stream")
+		case "/huge":
+			w.Header().Set("Content-Type", "audio/flac")
+			w.Header().Set("Content-Length", "3221225472")
+		case "/gone":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *mediaServer) headCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.heads[path]
+}
+
+// useDirectConfig sets a configuration with a 100 MB file size limit for the duration of a test.
+func useDirectConfig(t *testing.T) {
+	t.Helper()
+	saved := config.Conf
+	config.Conf = &config.BotConfig{MaxFileSize: 100 * 1024 * 1024, RetryStatusCodes: []int{503}}
+	t.Cleanup(func() { config.Conf = saved })
+}
+
+func TestDirectProbe(t *testing.T) {
+	useDirectConfig(t)
+	server := newMediaServer(t)
+
+	tests := []struct {
+		path      string
+		wantMedia bool
+		wantSize  int64
+	}{
+		{"/stream", true, 1024},
+		{"/clip", true, 0},
+		{"/page", false, 0},
+		{"/disguised", false, 0},
+		{"/huge", true, 3221225472},
+		{"/gone", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			d := NewDirectURLData(server.URL + tt.path)
+			got := d.probeFrom(context.Background())
+			if got.media != tt.wantMedia || got.size != tt.wantSize {
+				t.Errorf("probe() = %+v, want media %t, size %d", got, tt.wantMedia, tt.wantSize)
+			}
+
+			// The wrappers of a /play request probe the same link again; that is answered from the cache.
+			if again := d.probeFrom(context.Background()); again != got {
+				t.Errorf("second probe() = %+v, want %+v", again, got)
+			}
+			if n := server.headCount(tt.path); n != 1 {
+				t.Errorf("%d HEAD requests for %s, want 1", n, tt.path)
+			}
+		})
+	}
+}
+
+func TestDirectProbeUnreachable(t *testing.T) {
+	useDirectConfig(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	link := server.URL + "/song"
+	server.Close()
+
+	if got := NewDirectURLData(link).probeFrom(context.Background()); got.media {
+		t.Errorf("probe() of an unreachable link = %+v, want no media", got)
+	}
+}
+
+func TestDirectDownloadRefusesLargeFiles(t *testing.T) {
+	useDirectConfig(t)
+	server := newMediaServer(t)
+	d := NewDirectURLData(server.URL + "/huge")
+	d.probeFrom(context.Background())
+
+	_, err := d.downloadTrack(context.Background(), cache.TrackInfo{URL: d.Query, CdnURL: d.Query}, false)
+	if err == nil || !strings.Contains(err.Error(), "over the 100 MB limit") {
+		t.Errorf("downloadTrack() of a 3 GB file = %v, want the size limit error", err)
+	}
+	if errors.Is(err, errNonPublicAddress) {
+		t.Error("the size was not checked before the host")
+	}
+}
+
+func TestDirectMediaExtension(t *testing.T) {
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"https://example.com/song.mp3", true},
+		{"https://example.com/a/b/Track.FLAC?token=1", true},
+		{"https://example.com/video.webm#t=10", true},
+		{"https://example.com/page.html", false},
+		{"https://example.com/song.mp3.exe", false},
+		{"https://example.com/download?file=song.mp3", false},
+		{"ftp://example.com/song.mp3", false},
+		{"song.mp3", false},
+	}
+	for _, tt := range tests {
+		if got := NewDirectURLData(tt.link).hasMediaExtension(); got != tt.want {
+			t.Errorf("hasMediaExtension(%q) = %t, want %t", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestDirectName(t *testing.T) {
+	tests := []struct {
+		link, want string
+	}{
+		{"https://example.com/music/My%20Song.mp3", "My Song.mp3"},
+		{"https://example.com/stream?id=1", "stream"},
+		{"https://example.com/", "example.com"},
+		{"https://example.com", "example.com"},
+	}
+	for _, tt := range tests {
+		if got := NewDirectURLData(tt.link).name(); got != tt.want {
+			t.Errorf("name(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
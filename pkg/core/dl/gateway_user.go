@@ -0,0 +1,63 @@
+package dl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GatewayPlaylist is a playlist of a user with a linked gateway account.
+type GatewayPlaylist struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+	Tracks   int    `json:"tracks"`
+}
+
+// errGatewayUnset is returned when no API gateway is configured.
+var errGatewayUnset = errors.New("the API URL or API key is missing")
+
+// GatewayLinkURL returns the address of the gateway's page where a user links their account.
+// The gateway sends the user back to redirect with the state and the user's token once they have signed in.
+func GatewayLinkURL(state, redirect string) (string, error) {
+	apiURL := strings.TrimRight(config.Conf.ApiUrl, "/")
+	if apiURL == "" || config.Conf.ApiKey == "" {
+		return "", errGatewayUnset
+	}
+	return fmt.Sprintf("%s/oauth/authorize?%s", apiURL, url.Values{"state": {state}, "redirect_uri": {redirect}}.Encode()), nil
+}
+
+// UserPlaylists fetches the playlists of the user the gateway token belongs to, including their liked tracks.
+func UserPlaylists(ctx context.Context, userToken string) ([]GatewayPlaylist, error) {
+	apiURL := strings.TrimRight(config.Conf.ApiUrl, "/")
+	if apiURL == "" || config.Conf.ApiKey == "" {
+		return nil, errGatewayUnset
+	}
+
+	resp, err := sendRequest(ctx, http.MethodGet, apiURL+"/user/playlists", nil, map[string]string{
+		"X-API-Key":    config.Conf.ApiKey,
+		"X-User-Token": userToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("the UserPlaylists request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code while fetching playlists: %s", resp.Status)
+	}
+
+	var data struct {
+		Playlists []GatewayPlaylist `json:"playlists"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode the UserPlaylists response: %w", err)
+	}
+	return data.Playlists, nil
+}
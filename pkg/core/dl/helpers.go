@@ -2,15 +2,23 @@ package dl
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
 	"github.com/zuchzub/Go/pkg/core/cache"
+	"log"
+	"math/big"
 	"net/url"
+	"os"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 )
 
 const (
-	downloadTimeout        = 300
+	downloadTimeout        = 300 * time.Second
 	defaultDownloadDirPerm = 0755
 )
 
@@ -18,6 +26,22 @@ var (
 	tgURLRegex             = regexp.MustCompile(`^https?://t\.me/`)
 	errMissingCDNURL       = errors.New("missing cdn url")
 	errUnsupportedPlatform = errors.New("unsupported platform")
+
+	// ErrCorruptDownload is returned when a downloaded file is empty or cannot be read by ffprobe.
+	ErrCorruptDownload = errors.New("the downloaded file is empty or corrupt")
+
+	// ErrSilentDownload is returned when a downloaded file contains only silence.
+	ErrSilentDownload = errors.New("the downloaded file is silent")
+
+	// ErrTrackUnavailable is returned when a track's platform reports that it does not exist or can no longer be
+	// played, rather than failing to answer.
+	ErrTrackUnavailable = errors.New("the track is not available")
+)
+
+const (
+	silenceProbeSeconds = 30
+	silenceThresholdDB  = -70.0
+	silenceProbeTimeout = 20 * time.Second
 )
 
 // Download encapsulates the information and context required for a download operation.
@@ -49,6 +73,7 @@ func (d *Download) Process() (string, error) {
 }
 
 // processDirectDL manages direct downloads and includes improved error handling.
+// The cookie file of the track's platform, such as COOKIES_URL_SC for SoundCloud, is sent with the request.
 // It returns the file path of the downloaded track or an error if the download fails.
 func (d *Download) processDirectDL() (string, error) {
 	track := d.Track
@@ -56,7 +81,7 @@ func (d *Download) processDirectDL() (string, error) {
 		return track.CdnURL, nil
 	}
 
-	filePath, err := DownloadFile(d.ctx, track.CdnURL, "", false)
+	filePath, err := downloadFile(d.ctx, track.CdnURL, "", false, getCookieFile(cookiePlatform(track.Platform)))
 	if err != nil {
 		return "", err
 	}
@@ -89,8 +114,82 @@ func extractFilename(contentDisp string) string {
 		// URL-decode the filename to handle encoded characters.
 		decoded, err := url.QueryUnescape(matches[1])
 		if err == nil {
-			return decoded
+			return strings.Trim(strings.TrimSpace(decoded), `"`)
 		}
 	}
 	return ""
 }
+
+// validateDownload performs a quick sanity check on a downloaded file.
+// It ensures the file is not empty and that ffprobe can read a duration from it.
+// It returns an error wrapping ErrCorruptDownload if the check fails, or nil if validation is disabled.
+func validateDownload(filePath string) error {
+	if !config.Conf.ValidateDownloads {
+		return nil
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptDownload, err)
+	}
+
+	if info.Size() == 0 {
+		return fmt.Errorf("%w: %s is zero bytes", ErrCorruptDownload, filePath)
+	}
+
+	if cache.GetFileDuration(filePath) <= 0 {
+		return fmt.Errorf("%w: ffprobe could not read %s", ErrCorruptDownload, filePath)
+	}
+
+	return nil
+}
+
+// getCookieFile retrieves the path to a cookie file configured for the given platform key.
+// It returns the path to a randomly selected cookie file, or an empty string if none are configured.
+func getCookieFile(platform string) string {
+	cookiesPath := config.Conf.CookieFiles(platform)
+	if len(cookiesPath) == 0 {
+		return ""
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(cookiesPath))))
+	if err != nil {
+		log.Printf("Could not generate a random number: %v", err)
+		return cookiesPath[0]
+	}
+
+	return cookiesPath[n.Int64()]
+}
+
+// checkSilence probes the start of a downloaded file and reports whether it is effectively silent.
+// It only runs when the silence check is enabled for the track's platform.
+// It returns an error wrapping ErrSilentDownload if the mean volume is below the threshold.
+func checkSilence(ctx context.Context, filePath, platform string) error {
+	if !config.Conf.SilenceCheck || !slices.Contains(config.Conf.SilenceCheckPlatforms, strings.ToLower(platform)) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, silenceProbeTimeout)
+	defer cancel()
+
+	meanVolume, err := cache.GetMeanVolume(ctx, filePath, silenceProbeSeconds)
+	if err != nil {
+		// A failed probe is not proof of silence; let playback decide.
+		log.Printf("Silence check skipped for %s: %v", filePath, err)
+		return nil
+	}
+
+	if meanVolume < silenceThresholdDB {
+		return fmt.Errorf("%w: mean volume of %s is %.1f dB", ErrSilentDownload, filePath, meanVolume)
+	}
+	return nil
+}
+
+// fallbackQuery returns the YouTube search query for a track that could not be downloaded from its own platform.
+// The artist is added to the name, unless the name already holds it, so that a common title finds the right track.
+func fallbackQuery(info cache.TrackInfo) string {
+	name, artist := strings.TrimSpace(info.Name), strings.TrimSpace(info.Artist)
+	if artist == "" || strings.Contains(strings.ToLower(name), strings.ToLower(artist)) {
+		return name
+	}
+	return name + " " + artist
+}
@@ -1,8 +1,11 @@
 package dl
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/zuchzub/Go/pkg/config"
@@ -15,6 +18,8 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,68 +30,149 @@ const (
 	defaultRequestTimeout = 30 * time.Second
 	defaultConnectTimeout = 10 * time.Second
 	maxRetries            = 2
+	maxStatusRetries      = 2
+	maxRetryAfter         = 30 * time.Second
 	initialBackoff        = 1 * time.Second
 )
 
+var transport = &http.Transport{
+	DialContext:           newDialer(),
+	TLSHandshakeTimeout:   defaultConnectTimeout,
+	ResponseHeaderTimeout: defaultRequestTimeout,
+	IdleConnTimeout:       90 * time.Second,
+	MaxIdleConns:          100,
+}
+
 var client = &http.Client{
-	Timeout: defaultRequestTimeout,
-	Transport: &http.Transport{
-		TLSHandshakeTimeout:   defaultConnectTimeout,
-		ResponseHeaderTimeout: defaultRequestTimeout,
-		IdleConnTimeout:       90 * time.Second,
-		MaxIdleConns:          100,
-	},
+	Timeout:       defaultRequestTimeout,
+	Transport:     publicOnlyTransport{transport},
+	CheckRedirect: checkRedirect,
+}
+
+// fileClient downloads files. It shares the transport of client, but has no overall timeout, as a large file can take
+// longer than defaultRequestTimeout.
+var fileClient = &http.Client{
+	Transport:     publicOnlyTransport{transport},
+	CheckRedirect: checkRedirect,
+}
+
+// HTTPClient returns the HTTP client shared by all outgoing requests.
+func HTTPClient() *http.Client {
+	return client
 }
 
 // sendRequest performs an HTTP request with a given context, method, URL, body, and headers.
-// It includes retry logic with exponential backoff for temporary network errors and server-side issues.
+// The request is rebuilt for every attempt so a body can be replayed. Temporary network errors are retried up to
+// maxRetries times, and the configured retryable status codes (e.g. 429, 5xx) are retried up to maxStatusRetries
+// times, honoring Retry-After when the server sends it. A host that keeps answering with 429 is skipped for a while,
+// and requests to it fail at once with ErrRateLimited.
 // It returns an HTTP response or an error if the request fails after all retries.
 func sendRequest(ctx context.Context, method, fullURL string, body io.Reader, headers map[string]string) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
-	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-	req.Header.Set("Accept", "*/*")
-
-	for k, v := range headers {
-		req.Header.Set(k, v)
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = io.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("failed to read the request body: %w", err)
+		}
 	}
 
-	var resp *http.Response
 	var reqErr error
 	backoff := initialBackoff
+	networkAttempts, statusAttempts := 0, 0
+
+	for {
+		req, err := newRequest(ctx, method, fullURL, payload, headers)
+		if err != nil {
+			log.Printf("Error creating request: %v", err)
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			time.Sleep(backoff)
-			backoff *= 2
+		if err := limiter.allow(req.URL.Host); err != nil {
+			return nil, err
 		}
 
-		resp, reqErr = client.Do(req)
-		if reqErr == nil {
-			if resp.StatusCode < 500 {
-				return resp, nil // Success
+		resp, err := client.Do(req)
+		if err == nil {
+			limiter.record(req.URL.Host, resp.StatusCode)
+		}
+		wait := backoff
+		switch {
+		case err == nil && !slices.Contains(config.Conf.RetryStatusCodes, resp.StatusCode):
+			return resp, nil // Success
+		case err == nil:
+			statusAttempts++
+			if statusAttempts > maxStatusRetries {
+				return resp, nil // Let the caller handle the final status.
+			}
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = retryAfter
 			}
 			if err := resp.Body.Close(); err != nil {
 				gologging.WarnF("failed to close response body: %v", err)
 			}
 			reqErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-		} else if isTemporaryError(reqErr) {
-			gologging.InfoF("Temporary error on attempt %d/%d: %v", attempt+1, maxRetries, reqErr)
-			continue // Retry on temporary errors
-		} else {
-			break // Do not retry on permanent errors
+			gologging.InfoF("Retryable status %d on attempt %d/%d", resp.StatusCode, statusAttempts, maxStatusRetries)
+		case isTemporaryError(err):
+			networkAttempts++
+			reqErr = err
+			if networkAttempts >= maxRetries {
+				return nil, fmt.Errorf("request failed after %d attempts: %w", networkAttempts, reqErr)
+			}
+			gologging.InfoF("Temporary error on attempt %d/%d: %v", networkAttempts, maxRetries, err)
+		default:
+			return nil, fmt.Errorf("request failed: %w", err) // Do not retry on permanent errors
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("request failed: %w", errors.Join(reqErr, ctx.Err()))
+		case <-time.After(wait):
 		}
+		backoff *= 2
+	}
+}
+
+// newRequest builds a fresh request for a single attempt, replaying the buffered payload if there is one.
+func newRequest(ctx context.Context, method, fullURL string, payload []byte, headers map[string]string) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
 	}
 
-	if reqErr == nil {
-		reqErr = fmt.Errorf("request failed after %d attempts", maxRetries)
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("request failed: %w", reqErr)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	req.Header.Set("Accept", "*/*")
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+// It returns the wait duration capped at maxRetryAfter, and false if the header is missing or invalid.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if secs, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		wait = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = time.Until(at)
+	} else {
+		return 0, false
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	return min(wait, maxRetryAfter), true
 }
 
 // isTemporaryError determines if an error is temporary and thus worth retrying.
@@ -105,22 +191,24 @@ func generateUniqueName(ext string) string {
 	return fmt.Sprintf("%d_%05d%s", time.Now().UnixNano(), n.Int64(), ext)
 }
 
-// determineFilename safely determines a valid filename for a download.
-// It prioritizes the Content-Disposition header, falls back to the URL path, and generates a unique name if neither is available.
-// It returns a secure and sanitized filename.
+// determineFilename determines the path a download from urlStr is saved to.
+// The name is derived from a hash of the URL, so that the same URL is saved to the same file and neither the server nor
+// the link can choose it; the file name is later put into a shell command for ffmpeg. Only the extension is kept from
+// the Content-Disposition header or the URL path, and only if it is a known media extension.
 func determineFilename(urlStr, contentDisp string) string {
-	if filename := extractFilename(contentDisp); filename != "" {
-		return filepath.Join(config.Conf.DownloadsDir, sanitizeFilename(filename))
-	}
-
-	if parsedURL, err := url.Parse(urlStr); err == nil {
-		filename := path.Base(parsedURL.Path)
-		if filename != "" && filename != "/" && !strings.Contains(filename, "?") {
-			return filepath.Join(config.Conf.DownloadsDir, sanitizeFilename(filename))
+	ext := ".tmp"
+	candidate := extractFilename(contentDisp)
+	if candidate == "" {
+		if parsedURL, err := url.Parse(urlStr); err == nil {
+			candidate = path.Base(parsedURL.Path)
 		}
 	}
+	if candidateExt := strings.ToLower(path.Ext(candidate)); slices.Contains(directMediaExtensions, candidateExt) {
+		ext = candidateExt
+	}
 
-	return filepath.Join(config.Conf.DownloadsDir, generateUniqueName(".tmp"))
+	sum := sha256.Sum256([]byte(urlStr))
+	return filepath.Join(config.Conf.DownloadsDir, hex.EncodeToString(sum[:16])+ext)
 }
 
 // writeToFile writes data from an io.Reader to a specified file.
@@ -144,6 +232,11 @@ func writeToFile(filename string, data io.Reader) error {
 // It supports overwriting existing files and determines the filename automatically if not provided.
 // It returns the final file path or an error if the download fails.
 func DownloadFile(ctx context.Context, urlStr, fileName string, overwrite bool) (string, error) {
+	return downloadFile(ctx, urlStr, fileName, overwrite, "")
+}
+
+// downloadFile implements DownloadFile, sending the cookies of cookieFile that match the URL, if one is given.
+func downloadFile(ctx context.Context, urlStr, fileName string, overwrite bool, cookieFile string) (string, error) {
 	if urlStr == "" {
 		return "", errors.New("an empty URL was provided")
 	}
@@ -155,8 +248,9 @@ func DownloadFile(ctx context.Context, urlStr, fileName string, overwrite bool)
 	if err != nil {
 		return "", fmt.Errorf("failed to create the request: %w", err)
 	}
+	addCookies(req, cookieFile)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := fileClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("the request failed: %w", err)
 	}
@@ -182,7 +276,8 @@ func DownloadFile(ctx context.Context, urlStr, fileName string, overwrite bool)
 
 	// Download to a temporary .part file to ensure atomicity.
 	tempPath := fileName + ".part"
-	if err := writeToFile(tempPath, resp.Body); err != nil {
+	body := &progressReader{Reader: resp.Body, ctx: ctx, total: max(resp.ContentLength, 0)}
+	if err := writeToFile(tempPath, body); err != nil {
 		return "", err
 	}
 
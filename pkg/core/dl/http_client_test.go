@@ -0,0 +1,50 @@
+package dl
+
+import (
+	"github.com/zuchzub/Go/pkg/config"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetermineFilename(t *testing.T) {
+	dir := t.TempDir()
+	config.Conf = &config.BotConfig{DownloadsDir: dir}
+
+	tests := []struct {
+		name        string
+		url         string
+		contentDisp string
+		wantExt     string
+	}{
+		{"extension from the URL", "https://example.com/song.mp3", "", ".mp3"},
+		{"extension from Content-Disposition", "https://example.com/get?id=1", `attachment; filename="Track.FLAC"`, ".flac"},
+		{"shell characters in the header", "https://example.com/a", `attachment; filename="$(touch pwned).mp3"`, ".mp3"},
+		{"shell characters in the URL", "https://example.com/%60id%60;x&y.ogg", "", ".ogg"},
+		{"unknown extension", "https://example.com/run.sh", "", ".tmp"},
+		{"no name", "https://example.com/", "", ".tmp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := determineFilename(tt.url, tt.contentDisp)
+			if filepath.Dir(got) != dir {
+				t.Fatalf("determineFilename() = %q, want a file in %q", got, dir)
+			}
+			base := filepath.Base(got)
+			if !strings.HasSuffix(base, tt.wantExt) {
+				t.Errorf("determineFilename() = %q, want extension %q", base, tt.wantExt)
+			}
+			if strings.ContainsAny(base, "$`();&'\" ") {
+				t.Errorf("determineFilename() = %q, which contains shell characters", base)
+			}
+			if again := determineFilename(tt.url, tt.contentDisp); again != got {
+				t.Errorf("determineFilename() is not stable: %q, then %q", got, again)
+			}
+		})
+	}
+
+	if determineFilename("https://a.example/x.mp3", "") == determineFilename("https://b.example/x.mp3", "") {
+		t.Error("determineFilename() gives two URLs with the same file name the same path")
+	}
+}
@@ -0,0 +1,158 @@
+package dl
+
+import (
+	"context"
+	"github.com/zuchzub/Go/pkg/config"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// IP families that NETWORK_FAMILY accepts.
+const (
+	FamilyAuto = "auto"
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+const (
+	// happyEyeballsDelay is how long a dual-stack dial waits on the preferred family before racing the other one.
+	happyEyeballsDelay = 300 * time.Millisecond
+	// probeTimeout bounds each request of the startup connectivity probe.
+	probeTimeout = 3 * time.Second
+)
+
+// probeEndpoints are well-known hosts reachable over both IPv4 and IPv6, used to tell whether a family works.
+var probeEndpoints = []string{
+	"https://www.google.com/generate_204",
+	"https://www.cloudflare.com/cdn-cgi/trace",
+}
+
+// dialFunc is the signature of net.Dialer.DialContext, so that dialing can be replaced.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// autoPinned is the family chosen by ProbeNetwork when NETWORK_FAMILY is auto and one family is broken.
+var autoPinned atomic.Value
+
+// networkFamily returns the IP family outgoing connections use: the configured one, or the one pinned by the probe.
+// It returns FamilyAuto when both families may be used.
+func networkFamily() string {
+	if config.Conf != nil && config.Conf.NetworkFamily != FamilyAuto && config.Conf.NetworkFamily != "" {
+		return config.Conf.NetworkFamily
+	}
+	if pinned, ok := autoPinned.Load().(string); ok {
+		return pinned
+	}
+	return FamilyAuto
+}
+
+// familyNetwork restricts a network name such as "tcp" to a single IP family.
+func familyNetwork(family, network string) string {
+	switch family {
+	case FamilyIPv4:
+		return network + "4"
+	case FamilyIPv6:
+		return network + "6"
+	default:
+		return network
+	}
+}
+
+// familyDialer wraps dial so that it honors the family returned by family at the time of each dial.
+func familyDialer(dial dialFunc, family func() string) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if network == "tcp" || network == "udp" {
+			network = familyNetwork(family(), network)
+		}
+		return dial(ctx, network, addr)
+	}
+}
+
+// newDialer returns the dialer of the shared transport: dual-stack with a short Happy Eyeballs fallback delay,
+// restricted to one family when NETWORK_FAMILY or the startup probe pins it. Public-only requests cannot connect to
+// non-public addresses.
+func newDialer() dialFunc {
+	d := &net.Dialer{Timeout: defaultConnectTimeout, FallbackDelay: happyEyeballsDelay, ControlContext: rejectNonPublicDial}
+	return familyDialer(d.DialContext, networkFamily)
+}
+
+// ytdlpFamilyParams returns the yt-dlp flag forcing the pinned IP family, if any.
+func ytdlpFamilyParams() []string {
+	switch networkFamily() {
+	case FamilyIPv4:
+		return []string{"--force-ipv4"}
+	case FamilyIPv6:
+		return []string{"--force-ipv6"}
+	default:
+		return nil
+	}
+}
+
+// probeFamily reports whether any of the endpoints answers a HEAD request over the given family.
+func probeFamily(ctx context.Context, dial dialFunc, family string, endpoints []string) bool {
+	transport := &http.Transport{DialContext: familyDialer(dial, func() string { return family })}
+	defer transport.CloseIdleConnections()
+	probeClient := &http.Client{Transport: transport, Timeout: probeTimeout}
+
+	for _, endpoint := range endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+		if err != nil {
+			continue
+		}
+		resp, err := probeClient.Do(req)
+		if err != nil {
+			gologging.DebugF("[Network] %s probe of %s failed: %v", family, endpoint, err)
+			continue
+		}
+		_ = resp.Body.Close()
+		return true
+	}
+	return false
+}
+
+// probeFamilies probes IPv4 and IPv6 at the same time and returns which of them work.
+func probeFamilies(ctx context.Context, dial dialFunc, endpoints []string) (ipv4, ipv6 bool) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ipv4 = probeFamily(ctx, dial, FamilyIPv4, endpoints)
+	}()
+	go func() {
+		defer wg.Done()
+		ipv6 = probeFamily(ctx, dial, FamilyIPv6, endpoints)
+	}()
+	wg.Wait()
+	return ipv4, ipv6
+}
+
+// ProbeNetwork checks which IP families can reach the internet and logs the result.
+// When NETWORK_FAMILY is auto and exactly one family works, downloads are pinned to it, so hosts with broken IPv6
+// (or IPv4) stop waiting for the connect timeout on every request.
+func ProbeNetwork(ctx context.Context) {
+	d := &net.Dialer{Timeout: probeTimeout}
+	probeNetwork(ctx, d.DialContext, probeEndpoints)
+}
+
+// probeNetwork is ProbeNetwork with the dialer and the endpoints to probe given.
+func probeNetwork(ctx context.Context, dial dialFunc, endpoints []string) {
+	ipv4, ipv6 := probeFamilies(ctx, dial, endpoints)
+	gologging.InfoF("[Network] Connectivity probe: ipv4=%t ipv6=%t", ipv4, ipv6)
+
+	if config.Conf.NetworkFamily != FamilyAuto {
+		return
+	}
+	switch {
+	case ipv4 && !ipv6:
+		autoPinned.Store(FamilyIPv4)
+	case ipv6 && !ipv4:
+		autoPinned.Store(FamilyIPv6)
+	default:
+		return
+	}
+	gologging.InfoF("[Network] Pinning downloads to %s", networkFamily())
+}
@@ -0,0 +1,141 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"github.com/zuchzub/Go/pkg/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// useNetworkFamily sets NETWORK_FAMILY and clears any family pinned by the probe, until the test ends.
+func useNetworkFamily(t *testing.T, family string) {
+	t.Helper()
+	saved := config.Conf
+	config.Conf = &config.BotConfig{NetworkFamily: family}
+	autoPinned.Store(FamilyAuto)
+	t.Cleanup(func() {
+		config.Conf = saved
+		autoPinned.Store(FamilyAuto)
+	})
+}
+
+// familyDial connects to the test server over the families in works, and fails over the others, like a host whose
+// other family is broken. The server only listens on IPv4 loopback, so every working dial goes there.
+func familyDial(works ...string) dialFunc {
+	var d net.Dialer
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		for _, family := range works {
+			if network == familyNetwork(family, "tcp") {
+				return d.DialContext(ctx, "tcp4", addr)
+			}
+		}
+		return nil, errors.New("network is unreachable")
+	}
+}
+
+func TestFamilyDialer(t *testing.T) {
+	family := FamilyAuto
+	var networks []string
+	dial := familyDialer(func(_ context.Context, network, _ string) (net.Conn, error) {
+		networks = append(networks, network)
+		return nil, errors.New("not dialing")
+	}, func() string { return family })
+
+	for _, f := range []string{FamilyAuto, FamilyIPv4, FamilyIPv6} {
+		family = f
+		_, _ = dial(context.Background(), "tcp", "example.com:443")
+	}
+	_, _ = dial(context.Background(), "udp", "example.com:53")
+	_, _ = dial(context.Background(), "tcp4", "example.com:443")
+	_, _ = dial(context.Background(), "unix", "/tmp/socket")
+
+	want := []string{"tcp", "tcp4", "tcp6", "udp6", "tcp4", "unix"}
+	if !reflect.DeepEqual(networks, want) {
+		t.Errorf("dialed %v, want %v", networks, want)
+	}
+}
+
+func TestNetworkFamily(t *testing.T) {
+	useNetworkFamily(t, FamilyAuto)
+	if got := networkFamily(); got != FamilyAuto {
+		t.Errorf("networkFamily() = %q, want auto", got)
+	}
+	if got := ytdlpFamilyParams(); got != nil {
+		t.Errorf("ytdlpFamilyParams() = %v, want none", got)
+	}
+
+	autoPinned.Store(FamilyIPv4)
+	if got := ytdlpFamilyParams(); !reflect.DeepEqual(got, []string{"--force-ipv4"}) {
+		t.Errorf("ytdlpFamilyParams() after the probe pinned ipv4 = %v", got)
+	}
+
+	// The configured family wins over the one pinned by the probe.
+	config.Conf.NetworkFamily = FamilyIPv6
+	if got := ytdlpFamilyParams(); !reflect.DeepEqual(got, []string{"--force-ipv6"}) {
+		t.Errorf("ytdlpFamilyParams() with NETWORK_FAMILY=ipv6 = %v", got)
+	}
+}
+
+func TestProbeFamilies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("probe sent %s, want HEAD", r.Method)
+		}
+	}))
+	defer server.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	unreachable := closed.URL
+
+	tests := []struct {
+		name               string
+		works              []string
+		endpoints          []string
+		wantIPv4, wantIPv6 bool
+	}{
+		{"both work", []string{FamilyIPv4, FamilyIPv6}, []string{server.URL}, true, true},
+		{"broken ipv6", []string{FamilyIPv4}, []string{server.URL}, true, false},
+		{"broken ipv4", []string{FamilyIPv6}, []string{server.URL}, false, true},
+		{"offline", nil, []string{server.URL}, false, false},
+		{"second endpoint answers", []string{FamilyIPv4}, []string{unreachable, server.URL}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipv4, ipv6 := probeFamilies(context.Background(), familyDial(tt.works...), tt.endpoints)
+			if ipv4 != tt.wantIPv4 || ipv6 != tt.wantIPv6 {
+				t.Errorf("probeFamilies() = %t, %t; want %t, %t", ipv4, ipv6, tt.wantIPv4, tt.wantIPv6)
+			}
+		})
+	}
+}
+
+func TestProbeNetworkPins(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		configured string
+		works      []string
+		want       string
+	}{
+		{"broken ipv6 pins ipv4", FamilyAuto, []string{FamilyIPv4}, FamilyIPv4},
+		{"broken ipv4 pins ipv6", FamilyAuto, []string{FamilyIPv6}, FamilyIPv6},
+		{"dual stack stays auto", FamilyAuto, []string{FamilyIPv4, FamilyIPv6}, FamilyAuto},
+		{"offline stays auto", FamilyAuto, nil, FamilyAuto},
+		{"configured family is kept", FamilyIPv6, []string{FamilyIPv4}, FamilyIPv6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useNetworkFamily(t, tt.configured)
+			probeNetwork(context.Background(), familyDial(tt.works...), []string{server.URL})
+			if got := networkFamily(); got != tt.want {
+				t.Errorf("networkFamily() after the probe = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// errNonPublicAddress is returned for a request made for a user that would reach a loopback, private, link-local or
+// unspecified address, such as the bot host itself, its local network or a cloud metadata service.
+var errNonPublicAddress = errors.New("the link points to a non-public address")
+
+// publicOnlyKey marks a context whose requests may only reach public addresses.
+type publicOnlyKey struct{}
+
+// withPublicOnly returns a context whose requests, and the redirects they follow, may only reach public addresses.
+// It is used for the links users send, which the bot would otherwise fetch from inside its own network.
+func withPublicOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, publicOnlyKey{}, true)
+}
+
+// publicOnly reports whether the requests of ctx may only reach public addresses.
+func publicOnly(ctx context.Context) bool {
+	only, _ := ctx.Value(publicOnlyKey{}).(bool)
+	return only
+}
+
+// isPublicIP reports whether ip is an address a user's link may reach: not loopback, private, link-local or
+// unspecified.
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
+
+// checkPublicHost resolves host and returns errNonPublicAddress if any of its addresses is not public.
+func checkPublicHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("%w: %s", errNonPublicAddress, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("failed to resolve %s: no addresses", host)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", errNonPublicAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// rejectNonPublicDial is the Control function of the shared dialer. For a public-only request, it refuses to connect
+// to a non-public address, checking the address actually dialed, so that a host cannot resolve to a public address
+// when checked and to a private one when connected to.
+func rejectNonPublicDial(ctx context.Context, _, address string, _ syscall.RawConn) error {
+	if !publicOnly(ctx) {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", errNonPublicAddress, host)
+	}
+	return nil
+}
+
+// publicOnlyTransport checks the host of each public-only request before sending it. The check at dial time does not
+// cover a request sent over a connection already open to that host and port.
+type publicOnlyTransport struct {
+	http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t publicOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if publicOnly(req.Context()) {
+		if err := checkPublicHost(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
+
+// checkRedirect is the CheckRedirect policy of the HTTP clients. It keeps the default limit of 10 redirects, and
+// refuses to follow a public-only request to a non-public host.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if publicOnly(req.Context()) {
+		return checkPublicHost(req.Context(), req.URL.Hostname())
+	}
+	return nil
+}
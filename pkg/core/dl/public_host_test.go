@@ -0,0 +1,91 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"github.com/zuchzub/Go/pkg/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublicIP(%s) = %t, want %t", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestDirectURLRefusesNonPublicHosts(t *testing.T) {
+	for _, link := range []string{
+		"http://127.0.0.1/song.mp3",
+		"http://localhost:8080/song.mp3",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/song.ogg",
+		"http://192.168.0.10/stream",
+	} {
+		if NewDirectURLData(link).IsValid() {
+			t.Errorf("IsValid(%s) = true, want false", link)
+		}
+	}
+}
+
+func TestPublicOnlyRequests(t *testing.T) {
+	config.Conf = &config.BotConfig{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Without the mark, internal requests such as those to a local API gateway still work.
+	resp, err := sendRequest(context.Background(), http.MethodHead, server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("sendRequest() without the public-only mark: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	_, err = sendRequest(withPublicOnly(context.Background()), http.MethodHead, server.URL, nil, nil)
+	if !errors.Is(err, errNonPublicAddress) {
+		t.Errorf("sendRequest() to %s with the public-only mark: %v, want %v", server.URL, err, errNonPublicAddress)
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	redirect := func(ctx context.Context, target string) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return checkRedirect(req, []*http.Request{req})
+	}
+
+	if err := redirect(withPublicOnly(context.Background()), "http://169.254.169.254/"); !errors.Is(err, errNonPublicAddress) {
+		t.Errorf("redirect of a public-only request to a metadata address: %v, want %v", err, errNonPublicAddress)
+	}
+	if err := redirect(context.Background(), "http://127.0.0.1/"); err != nil {
+		t.Errorf("redirect of an internal request to loopback: %v, want nil", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://8.8.8.8/", nil)
+	if err := checkRedirect(req, make([]*http.Request, 10)); err == nil {
+		t.Error("checkRedirect() followed an 11th redirect")
+	}
+}
@@ -0,0 +1,96 @@
+package dl
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+const (
+	// rateLimitTrip is how many 429 responses in a row make a host cool down.
+	rateLimitTrip = 3
+	// rateLimitCooldown is how long requests to a host are skipped once it trips.
+	rateLimitCooldown = 2 * time.Minute
+)
+
+// ErrRateLimited is returned instead of sending a request to a host that keeps answering with 429.
+var ErrRateLimited = errors.New("the server is rate limiting requests")
+
+// hostLimit is the rate limit state of a single host.
+type hostLimit struct {
+	consecutive int
+	until       time.Time
+}
+
+// rateLimiter counts consecutive 429 responses per host and short-circuits requests to hosts cooling down.
+type rateLimiter struct {
+	mu    sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+var limiter = &rateLimiter{hosts: make(map[string]*hostLimit)}
+
+// allow returns an error wrapping ErrRateLimited if host is cooling down.
+func (r *rateLimiter) allow(host string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hosts[host]
+	if !ok || h.until.IsZero() {
+		return nil
+	}
+	if left := time.Until(h.until); left > 0 {
+		return fmt.Errorf("%w: %s, retrying in %s", ErrRateLimited, host, left.Round(time.Second))
+	}
+	return nil
+}
+
+// record updates the state of host with the status code of a response.
+func (r *rateLimiter) record(host string, status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hosts[host]
+	if !ok {
+		h = &hostLimit{}
+		r.hosts[host] = h
+	}
+
+	if status != http.StatusTooManyRequests {
+		if !h.until.IsZero() {
+			gologging.InfoF("[RateLimit] %s is answering again after cooling down.", host)
+		}
+		delete(r.hosts, host)
+		return
+	}
+
+	h.consecutive++
+	if h.consecutive >= rateLimitTrip && !time.Now().Before(h.until) {
+		h.until = time.Now().Add(rateLimitCooldown)
+		gologging.WarnF("[RateLimit] %s returned %d responses with status 429 in a row; skipping it for %s.", host, h.consecutive, rateLimitCooldown)
+	}
+}
+
+// cooldown returns how long host is still cooling down, or zero.
+func (r *rateLimiter) cooldown(host string) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if h, ok := r.hosts[host]; ok {
+		return max(time.Until(h.until), 0)
+	}
+	return 0
+}
+
+// ApiCooldown returns how long requests to the API gateway are still skipped because it kept rate limiting, or zero.
+func ApiCooldown() time.Duration {
+	u, err := url.Parse(strings.TrimRight(config.Conf.ApiUrl, "/"))
+	if err != nil || u.Host == "" {
+		return 0
+	}
+	return limiter.cooldown(u.Host)
+}
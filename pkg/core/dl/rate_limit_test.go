@@ -0,0 +1,218 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"github.com/zuchzub/Go/pkg/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// resetLimiter forgets the rate limit state of every host, before the test and after it.
+func resetLimiter(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		limiter.mu.Lock()
+		limiter.hosts = make(map[string]*hostLimit)
+		limiter.mu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// scriptedServer answers with the status codes in script, one per request, then with 200.
+// Its 429 responses ask to retry at once, so the tests do not wait for the backoff.
+func scriptedServer(t *testing.T, script ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(hits.Add(1))
+		if n <= len(script) && script[n-1] != http.StatusOK {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(script[n-1])
+			return
+		}
+		_, _ = w.Write([]byte(`{"results":[]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func useRetryConfig(t *testing.T, apiURL string) {
+	t.Helper()
+	saved := config.Conf
+	config.Conf = &config.BotConfig{ApiUrl: apiURL, ApiKey: "key", RetryStatusCodes: []int{429, 500, 502, 503, 504}}
+	t.Cleanup(func() { config.Conf = saved })
+}
+
+func hostOf(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
+
+func TestSendRequestRetriesAfter429(t *testing.T) {
+	resetLimiter(t)
+	server, hits := scriptedServer(t, http.StatusTooManyRequests, http.StatusOK)
+	useRetryConfig(t, server.URL)
+
+	start := time.Now()
+	resp, err := sendRequest(context.Background(), http.MethodGet, server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("sendRequest() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || hits.Load() != 2 {
+		t.Errorf("sendRequest() = %d after %d requests, want 200 after 2", resp.StatusCode, hits.Load())
+	}
+	if elapsed := time.Since(start); elapsed >= initialBackoff {
+		t.Errorf("sendRequest() took %s, so Retry-After: 0 was not honored", elapsed)
+	}
+	if left := ApiCooldown(); left != 0 {
+		t.Errorf("ApiCooldown() after a success = %s, want 0", left)
+	}
+}
+
+func TestSendRequestTripsLimiter(t *testing.T) {
+	resetLimiter(t)
+	server, hits := scriptedServer(t, 429, 429, 429, 429, 429)
+	useRetryConfig(t, server.URL)
+
+	resp, err := sendRequest(context.Background(), http.MethodGet, server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("sendRequest() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	// Each attempt is a 429 in a row, enough to trip the limiter.
+	attempts := int32(maxStatusRetries + 1)
+	if resp.StatusCode != http.StatusTooManyRequests || hits.Load() != attempts {
+		t.Fatalf("sendRequest() = %d after %d requests, want 429 after %d", resp.StatusCode, hits.Load(), attempts)
+	}
+	if left := ApiCooldown(); left <= 0 || left > rateLimitCooldown {
+		t.Errorf("ApiCooldown() = %s, want up to %s", left, rateLimitCooldown)
+	}
+
+	_, err = sendRequest(context.Background(), http.MethodGet, server.URL, nil, nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("sendRequest() during the cooldown = %v, want ErrRateLimited", err)
+	}
+	if hits.Load() != attempts {
+		t.Errorf("sendRequest() during the cooldown reached the server (%d requests)", hits.Load())
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	r := &rateLimiter{hosts: make(map[string]*hostLimit)}
+	const host = "api.example.com"
+
+	for i := 0; i < rateLimitTrip-1; i++ {
+		r.record(host, http.StatusTooManyRequests)
+	}
+	r.record(host, http.StatusOK)
+	r.record(host, http.StatusTooManyRequests)
+	if err := r.allow(host); err != nil {
+		t.Fatalf("allow() after a success broke the run of 429s = %v, want nil", err)
+	}
+
+	for i := 0; i < rateLimitTrip; i++ {
+		r.record(host, http.StatusTooManyRequests)
+	}
+	if err := r.allow(host); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("allow() after %d 429s in a row = %v, want ErrRateLimited", rateLimitTrip+1, err)
+	}
+	if err := r.allow("other.example.com"); err != nil {
+		t.Errorf("allow() of another host = %v, want nil", err)
+	}
+
+	// Once the cooldown is over, one more 429 trips the host again at once.
+	r.hosts[host].until = time.Now().Add(-time.Second)
+	if err := r.allow(host); err != nil {
+		t.Fatalf("allow() after the cooldown = %v, want nil", err)
+	}
+	r.record(host, http.StatusTooManyRequests)
+	if err := r.allow(host); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("allow() after another 429 = %v, want ErrRateLimited", err)
+	}
+
+	r.hosts[host].until = time.Now().Add(-time.Second)
+	r.record(host, http.StatusOK)
+	if _, ok := r.hosts[host]; ok || r.cooldown(host) != 0 {
+		t.Error("a success after the cooldown did not forget the host")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"5", 5 * time.Second, true},
+		{" 7 ", 7 * time.Second, true},
+		{"3600", maxRetryAfter, true},
+		{"-3", 0, true},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), maxRetryAfter, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %s, %t; want %s, %t", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// roundTripFunc serves requests with a function, standing in for the network.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestSearchFallsBackToYouTube(t *testing.T) {
+	resetLimiter(t)
+	server, hits := scriptedServer(t)
+	useRetryConfig(t, server.URL)
+	for i := 0; i < rateLimitTrip; i++ {
+		limiter.record(hostOf(t, server.URL), http.StatusTooManyRequests)
+	}
+
+	// The YouTube search page, as far as searchYouTube reads it.
+	page := `<script>var ytInitialData = {"contents":{"twoColumnSearchResultsRenderer":{"primaryContents":` +
+		`{"sectionListRenderer":{"contents":[{"videoRenderer":{"videoId":"dQw4w9WgXcQ","title":{"runs":[{"text":"Song"}]},` +
+		`"lengthText":{"simpleText":"3:33"}}}]}}}}};</script>`
+	var searched atomic.Bool
+	saved := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host != "www.youtube.com" {
+			return nil, errors.New("unexpected request to " + r.URL.Host)
+		}
+		searched.Store(true)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(page)), Header: http.Header{}}, nil
+	})}
+	defer func() { http.DefaultClient = saved }()
+
+	d := &DownloaderWrapper{Query: "some song", Service: NewApiData("some song")}
+	results, err := d.Search(context.Background())
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if !searched.Load() || len(results.Results) != 1 || results.Results[0].ID != "dQw4w9WgXcQ" {
+		t.Errorf("Search() = %+v, want the YouTube result", results.Results)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("Search() sent %d requests to the rate limited gateway", hits.Load())
+	}
+}
@@ -2,8 +2,12 @@ package dl
 
 import (
 	"context"
+	"errors"
 	"github.com/zuchzub/Go/pkg/config"
 	"github.com/zuchzub/Go/pkg/core/cache"
+	"slices"
+
+	"github.com/Laky-64/gologging"
 )
 
 // MusicService defines a standard interface for interacting with various music services.
@@ -29,15 +33,22 @@ type DownloaderWrapper struct {
 }
 
 // NewDownloaderWrapper selects the appropriate MusicService based on the query format or configuration defaults.
+// Links ending in a media file extension are always played directly; other links that no platform matches are
+// probed to see whether they serve media before the query falls back to a search.
 // It returns a new DownloaderWrapper configured with the chosen service.
 func NewDownloaderWrapper(query string) *DownloaderWrapper {
 	yt := NewYouTubeData(query)
 	api := NewApiData(query)
+	direct := NewDirectURLData(query)
 	var chosen MusicService
-	if yt.IsValid() {
+	if direct.hasMediaExtension() {
+		chosen = direct
+	} else if yt.IsValid() {
 		chosen = yt
 	} else if api.IsValid() {
 		chosen = api
+	} else if direct.IsValid() {
+		chosen = direct
 	} else {
 		switch config.Conf.DefaultService {
 		case "spotify":
@@ -53,6 +64,17 @@ func NewDownloaderWrapper(query string) *DownloaderWrapper {
 	}
 }
 
+// EnabledPlatforms returns the platforms that tracks can be played from, sorted.
+// The platforms served through the API gateway are only enabled when its URL and key are configured.
+func EnabledPlatforms() []string {
+	platforms := []string{cache.Direct, cache.Telegram, cache.YouTube}
+	if config.Conf.ApiUrl != "" && config.Conf.ApiKey != "" {
+		platforms = append(platforms, cache.Apple, cache.JioSaavn, "soundcloud", cache.Spotify)
+	}
+	slices.Sort(platforms)
+	return platforms
+}
+
 // IsValid checks if the underlying service can handle the query.
 func (d *DownloaderWrapper) IsValid() bool {
 	return d.Service != nil && d.Service.IsValid()
@@ -64,8 +86,14 @@ func (d *DownloaderWrapper) GetInfo(ctx context.Context) (cache.PlatformTracks,
 }
 
 // Search performs a search by delegating the call to the wrapped service.
+// A text search sent to the API gateway while it is rate limiting falls back to a YouTube search.
 func (d *DownloaderWrapper) Search(ctx context.Context) (cache.PlatformTracks, error) {
-	return d.Service.Search(ctx)
+	results, err := d.Service.Search(ctx)
+	if api, ok := d.Service.(*ApiData); ok && errors.Is(err, ErrRateLimited) && !api.IsValid() {
+		gologging.InfoF("[DownloaderWrapper] The API gateway is rate limiting; searching YouTube instead: %v", err)
+		return NewYouTubeData(d.Query).Search(ctx)
+	}
+	return results, err
 }
 
 // GetTrack retrieves detailed track information by delegating the call to the wrapped service.
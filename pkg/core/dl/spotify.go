@@ -182,7 +182,7 @@ func rebuildOGG(filename string) error {
 func fixOGG(inputFile string, track cache.TrackInfo) (string, error) {
 	outputFile := filepath.Join(config.Conf.DownloadsDir, fmt.Sprintf("%s.ogg", track.TC))
 	// #nosec G204 - The input file path is trusted as it's generated internally.
-	cmd := exec.Command("ffmpeg", "-i", inputFile, "-c", "copy", outputFile)
+	cmd := exec.Command(config.Conf.FFmpegPath, "-i", inputFile, "-c", "copy", outputFile)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return "", fmt.Errorf("ffmpeg failed with error: %w\nOutput: %s", err, string(output))
 	}
@@ -0,0 +1,234 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"os"
+	"strings"
+
+	"github.com/Laky-64/gologging"
+)
+
+// Download strategies, named in a platform's <PLATFORM>_STRATEGY setting.
+const (
+	// StrategyAPI asks the API gateway for a fresh CDN URL and downloads it.
+	StrategyAPI = "api"
+	// StrategyCdnDirect downloads the CDN URL the track info already carries.
+	StrategyCdnDirect = "cdn-direct"
+	// StrategyYtDlp downloads a YouTube track with yt-dlp, or resolves the stream URL of a livestream.
+	StrategyYtDlp = "ytdlp"
+	// StrategyYouTubeSearch searches YouTube for the track name and artist and downloads the first result with yt-dlp.
+	StrategyYouTubeSearch = "youtube-search"
+)
+
+// defaultStrategies are the strategies tried for each platform that has no <PLATFORM>_STRATEGY setting.
+// Platforms missing here only download their CDN URL.
+var defaultStrategies = map[string][]string{
+	cache.YouTube: {StrategyCdnDirect, StrategyAPI, StrategyYtDlp},
+	cache.Spotify: {StrategyCdnDirect, StrategyYouTubeSearch},
+}
+
+// errStrategySkipped is returned by a strategy that cannot download a track at all, such as the API gateway when
+// no API key is set. The next strategy is tried without the skip being reported as a failure.
+var errStrategySkipped = errors.New("the strategy does not apply to this track")
+
+// downloadStrategy is one way of downloading a track.
+type downloadStrategy interface {
+	// download returns the file path of the downloaded track, or errStrategySkipped if it does not apply.
+	download(ctx context.Context, info cache.TrackInfo, video bool) (string, error)
+}
+
+// strategies maps each strategy name to its implementation.
+var strategies = map[string]downloadStrategy{
+	StrategyAPI:           apiStrategy{},
+	StrategyCdnDirect:     cdnDirectStrategy{},
+	StrategyYtDlp:         ytDlpStrategy{},
+	StrategyYouTubeSearch: youtubeSearchStrategy{},
+}
+
+// StrategyAttempt is a download strategy that failed and its error.
+type StrategyAttempt struct {
+	Strategy string
+	Err      error
+}
+
+// StrategyError is returned when every download strategy tried for a track failed.
+// Its message names each attempt in order, such as "api: 402 Payment Required; ytdlp: age restricted".
+type StrategyError struct {
+	Platform string
+	Attempts []StrategyAttempt
+}
+
+// Error implements the error interface.
+func (e *StrategyError) Error() string {
+	if len(e.Attempts) == 0 {
+		return fmt.Sprintf("no download strategy applies to this %s track", e.Platform)
+	}
+
+	parts := make([]string, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		parts[i] = fmt.Sprintf("%s: %v", attempt.Strategy, attempt.Err)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Unwrap returns the errors of the attempts, so errors.Is and errors.As see each of them.
+func (e *StrategyError) Unwrap() []error {
+	errs := make([]error, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		errs[i] = attempt.Err
+	}
+	return errs
+}
+
+// strategyChain returns the strategies tried for a platform, in order.
+func strategyChain(platform string) []string {
+	platform = strings.ToLower(platform)
+	if chain, ok := config.Conf.DownloadStrategies[platform]; ok {
+		return chain
+	}
+	if chain, ok := defaultStrategies[platform]; ok {
+		return chain
+	}
+	return []string{StrategyCdnDirect}
+}
+
+// runStrategies downloads a track with the strategies of its platform, trying each in order.
+// It returns the file path from the first strategy that succeeds, or a *StrategyError naming every failed attempt.
+func runStrategies(ctx context.Context, info cache.TrackInfo, video bool) (string, error) {
+	failed := &StrategyError{Platform: info.Platform}
+	for _, name := range strategyChain(info.Platform) {
+		strategy, ok := strategies[name]
+		if !ok {
+			failed.Attempts = append(failed.Attempts, StrategyAttempt{Strategy: name, Err: errors.New("unknown strategy")})
+			continue
+		}
+
+		filePath, err := strategy.download(ctx, info, video)
+		if err == nil {
+			return filePath, nil
+		}
+		if errors.Is(err, errStrategySkipped) {
+			gologging.DebugF("[Downloader] Skipped %s for %s.", name, info.Name)
+			continue
+		}
+
+		gologging.InfoF("[Downloader] %s failed for %s: %v", name, info.Name, err)
+		failed.Attempts = append(failed.Attempts, StrategyAttempt{Strategy: name, Err: err})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return "", failed
+}
+
+// hasCdnURL reports whether track info carries a CDN URL to download.
+func hasCdnURL(info cache.TrackInfo) bool {
+	return info.CdnURL != "" && info.CdnURL != "None"
+}
+
+// servedByGateway reports whether the API gateway can serve a track: it serves no livestreams, and YouTube as audio only.
+func servedByGateway(info cache.TrackInfo, video bool) bool {
+	return !info.IsLive && !(video && info.Platform == cache.YouTube)
+}
+
+// downloadCdn downloads a track from its CDN URL and checks it for silence.
+// A silent file is removed and downloaded once more before the silence is returned as an error.
+func downloadCdn(ctx context.Context, info cache.TrackInfo) (string, error) {
+	downloader, err := NewDownload(ctx, info)
+	if err != nil {
+		return "", err
+	}
+
+	filePath, err := processChecked(ctx, downloader)
+	if errors.Is(err, ErrSilentDownload) {
+		gologging.WarnF("%v; retrying the download once.", err)
+		filePath, err = processChecked(ctx, downloader)
+	}
+	return filePath, err
+}
+
+// processChecked runs a download and removes the file if it is silent.
+func processChecked(ctx context.Context, downloader *Download) (string, error) {
+	filePath, err := downloader.Process()
+	if err != nil {
+		return "", err
+	}
+	if err := checkSilence(ctx, filePath, downloader.Track.Platform); err != nil {
+		_ = os.Remove(filePath)
+		return "", err
+	}
+	return filePath, nil
+}
+
+// cdnDirectStrategy implements StrategyCdnDirect.
+type cdnDirectStrategy struct{}
+
+func (cdnDirectStrategy) download(ctx context.Context, info cache.TrackInfo, video bool) (string, error) {
+	if !hasCdnURL(info) || !servedByGateway(info, video) {
+		return "", errStrategySkipped
+	}
+	return downloadCdn(ctx, info)
+}
+
+// apiStrategy implements StrategyAPI.
+type apiStrategy struct{}
+
+func (apiStrategy) download(ctx context.Context, info cache.TrackInfo, video bool) (string, error) {
+	if config.Conf.ApiUrl == "" || config.Conf.ApiKey == "" || !servedByGateway(info, video) {
+		return "", errStrategySkipped
+	}
+
+	query := info.URL
+	if info.Platform == cache.YouTube && info.TC != "" {
+		query = fmt.Sprintf("https://www.youtube.com/watch?v=%s", info.TC)
+	}
+	track, err := NewApiData(query).GetTrack(ctx)
+	if err != nil {
+		return "", err
+	}
+	return downloadCdn(ctx, track)
+}
+
+// ytDlpStrategy implements StrategyYtDlp.
+type ytDlpStrategy struct{}
+
+func (ytDlpStrategy) download(ctx context.Context, info cache.TrackInfo, video bool) (string, error) {
+	if info.Platform != cache.YouTube || info.TC == "" {
+		return "", errStrategySkipped
+	}
+
+	yt := NewYouTubeData(info.URL)
+	if info.IsLive {
+		return yt.resolveLiveURL(ctx, info.TC, video)
+	}
+	return yt.downloadWithYtDlp(ctx, info.TC, video)
+}
+
+// youtubeSearchStrategy implements StrategyYouTubeSearch.
+type youtubeSearchStrategy struct{}
+
+func (youtubeSearchStrategy) download(ctx context.Context, info cache.TrackInfo, video bool) (string, error) {
+	if info.Platform == cache.YouTube || info.Name == "" {
+		return "", errStrategySkipped
+	}
+
+	results, err := NewYouTubeData(fallbackQuery(info)).Search(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	track := results.Results[0]
+	return ytDlpStrategy{}.download(ctx, cache.TrackInfo{
+		URL:      track.URL,
+		Name:     track.Name,
+		TC:       track.ID,
+		Cover:    track.Cover,
+		Duration: track.Duration,
+		Platform: cache.YouTube,
+		IsLive:   track.IsLive,
+	}, video)
+}
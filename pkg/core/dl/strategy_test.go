@@ -0,0 +1,181 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// fakeStrategy returns a canned result and records that it was tried.
+type fakeStrategy struct {
+	name  string
+	path  string
+	err   error
+	tried *[]string
+}
+
+func (s fakeStrategy) download(context.Context, cache.TrackInfo, bool) (string, error) {
+	*s.tried = append(*s.tried, s.name)
+	return s.path, s.err
+}
+
+// useStrategies replaces the strategy implementations and the configured chains for the duration of a test.
+func useStrategies(t *testing.T, impls map[string]downloadStrategy, chains map[string][]string) {
+	t.Helper()
+	oldStrategies, oldConf := strategies, config.Conf
+	strategies = impls
+	config.Conf = &config.BotConfig{DownloadStrategies: chains}
+	t.Cleanup(func() { strategies, config.Conf = oldStrategies, oldConf })
+}
+
+func TestRunStrategies(t *testing.T) {
+	quota := errors.New("402 quota")
+	ageRestricted := errors.New("age restricted")
+
+	tests := []struct {
+		name      string
+		results   map[string]error // results maps each strategy to its error; nil succeeds.
+		chain     []string
+		wantPath  string
+		wantErr   string
+		wantTried []string
+	}{
+		{
+			name:      "first succeeds",
+			results:   map[string]error{"api": nil, "ytdlp": nil},
+			chain:     []string{"api", "ytdlp"},
+			wantPath:  "api.mp3",
+			wantTried: []string{"api"},
+		},
+		{
+			name:      "falls back",
+			results:   map[string]error{"api": quota, "ytdlp": nil},
+			chain:     []string{"api", "ytdlp"},
+			wantPath:  "ytdlp.mp3",
+			wantTried: []string{"api", "ytdlp"},
+		},
+		{
+			name:      "all fail",
+			results:   map[string]error{"api": quota, "ytdlp": ageRestricted},
+			chain:     []string{"api", "ytdlp"},
+			wantErr:   "api: 402 quota; ytdlp: age restricted",
+			wantTried: []string{"api", "ytdlp"},
+		},
+		{
+			name:      "skipped strategies are not reported",
+			results:   map[string]error{"api": errStrategySkipped, "ytdlp": ageRestricted},
+			chain:     []string{"api", "ytdlp"},
+			wantErr:   "ytdlp: age restricted",
+			wantTried: []string{"api", "ytdlp"},
+		},
+		{
+			name:      "nothing applies",
+			results:   map[string]error{"api": errStrategySkipped},
+			chain:     []string{"api"},
+			wantErr:   "no download strategy applies to this youtube track",
+			wantTried: []string{"api"},
+		},
+		{
+			name:      "unknown strategy",
+			results:   map[string]error{"ytdlp": nil},
+			chain:     []string{"torrent", "ytdlp"},
+			wantPath:  "ytdlp.mp3",
+			wantTried: []string{"ytdlp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tried []string
+			impls := make(map[string]downloadStrategy)
+			for name, err := range tt.results {
+				impls[name] = fakeStrategy{name: name, path: name + ".mp3", err: err, tried: &tried}
+			}
+			useStrategies(t, impls, map[string][]string{cache.YouTube: tt.chain})
+
+			path, err := runStrategies(context.Background(), cache.TrackInfo{Platform: cache.YouTube}, false)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("runStrategies() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil || path != tt.wantPath {
+				t.Errorf("runStrategies() = %q, %v, want %q, nil", path, err, tt.wantPath)
+			}
+			if !reflect.DeepEqual(tried, tt.wantTried) {
+				t.Errorf("tried %v, want %v", tried, tt.wantTried)
+			}
+		})
+	}
+}
+
+func TestRunStrategiesStopsWhenCancelled(t *testing.T) {
+	var tried []string
+	useStrategies(t, map[string]downloadStrategy{
+		"api":   fakeStrategy{name: "api", err: context.Canceled, tried: &tried},
+		"ytdlp": fakeStrategy{name: "ytdlp", tried: &tried},
+	}, map[string][]string{cache.YouTube: {"api", "ytdlp"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := runStrategies(ctx, cache.TrackInfo{Platform: cache.YouTube}, false)
+
+	var failed *StrategyError
+	if !errors.As(err, &failed) || !errors.Is(err, context.Canceled) {
+		t.Errorf("runStrategies() error = %v, want a *StrategyError wrapping context.Canceled", err)
+	}
+	if !reflect.DeepEqual(tried, []string{"api"}) {
+		t.Errorf("tried %v after the context was cancelled, want only api", tried)
+	}
+}
+
+func TestStrategyChain(t *testing.T) {
+	useStrategies(t, strategies, map[string][]string{cache.Spotify: {StrategyYouTubeSearch}})
+
+	tests := []struct {
+		platform string
+		want     []string
+	}{
+		{cache.YouTube, []string{StrategyCdnDirect, StrategyAPI, StrategyYtDlp}},
+		{"YouTube", []string{StrategyCdnDirect, StrategyAPI, StrategyYtDlp}},
+		{cache.Spotify, []string{StrategyYouTubeSearch}},
+		{"soundcloud", []string{StrategyCdnDirect}},
+	}
+	for _, tt := range tests {
+		if got := strategyChain(tt.platform); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("strategyChain(%q) = %v, want %v", tt.platform, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultStrategiesExist(t *testing.T) {
+	for platform, chain := range defaultStrategies {
+		for _, name := range chain {
+			if _, ok := strategies[name]; !ok {
+				t.Errorf("default strategy %q of %s is not implemented", name, platform)
+			}
+		}
+	}
+	if !slices.Equal(defaultStrategies[cache.Spotify], []string{StrategyCdnDirect, StrategyYouTubeSearch}) {
+		t.Errorf("Spotify defaults = %v, want a YouTube search after the CDN", defaultStrategies[cache.Spotify])
+	}
+}
+
+func TestFallbackQuery(t *testing.T) {
+	tests := []struct {
+		name, artist, want string
+	}{
+		{"Hello", "Adele", "Hello Adele"},
+		{"Hello", "", "Hello"},
+		{" Hello ", " Adele ", "Hello Adele"},
+		{"Adele - Hello", "Adele", "Adele - Hello"},
+		{"Hello (ADELE cover)", "adele", "Hello (ADELE cover)"},
+	}
+	for _, tt := range tests {
+		if got := fallbackQuery(cache.TrackInfo{Name: tt.name, Artist: tt.artist}); got != tt.want {
+			t.Errorf("fallbackQuery(%q, %q) = %q, want %q", tt.name, tt.artist, got, tt.want)
+		}
+	}
+}
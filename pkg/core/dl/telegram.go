@@ -1,14 +1,32 @@
 package dl
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram"
 	tg "github.com/amarnathcjd/gogram/telegram"
 )
 
+// telegramDownloadAttempts is how many times DownloadTelegram tries a download.
+const telegramDownloadAttempts = 3
+
+// telegramRetryBackoff is the wait before the first retry of a Telegram download; it doubles after each one.
+var telegramRetryBackoff = 2 * time.Second
+
+// TelegramDownloader downloads Telegram media with the given options, as (*tg.NewMessage).Download and
+// (*tg.Client).DownloadMedia do, and returns the path of the file written.
+type TelegramDownloader func(opts *tg.DownloadOptions) (string, error)
+
 // GetMessage retrieves a Telegram message by its URL.
 // It supports both public (e.g., https://t.me/ChannelName/1234) and private (e.g., https://t.me/c/12345678/90) URLs.
 // It returns the message object or an error if the URL is invalid or the message cannot be fetched.
@@ -54,3 +72,86 @@ func GetMessage(client *tg.Client, url string) (*tg.NewMessage, error) {
 
 	return client.GetMessageByID(username, int32(msgID))
 }
+
+// TelegramProgress returns a progress manager that reports the progress of a Telegram media download to the download
+// registered in ctx with StartDownload.
+func TelegramProgress(ctx context.Context) *tg.ProgressManager {
+	return tg.NewProgressManager(2, func(total, current int64) {
+		SetDownloadProgress(ctx, current, total)
+	})
+}
+
+// TelegramFilePath returns the path in the downloads directory that a Telegram file named name, of size bytes, is saved
+// to. The name is sanitized. If another file of the same name but a different size is already there, a number is added
+// to the name so that it is not overwritten; with size 0, the file there is assumed to be the same one.
+func TelegramFilePath(name string, size int64) string {
+	name = sanitizeFilename(name)
+	if name == "" {
+		name = generateUniqueName("")
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	path := filepath.Join(config.Conf.DownloadsDir, name)
+	for i := 1; ; i++ {
+		info, err := os.Stat(path)
+		if err != nil || size == 0 || info.Size() == size {
+			return path
+		}
+		path = filepath.Join(config.Conf.DownloadsDir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
+
+// DownloadTelegram downloads Telegram media named name, of size bytes, to the downloads directory, reporting its
+// progress to the download registered in ctx with StartDownload.
+// Large downloads sometimes fail partway when the connection to the data center is reset, so a failed download is tried
+// again, up to telegramDownloadAttempts times in all, waiting longer before each retry. gogram cannot resume a download
+// at an offset, so every attempt starts over. Errors returned by Telegram itself are not retried, since asking again
+// gets the same answer. With a size other than 0, a file of any other size is deleted and counts as a failed attempt;
+// so is what was written of a download that failed for good.
+func DownloadTelegram(ctx context.Context, download TelegramDownloader, name string, size int64) (string, error) {
+	opts := &tg.DownloadOptions{FileName: TelegramFilePath(name, size), ProgressManager: TelegramProgress(ctx)}
+	backoff := telegramRetryBackoff
+	var err error
+	for attempt := 1; attempt <= telegramDownloadAttempts; attempt++ {
+		var filePath string
+		filePath, err = download(opts)
+		if err == nil {
+			if err = checkDownloadSize(filePath, size); err == nil {
+				return filePath, nil
+			}
+			_ = os.Remove(filePath)
+		}
+
+		var rpcErr *gogram.ErrResponseCode
+		if errors.As(err, &rpcErr) || attempt == telegramDownloadAttempts {
+			break
+		}
+		gologging.WarnF("[DownloadTelegram] Attempt %d/%d for %s failed: %v", attempt, telegramDownloadAttempts, name, err)
+		select {
+		case <-ctx.Done():
+			return "", context.Cause(ctx)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if checkDownloadSize(opts.FileName, size) != nil {
+		_ = os.Remove(opts.FileName)
+	}
+	return "", err
+}
+
+// checkDownloadSize returns an error if the file at filePath is not size bytes long. A size of 0 is not checked.
+func checkDownloadSize(filePath string, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if info.Size() != size {
+		return fmt.Errorf("the download of %s is incomplete: %d of %d bytes", filepath.Base(filePath), info.Size(), size)
+	}
+	return nil
+}
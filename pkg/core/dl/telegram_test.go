@@ -0,0 +1,199 @@
+package dl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/zuchzub/Go/pkg/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amarnathcjd/gogram"
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+// useTelegramDownloads points the downloads directory at a temporary one and removes the retry backoff, restoring both
+// after the test. It returns the directory.
+func useTelegramDownloads(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	saved, savedBackoff := config.Conf, telegramRetryBackoff
+	config.Conf = &config.BotConfig{DownloadsDir: dir}
+	telegramRetryBackoff = 0
+	t.Cleanup(func() {
+		config.Conf = saved
+		telegramRetryBackoff = savedBackoff
+	})
+	return dir
+}
+
+// fakeDownloader downloads a file of size bytes. Each attempt writes the bytes listed in script for it, if any, and
+// then fails with err; attempts beyond the script write the whole file. Like gogram, it writes over the destination
+// without truncating it.
+type fakeDownloader struct {
+	size   int64
+	script []int64
+	err    error
+	calls  int
+	names  []string
+}
+
+func (f *fakeDownloader) download(opts *tg.DownloadOptions) (string, error) {
+	f.calls++
+	f.names = append(f.names, opts.FileName)
+	written, err := f.size, error(nil)
+	if f.calls <= len(f.script) {
+		written, err = f.script[f.calls-1], f.err
+	}
+
+	file, openErr := os.OpenFile(opts.FileName, os.O_CREATE|os.O_WRONLY, 0o644)
+	if openErr != nil {
+		return "", openErr
+	}
+	defer file.Close()
+	if _, writeErr := file.Write(bytes.Repeat([]byte{'x'}, int(written))); writeErr != nil {
+		return "", writeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return opts.FileName, nil
+}
+
+func TestDownloadTelegram(t *testing.T) {
+	reset := errors.New("connection reset by peer")
+	tests := []struct {
+		name      string
+		fake      fakeDownloader
+		size      int64
+		wantCalls int
+		wantErr   bool
+	}{
+		{"first attempt", fakeDownloader{size: 1000}, 1000, 1, false},
+		{"fails partway once", fakeDownloader{size: 1000, script: []int64{400}, err: reset}, 1000, 2, false},
+		{"fails before any byte", fakeDownloader{size: 1000, script: []int64{0, 0}, err: reset}, 1000, 3, false},
+		{"fails every attempt", fakeDownloader{size: 1000, script: []int64{100, 600, 999}, err: reset}, 1000, 3, true},
+		{"telegram error", fakeDownloader{size: 1000, script: []int64{0}, err: &gogram.ErrResponseCode{Code: 400, Message: "FILE_REFERENCE_EXPIRED"}}, 1000, 1, true},
+		{"undersized without error", fakeDownloader{size: 600, script: []int64{}}, 1000, 3, true},
+		{"unknown size", fakeDownloader{size: 600}, 0, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTelegramDownloads(t)
+			fake := tt.fake
+
+			path, err := DownloadTelegram(context.Background(), fake.download, "song.mp3", tt.size)
+			if fake.calls != tt.wantCalls {
+				t.Errorf("%d download attempts, want %d", fake.calls, tt.wantCalls)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("DownloadTelegram() = %q, want an error", path)
+				}
+				if _, statErr := os.Stat(fake.names[0]); !errors.Is(statErr, os.ErrNotExist) {
+					t.Errorf("the partial download was left behind: %v", statErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DownloadTelegram() error = %v", err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != fake.size {
+				t.Errorf("downloaded %d bytes, want %d", info.Size(), fake.size)
+			}
+			for _, name := range fake.names {
+				if name != path {
+					t.Errorf("an attempt wrote to %q, want every attempt to write to %q", name, path)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadTelegramKeepsFailingError(t *testing.T) {
+	useTelegramDownloads(t)
+	reset := errors.New("connection reset by peer")
+	fake := fakeDownloader{size: 1000, script: []int64{10, 20, 30}, err: reset}
+
+	if _, err := DownloadTelegram(context.Background(), fake.download, "song.mp3", 1000); !errors.Is(err, reset) {
+		t.Errorf("DownloadTelegram() error = %v, want the error of the last attempt", err)
+	}
+}
+
+func TestDownloadTelegramStopsWhenCancelled(t *testing.T) {
+	useTelegramDownloads(t)
+	telegramRetryBackoff = time.Hour
+	cause := errors.New("cancelled from /downloads")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(cause)
+	fake := fakeDownloader{size: 1000, script: []int64{500}, err: errors.New("connection reset by peer")}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := DownloadTelegram(ctx, fake.download, "song.mp3", 1000)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, cause) {
+			t.Errorf("DownloadTelegram() error = %v, want %v", err, cause)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("DownloadTelegram() waited out the backoff of a cancelled download")
+	}
+	if fake.calls != 1 {
+		t.Errorf("%d download attempts, want 1", fake.calls)
+	}
+}
+
+func TestTelegramFilePath(t *testing.T) {
+	dir := useTelegramDownloads(t)
+	write := func(name string, size int) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), bytes.Repeat([]byte{'x'}, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("taken.mp3", 10)
+	write("full.mp3", 10)
+	write("full (1).mp3", 20)
+
+	tests := []struct {
+		name string
+		size int64
+		want string
+	}{
+		{"song.mp3", 10, "song.mp3"},
+		{`../a/b\c:d?e*f"g<h>i|.mp3`, 10, "..abcdefghi.mp3"},
+		{"  padded.mp3  ", 10, "padded.mp3"},
+		{"taken.mp3", 10, "taken.mp3"},
+		{"taken.mp3", 0, "taken.mp3"},
+		{"taken.mp3", 30, "taken (1).mp3"},
+		{"full.mp3", 30, "full (2).mp3"},
+		{"full.mp3", 20, "full (1).mp3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TelegramFilePath(tt.name, tt.size); got != filepath.Join(dir, tt.want) {
+				t.Errorf("TelegramFilePath(%q, %d) = %q, want %q", tt.name, tt.size, got, filepath.Join(dir, tt.want))
+			}
+		})
+	}
+}
+
+func TestTelegramFilePathWithoutName(t *testing.T) {
+	dir := useTelegramDownloads(t)
+	for _, name := range []string{"", "   ", "/?*"} {
+		got := TelegramFilePath(name, 10)
+		if filepath.Dir(got) != dir || filepath.Base(got) == "" || strings.ContainsAny(filepath.Base(got), `/\?* `) {
+			t.Errorf("TelegramFilePath(%q) = %q, want a generated name in %s", name, got, dir)
+		}
+	}
+}
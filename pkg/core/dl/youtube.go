@@ -1,32 +1,42 @@
 package dl
 
 import (
+	"bufio"
 	"context"
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"github.com/zuchzub/Go/pkg/config"
 	"github.com/zuchzub/Go/pkg/core/cache"
+	"io"
 	"log"
-	"math/big"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 )
 
+// ytdlpProgressPrefix starts the progress lines yt-dlp prints with ytdlpProgressTemplate.
+const ytdlpProgressPrefix = "[progress]"
+
+// ytdlpProgressTemplate makes yt-dlp print its progress as the bytes downloaded, the total size and the estimated
+// total size, one update per line. Sizes it does not know are printed as NA.
+const ytdlpProgressTemplate = "download:" + ytdlpProgressPrefix + " %(progress.downloaded_bytes)s %(progress.total_bytes)s %(progress.total_bytes_estimate)s"
+
 // YouTubeData provides an interface for fetching track and playlist information from YouTube.
 type YouTubeData struct {
 	Query    string
 	ApiUrl   string
 	APIKey   string
 	Patterns map[string]*regexp.Regexp
+	Metadata MetadataFetcher
 }
 
 // NewYouTubeData initializes a YouTubeData instance with pre-compiled regex patterns and a cleaned query.
 func NewYouTubeData(query string) *YouTubeData {
-	return &YouTubeData{
+	y := &YouTubeData{
 		Query:  clearQuery(query),
 		ApiUrl: strings.TrimRight(config.Conf.ApiUrl, "/"),
 		APIKey: config.Conf.ApiKey,
@@ -36,6 +46,8 @@ func NewYouTubeData(query string) *YouTubeData {
 			"yt_shorts": regexp.MustCompile(`^(?:https?://)?(?:www\.)?youtube\.com/shorts/([\w-]{11})(?:[?#].*)?$`),
 		},
 	}
+	y.Metadata = ytDlpMetadataFetcher{y: y}
+	return y
 }
 
 // clearQuery removes extraneous URL parameters and fragments from a given query string.
@@ -46,23 +58,15 @@ func clearQuery(query string) string {
 }
 
 // normalizeYouTubeURL converts various YouTube URL formats (e.g., youtu.be, shorts) into a standard watch URL.
+// Links that name no video are returned unchanged.
 func (y *YouTubeData) normalizeYouTubeURL(url string) string {
 	if url == "" {
 		return ""
 	}
 
-	if strings.Contains(url, "youtu.be/") {
-		parts := strings.SplitN(strings.SplitN(url, "youtu.be/", 2)[1], "?", 2)
-		videoID := strings.SplitN(parts[0], "#", 2)[0]
-		return "https://www.youtube.com/watch?v=" + videoID
-	}
-
-	if strings.Contains(url, "youtube.com/shorts/") {
-		parts := strings.SplitN(strings.SplitN(url, "youtube.com/shorts/", 2)[1], "?", 2)
-		videoID := strings.SplitN(parts[0], "#", 2)[0]
-		return "https://www.youtube.com/watch?v=" + videoID
+	if parsed := ParseYouTubeURL(url); parsed.VideoID != "" {
+		return parsed.VideoURL()
 	}
-
 	return url
 }
 
@@ -115,7 +119,7 @@ func (y *YouTubeData) GetInfo(ctx context.Context) (cache.PlatformTracks, error)
 		}
 	}
 
-	return cache.PlatformTracks{}, errors.New("no video results were found")
+	return cache.PlatformTracks{}, fmt.Errorf("%w: no video results were found for %s", ErrTrackUnavailable, videoID)
 }
 
 // Search performs a search for a track on YouTube.
@@ -143,6 +147,9 @@ func (y *YouTubeData) GetTrack(ctx context.Context) (cache.TrackInfo, error) {
 
 	if y.ApiUrl != "" && y.APIKey != "" {
 		if trackInfo, err := NewApiData(y.Query).GetTrack(ctx); err == nil {
+			if trackInfo.Duration == 0 {
+				trackInfo.IsLive = y.checkLive(ctx, y.Query)
+			}
 			return trackInfo, nil
 		}
 	}
@@ -165,22 +172,34 @@ func (y *YouTubeData) GetTrack(ctx context.Context) (cache.TrackInfo, error) {
 		TC:       track.ID,
 		Cover:    track.Cover,
 		Platform: "youtube",
+		IsLive:   track.IsLive,
+	}
+
+	if !trackInfo.IsLive && trackInfo.Duration == 0 {
+		trackInfo.IsLive = y.checkLive(ctx, track.URL)
+	}
+	if trackInfo.IsLive {
+		trackInfo.Duration = 0
 	}
 
 	return trackInfo, nil
 }
 
-// downloadTrack handles the download of a track from YouTube.
-// It returns the file path of the downloaded track or an error if the download fails.
-func (y *YouTubeData) downloadTrack(ctx context.Context, info cache.TrackInfo, video bool) (string, error) {
-	if !video && y.ApiUrl != "" && y.APIKey != "" {
-		if filePath, err := y.downloadWithApi(ctx, info.TC, video); err == nil {
-			return filePath, nil
-		}
+// checkLive reports whether a URL is currently live, treating a failed lookup as not live.
+func (y *YouTubeData) checkLive(ctx context.Context, videoURL string) bool {
+	live, err := y.detectLive(ctx, videoURL)
+	if err != nil {
+		log.Printf("Live detection failed for %s: %v", videoURL, err)
+		return false
 	}
+	return live
+}
 
-	filePath, err := y.downloadWithYtDlp(ctx, info.TC, video)
-	return filePath, err
+// downloadTrack downloads a track with the download strategies of YouTube.
+// It returns the file path of the downloaded track or a *StrategyError naming every failed attempt.
+// Livestreams are never downloaded; the ytdlp strategy returns their HLS manifest URL so they can be streamed directly.
+func (y *YouTubeData) downloadTrack(ctx context.Context, info cache.TrackInfo, video bool) (string, error) {
+	return runStrategies(ctx, info, video)
 }
 
 // BuildYtdlpParams constructs the command-line parameters for yt-dlp to download media.
@@ -191,7 +210,9 @@ func (y *YouTubeData) BuildYtdlpParams(videoID string, video bool) []string {
 	params := []string{
 		"yt-dlp",
 		"--no-warnings",
-		"--quiet",
+		"--newline",
+		"--progress",
+		"--progress-template", ytdlpProgressTemplate,
 		"--geo-bypass",
 		"--retries", "2",
 		"--continue",
@@ -210,35 +231,100 @@ func (y *YouTubeData) BuildYtdlpParams(videoID string, video bool) []string {
 
 	formatSelector := "bestaudio[ext=m4a]/bestaudio[ext=mp4]/bestaudio[ext=webm]/bestaudio/best"
 	if video {
-		formatSelector = "bestvideo[ext=mp4][height<=1080]+bestaudio[ext=m4a]/best[ext=mp4][height<=1080]"
+		formatSelector = videoFormatSelector(config.Conf.VideoMaxHeight)
 		params = append(params, "--merge-output-format", "mp4")
 	}
 	params = append(params, "-f", formatSelector)
 
-	if cookieFile := y.getCookieFile(); cookieFile != "" {
-		params = append(params, "--cookies", cookieFile)
-	} else if config.Conf.Proxy != "" {
-		params = append(params, "--proxy", config.Conf.Proxy)
-	}
+	params = y.appendNetworkParams(params)
 
 	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
+	if video {
+		// The selected quality is printed before the path, which stays the last line of the output.
+		params = append(params, "--print", "after_move:%(height)sp (%(format_id)s)")
+	}
 	params = append(params, videoURL, "--print", "after_move:filepath")
 
 	return params
 }
 
+// videoFormatSelector returns the yt-dlp format selector for videos of at most maxHeight.
+// MP4 with M4A audio is preferred, then any container at that height, then the best format of any height, so a
+// video is never refused for lack of a matching format. Lower heights need no rungs of their own: a video without
+// a format under maxHeight has none under a smaller height either.
+func videoFormatSelector(maxHeight int) string {
+	return strings.Join([]string{
+		fmt.Sprintf("bestvideo[ext=mp4][height<=%d]+bestaudio[ext=m4a]", maxHeight),
+		fmt.Sprintf("best[ext=mp4][height<=%d]", maxHeight),
+		fmt.Sprintf("bestvideo[height<=%d]+bestaudio", maxHeight),
+		fmt.Sprintf("best[height<=%d]", maxHeight),
+		"bestvideo+bestaudio",
+		"best",
+	}, "/")
+}
+
 // downloadWithYtDlp downloads media from YouTube using the yt-dlp command-line tool.
+// Each download is validated, and a corrupt file is removed and retried up to the configured number of times.
 // It returns the file path of the downloaded track or an error if the download fails.
 func (y *YouTubeData) downloadWithYtDlp(ctx context.Context, videoID string, video bool) (string, error) {
+	var lastErr error
+	for attempt := 0; attempt <= config.Conf.DownloadRetries; attempt++ {
+		filePath, err := y.runYtDlp(ctx, videoID, video)
+		if err != nil {
+			return "", err
+		}
+
+		if err := validateDownload(filePath); err != nil {
+			log.Printf("Validation failed for %s (attempt %d/%d): %v", videoID, attempt+1, config.Conf.DownloadRetries+1, err)
+			_ = os.Remove(filePath)
+			lastErr = err
+			continue
+		}
+
+		return filePath, nil
+	}
+
+	return "", lastErr
+}
+
+// runYtDlp runs a single yt-dlp invocation and returns the path it reports.
+// Its progress is read from its output as it downloads, and reported to the download registered in ctx.
+// It returns an error if yt-dlp fails or the reported file does not exist.
+func (y *YouTubeData) runYtDlp(ctx context.Context, videoID string, video bool) (string, error) {
 	ytdlpParams := y.BuildYtdlpParams(videoID, video)
 	// #nosec G204 - The parameters are constructed internally and are not from user input.
 	cmd := exec.CommandContext(ctx, ytdlpParams[0], ytdlpParams[1:]...)
-
-	output, err := cmd.Output()
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		return "", err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return "", err
+	}
+	if err = cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start yt-dlp: %w", err)
+	}
+
+	// yt-dlp prints its progress to stdout or stderr depending on its version and options, so both are read.
+	var outLines, errLines []string
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		outLines = readYtdlpOutput(ctx, stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		errLines = readYtdlpOutput(ctx, stderr)
+	}()
+	wg.Wait()
+	output := strings.Join(outLines, "\n")
+
+	if err = cmd.Wait(); err != nil {
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
-			stderr := string(exitErr.Stderr)
+			stderr := strings.Join(errLines, "\n")
 			return "", fmt.Errorf("yt-dlp failed with exit code %d: %s", exitErr.ExitCode(), stderr)
 		}
 
@@ -249,7 +335,11 @@ func (y *YouTubeData) downloadWithYtDlp(ctx context.Context, videoID string, vid
 		return "", fmt.Errorf("an unexpected error occurred while downloading %s: %w", videoID, err)
 	}
 
-	downloadedPathStr := strings.TrimSpace(string(output))
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	downloadedPathStr := strings.TrimSpace(lines[len(lines)-1])
+	if video && len(lines) > 1 {
+		log.Printf("Downloaded %s in %s", videoID, strings.TrimSpace(lines[0]))
+	}
 	if downloadedPathStr == "" {
 		return "", fmt.Errorf("no output path was returned for %s", videoID)
 	}
@@ -261,37 +351,59 @@ func (y *YouTubeData) downloadWithYtDlp(ctx context.Context, videoID string, vid
 	return downloadedPathStr, nil
 }
 
-// getCookieFile retrieves the path to a cookie file from the configured list.
-// It returns the path to a randomly selected cookie file.
-func (y *YouTubeData) getCookieFile() string {
-	cookiesPath := config.Conf.CookiesPath
-	if len(cookiesPath) == 0 {
-		return ""
-	}
-	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(cookiesPath))))
-	if err != nil {
-		log.Printf("Could not generate a random number: %v", err)
-		return cookiesPath[0]
+// readYtdlpOutput reads the output of yt-dlp line by line until it ends. Progress lines are reported to the download
+// registered in ctx; the other lines are returned.
+func readYtdlpOutput(ctx context.Context, r io.Reader) []string {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if done, total, ok := parseYtdlpProgress(line); ok {
+			SetDownloadProgress(ctx, done, total)
+			continue
+		}
+		lines = append(lines, line)
 	}
-
-	return cookiesPath[n.Int64()]
+	return lines
 }
 
-// downloadWithApi downloads a track using the external API.
-// It returns the file path of the downloaded track or an error if the download fails.
-func (y *YouTubeData) downloadWithApi(ctx context.Context, videoID string, _ bool) (string, error) {
-	videoUrl := fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID)
-	api := NewApiData(videoUrl)
-	track, err := api.GetTrack(ctx)
-	if err != nil {
-		return "", err
+// parseYtdlpProgress parses a progress line printed with ytdlpProgressTemplate into the bytes downloaded and the
+// total size, or the estimated one if the total is unknown. total is 0 if neither is known.
+// It returns false if the line is not a progress line.
+func parseYtdlpProgress(line string) (done, total int64, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(line), ytdlpProgressPrefix)
+	if !found {
+		return 0, 0, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) != 3 {
+		return 0, 0, false
 	}
 
-	down, err := NewDownload(ctx, track)
-	if err != nil {
-		log.Println("Error creating download: " + err.Error())
-		return "", err
+	// Sizes may be printed as floats, and as NA when unknown.
+	size := func(s string) int64 {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0
+		}
+		return int64(n)
+	}
+	done, total = size(fields[0]), size(fields[1])
+	if total == 0 {
+		total = size(fields[2])
 	}
+	return done, total, true
+}
 
-	return down.Process()
+// appendNetworkParams adds the pinned IP family, and the cookie file or, when no cookies are configured, the proxy,
+// to a set of yt-dlp parameters.
+func (y *YouTubeData) appendNetworkParams(params []string) []string {
+	params = append(params, ytdlpFamilyParams()...)
+	if cookieFile := getCookieFile("yt"); cookieFile != "" {
+		return append(params, "--cookies", cookieFile)
+	}
+	if config.Conf.Proxy != "" {
+		return append(params, "--proxy", config.Conf.Proxy)
+	}
+	return params
 }
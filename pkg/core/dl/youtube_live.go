@@ -0,0 +1,78 @@
+package dl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// MetadataFetcher returns the JSON document that yt-dlp prints with -J for a URL.
+// It is an interface so that live detection can be fed canned yt-dlp output.
+type MetadataFetcher interface {
+	FetchMetadata(ctx context.Context, videoURL string) ([]byte, error)
+}
+
+// ytDlpMetadataFetcher fetches metadata by invoking yt-dlp with the configured cookies or proxy.
+type ytDlpMetadataFetcher struct {
+	y *YouTubeData
+}
+
+// FetchMetadata runs yt-dlp -J for the given URL and returns its raw output.
+func (f ytDlpMetadataFetcher) FetchMetadata(ctx context.Context, videoURL string) ([]byte, error) {
+	params := f.y.appendNetworkParams([]string{"--no-warnings", "--skip-download", "-J"})
+	params = append(params, videoURL)
+	// #nosec G204 - The parameters are constructed internally and are not from user input.
+	output, err := exec.CommandContext(ctx, "yt-dlp", params...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp could not fetch metadata for %s: %w", videoURL, err)
+	}
+	return output, nil
+}
+
+// parseLiveStatus reports whether a yt-dlp -J document describes a stream that is live right now.
+// Streams that have ended and become VODs report "was_live" or "post_live" and are not considered live.
+func parseLiveStatus(raw []byte) (bool, error) {
+	var info struct {
+		IsLive     bool   `json:"is_live"`
+		LiveStatus string `json:"live_status"`
+	}
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return false, fmt.Errorf("failed to parse the yt-dlp metadata: %w", err)
+	}
+	return info.IsLive || info.LiveStatus == "is_live", nil
+}
+
+// detectLive asks the metadata fetcher whether the given URL is currently live.
+func (y *YouTubeData) detectLive(ctx context.Context, videoURL string) (bool, error) {
+	raw, err := y.Metadata.FetchMetadata(ctx, videoURL)
+	if err != nil {
+		return false, err
+	}
+	return parseLiveStatus(raw)
+}
+
+// resolveLiveURL resolves the direct HLS manifest URL of a livestream so it can be streamed without downloading.
+// It returns the manifest URL or an error if yt-dlp cannot resolve one.
+func (y *YouTubeData) resolveLiveURL(ctx context.Context, videoID string, video bool) (string, error) {
+	formatSelector := "best[height<=480]/best"
+	if video {
+		formatSelector = "best[height<=720]/best"
+	}
+
+	params := y.appendNetworkParams([]string{"--no-warnings", "-g", "-f", formatSelector})
+	params = append(params, fmt.Sprintf("https://www.youtube.com/watch?v=%s", videoID))
+	// #nosec G204 - The parameters are constructed internally and are not from user input.
+	output, err := exec.CommandContext(ctx, "yt-dlp", params...).Output()
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp could not resolve the livestream URL for %s: %w", videoID, err)
+	}
+
+	manifestURL := strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+	if manifestURL == "" {
+		return "", errors.New("yt-dlp returned an empty livestream URL")
+	}
+	return manifestURL, nil
+}
@@ -0,0 +1,91 @@
+package dl
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// cannedMetadata is a MetadataFetcher that returns fixed yt-dlp output.
+type cannedMetadata struct {
+	raw string
+	err error
+}
+
+func (c cannedMetadata) FetchMetadata(context.Context, string) ([]byte, error) {
+	return []byte(c.raw), c.err
+}
+
+func TestDetectLive(t *testing.T) {
+	tests := []struct {
+		name    string
+		fetcher cannedMetadata
+		want    bool
+		wantErr bool
+	}{
+		{name: "live", fetcher: cannedMetadata{raw: `{"id":"abc","is_live":true,"live_status":"is_live"}`}, want: true},
+		{name: "live status only", fetcher: cannedMetadata{raw: `{"id":"abc","live_status":"is_live"}`}, want: true},
+		{name: "ended stream", fetcher: cannedMetadata{raw: `{"id":"abc","is_live":false,"live_status":"was_live"}`}},
+		{name: "processing VOD", fetcher: cannedMetadata{raw: `{"id":"abc","live_status":"post_live"}`}},
+		{name: "upcoming", fetcher: cannedMetadata{raw: `{"id":"abc","live_status":"is_upcoming"}`}},
+		{name: "video", fetcher: cannedMetadata{raw: `{"id":"abc","duration":212,"live_status":"not_live"}`}},
+		{name: "no live fields", fetcher: cannedMetadata{raw: `{"id":"abc"}`}},
+		{name: "not JSON", fetcher: cannedMetadata{raw: "ERROR: Video unavailable"}, wantErr: true},
+		{name: "yt-dlp failed", fetcher: cannedMetadata{err: errors.New("exit status 1")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			y := &YouTubeData{Metadata: tt.fetcher}
+			got, err := y.detectLive(context.Background(), "https://www.youtube.com/watch?v=abc")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("detectLive() error = %v, want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("detectLive() = %t, want %t", got, tt.want)
+			}
+			if live := y.checkLive(context.Background(), "https://www.youtube.com/watch?v=abc"); live != tt.want {
+				t.Errorf("checkLive() = %t, want %t", live, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLiveRenderer(t *testing.T) {
+	tests := []struct {
+		name     string
+		renderer map[string]interface{}
+		want     bool
+	}{
+		{
+			name: "live badge",
+			renderer: map[string]interface{}{"badges": []interface{}{
+				map[string]interface{}{"metadataBadgeRenderer": map[string]interface{}{"style": "BADGE_STYLE_TYPE_LIVE_NOW"}},
+			}},
+			want: true,
+		},
+		{
+			name: "live overlay",
+			renderer: map[string]interface{}{"thumbnailOverlays": []interface{}{
+				map[string]interface{}{"thumbnailOverlayTimeStatusRenderer": map[string]interface{}{"style": "LIVE"}},
+			}},
+			want: true,
+		},
+		{
+			name: "video",
+			renderer: map[string]interface{}{
+				"badges": []interface{}{
+					map[string]interface{}{"metadataBadgeRenderer": map[string]interface{}{"style": "BADGE_STYLE_TYPE_SIMPLE"}},
+				},
+				"thumbnailOverlays": []interface{}{
+					map[string]interface{}{"thumbnailOverlayTimeStatusRenderer": map[string]interface{}{"style": "DEFAULT"}},
+				},
+			},
+		},
+		{name: "no badges", renderer: map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		if got := isLiveRenderer(tt.renderer); got != tt.want {
+			t.Errorf("%s: isLiveRenderer() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package dl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"os/exec"
+	"strconv"
+)
+
+// mixURL returns the URL of the YouTube Mix (radio) generated for a video.
+func mixURL(videoID string) string {
+	return fmt.Sprintf("https://www.youtube.com/watch?v=%s&list=RD%s", videoID, videoID)
+}
+
+// RelatedTracks returns up to limit tracks from the YouTube Mix of a track, for continuing playback once a queue runs out.
+// YouTube tracks seed the mix directly; tracks from other platforms are first looked up on YouTube by name.
+// The seed video itself is left out of the result.
+func RelatedTracks(ctx context.Context, track *cache.CachedTrack, limit int) ([]cache.MusicTrack, error) {
+	y := NewYouTubeData(track.URL)
+	videoID := ""
+	if y.IsValid() {
+		videoID = y.extractVideoID(y.Query)
+	}
+	if videoID == "" {
+		results, err := searchYouTube(track.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find %s on YouTube: %w", track.Name, err)
+		}
+		if len(results) == 0 {
+			return nil, errors.New("no video results were found")
+		}
+		videoID = results[0].ID
+	}
+
+	return y.mixTracks(ctx, videoID, limit)
+}
+
+// mixTracks lists the entries of a video's YouTube Mix with yt-dlp, skipping the video itself and livestreams.
+func (y *YouTubeData) mixTracks(ctx context.Context, videoID string, limit int) ([]cache.MusicTrack, error) {
+	// The first entry of a mix is the seed video, so one extra entry is requested.
+	params := y.appendNetworkParams([]string{"--no-warnings", "--flat-playlist", "-J", "--playlist-end", strconv.Itoa(limit + 1)})
+	params = append(params, mixURL(videoID))
+	// #nosec G204 - The parameters are constructed internally and are not from user input.
+	output, err := exec.CommandContext(ctx, "yt-dlp", params...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp could not fetch the mix for %s: %w", videoID, err)
+	}
+
+	var mix struct {
+		Entries []struct {
+			ID         string  `json:"id"`
+			Title      string  `json:"title"`
+			Duration   float64 `json:"duration"`
+			LiveStatus string  `json:"live_status"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(output, &mix); err != nil {
+		return nil, fmt.Errorf("failed to parse the mix for %s: %w", videoID, err)
+	}
+
+	tracks := make([]cache.MusicTrack, 0, limit)
+	for _, entry := range mix.Entries {
+		if entry.ID == "" || entry.ID == videoID || entry.LiveStatus == "is_live" {
+			continue
+		}
+		tracks = append(tracks, cache.MusicTrack{
+			URL:      "https://www.youtube.com/watch?v=" + entry.ID,
+			Name:     entry.Title,
+			ID:       entry.ID,
+			Cover:    fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", entry.ID),
+			Duration: int(entry.Duration),
+			Platform: cache.YouTube,
+		})
+		if len(tracks) == limit {
+			break
+		}
+	}
+	return tracks, nil
+}
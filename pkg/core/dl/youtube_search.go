@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/timeparse"
 	"io"
 	"net/http"
 	"regexp"
@@ -69,6 +70,7 @@ func parseSearchResults(node interface{}, tracks *[]cache.MusicTrack) {
 				Cover:    thumb,
 				Duration: duration,
 				Platform: "youtube",
+				IsLive:   isLiveRenderer(vid),
 			})
 		} else {
 			for _, child := range v {
@@ -78,6 +80,25 @@ func parseSearchResults(node interface{}, tracks *[]cache.MusicTrack) {
 	}
 }
 
+// isLiveRenderer reports whether a videoRenderer carries YouTube's LIVE badge or overlay
+func isLiveRenderer(vid map[string]interface{}) bool {
+	if badges, ok := vid["badges"].([]interface{}); ok {
+		for _, badge := range badges {
+			if safeString(dig(badge, "metadataBadgeRenderer", "style")) == "BADGE_STYLE_TYPE_LIVE_NOW" {
+				return true
+			}
+		}
+	}
+	if overlays, ok := vid["thumbnailOverlays"].([]interface{}); ok {
+		for _, overlay := range overlays {
+			if safeString(dig(overlay, "thumbnailOverlayTimeStatusRenderer", "style")) == "LIVE" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // safely dig into nested JSON
 func dig(m interface{}, path ...interface{}) interface{} {
 	curr := m
@@ -110,28 +131,9 @@ func safeString(v interface{}) string {
 
 // parse duration like "3:45" -> 225 seconds
 func parseDuration(s string) int {
-	if s == "" {
+	d, err := timeparse.ParseDuration(s)
+	if err != nil {
 		return 0
 	}
-	parts := strings.Split(s, ":")
-	total := 0
-	multiplier := 1
-
-	// Process from right to left (seconds → minutes → hours)
-	for i := len(parts) - 1; i >= 0; i-- {
-		total += atoi(parts[i]) * multiplier
-		multiplier *= 60
-	}
-	return total
-}
-
-// atoi converts a string to an integer
-func atoi(s string) int {
-	var n int
-	for _, r := range s {
-		if r >= '0' && r <= '9' {
-			n = n*10 + int(r-'0')
-		}
-	}
-	return n
+	return int(d.Seconds())
 }
@@ -0,0 +1,106 @@
+package dl
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// YouTubeURLKind is the shape of a YouTube link, which decides whether it plays a video, a playlist, or asks.
+type YouTubeURLKind int
+
+const (
+	// NotYouTubeURL is anything that is not a YouTube video or playlist link.
+	NotYouTubeURL YouTubeURLKind = iota
+	// YouTubeVideo is a link to a single video.
+	YouTubeVideo
+	// YouTubePlaylist is a link to a playlist with no video selected.
+	YouTubePlaylist
+	// YouTubeVideoInPlaylist is a link to a video opened from a playlist, carrying both v= and list=.
+	YouTubeVideoInPlaylist
+)
+
+// YouTubeURL is a YouTube link broken down into its video and playlist IDs.
+type YouTubeURL struct {
+	Kind    YouTubeURLKind
+	VideoID string
+	ListID  string
+}
+
+var (
+	youTubeVideoID = regexp.MustCompile(`^[\w-]{11}$`)
+	youTubeListID  = regexp.MustCompile(`^[\w-]+$`)
+)
+
+// youTubeHosts are the hosts serving watch and playlist pages.
+var youTubeHosts = []string{"youtube.com", "www.youtube.com", "m.youtube.com", "music.youtube.com"}
+
+// IsYouTubeHost reports whether host, such as "www.youtube.com" or "youtu.be", serves YouTube videos.
+func IsYouTubeHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "youtu.be" || host == "www.youtu.be" || slices.Contains(youTubeHosts, host)
+}
+
+// generatedList reports whether a playlist ID is one YouTube generates per user or per video, such as a Mix (RD...),
+// Liked videos (LL) or Watch later (WL). Such lists are not shared playlists, so a link carrying one plays the video.
+func generatedList(listID string) bool {
+	return strings.HasPrefix(listID, "RD") || listID == "LL" || listID == "WL"
+}
+
+// ParseYouTubeURL classifies a YouTube link and extracts its video and playlist IDs.
+// Links to generated lists such as Mixes are classified by their video alone.
+func ParseYouTubeURL(raw string) YouTubeURL {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return YouTubeURL{}
+	}
+
+	host := strings.ToLower(u.Host)
+	query := u.Query()
+	var videoID string
+	switch {
+	case host == "youtu.be" || host == "www.youtu.be":
+		videoID = strings.Trim(u.Path, "/")
+	case !slices.Contains(youTubeHosts, host):
+		return YouTubeURL{}
+	case strings.HasPrefix(u.Path, "/shorts/"):
+		videoID = strings.Trim(strings.TrimPrefix(u.Path, "/shorts/"), "/")
+	case u.Path == "/watch" || u.Path == "/playlist":
+		videoID = query.Get("v")
+	default:
+		return YouTubeURL{}
+	}
+
+	result := YouTubeURL{}
+	if youTubeVideoID.MatchString(videoID) {
+		result.VideoID = videoID
+	}
+	if listID := query.Get("list"); youTubeListID.MatchString(listID) && (result.VideoID == "" || !generatedList(listID)) {
+		result.ListID = listID
+	}
+
+	switch {
+	case result.VideoID != "" && result.ListID != "":
+		result.Kind = YouTubeVideoInPlaylist
+	case result.VideoID != "":
+		result.Kind = YouTubeVideo
+	case result.ListID != "":
+		result.Kind = YouTubePlaylist
+	}
+	return result
+}
+
+// VideoURL returns the canonical watch URL of the video, without the playlist.
+func (u YouTubeURL) VideoURL() string {
+	return "https://www.youtube.com/watch?v=" + u.VideoID
+}
+
+// PlaylistURL returns the canonical URL of the playlist, without the video.
+func (u YouTubeURL) PlaylistURL() string {
+	return "https://www.youtube.com/playlist?list=" + u.ListID
+}
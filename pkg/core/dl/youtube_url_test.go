@@ -0,0 +1,80 @@
+package dl
+
+import "testing"
+
+func TestParseYouTubeURL(t *testing.T) {
+	const video, list = "dQw4w9WgXcQ", "PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG"
+	tests := []struct {
+		url  string
+		want YouTubeURL
+	}{
+		{"https://www.youtube.com/watch?v=" + video, YouTubeURL{YouTubeVideo, video, ""}},
+		{"youtube.com/watch?v=" + video + "&t=42s", YouTubeURL{YouTubeVideo, video, ""}},
+		{"https://m.youtube.com/watch?v=" + video, YouTubeURL{YouTubeVideo, video, ""}},
+		{"https://music.youtube.com/watch?v=" + video, YouTubeURL{YouTubeVideo, video, ""}},
+		{"https://youtu.be/" + video + "?si=abc", YouTubeURL{YouTubeVideo, video, ""}},
+		{"https://www.youtube.com/shorts/" + video, YouTubeURL{YouTubeVideo, video, ""}},
+		{"  https://WWW.YOUTUBE.COM/watch?v=" + video + "  ", YouTubeURL{YouTubeVideo, video, ""}},
+		{"https://www.youtube.com/playlist?list=" + list, YouTubeURL{YouTubePlaylist, "", list}},
+		{"https://www.youtube.com/watch?v=" + video + "&list=" + list + "&index=3", YouTubeURL{YouTubeVideoInPlaylist, video, list}},
+		{"https://www.youtube.com/watch?list=" + list + "&v=" + video, YouTubeURL{YouTubeVideoInPlaylist, video, list}},
+		{"https://youtu.be/" + video + "?list=" + list, YouTubeURL{YouTubeVideoInPlaylist, video, list}},
+		{"https://music.youtube.com/playlist?list=" + list, YouTubeURL{YouTubePlaylist, "", list}},
+
+		// Generated lists play the video alone.
+		{"https://www.youtube.com/watch?v=" + video + "&list=RD" + video, YouTubeURL{YouTubeVideo, video, ""}},
+		{"https://www.youtube.com/watch?v=" + video + "&list=LL", YouTubeURL{YouTubeVideo, video, ""}},
+		{"https://www.youtube.com/watch?v=" + video + "&list=WL", YouTubeURL{YouTubeVideo, video, ""}},
+
+		// Malformed IDs are ignored.
+		{"https://www.youtube.com/watch?v=short", YouTubeURL{}},
+		{"https://www.youtube.com/watch?v=short&list=" + list, YouTubeURL{YouTubePlaylist, "", list}},
+		{"https://www.youtube.com/watch?v=" + video + "&list=bad!id", YouTubeURL{YouTubeVideo, video, ""}},
+
+		// Not YouTube videos or playlists.
+		{"https://www.youtube.com/@channel", YouTubeURL{}},
+		{"https://www.youtube.com/results?search_query=song", YouTubeURL{}},
+		{"https://notyoutube.com/watch?v=" + video, YouTubeURL{}},
+		{"https://youtube.com.evil.example/watch?v=" + video, YouTubeURL{}},
+		{"https://open.spotify.com/track/abc", YouTubeURL{}},
+		{"never gonna give you up", YouTubeURL{}},
+		{"", YouTubeURL{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := ParseYouTubeURL(tt.url); got != tt.want {
+				t.Errorf("ParseYouTubeURL(%q) = %+v, want %+v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYouTubeURLs(t *testing.T) {
+	u := YouTubeURL{Kind: YouTubeVideoInPlaylist, VideoID: "dQw4w9WgXcQ", ListID: "PL123"}
+	if got, want := u.VideoURL(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ"; got != want {
+		t.Errorf("VideoURL() = %q, want %q", got, want)
+	}
+	if got, want := u.PlaylistURL(), "https://www.youtube.com/playlist?list=PL123"; got != want {
+		t.Errorf("PlaylistURL() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeYouTubeURL(t *testing.T) {
+	// The YouTube service shares ParseYouTubeURL, so every video link becomes the same watch URL.
+	y := &YouTubeData{}
+	const want = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+	for _, url := range []string{
+		"https://youtu.be/dQw4w9WgXcQ",
+		"https://www.youtube.com/shorts/dQw4w9WgXcQ",
+		"https://music.youtube.com/watch?v=dQw4w9WgXcQ&list=PL123",
+	} {
+		if got := y.normalizeYouTubeURL(url); got != want {
+			t.Errorf("normalizeYouTubeURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+
+	playlist := "https://www.youtube.com/playlist?list=PL123"
+	if got := y.normalizeYouTubeURL(playlist); got != playlist {
+		t.Errorf("normalizeYouTubeURL(%q) = %q, want it unchanged", playlist, got)
+	}
+}
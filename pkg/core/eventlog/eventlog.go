@@ -0,0 +1,230 @@
+// Package eventlog keeps an append-only JSONL record of queue and playback events for crash forensics.
+// Events are written by a single goroutine, so emitting one never blocks the caller.
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// bufferSize is how many events can be pending before new ones are dropped.
+const bufferSize = 1024
+
+// ErrDisabled is returned by Tail when the event log is not open.
+var ErrDisabled = errors.New("eventlog: disabled")
+
+// Event is a single line of the event log.
+type Event struct {
+	Ts      time.Time `json:"ts"`
+	Chat    int64     `json:"chat"`
+	Event   string    `json:"event"`
+	TrackID string    `json:"track_id,omitempty"`
+	Extra   string    `json:"extra,omitempty"`
+}
+
+// logger writes events to a size-rotated file.
+type logger struct {
+	path    string
+	maxSize int64
+	keep    int
+
+	mu     sync.RWMutex
+	closed bool
+	events chan Event
+	done   chan struct{}
+
+	file *os.File
+	size int64
+}
+
+var (
+	current atomic.Pointer[logger]
+	dropped atomic.Uint64
+)
+
+// Open starts writing events to path, rotating it once it grows past maxSize bytes and keeping up to keep rotated files.
+// An empty path leaves the event log disabled.
+func Open(path string, maxSize int64, keep int) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("eventlog: failed to create the log dir: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("eventlog: failed to open %s: %w", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("eventlog: failed to stat %s: %w", path, err)
+	}
+
+	l := &logger{
+		path:    path,
+		maxSize: maxSize,
+		keep:    keep,
+		events:  make(chan Event, bufferSize),
+		done:    make(chan struct{}),
+		file:    file,
+		size:    info.Size(),
+	}
+	if old := current.Swap(l); old != nil {
+		old.close()
+	}
+
+	go l.run()
+	return nil
+}
+
+// Emit records an event for a chat. It never blocks: if the buffer is full the event is dropped and counted.
+func Emit(chatID int64, event, trackID, extra string) {
+	l := current.Load()
+	if l == nil {
+		return
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+
+	select {
+	case l.events <- Event{Ts: time.Now().UTC(), Chat: chatID, Event: event, TrackID: trackID, Extra: extra}:
+	default:
+		dropped.Add(1)
+	}
+}
+
+// Dropped returns how many events have been dropped because the buffer was full.
+func Dropped() uint64 {
+	return dropped.Load()
+}
+
+// Close flushes pending events and closes the log file.
+func Close() {
+	if l := current.Swap(nil); l != nil {
+		l.close()
+	}
+}
+
+// Tail returns up to n of the most recent lines for a chat from the current log file.
+func Tail(chatID int64, n int) ([]string, error) {
+	l := current.Load()
+	if l == nil {
+		return nil, ErrDisabled
+	}
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("eventlog: failed to open %s: %w", l.path, err)
+	}
+	defer file.Close()
+
+	needle := fmt.Sprintf(`"chat":%d,`, chatID)
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, needle) {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("eventlog: failed to read %s: %w", l.path, err)
+	}
+	return lines, nil
+}
+
+// close stops accepting events and waits for the writer to drain the buffer.
+func (l *logger) close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.events)
+	l.mu.Unlock()
+
+	<-l.done
+}
+
+// run writes events until the channel is closed.
+func (l *logger) run() {
+	defer close(l.done)
+	defer func() {
+		if l.file != nil {
+			_ = l.file.Close()
+		}
+	}()
+
+	for event := range l.events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		line = append(line, '\n')
+
+		if l.maxSize > 0 && l.size+int64(len(line)) > l.maxSize {
+			if err := l.rotate(); err != nil {
+				gologging.WarnF("[eventlog] Rotation failed: %v", err)
+			}
+		}
+		if l.file == nil {
+			continue
+		}
+
+		n, err := l.file.Write(line)
+		l.size += int64(n)
+		if err != nil {
+			gologging.WarnF("[eventlog] Write failed: %v", err)
+		}
+	}
+}
+
+// rotate syncs and closes the current file, shifts the rotated files up by one, and opens a fresh file.
+func (l *logger) rotate() error {
+	if l.file != nil {
+		_ = l.file.Sync()
+		_ = l.file.Close()
+		l.file = nil
+	}
+
+	if l.keep > 0 {
+		_ = os.Remove(fmt.Sprintf("%s.%d", l.path, l.keep))
+		for i := l.keep - 1; i >= 1; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+		}
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	l.size = 0
+	return nil
+}
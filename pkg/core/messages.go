@@ -0,0 +1,235 @@
+package core
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// MaxMessageLength is the longest text Telegram accepts in a single message.
+const MaxMessageLength = 4096
+
+// MaxCaptionLength is the longest caption Telegram accepts on a media message.
+const MaxCaptionLength = 1024
+
+// pageFooterReserve is the room kept free in each part for the "1/3" footer added by SendLong.
+const pageFooterReserve = 32
+
+// balancedTags are the HTML tags whose nesting SplitMessage keeps balanced across parts.
+var balancedTags = []string{"b", "strong", "i", "em", "u", "ins", "s", "strike", "del", "code", "pre", "a", "tg-spoiler", "blockquote"}
+
+// openTag is a tag that is open at some point of the text, kept with its raw form so it can be reopened as written.
+type openTag struct {
+	name string
+	raw  string
+}
+
+// SplitMessage splits an HTML message into parts of at most limit characters.
+// Parts end at line breaks where possible; a line longer than limit is cut between characters, but never inside a tag
+// or an HTML entity. Formatting tags left open at a cut are closed at the end of the part and reopened at the start of
+// the next, so each part is valid on its own.
+func SplitMessage(text string, limit int) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	type breakPoint struct {
+		token int
+		bytes int
+		runes int
+		stack []openTag
+	}
+
+	var (
+		parts     []string
+		cur       strings.Builder
+		curRunes  int
+		prefix    int
+		stack     []openTag
+		lastBreak *breakPoint
+	)
+
+	flush := func() {
+		part := strings.TrimRight(cur.String(), "\n") + closingTags(stack)
+		parts = append(parts, part)
+		cur.Reset()
+		cur.WriteString(openingTags(stack))
+		curRunes = utf8.RuneCountInString(cur.String())
+		prefix = curRunes
+		lastBreak = nil
+	}
+
+	tokens := tokenizeHTML(text)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		next := applyTag(stack, token)
+		need := curRunes + utf8.RuneCountInString(token) + utf8.RuneCountInString(closingTags(next))
+		if need > limit && curRunes > prefix {
+			if lastBreak != nil && lastBreak.runes > prefix+1 {
+				rest := cur.String()[:lastBreak.bytes]
+				cur.Reset()
+				cur.WriteString(rest)
+				curRunes = lastBreak.runes
+				stack = lastBreak.stack
+				i = lastBreak.token
+			}
+			flush()
+			if tokens[i] == "\n" {
+				continue // The cut takes the place of the line break.
+			}
+			i--
+			continue
+		}
+
+		cur.WriteString(token)
+		curRunes += utf8.RuneCountInString(token)
+		stack = next
+		if token == "\n" {
+			lastBreak = &breakPoint{token: i + 1, bytes: cur.Len(), runes: curRunes, stack: slices.Clone(stack)}
+		}
+	}
+
+	if curRunes > prefix {
+		parts = append(parts, cur.String()+closingTags(stack))
+	}
+	return parts
+}
+
+// tokenizeHTML splits text into tags, HTML entities, and single characters.
+func tokenizeHTML(text string) []string {
+	var tokens []string
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			if end := strings.IndexByte(text[i:], '>'); end > 0 {
+				tokens = append(tokens, text[i:i+end+1])
+				i += end + 1
+				continue
+			}
+		case '&':
+			if end := strings.IndexByte(text[i:], ';'); end > 1 && end <= 10 && !strings.ContainsAny(text[i+1:i+end], " \n<&") {
+				tokens = append(tokens, text[i:i+end+1])
+				i += end + 1
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		tokens = append(tokens, text[i:i+size])
+		i += size
+	}
+	return tokens
+}
+
+// tagName returns the lowercase name of a tag token and whether it is a closing tag.
+// It returns an empty name if the token is not one of the balanced tags.
+func tagName(token string) (string, bool) {
+	if len(token) < 3 || token[0] != '<' {
+		return "", false
+	}
+
+	inner := strings.TrimSuffix(token[1:], ">")
+	closing := strings.HasPrefix(inner, "/")
+	inner = strings.TrimPrefix(inner, "/")
+	fields := strings.Fields(inner)
+	if len(fields) == 0 {
+		return "", false
+	}
+	name := strings.ToLower(fields[0])
+	if !slices.Contains(balancedTags, name) {
+		return "", false
+	}
+	return name, closing
+}
+
+// applyTag returns the stack of open tags after the token. The stack passed in is not modified.
+func applyTag(stack []openTag, token string) []openTag {
+	name, closing := tagName(token)
+	if name == "" {
+		return stack
+	}
+	if !closing {
+		return append(slices.Clip(stack), openTag{name: name, raw: token})
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].name == name {
+			return slices.Delete(slices.Clone(stack), i, i+1)
+		}
+	}
+	return stack
+}
+
+// openingTags returns the open tags in the order they were opened.
+func openingTags(stack []openTag) string {
+	var b strings.Builder
+	for _, tag := range stack {
+		b.WriteString(tag.raw)
+	}
+	return b.String()
+}
+
+// closingTags returns the closing tags for the open tags, innermost first.
+func closingTags(stack []openTag) string {
+	var b strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		b.WriteString("</" + stack[i].name + ">")
+	}
+	return b.String()
+}
+
+// truncateSpaceWindow is how far back from the cut TruncateDisplay looks for a space to cut at instead.
+const truncateSpaceWindow = 10
+
+// TruncateDisplay shortens text to at most max characters for display in an HTML message, and escapes it.
+// The raw text is cut first and escaped afterwards, so a cut never splits an HTML entity, and max counts characters
+// as shown rather than escaped. Cuts fall between runes, never before a combining mark or next to a zero-width joiner;
+// if a space is among the last few characters kept, the cut moves back to it. A cut text ends with "…".
+func TruncateDisplay(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return html.EscapeString(text)
+	}
+	if max < 1 {
+		return ""
+	}
+
+	cut := max - 1
+	for cut > 0 && (unicode.In(runes[cut], unicode.Mn, unicode.Mc, unicode.Me, unicode.Variation_Selector) ||
+		runes[cut] == '\u200d' || runes[cut-1] == '\u200d') {
+		cut--
+	}
+	for i := cut; i > 0 && i >= cut-truncateSpaceWindow; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return html.EscapeString(strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)) + "…"
+}
+
+// Replier is anything SendLong can reply to, such as a *telegram.NewMessage.
+type Replier interface {
+	Reply(text any, opts ...telegram.SendOptions) (*telegram.NewMessage, error)
+}
+
+// SendLong replies to a message with text that may exceed Telegram's length limit.
+// Longer texts are split with SplitMessage and sent one part after another, each with a "1/3" page footer.
+func SendLong(m Replier, text string, opts ...telegram.SendOptions) error {
+	parts := SplitMessage(text, MaxMessageLength-pageFooterReserve)
+	if len(parts) == 1 {
+		_, err := m.Reply(text, opts...)
+		return err
+	}
+
+	for i, part := range parts {
+		if _, err := m.Reply(fmt.Sprintf("%s\n\n<i>%d/%d</i>", part, i+1, len(parts)), opts...); err != nil {
+			return fmt.Errorf("failed to send part %d of %d: %w", i+1, len(parts), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+func TestTruncateDisplay(t *testing.T) {
+	tests := []struct {
+		name, text string
+		max        int
+		want       string
+	}{
+		{"fits", "short", 5, "short"},
+		{"escaped when it fits", "a<b", 3, "a&lt;b"},
+		{"cut at a space", "connection reset by peer", 20, "connection reset by…"},
+		{"cut mid-word without a near space", "abcdefghijklmnopqrstuvwxyz", 6, "abcde…"},
+		{"cut before escaping", "a&b&c&d&e", 4, "a&amp;b…"},
+		{"cut not before a combining mark", "cafe\u0301 au lait", 5, "caf…"},
+		{"cut not inside a joined emoji", "\U0001F468\u200d\U0001F469 family", 3, "…"},
+		{"no room", "anything", 0, ""},
+		{"emoji title", "\U0001F3B5\U0001F3B6\U0001F3B8 music", 3, "\U0001F3B5\U0001F3B6…"},
+		{"devanagari cut at a space", "नमस्ते दुनिया", 9, "नमस्ते…"},
+		{"devanagari not before a vowel sign", "नमस्ते दुनिया", 6, "नमस्…"},
+		{"ampersand title", "Simon & Garfunkel - The Boxer", 10, "Simon &amp;…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TruncateDisplay(tt.text, tt.max); got != tt.want {
+				t.Errorf("TruncateDisplay(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateDisplayKeepsTextIntact(t *testing.T) {
+	titles := []string{
+		"\U0001F468\u200d\U0001F469\u200d\U0001F467 Family Song \U0001F3B6",
+		"कभी कभी मेरे दिल में ख़याल आता है",
+		"Tom & Jerry <Theme> \"Live\" & 'More'",
+	}
+	for _, title := range titles {
+		for max := 1; max <= utf8.RuneCountInString(title); max++ {
+			got := TruncateDisplay(title, max)
+			if !utf8.ValidString(got) {
+				t.Fatalf("TruncateDisplay(%q, %d) = %q, not valid UTF-8", title, max, got)
+			}
+			raw := html.UnescapeString(strings.TrimSuffix(got, "…"))
+			if !strings.HasPrefix(title, raw) || html.EscapeString(raw) != strings.TrimSuffix(got, "…") {
+				t.Errorf("TruncateDisplay(%q, %d) = %q, not an escaped prefix of the title", title, max, got)
+			}
+			if n := utf8.RuneCountInString(raw); n > max {
+				t.Errorf("TruncateDisplay(%q, %d) kept %d characters", title, max, n)
+			}
+		}
+	}
+}
+
+// checkParts fails the test unless every part fits in limit characters and closes every tag it opens, in order.
+func checkParts(t *testing.T, parts []string, limit int) {
+	t.Helper()
+	for i, part := range parts {
+		if n := utf8.RuneCountInString(part); n > limit {
+			t.Errorf("part %d has %d characters, over the limit of %d: %q", i, n, limit, part)
+		}
+		var stack []openTag
+		for _, token := range tokenizeHTML(part) {
+			name, closing := tagName(token)
+			switch {
+			case name == "":
+			case !closing:
+				stack = append(stack, openTag{name: name})
+			case len(stack) == 0 || stack[len(stack)-1].name != name:
+				t.Errorf("part %d closes <%s> out of order: %q", i, name, part)
+			default:
+				stack = stack[:len(stack)-1]
+			}
+		}
+		if len(stack) > 0 {
+			t.Errorf("part %d leaves %d tags open: %q", i, len(stack), part)
+		}
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"fits", "<b>short</b>", 20, []string{"<b>short</b>"}},
+		{"on line breaks", "aaaa\nbbbb\ncccc", 10, []string{"aaaa\nbbbb", "cccc"}},
+		{"line that just fits", "aaaaaaaaaa\nbbbb", 10, []string{"aaaaaaaaaa", "bbbb"}},
+		{"long line", strings.Repeat("x", 25), 10, []string{"xxxxxxxxxx", "xxxxxxxxxx", "xxxxx"}},
+		{"long line after short ones", "ab\n" + strings.Repeat("x", 12), 10, []string{"ab", "xxxxxxxxxx", "xx"}},
+		{"bold across a line break", "<b>aaaa\nbbbb</b>", 12, []string{"<b>aaaa</b>", "<b>bbbb</b>"}},
+		{"bold long line", "<b>" + strings.Repeat("x", 7) + "</b>", 10, []string{"<b>xxx</b>", "<b>xxx</b>", "<b>x</b>"}},
+		{"nested tags", "<b><i>aa\nbb</i></b>", 16, []string{"<b><i>aa</i></b>", "<b><i>bb</i></b>"}},
+		{
+			"link reopened as written",
+			`<a href="https://e.x/">one` + "\n" + `two</a>`,
+			30,
+			[]string{`<a href="https://e.x/">one</a>`, `<a href="https://e.x/">two</a>`},
+		},
+		{"entities kept whole", "&amp;&amp;&amp;", 7, []string{"&amp;", "&amp;", "&amp;"}},
+		{"unknown tags are text", "<br>\n<br>", 5, []string{"<br>", "<br>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitMessage(tt.text, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitMessage(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+			checkParts(t, got, tt.limit)
+		})
+	}
+}
+
+func TestSplitMessageLongList(t *testing.T) {
+	// A listing like /auth with hundreds of users, in a blockquote and with bold names.
+	var b strings.Builder
+	b.WriteString("<b>Authorized users</b>\n<blockquote>")
+	for i := 0; i < 400; i++ {
+		fmt.Fprintf(&b, "%d. <b>User &lt;%d&gt;</b> <code>%d</code>\n", i+1, i, 1000000+i)
+	}
+	b.WriteString("</blockquote>")
+	text := b.String()
+
+	parts := SplitMessage(text, MaxMessageLength)
+	if len(parts) < 2 {
+		t.Fatalf("SplitMessage() = %d parts, want the list split", len(parts))
+	}
+	checkParts(t, parts, MaxMessageLength)
+
+	// No line is lost or cut: every part but the first reopens the blockquote, and lines are whole.
+	lines := 0
+	for i, part := range parts {
+		if i > 0 && !strings.HasPrefix(part, "<blockquote>") {
+			t.Errorf("part %d does not reopen the blockquote: %.40q", i, part)
+		}
+		lines += strings.Count(part, "</code>")
+	}
+	if lines != 400 {
+		t.Errorf("the parts hold %d of 400 lines", lines)
+	}
+}
+
+// replies is a Replier recording what was sent.
+type replies struct {
+	sent   []string
+	failAt int // failAt, if not 0, is the 1-based reply that fails.
+}
+
+func (r *replies) Reply(text any, _ ...telegram.SendOptions) (*telegram.NewMessage, error) {
+	if len(r.sent)+1 == r.failAt {
+		return nil, errors.New("flood wait")
+	}
+	r.sent = append(r.sent, text.(string))
+	return nil, nil
+}
+
+func TestSendLong(t *testing.T) {
+	r := &replies{}
+	if err := SendLong(r, "short"); err != nil || !reflect.DeepEqual(r.sent, []string{"short"}) {
+		t.Errorf("SendLong() of a short text sent %q, %v", r.sent, err)
+	}
+
+	long := strings.Repeat(strings.Repeat("x", 99)+"\n", 60)
+	r = &replies{}
+	if err := SendLong(r, long); err != nil {
+		t.Fatalf("SendLong() error = %v", err)
+	}
+	if len(r.sent) != 2 {
+		t.Fatalf("SendLong() sent %d parts, want 2", len(r.sent))
+	}
+	for i, part := range r.sent {
+		footer := fmt.Sprintf("\n\n<i>%d/2</i>", i+1)
+		if !strings.HasSuffix(part, footer) {
+			t.Errorf("part %d does not end with %q", i, footer)
+		}
+		if n := utf8.RuneCountInString(part); n > MaxMessageLength {
+			t.Errorf("part %d with its footer has %d characters", i, n)
+		}
+	}
+
+	r = &replies{failAt: 2}
+	if err := SendLong(r, long); err == nil || len(r.sent) != 1 {
+		t.Errorf("SendLong() with a failing second part = %v after %d parts, want an error after 1", err, len(r.sent))
+	}
+}
@@ -0,0 +1,87 @@
+// Package musicfeed posts the music messages of a chat, such as queue confirmations and now-playing messages, to the
+// chat's music feed: a channel or topic set with /setmusicfeed, so that they do not crowd the chat itself.
+// A feed the bot can no longer post to is removed, and the chat is told.
+package musicfeed
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
+
+	"github.com/Laky-64/gologging"
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+// Target returns the chat and topic that the music messages of a chat are posted to: its feed, or the chat itself.
+func Target(chatID int64) (int64, int32) {
+	if feed, ok := db.Instance.GetMusicFeed(chatID); ok {
+		return feed.ChatID, feed.TopicID
+	}
+	return chatID, 0
+}
+
+// ChatOf returns the chat that a message or callback from chatID acts on: the chat whose feed chatID is, or chatID
+// itself.
+func ChatOf(chatID int64) int64 {
+	if owner, ok := db.Instance.MusicFeedOwner(chatID); ok {
+		return owner
+	}
+	return chatID
+}
+
+// Send posts a music message of a chat to its feed, and reports whether it did.
+// It reports false, without posting anything, if the chat has no feed or the message could not be posted there; the
+// message is then to be posted to the chat instead. A feed the bot can no longer post to is removed first.
+func Send(client *tg.Client, chatID int64, text string, opts *tg.SendOptions) (*tg.NewMessage, bool) {
+	feed, ok := db.Instance.GetMusicFeed(chatID)
+	if !ok {
+		return nil, false
+	}
+
+	sendOpts := tg.SendOptions{}
+	if opts != nil {
+		sendOpts = *opts
+	}
+	sendOpts.TopicID = feed.TopicID
+	msg, err := client.SendMessage(feed.ChatID, text, &sendOpts)
+	if err == nil {
+		return msg, true
+	}
+
+	gologging.WarnF("[MusicFeed] Failed to post to the feed %d of chat %d: %v", feed.ChatID, chatID, err)
+	if Unpostable(err) {
+		Revert(client, chatID, err)
+	}
+	return nil, false
+}
+
+// Revert removes the music feed of a chat the bot can no longer post to, and tells the chat why.
+func Revert(client *tg.Client, chatID int64, reason error) {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	if removed, err := db.Instance.DeleteMusicFeed(ctx, chatID); err != nil || !removed {
+		return
+	}
+
+	langCode := db.Instance.GetLang(ctx, chatID)
+	text := fmt.Sprintf(lang.GetString(langCode, "musicfeed_reverted"), reason.Error())
+	if _, err := client.SendMessage(chatID, text); err != nil {
+		gologging.WarnF("[MusicFeed] Failed to tell chat %d that its feed was removed: %v", chatID, err)
+	}
+}
+
+// Unpostable reports whether err means that the bot can no longer post to a chat, for instance because it was removed
+// or lost the right to post, rather than a failure that may pass.
+func Unpostable(err error) bool {
+	msg := err.Error()
+	for _, code := range []string{
+		"CHAT_WRITE_FORBIDDEN", "CHAT_ADMIN_REQUIRED", "CHANNEL_PRIVATE", "CHANNEL_INVALID", "CHAT_SEND_PLAIN_FORBIDDEN",
+		"USER_BANNED_IN_CHANNEL", "PEER_ID_INVALID", "TOPIC_CLOSED", "TOPIC_DELETED", "MESSAGE_THREAD_INVALID",
+	} {
+		if strings.Contains(msg, code) {
+			return true
+		}
+	}
+	return false
+}
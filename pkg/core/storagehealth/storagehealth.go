@@ -0,0 +1,107 @@
+// Package storagehealth tracks whether the downloads directory can be written to.
+// A download failing because the directory became read-only marks storage as degraded, so that new requests fail fast
+// instead of searching and downloading first; a periodic probe clears the mark once writing works again.
+package storagehealth
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// probeInterval is how often the directory is probed.
+const probeInterval = time.Minute
+
+var (
+	degraded atomic.Bool
+
+	mu sync.Mutex
+	// alert is called once each time storage becomes degraded.
+	alert func(err error)
+)
+
+// Probe checks that dir can be written to by creating and removing a file in it.
+func Probe(dir string) error {
+	f, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return fmt.Errorf("the directory %s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	if err = os.Remove(name); err != nil {
+		return fmt.Errorf("the directory %s is not writable: %w", dir, err)
+	}
+	return nil
+}
+
+// IsStorageError reports whether err means that a file could not be written because of the filesystem, e.g. because
+// it is mounted read-only or its permissions changed. Errors from external downloaders only carry their message,
+// so it is checked as well.
+func IsStorageError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.EROFS) || errors.Is(err, fs.ErrPermission) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "read-only file system") || strings.Contains(msg, "permission denied")
+}
+
+// Report marks storage as degraded if err is a storage error, and reports whether it was.
+// The alert set with Start is called only when storage was healthy before.
+func Report(err error) bool {
+	if !IsStorageError(err) {
+		return false
+	}
+	if degraded.CompareAndSwap(false, true) {
+		gologging.WarnF("[Storage] Downloads are failing, marking storage as unavailable: %v", err)
+		mu.Lock()
+		notify := alert
+		mu.Unlock()
+		if notify != nil {
+			go notify(err)
+		}
+	}
+	return true
+}
+
+// Degraded reports whether storage is currently marked as unavailable.
+func Degraded() bool {
+	return degraded.Load()
+}
+
+// Start probes dir periodically in the background, and returns at once. A failed probe marks storage as degraded
+// and a successful one clears the mark. onDegraded, if not nil, is called once each time storage becomes degraded.
+func Start(dir string, onDegraded func(err error)) {
+	mu.Lock()
+	alert = onDegraded
+	mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(probeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			check(dir)
+		}
+	}()
+}
+
+// check probes dir once. A failed probe marks storage as degraded and a successful one clears the mark.
+func check(dir string) {
+	if err := Probe(dir); err != nil {
+		Report(err)
+		return
+	}
+	if degraded.CompareAndSwap(true, false) {
+		gologging.InfoF("[Storage] The downloads directory is writable again.")
+	}
+}
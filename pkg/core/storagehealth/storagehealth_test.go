@@ -0,0 +1,153 @@
+package storagehealth
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// reset clears the degraded mark and sets the alert, until the test ends. Alerts are sent on the returned channel.
+func reset(t *testing.T) <-chan error {
+	t.Helper()
+	alerts := make(chan error, 10)
+	degraded.Store(false)
+	mu.Lock()
+	alert = func(err error) { alerts <- err }
+	mu.Unlock()
+	t.Cleanup(func() {
+		degraded.Store(false)
+		mu.Lock()
+		alert = nil
+		mu.Unlock()
+	})
+	return alerts
+}
+
+// alerted returns how many alerts arrive on alerts within a short wait.
+func alerted(alerts <-chan error) int {
+	n := 0
+	for {
+		select {
+		case <-alerts:
+			n++
+		case <-time.After(50 * time.Millisecond):
+			return n
+		}
+	}
+}
+
+// readOnlyDir returns a temporary directory without write permission. The test is skipped if the permissions are not
+// enforced, as for root.
+func readOnlyDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(dir, 0o700) })
+	if Probe(dir) == nil {
+		t.Skip("directory permissions are not enforced for this user")
+	}
+	return dir
+}
+
+func TestIsStorageError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"read-only filesystem", &fs.PathError{Op: "open", Path: "/downloads/a.mp3", Err: syscall.EROFS}, true},
+		{"permission denied", &fs.PathError{Op: "open", Path: "/downloads/a.mp3", Err: syscall.EACCES}, true},
+		{"wrapped", fmt.Errorf("download failed: %w", &fs.PathError{Op: "write", Err: syscall.EROFS}), true},
+		{"yt-dlp message", errors.New("ERROR: unable to open for writing: [Errno 30] Read-only file system"), true},
+		{"downloader permission message", errors.New("Permission denied: 'downloads/x.part'"), true},
+		{"missing file", &fs.PathError{Op: "open", Err: syscall.ENOENT}, false},
+		{"disk full", &fs.PathError{Op: "write", Err: syscall.ENOSPC}, false},
+		{"network", errors.New("connection reset by peer"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStorageError(tt.err); got != tt.want {
+				t.Errorf("IsStorageError(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbe(t *testing.T) {
+	dir := t.TempDir()
+	if err := Probe(dir); err != nil {
+		t.Fatalf("Probe() of a writable directory = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Probe() left %d files behind", len(entries))
+	}
+}
+
+func TestProbeReadOnlyDir(t *testing.T) {
+	err := Probe(readOnlyDir(t))
+	if err == nil || !IsStorageError(err) {
+		t.Errorf("Probe() of a read-only directory = %v, want a storage error", err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	alerts := reset(t)
+
+	if Report(errors.New("connection reset by peer")) || Degraded() {
+		t.Fatal("Report() of a network error marked storage as degraded")
+	}
+	storageErr := &fs.PathError{Op: "open", Path: "/downloads/a.mp3", Err: syscall.EROFS}
+	if !Report(storageErr) || !Degraded() {
+		t.Fatal("Report() of a read-only filesystem error did not mark storage as degraded")
+	}
+	Report(storageErr)
+	if n := alerted(alerts); n != 1 {
+		t.Errorf("%d alerts for two failures in a row, want 1", n)
+	}
+}
+
+func TestCheckRecovers(t *testing.T) {
+	alerts := reset(t)
+	dir := t.TempDir()
+
+	Report(&fs.PathError{Op: "open", Err: syscall.EROFS})
+	check(dir)
+	if Degraded() {
+		t.Error("a successful probe did not clear the degraded mark")
+	}
+
+	// Storage failing again after recovering alerts again.
+	Report(&fs.PathError{Op: "open", Err: syscall.EACCES})
+	if n := alerted(alerts); n != 2 {
+		t.Errorf("%d alerts, want one for each time storage failed", n)
+	}
+}
+
+func TestCheckReadOnlyDir(t *testing.T) {
+	alerts := reset(t)
+	dir := readOnlyDir(t)
+
+	check(dir)
+	check(dir)
+	if !Degraded() {
+		t.Fatal("probing a read-only directory did not mark storage as degraded")
+	}
+	if n := alerted(alerts); n != 1 {
+		t.Errorf("%d alerts, want 1", n)
+	}
+
+	if err := os.Chmod(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	check(dir)
+	if Degraded() {
+		t.Error("probing the directory once it was writable again did not clear the mark")
+	}
+}
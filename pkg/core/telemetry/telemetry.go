@@ -0,0 +1,155 @@
+// Package telemetry sends an opt-in, anonymous daily usage ping for operators who set TELEMETRY_URL.
+// Nothing is sent unless that URL is configured, and no endpoint is built in.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// requestTimeout bounds each attempt to send the ping.
+const requestTimeout = 5 * time.Second
+
+// The schedule of the ping. These are variables so that tests can shorten them.
+var (
+	// interval is how often the ping is sent.
+	interval = 24 * time.Hour
+	// firstPingDelay gives the bot time to finish starting up before the first ping.
+	firstPingDelay = 10 * time.Minute
+)
+
+// Counts are the usage figures reported in the ping.
+type Counts struct {
+	Chats      int `json:"chats"`
+	Users      int `json:"users"`
+	Assistants int `json:"assistants"`
+	Plays24h   int `json:"plays_24h"`
+}
+
+// Payload is the JSON body of the ping.
+type Payload struct {
+	InstanceID string `json:"instance_id"`
+	Version    string `json:"version"`
+	GoVersion  string `json:"go_version"`
+	Counts     Counts `json:"counts"`
+}
+
+// newPayload builds the ping payload from the instance details and the current counts.
+func newPayload(instanceID, version string, counts Counts) Payload {
+	return Payload{
+		InstanceID: instanceID,
+		Version:    version,
+		GoVersion:  runtime.Version(),
+		Counts:     counts,
+	}
+}
+
+// plays counts tracks started per hour over the last 24 hours.
+var plays struct {
+	mu    sync.Mutex
+	hours [24]int
+	// hour is the hour, in Unix hours, that the bucket for the current hour was last reset for.
+	hour int64
+}
+
+// advance clears the buckets of the hours that have passed since the last call. plays.mu must be held.
+func advance(now time.Time) {
+	hour := now.Unix() / 3600
+	for h := max(plays.hour+1, hour-23); h <= hour; h++ {
+		plays.hours[h%24] = 0
+	}
+	plays.hour = max(plays.hour, hour)
+}
+
+// RecordPlay counts a track starting to play.
+func RecordPlay() {
+	plays.mu.Lock()
+	defer plays.mu.Unlock()
+	now := time.Now()
+	advance(now)
+	plays.hours[(now.Unix()/3600)%24]++
+}
+
+// Plays24h returns how many tracks started playing in the last 24 hours.
+func Plays24h() int {
+	plays.mu.Lock()
+	defer plays.mu.Unlock()
+	advance(time.Now())
+	total := 0
+	for _, n := range plays.hours {
+		total += n
+	}
+	return total
+}
+
+// Start sends the ping to url once a day in the background, and returns at once.
+// instanceID and counts are called for every ping. It does nothing if url is empty, which is the default.
+func Start(url, version string, instanceID func(ctx context.Context) (string, error), counts func(ctx context.Context) Counts) {
+	if url == "" {
+		return
+	}
+
+	gologging.InfoF("[Telemetry] Sending a daily anonymous usage ping to %s", url)
+	delay, every := firstPingDelay, interval
+	go func() {
+		time.Sleep(delay)
+		for {
+			if err := ping(url, version, instanceID, counts); err != nil {
+				gologging.DebugF("[Telemetry] The ping failed: %v", err)
+			}
+			time.Sleep(every)
+		}
+	}()
+}
+
+// ping sends a single ping, retrying once if the first attempt fails.
+func ping(url, version string, instanceID func(ctx context.Context) (string, error), counts func(ctx context.Context) Counts) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	id, err := instanceID(ctx)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to get the instance ID: %w", err)
+	}
+	body, err := json.Marshal(newPayload(id, version, counts(ctx)))
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to encode the payload: %w", err)
+	}
+
+	if err = send(url, body); err != nil {
+		err = send(url, body)
+	}
+	return err
+}
+
+// send posts the payload to url within requestTimeout.
+func send(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := dl.HTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
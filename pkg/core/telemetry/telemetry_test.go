@@ -0,0 +1,173 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewPayload(t *testing.T) {
+	p := newPayload("0b6c6c1e-5b5c-4c7e-9d5e-1f2a3b4c5d6e", "v1.2.3", Counts{Chats: 12, Users: 340, Assistants: 2, Plays24h: 57})
+	got, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	want := `{"instance_id":"0b6c6c1e-5b5c-4c7e-9d5e-1f2a3b4c5d6e","version":"v1.2.3","go_version":"` + runtime.Version() +
+		`","counts":{"chats":12,"users":340,"assistants":2,"plays_24h":57}}`
+	if string(got) != want {
+		t.Errorf("payload = %s, want %s", got, want)
+	}
+}
+
+// shortSchedule makes Start ping at once, and restores the schedule when the test ends.
+func shortSchedule(t *testing.T) {
+	savedDelay, savedInterval := firstPingDelay, interval
+	firstPingDelay, interval = 0, time.Hour
+	t.Cleanup(func() { firstPingDelay, interval = savedDelay, savedInterval })
+}
+
+func TestStartDisabledWithoutURL(t *testing.T) {
+	shortSchedule(t)
+	var calls atomic.Int32
+	Start("", "v1", func(context.Context) (string, error) {
+		calls.Add(1)
+		return "id", nil
+	}, func(context.Context) Counts {
+		calls.Add(1)
+		return Counts{}
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	if n := calls.Load(); n != 0 {
+		t.Errorf("Start without a URL gathered the payload %d times", n)
+	}
+}
+
+func TestStartSendsPing(t *testing.T) {
+	shortSchedule(t)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	Start(server.URL, "v1", func(context.Context) (string, error) {
+		return "instance", nil
+	}, func(context.Context) Counts {
+		return Counts{Chats: 3}
+	})
+
+	select {
+	case body := <-bodies:
+		var p Payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Fatalf("payload %s is not JSON: %v", body, err)
+		}
+		if p.InstanceID != "instance" || p.Version != "v1" || p.Counts.Chats != 3 {
+			t.Errorf("payload = %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no ping was sent")
+	}
+}
+
+func TestPingRetriesOnce(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		wantErr   bool
+		wantTries int32
+	}{
+		{"first attempt succeeds", 0, false, 1},
+		{"retry succeeds", 1, false, 2},
+		{"both attempts fail", 5, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tries atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tries.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			err := ping(server.URL, "v1", func(context.Context) (string, error) {
+				return "instance", nil
+			}, func(context.Context) Counts {
+				return Counts{}
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ping() error = %v, want error %t", err, tt.wantErr)
+			}
+			if n := tries.Load(); n != tt.wantTries {
+				t.Errorf("%d attempts, want %d", n, tt.wantTries)
+			}
+		})
+	}
+}
+
+func TestPingWithoutInstanceID(t *testing.T) {
+	var tries atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tries.Add(1)
+	}))
+	defer server.Close()
+
+	err := ping(server.URL, "v1", func(context.Context) (string, error) {
+		return "", errors.New("database down")
+	}, func(context.Context) Counts {
+		return Counts{}
+	})
+	if err == nil || tries.Load() != 0 {
+		t.Errorf("ping() = %v after %d requests, want an error and no request", err, tries.Load())
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	plays.mu.Lock()
+	defer plays.mu.Unlock()
+	saved := plays.hours
+	savedHour := plays.hour
+	defer func() { plays.hours, plays.hour = saved, savedHour }()
+
+	start := time.Unix(1_000_000*3600, 0)
+	plays.hours = [24]int{}
+	plays.hour = start.Unix() / 3600
+	for h := range plays.hours {
+		plays.hours[h] = 1
+	}
+
+	advance(start.Add(2 * time.Hour))
+	if total := sum(plays.hours); total != 22 {
+		t.Errorf("after 2 hours, %d plays are left, want 22", total)
+	}
+	advance(start) // The clock going back clears nothing.
+	if total := sum(plays.hours); total != 22 {
+		t.Errorf("after the clock went back, %d plays are left, want 22", total)
+	}
+	advance(start.Add(30 * time.Hour))
+	if total := sum(plays.hours); total != 0 {
+		t.Errorf("after 30 hours, %d plays are left, want 0", total)
+	}
+}
+
+func sum(hours [24]int) int {
+	total := 0
+	for _, n := range hours {
+		total += n
+	}
+	return total
+}
@@ -0,0 +1,137 @@
+// Package timeparse parses the human time inputs accepted by commands, such as "90", "1:30", "1h30m", or "7d".
+package timeparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrEmpty is returned when the input is empty or only whitespace.
+	ErrEmpty = errors.New("timeparse: empty input")
+	// ErrBadFormat is returned when the input does not match any accepted format.
+	ErrBadFormat = errors.New("timeparse: bad format")
+	// ErrOutOfRange is returned when the input is well-formed but a component is out of range.
+	ErrOutOfRange = errors.New("timeparse: out of range")
+)
+
+// MaxDuration is the longest duration ParseDuration accepts.
+const MaxDuration = 365 * 24 * time.Hour
+
+var (
+	digitsRegex = regexp.MustCompile(`^\d+$`)
+	unitsRegex  = regexp.MustCompile(`^(?:\d+[dhms])+$`)
+	unitRegex   = regexp.MustCompile(`(\d+)([dhms])`)
+	clockRegex  = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+)
+
+var unitDurations = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"h": time.Hour,
+	"m": time.Minute,
+	"s": time.Second,
+}
+
+// ParseDuration parses a human duration.
+// It accepts bare seconds ("90"), colon forms ("mm:ss" and "h:mm:ss"), and unit suffixes ("1h30m", "45s", "7d").
+// The returned error wraps ErrEmpty, ErrBadFormat, or ErrOutOfRange.
+func ParseDuration(input string) (time.Duration, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	switch {
+	case s == "":
+		return 0, ErrEmpty
+	case digitsRegex.MatchString(s):
+		return checkRange(input, parseUnit(s, time.Second))
+	case strings.Contains(s, ":"):
+		return parseColon(input, s)
+	case unitsRegex.MatchString(s):
+		return parseUnits(input, s)
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrBadFormat, input)
+	}
+}
+
+// ParseClock parses a 24-hour "HH:MM" time of day.
+// It returns the hour and minute, or an error wrapping ErrEmpty, ErrBadFormat, or ErrOutOfRange.
+func ParseClock(input string) (hour, minute int, err error) {
+	s := strings.TrimSpace(input)
+	if s == "" {
+		return 0, 0, ErrEmpty
+	}
+
+	match := clockRegex.FindStringSubmatch(s)
+	if match == nil {
+		return 0, 0, fmt.Errorf("%w: %q", ErrBadFormat, input)
+	}
+
+	hour, _ = strconv.Atoi(match[1])
+	minute, _ = strconv.Atoi(match[2])
+	if hour > 23 || minute > 59 {
+		return 0, 0, fmt.Errorf("%w: %q", ErrOutOfRange, input)
+	}
+	return hour, minute, nil
+}
+
+// parseColon parses the "mm:ss" and "h:mm:ss" forms.
+func parseColon(input, s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("%w: %q", ErrBadFormat, input)
+	}
+	for _, part := range parts {
+		if !digitsRegex.MatchString(part) {
+			return 0, fmt.Errorf("%w: %q", ErrBadFormat, input)
+		}
+	}
+
+	seconds := parseUnit(parts[len(parts)-1], time.Second)
+	minutes := parseUnit(parts[len(parts)-2], time.Minute)
+	if seconds < 0 || minutes < 0 || seconds >= time.Minute {
+		return 0, fmt.Errorf("%w: %q", ErrOutOfRange, input)
+	}
+
+	total := minutes + seconds
+	if len(parts) == 3 {
+		hours := parseUnit(parts[0], time.Hour)
+		if hours < 0 || minutes >= time.Hour {
+			return 0, fmt.Errorf("%w: %q", ErrOutOfRange, input)
+		}
+		total += hours
+	}
+	return checkRange(input, total)
+}
+
+// parseUnits parses a sequence of number and unit pairs such as "1h30m".
+func parseUnits(input, s string) (time.Duration, error) {
+	var total time.Duration
+	for _, match := range unitRegex.FindAllStringSubmatch(s, -1) {
+		part := parseUnit(match[1], unitDurations[match[2]])
+		if part < 0 || total+part < total {
+			return 0, fmt.Errorf("%w: %q", ErrOutOfRange, input)
+		}
+		total += part
+	}
+	return checkRange(input, total)
+}
+
+// parseUnit converts a string of digits into a multiple of unit.
+// It returns -1 if the value cannot be represented.
+func parseUnit(digits string, unit time.Duration) time.Duration {
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n > int64(MaxDuration/unit) {
+		return -1
+	}
+	return time.Duration(n) * unit
+}
+
+// checkRange rejects durations that overflowed or exceed MaxDuration.
+func checkRange(input string, d time.Duration) (time.Duration, error) {
+	if d < 0 || d > MaxDuration {
+		return 0, fmt.Errorf("%w: %q", ErrOutOfRange, input)
+	}
+	return d, nil
+}
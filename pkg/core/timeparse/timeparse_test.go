@@ -0,0 +1,125 @@
+package timeparse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr error
+	}{
+		// Bare seconds.
+		{"0", 0, nil},
+		{"90", 90 * time.Second, nil},
+		{" 45 ", 45 * time.Second, nil},
+		{"31536000", MaxDuration, nil},
+		{"31536001", 0, ErrOutOfRange},
+		{"99999999999999999999", 0, ErrOutOfRange},
+
+		// Colon forms.
+		{"1:30", 90 * time.Second, nil},
+		{"0:05", 5 * time.Second, nil},
+		{"90:00", 90 * time.Minute, nil},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second, nil},
+		{"1:60", 0, ErrOutOfRange},
+		{"1:60:00", 0, ErrOutOfRange},
+		{"8761:00:00", 0, ErrOutOfRange},
+		{"99999999999:30", 0, ErrOutOfRange},
+		{"99999999999999999999:30", 0, ErrOutOfRange},
+		{"99999999999:00:30", 0, ErrOutOfRange},
+		{"1:99999999999:30", 0, ErrOutOfRange},
+		{"99999999999999999999:00:00", 0, ErrOutOfRange},
+		{"1:2:3:4", 0, ErrBadFormat},
+		{":30", 0, ErrBadFormat},
+		{"1:", 0, ErrBadFormat},
+		{"1::30", 0, ErrBadFormat},
+		{"-1:30", 0, ErrBadFormat},
+
+		// Unit suffixes.
+		{"45s", 45 * time.Second, nil},
+		{"1m30s", 90 * time.Second, nil},
+		{"1h30m", 90 * time.Minute, nil},
+		{"1H30M", 90 * time.Minute, nil},
+		{"7d", 7 * 24 * time.Hour, nil},
+		{"1d2h3m4s", 26*time.Hour + 3*time.Minute + 4*time.Second, nil},
+		{"30s1m", 90 * time.Second, nil},
+		{"365d", MaxDuration, nil},
+		{"366d", 0, ErrOutOfRange},
+		{"8761h", 0, ErrOutOfRange},
+		{"364d24h1s", 0, ErrOutOfRange},
+		{"1h 30m", 0, ErrBadFormat},
+		{"1.5h", 0, ErrBadFormat},
+		{"1w", 0, ErrBadFormat},
+		{"h", 0, ErrBadFormat},
+		{"1ms", 0, ErrBadFormat},
+
+		// Empty and malformed input.
+		{"", 0, ErrEmpty},
+		{"   ", 0, ErrEmpty},
+		{"-5", 0, ErrBadFormat},
+		{"+5", 0, ErrBadFormat},
+		{"soon", 0, ErrBadFormat},
+
+		// Locale-style separators and digits are rejected rather than guessed at.
+		{"1,30", 0, ErrBadFormat},
+		{"1.30", 0, ErrBadFormat},
+		{"1'30", 0, ErrBadFormat},
+		{"1٫30", 0, ErrBadFormat},
+		{"1：30", 0, ErrBadFormat},
+		{"１:３０", 0, ErrBadFormat},
+		{"١٢٠", 0, ErrBadFormat},
+		{"1 000", 0, ErrBadFormat},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseDuration(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		input             string
+		wantHour, wantMin int
+		wantErr           error
+	}{
+		{"00:00", 0, 0, nil},
+		{"7:05", 7, 5, nil},
+		{"07:05", 7, 5, nil},
+		{" 23:59 ", 23, 59, nil},
+		{"24:00", 0, 0, ErrOutOfRange},
+		{"12:60", 0, 0, ErrOutOfRange},
+		{"99:99", 0, 0, ErrOutOfRange},
+		{"", 0, 0, ErrEmpty},
+		{"  ", 0, 0, ErrEmpty},
+		{"7", 0, 0, ErrBadFormat},
+		{"07:5", 0, 0, ErrBadFormat},
+		{"123:00", 0, 0, ErrBadFormat},
+		{"12:30:00", 0, 0, ErrBadFormat},
+		{"12:30 pm", 0, 0, ErrBadFormat},
+		{"-1:30", 0, 0, ErrBadFormat},
+		{"12.30", 0, 0, ErrBadFormat},
+		{"12h30", 0, 0, ErrBadFormat},
+		{"12：30", 0, 0, ErrBadFormat},
+		{"１２:３０", 0, 0, ErrBadFormat},
+	}
+	for _, tt := range tests {
+		hour, minute, err := ParseClock(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseClock(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if hour != tt.wantHour || minute != tt.wantMin {
+			t.Errorf("ParseClock(%q) = %d:%02d, want %d:%02d", tt.input, hour, minute, tt.wantHour, tt.wantMin)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// TrackLineStyle selects how much of a track FormatTrackLine shows.
+type TrackLineStyle string
+
+const (
+	TrackLineCompact TrackLineStyle = "compact" // TrackLineCompact shows the platform and the name, for messages that list the other details themselves.
+	TrackLineQueue   TrackLineStyle = "queue"   // TrackLineQueue adds the duration, for queue listings.
+	TrackLineFull    TrackLineStyle = "full"    // TrackLineFull adds the duration and who requested the track.
+)
+
+// trackLineNameLength is the longest track name each style shows.
+var trackLineNameLength = map[TrackLineStyle]int{
+	TrackLineCompact: 60,
+	TrackLineQueue:   45,
+	TrackLineFull:    60,
+}
+
+// publicTelegramLink matches links to messages of public chats, which anyone can open. Links to messages of private
+// chats (t.me/c/...) only open for members and are left out.
+var publicTelegramLink = regexp.MustCompile(`^https?://t\.me/[a-zA-Z0-9_]{4,}/\d+$`)
+
+// PlatformEmoji returns the emoji shown before a track of a platform.
+func PlatformEmoji(platform string) string {
+	switch platform {
+	case cache.Telegram:
+		return "🎥"
+	case cache.YouTube:
+		return "▶️"
+	case cache.Direct:
+		return "📻"
+	default:
+		return "🎧"
+	}
+}
+
+// trackLink returns the address a track's name links to, or an empty string if it has none anyone could open.
+func trackLink(track *cache.CachedTrack) string {
+	if !strings.HasPrefix(track.URL, "https://") && !strings.HasPrefix(track.URL, "http://") {
+		return ""
+	}
+	if track.Platform == cache.Telegram && !publicTelegramLink.MatchString(track.URL) {
+		return ""
+	}
+	return track.URL
+}
+
+// FormatTrackLine formats a track as one line of an HTML message, the same way wherever tracks are shown: the
+// platform's emoji, or 🎙 for a podcast, then the name, linked when the track has a public address. The queue and full
+// styles add the duration, and the full style who requested the track.
+func FormatTrackLine(track *cache.CachedTrack, style TrackLineStyle) string {
+	emoji := PlatformEmoji(track.Platform)
+	if track.ContentType == cache.ContentPodcast {
+		emoji = "🎙"
+	}
+
+	maxLen, ok := trackLineNameLength[style]
+	if !ok {
+		maxLen = trackLineNameLength[TrackLineCompact]
+	}
+	name := TruncateDisplay(track.Name, maxLen)
+	if link := trackLink(track); link != "" {
+		name = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link), name)
+	}
+
+	var b strings.Builder
+	b.WriteString(emoji + " " + name)
+	if style == TrackLineQueue || style == TrackLineFull {
+		b.WriteString(" | ")
+		if track.IsLive {
+			b.WriteString("🔴")
+		} else {
+			b.WriteString(cache.SecToMin(track.Duration))
+		}
+	}
+	if style == TrackLineFull && track.User != "" {
+		b.WriteString(" — " + html.EscapeString(track.User))
+	}
+	return b.String()
+}
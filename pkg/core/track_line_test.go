@@ -0,0 +1,71 @@
+package core
+
+import (
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"strings"
+	"testing"
+)
+
+func TestFormatTrackLine(t *testing.T) {
+	youtube := &cache.CachedTrack{Name: "Song", URL: "https://youtu.be/abc?t=1&x=2", Platform: cache.YouTube, Duration: 215, User: "Ann"}
+	tests := []struct {
+		name  string
+		track *cache.CachedTrack
+		style TrackLineStyle
+		want  string
+	}{
+		{"compact", youtube, TrackLineCompact, `▶️ <a href="https://youtu.be/abc?t=1&amp;x=2">Song</a>`},
+		{"queue", youtube, TrackLineQueue, `▶️ <a href="https://youtu.be/abc?t=1&amp;x=2">Song</a> | 3:35`},
+		{"full", youtube, TrackLineFull, `▶️ <a href="https://youtu.be/abc?t=1&amp;x=2">Song</a> | 3:35 — Ann`},
+		{"unknown style is compact", youtube, "wide", `▶️ <a href="https://youtu.be/abc?t=1&amp;x=2">Song</a>`},
+		{
+			"spotify", &cache.CachedTrack{Name: "Hit", URL: "https://open.spotify.com/track/1", Platform: cache.Spotify, Duration: 3725},
+			TrackLineQueue, `🎧 <a href="https://open.spotify.com/track/1">Hit</a> | 1:02:05`,
+		},
+		{
+			"direct link", &cache.CachedTrack{Name: "file.mp3", URL: "http://example.com/file.mp3", Platform: cache.Direct},
+			TrackLineCompact, `📻 <a href="http://example.com/file.mp3">file.mp3</a>`,
+		},
+		{
+			"telegram file of a public chat", &cache.CachedTrack{Name: "Voice", URL: "https://t.me/somechannel/42", Platform: cache.Telegram},
+			TrackLineCompact, `🎥 <a href="https://t.me/somechannel/42">Voice</a>`,
+		},
+		{
+			"telegram file of a private chat", &cache.CachedTrack{Name: "Voice", URL: "https://t.me/c/1234567/42", Platform: cache.Telegram},
+			TrackLineCompact, `🎥 Voice`,
+		},
+		{
+			"no public address", &cache.CachedTrack{Name: "Local", URL: "/downloads/local.mp3", Platform: cache.JioSaavn},
+			TrackLineCompact, `🎧 Local`,
+		},
+		{
+			"podcast", &cache.CachedTrack{Name: "Episode 1", Platform: cache.Spotify, ContentType: cache.ContentPodcast, Duration: 60},
+			TrackLineQueue, `🎙 Episode 1 | 1:00`,
+		},
+		{
+			"livestream", &cache.CachedTrack{Name: "Radio", Platform: cache.YouTube, IsLive: true, Duration: 0, User: "Bo"},
+			TrackLineFull, `▶️ Radio | 🔴 — Bo`,
+		},
+		{
+			"escaped name and requester", &cache.CachedTrack{Name: "Tom & Jerry <Live>", Platform: cache.Apple, User: "<b>x</b>"},
+			TrackLineFull, `🎧 Tom &amp; Jerry &lt;Live&gt; | 0:00 — &lt;b&gt;x&lt;/b&gt;`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatTrackLine(tt.track, tt.style); got != tt.want {
+				t.Errorf("FormatTrackLine(%s) = %q, want %q", tt.style, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTrackLineTruncates(t *testing.T) {
+	track := &cache.CachedTrack{Name: strings.Repeat("x", 100), Platform: cache.YouTube}
+	for style, limit := range trackLineNameLength {
+		line := FormatTrackLine(track, style)
+		if !strings.Contains(line, strings.Repeat("x", limit-1)+"…") || strings.Contains(line, strings.Repeat("x", limit)) {
+			t.Errorf("FormatTrackLine(%s) = %q, want the name cut to %d characters", style, line, limit)
+		}
+	}
+}
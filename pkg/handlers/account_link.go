@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/lang"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// linkCallbackPath is the path of the HTTP endpoint the gateway sends users back to once they have linked an account.
+const linkCallbackPath = "/api/link/callback"
+
+// maxListedPlaylists is the most playlists /myplaylists lists.
+const maxListedPlaylists = 20
+
+// listedPlaylists holds the playlists last listed to each user by /myplaylists, so the numbers /playmine takes refer to
+// the list the user saw.
+var listedPlaylists = cache.NewCache[[]dl.GatewayPlaylist](10 * time.Minute)
+
+// linkingConfigured reports whether accounts can be linked: it needs the gateway, a key to encrypt the tokens with and
+// an address the gateway can send users back to.
+func linkingConfigured() bool {
+	return config.Conf.ApiUrl != "" && config.Conf.ApiKey != "" && config.Conf.SessionKey != "" && config.Conf.PublicURL != ""
+}
+
+// linkHandler handles the /link command.
+// It sends, in private, a link to the gateway's page where the user links their music account.
+func linkHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if !m.IsPrivate() {
+		_, err := m.Reply(lang.GetString(langCode, "link_private_only"))
+		return err
+	}
+	if !linkingConfigured() {
+		_, err := m.Reply(lang.GetString(langCode, "link_disabled"))
+		return err
+	}
+
+	state, err := db.Instance.CreateLinkState(ctx, m.SenderID())
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "link_error"), err.Error()))
+		return err
+	}
+	linkURL, err := dl.GatewayLinkURL(state, config.Conf.PublicURL+linkCallbackPath)
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "link_error"), err.Error()))
+		return err
+	}
+
+	keyboard := telegram.NewKeyboard().AddRow(telegram.Button.URL(lang.GetString(langCode, "link_button"), linkURL)).Build()
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "link_prompt"), int(db.LinkStateTTL.Minutes())), telegram.SendOptions{ReplyMarkup: keyboard})
+	return err
+}
+
+// LinkCallback returns the handler of GET /api/link/callback, where the gateway sends users back with the state
+// created by /link and their gateway token. The token is stored against the user the state belongs to, who is told in
+// private that their account is linked.
+func LinkCallback(client *telegram.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		state, token := query.Get("state"), query.Get("token")
+		if state == "" || token == "" {
+			http.Error(w, "The state or token is missing.", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := db.Ctx()
+		defer cancel()
+		userID, err := db.Instance.ConsumeLinkState(ctx, state)
+		if errors.Is(err, db.ErrInvalidLinkState) {
+			http.Error(w, "This link has expired. Send /link to the bot again.", http.StatusBadRequest)
+			return
+		}
+		if err == nil {
+			err = db.Instance.SetGatewayToken(ctx, userID, token)
+		}
+		if err != nil {
+			gologging.WarnF("[Link] Failed to link the account of user %d: %v", userID, err)
+			http.Error(w, "The account could not be linked. Please try again later.", http.StatusInternalServerError)
+			return
+		}
+
+		langCode := db.Instance.GetLang(ctx, userID)
+		if _, err = client.SendMessage(userID, lang.GetString(langCode, "link_success")); err != nil {
+			gologging.WarnF("[Link] Failed to notify user %d: %v", userID, err)
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("Your account is linked. You can go back to Telegram now."))
+	}
+}
+
+// unlinkHandler handles the /unlink command.
+// It deletes the user's stored gateway token.
+func unlinkHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if !m.IsPrivate() {
+		_, err := m.Reply(lang.GetString(langCode, "link_private_only"))
+		return err
+	}
+
+	removed, err := db.Instance.DeleteGatewayToken(ctx, m.SenderID())
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "link_error"), err.Error()))
+		return err
+	}
+	listedPlaylists.Delete(strconv.FormatInt(m.SenderID(), 10))
+	if !removed {
+		_, err = m.Reply(lang.GetString(langCode, "link_not_linked"))
+		return err
+	}
+	_, err = m.Reply(lang.GetString(langCode, "unlink_success"))
+	return err
+}
+
+// userPlaylists fetches the playlists of a user's linked account and remembers them for /playmine.
+// It returns an empty token if the user has not linked an account.
+func userPlaylists(ctx context.Context, userID int64) (string, []dl.GatewayPlaylist, error) {
+	token, err := db.Instance.GetGatewayToken(ctx, userID)
+	if err != nil || token == "" {
+		return "", nil, err
+	}
+
+	fetchCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	playlists, err := dl.UserPlaylists(fetchCtx, token)
+	if err != nil {
+		return token, nil, err
+	}
+	if len(playlists) > maxListedPlaylists {
+		playlists = playlists[:maxListedPlaylists]
+	}
+	listedPlaylists.Set(strconv.FormatInt(userID, 10), playlists)
+	return token, playlists, nil
+}
+
+// myPlaylistsHandler handles the /myplaylists command.
+// It lists, in private, the playlists of the user's linked account, numbered for /playmine.
+func myPlaylistsHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if !m.IsPrivate() {
+		_, err := m.Reply(lang.GetString(langCode, "link_private_only"))
+		return err
+	}
+
+	token, playlists, err := userPlaylists(ctx, m.SenderID())
+	switch {
+	case err != nil:
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "myplaylists_error"), err.Error()))
+		return err
+	case token == "":
+		_, err = m.Reply(lang.GetString(langCode, "link_not_linked"))
+		return err
+	case len(playlists) == 0:
+		_, err = m.Reply(lang.GetString(langCode, "myplaylists_empty"))
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(lang.GetString(langCode, "myplaylists_header"))
+	keyboard := telegram.NewKeyboard()
+	for i, p := range playlists {
+		name := core.TruncateDisplay(p.Name, 40)
+		b.WriteString(fmt.Sprintf(lang.GetString(langCode, "myplaylists_item"), i+1, name, html.EscapeString(p.Platform), p.Tracks))
+		if p.URL != "" {
+			// Button labels are not HTML, so the name is unescaped again.
+			keyboard.AddRow(telegram.Button.URL(fmt.Sprintf("%d. %s", i+1, html.UnescapeString(name)), p.URL))
+		}
+	}
+	b.WriteString(lang.GetString(langCode, "myplaylists_footer"))
+
+	opts := telegram.SendOptions{}
+	if markup := keyboard.Build(); len(markup.Rows) > 0 {
+		opts.ReplyMarkup = markup
+	}
+	_, err = m.Reply(b.String(), opts)
+	return err
+}
+
+// playMineHandler handles the /playmine command.
+// It queues the playlist numbered n in the sender's /myplaylists list, fetched from the gateway on their behalf.
+func playMineHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	n, err := strconv.Atoi(strings.TrimSpace(m.Args()))
+	if err != nil || n < 1 {
+		_, err = m.Reply(lang.GetString(langCode, "playmine_usage"))
+		return err
+	}
+	if queueLockedFor(ctx, m, chatID, langCode) {
+		return nil
+	}
+	if queue := cache.ChatCache.GetQueue(chatID); len(queue) > 10 {
+		cache.RecordQueueStat(chatID, cache.QueueRejected, 1)
+		_, err = m.Reply(lang.GetString(langCode, "play_queue_full"))
+		return err
+	}
+
+	userID := actorID(m)
+	token, err := db.Instance.GetGatewayToken(ctx, userID)
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "myplaylists_error"), err.Error()))
+		return err
+	}
+	if token == "" {
+		_, err = m.Reply(lang.GetString(langCode, "playmine_not_linked"))
+		return err
+	}
+	playlists, ok := listedPlaylists.Get(strconv.FormatInt(userID, 10))
+	if !ok {
+		if _, playlists, err = userPlaylists(ctx, userID); err != nil {
+			_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "myplaylists_error"), err.Error()))
+			return err
+		}
+	}
+	if n > len(playlists) {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "playmine_out_of_range"), len(playlists)))
+		return err
+	}
+	playlist := playlists[n-1]
+
+	statusMsg, err := m.Reply(lang.GetString(langCode, "play_searching"))
+	if err != nil {
+		gologging.WarnF("failed to send message: %v", err)
+		return err
+	}
+	updater := &statusUpdater{NewMessage: statusMsg, lastMessage: lang.GetString(langCode, "play_searching"), lastSent: time.Now()}
+
+	fetchCtx, cancelFetch := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelFetch()
+	info, err := dl.NewApiData(playlist.URL).GetUserInfo(fetchCtx, token)
+	if err != nil {
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_fetch_error"), err.Error()))
+		return err
+	}
+	if len(info.Results) == 0 {
+		_, err = updater.Edit(lang.GetString(langCode, "myplaylists_empty_playlist"))
+		return err
+	}
+
+	// The playlist is not recorded as the source of the tracks: /continue could not fetch it again without the token.
+	return handleMultipleTracks(m, updater, info.Results, chatID, false, nil, 0, langCode)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// anonymousAdminName is the name shown for an admin who remains anonymous, for instance as the requester of a track.
+const anonymousAdminName = "Anonymous admin"
+
+// resolveActor returns who sent a message: the name to show for them, their user ID, and whether they are an admin
+// who remains anonymous.
+//
+// An anonymous admin posts as the group itself, so the message's sender is the chat it was sent in. Such a message
+// carries no user at all: the user ID returned is 0, and the actor is to be treated as an admin of the chat, since only
+// admins can post that way. Checks that need a user, such as the auth list or the chat's DJs, cannot apply to them.
+// A message posted as another channel, such as a linked or personal channel, is returned with that channel's title
+// and ID; it is not an admin.
+func resolveActor(m *telegram.NewMessage) (name string, userID int64, anonymousAdmin bool) {
+	if from, ok := m.Message.FromID.(*telegram.PeerChannel); ok {
+		if peer, ok := m.Message.PeerID.(*telegram.PeerChannel); ok && peer.ChannelID == from.ChannelID {
+			return anonymousAdminName, 0, true
+		}
+	}
+
+	userID = m.SenderID()
+	if m.Sender != nil {
+		name = m.Sender.FirstName
+	}
+	return name, userID, false
+}
+
+// actorName returns the name to show for the sender of a message; see resolveActor.
+func actorName(m *telegram.NewMessage) string {
+	name, _, _ := resolveActor(m)
+	return name
+}
+
+// actorID returns the user ID of the sender of a message, or 0 for an anonymous admin; see resolveActor.
+func actorID(m *telegram.NewMessage) int64 {
+	_, userID, _ := resolveActor(m)
+	return userID
+}
+
+// mayAnswerPrompt reports whether senderID may answer a prompt sent in reply to a command of ownerID, the actorID of
+// the command. A command of an anonymous admin has no user to answer it, so any admin can.
+func mayAnswerPrompt(ctx context.Context, store storage, chatID, ownerID, senderID int64) bool {
+	return ownerID == senderID || ownerID == 0 && store.IsAdmin(ctx, chatID, senderID)
+}
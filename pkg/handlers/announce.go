@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	// announceWorkers is how many chats an announcement is sent to at the same time.
+	announceWorkers = 5
+	// announceProgressInterval is how often the progress message is updated while an announcement is sent.
+	announceProgressInterval = 3 * time.Second
+)
+
+// announcement is a message waiting for a developer to confirm sending it to the opted-in chats.
+type announcement struct {
+	message *telegram.NewMessage
+	hash    string
+	chats   []int64
+}
+
+var (
+	announceMu sync.Mutex
+	// pendingAnnouncements maps the ID of the confirmation message to the announcement it asks about.
+	pendingAnnouncements = make(map[int32]*announcement)
+)
+
+// announcementHash identifies an announcement by its text and media, so the same message is not delivered twice.
+func announcementHash(m *telegram.NewMessage) string {
+	content := m.MessageText()
+	if m.File != nil {
+		content += "\x00" + m.File.FileID
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// announceHandler handles the /announce command.
+// It asks for confirmation before sending the replied-to message to every chat that opted in to announcements.
+// It returns an error if any.
+func announceHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	if !m.IsReply() {
+		_, err := m.Reply(lang.GetString(langCode, "announce_usage"))
+		return err
+	}
+	reply, err := m.GetReplyMessage()
+	if err != nil {
+		_, err = m.Reply(lang.GetString(langCode, "announce_usage"))
+		return err
+	}
+
+	hash := announcementHash(reply)
+	chats, err := db.Instance.GetAnnouncementChats(ctx, hash)
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "announce_error"), err.Error()))
+		return err
+	}
+	if len(chats) == 0 {
+		_, err = m.Reply(lang.GetString(langCode, "announce_no_chats"))
+		return err
+	}
+
+	keyboard := telegram.NewKeyboard().AddRow(
+		telegram.Button.Data(lang.GetString(langCode, "announce_confirm_button"), "announce_confirm"),
+		telegram.Button.Data(lang.GetString(langCode, "announce_cancel_button"), "announce_cancel"),
+	).Build()
+	prompt, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "announce_confirm"), len(chats)), telegram.SendOptions{ReplyMarkup: keyboard})
+	if err != nil {
+		return err
+	}
+
+	announceMu.Lock()
+	pendingAnnouncements[prompt.ID] = &announcement{message: reply, hash: hash, chats: chats}
+	announceMu.Unlock()
+	return nil
+}
+
+// announceCallbackHandler handles the confirm and cancel buttons of /announce.
+func announceCallbackHandler(cb *telegram.CallbackQuery) error {
+	chatID, _ := getPeerId(cb.Client, cb.ChatID)
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	if !cache.IsDev(cb.SenderID) {
+		_, _ = cb.Answer(lang.GetString(langCode, "announce_not_dev"), &telegram.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	announceMu.Lock()
+	pending, ok := pendingAnnouncements[cb.MessageID]
+	delete(pendingAnnouncements, cb.MessageID)
+	announceMu.Unlock()
+	if !ok {
+		_, _ = cb.Answer(lang.GetString(langCode, "announce_expired"), &telegram.CallbackOptions{Alert: true})
+		_, _ = cb.Delete()
+		return nil
+	}
+
+	if strings.Contains(cb.DataString(), "announce_cancel") {
+		_, _ = cb.Answer(lang.GetString(langCode, "announce_cancelled"))
+		_, _ = cb.Edit(lang.GetString(langCode, "announce_cancelled"))
+		return nil
+	}
+
+	_, _ = cb.Answer(lang.GetString(langCode, "announce_started"))
+	go sendAnnouncement(cb, langCode, pending)
+	return nil
+}
+
+// sendAnnouncement delivers an announcement to its chats with a pool of workers, updating the confirmation message
+// with the progress. Flood waits are handled by the client's flood handler, which pauses the worker that hit them.
+func sendAnnouncement(cb *telegram.CallbackQuery, langCode string, a *announcement) {
+	var sent, failed atomic.Int64
+	total := len(a.chats)
+	progress := func(key string) {
+		text := fmt.Sprintf(lang.GetString(langCode, key), sent.Load(), failed.Load(), total)
+		if _, err := cb.Edit(text); err != nil && !strings.Contains(err.Error(), "MESSAGE_NOT_MODIFIED") {
+			gologging.WarnF("[announce] Failed to update the progress: %v", err)
+		}
+	}
+
+	jobs := make(chan int64)
+	var wg sync.WaitGroup
+	for range announceWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chatID := range jobs {
+				if err := deliverAnnouncement(cb.Client, chatID, a); err != nil {
+					gologging.DebugF("[announce] Failed to send to chat %d: %v", chatID, err)
+					failed.Add(1)
+					continue
+				}
+				sent.Add(1)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(announceProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				progress("announce_progress")
+			}
+		}
+	}()
+
+	for _, chatID := range a.chats {
+		jobs <- chatID
+	}
+	close(jobs)
+	wg.Wait()
+	close(done)
+	progress("announce_done")
+}
+
+// deliverAnnouncement sends the update header and a copy of the announcement to a chat, then records it as delivered.
+func deliverAnnouncement(client *telegram.Client, chatID int64, a *announcement) error {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	if _, err := client.SendMessage(chatID, lang.GetString(langCode, "announce_header")); err != nil {
+		return err
+	}
+	if _, err := client.SendMessage(chatID, *a.message); err != nil {
+		return err
+	}
+	return db.Instance.SetLastAnnouncement(ctx, chatID, a.hash)
+}
+
+// announcementsHandler handles the /announcements command.
+// It opts the chat in to or out of bot update announcements.
+func announcementsHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	var enabled bool
+	switch strings.ToLower(m.Args()) {
+	case "on", "enable":
+		enabled = true
+	case "off", "disable":
+		enabled = false
+	default:
+		status := lang.GetString(langCode, "announcements_status_off")
+		if db.Instance.GetAnnouncements(ctx, chatID) {
+			status = lang.GetString(langCode, "announcements_status_on")
+		}
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "announcements_usage"), status))
+		return err
+	}
+
+	if err := db.Instance.SetAnnouncements(ctx, chatID, enabled); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "announcements_error"), err.Error()))
+		return err
+	}
+
+	key := "announcements_disabled"
+	if enabled {
+		key = "announcements_enabled"
+	}
+	_, err := m.Reply(lang.GetString(langCode, key))
+	return err
+}
@@ -3,8 +3,10 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
 
 	"github.com/Laky-64/gologging"
 	"github.com/amarnathcjd/gogram/telegram"
@@ -14,6 +16,12 @@ import (
 // It takes a telegram.NewMessage object as input.
 // It returns the user ID and an error if any.
 func getTargetUserID(m *telegram.NewMessage, langCode string) (int64, error) {
+	return resolveTargetUser(m, m.Args(), langCode)
+}
+
+// resolveTargetUser gets the user ID from the message replied to, or else from the username given.
+// It returns the user ID and an error if any.
+func resolveTargetUser(m *telegram.NewMessage, username, langCode string) (int64, error) {
 	var userID int64
 
 	if m.IsReply() {
@@ -21,9 +29,10 @@ func getTargetUserID(m *telegram.NewMessage, langCode string) (int64, error) {
 		if err != nil {
 			return 0, err
 		}
-		userID = replyMsg.SenderID()
-	} else if len(m.Args()) > 0 {
-		user, err := m.Client.ResolveUsername(m.Args())
+		// An anonymous admin has no user ID to authorize.
+		_, userID, _ = resolveActor(replyMsg)
+	} else if len(username) > 0 {
+		user, err := m.Client.ResolveUsername(username)
 		if err != nil {
 			return 0, err
 		}
@@ -38,38 +47,38 @@ func getTargetUserID(m *telegram.NewMessage, langCode string) (int64, error) {
 		return 0, errors.New(lang.GetString(langCode, "auth_no_user_specified"))
 	}
 
-	if m.SenderID() == userID {
+	if _, senderID, _ := resolveActor(m); senderID == userID {
 		return 0, errors.New(lang.GetString(langCode, "auth_action_on_self"))
 	}
 
 	return userID, nil
 }
 
-// authListHandler handles the /auth command.
-// It takes a telegram.NewMessage object as input.
+// authListHandler handles the /authlist command.
+// It takes a handlerContext and a message as input.
 // It returns an error if any.
-func authListHandler(m *telegram.NewMessage) error {
+func authListHandler(hc *handlerContext, m message) error {
 	if m.IsPrivate() {
 		return nil
 	}
-	chatID, _ := getPeerId(m.Client, m.ChatID())
+	chatID, _ := getPeerId(hc.client, m.ChatID())
 	ctx, cancel := db.Ctx()
 	defer cancel()
-	langCode := db.Instance.GetLang(ctx, chatID)
+	langCode := hc.store.GetLang(ctx, chatID)
 
-	authUser := db.Instance.GetAuthUsers(ctx, chatID)
+	authUser := hc.store.GetAuthUsers(ctx, chatID)
 	if authUser == nil || len(authUser) == 0 {
 		_, _ = m.Reply(lang.GetString(langCode, "no_auth_users"))
 		return nil
 	}
 
-	text := lang.GetString(langCode, "auth_users_list")
+	var b strings.Builder
+	b.WriteString(lang.GetString(langCode, "auth_users_list"))
 	for _, uid := range authUser {
-		text += fmt.Sprintf("• <code>%d</code>\n", uid)
+		b.WriteString(fmt.Sprintf("• <code>%d</code>\n", uid))
 	}
 
-	_, err := m.Reply(text)
-	return err
+	return core.SendLong(m, b.String())
 }
 
 // addAuthHandler handles the /addauth command.
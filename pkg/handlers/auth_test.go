@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/handlers/testsupport"
+	"strings"
+	"testing"
+)
+
+func TestAuthListHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		private   bool
+		authUsers []int64
+		want      string
+	}{
+		{name: "private chat", private: true},
+		{name: "no auth users", want: "no_auth_users"},
+		{name: "auth users", authUsers: []int64{11, 22}, want: "auth_users_list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, _ := newTestContext(&testsupport.Store{AuthUsers: tt.authUsers}, &testsupport.Calls{})
+			m := &testsupport.Message{Chat: testChatID, Private: tt.private}
+
+			if err := authListHandler(hc, m); err != nil {
+				t.Fatalf("authListHandler() = %v", err)
+			}
+			if tt.want == "" {
+				if len(m.Replies) > 0 {
+					t.Errorf("replied %q", m.Replies)
+				}
+				return
+			}
+			checkReply(t, m, tt.want)
+			for _, id := range tt.authUsers {
+				if !strings.Contains(m.LastReply(), fmt.Sprintf("<code>%d</code>", id)) {
+					t.Errorf("reply %q does not list user %d", m.LastReply(), id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// autoplayHandler handles the /autoplay command.
+// It toggles queueing related tracks once the queue runs out.
+func autoplayHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	var enabled bool
+	switch strings.ToLower(m.Args()) {
+	case "on", "enable":
+		enabled = true
+	case "off", "disable":
+		enabled = false
+	default:
+		status := lang.GetString(langCode, "autoplay_status_off")
+		if db.Instance.GetAutoplay(ctx, chatID) {
+			status = lang.GetString(langCode, "autoplay_status_on")
+		}
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "autoplay_usage"), status))
+		return err
+	}
+
+	if err := db.Instance.SetAutoplay(ctx, chatID, enabled); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "autoplay_error"), err.Error()))
+		return err
+	}
+
+	key := "autoplay_disabled"
+	if enabled {
+		key = "autoplay_enabled"
+	}
+	_, err := m.Reply(lang.GetString(langCode, key))
+	return err
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// maxBannerSize is the largest photo or video accepted as the start banner.
+const maxBannerSize = 5 * 1024 * 1024
+
+// bannerDir is where the start banner is kept.
+var bannerDir = filepath.Join("database", "banner")
+
+// startSender sends the parts of a start message. It is implemented by replies to a command and to a callback query.
+type startSender interface {
+	SendText(text string, markup telegram.ReplyMarkup) error
+	SendMedia(path, caption string, markup telegram.ReplyMarkup) error
+}
+
+// sendStart sends the start text with the banner, if one is set.
+// The text is the banner's caption when it fits; a longer text is sent as a message of its own after the banner.
+// If the banner cannot be sent, the text is sent alone.
+func sendStart(s startSender, banner, text string, markup telegram.ReplyMarkup) error {
+	if banner == "" {
+		return s.SendText(text, markup)
+	}
+	if _, err := os.Stat(banner); err != nil {
+		gologging.WarnF("[Banner] The start banner is missing: %v", err)
+		return s.SendText(text, markup)
+	}
+
+	if utf8.RuneCountInString(text) <= core.MaxCaptionLength {
+		err := s.SendMedia(banner, text, markup)
+		if err == nil {
+			return nil
+		}
+		gologging.WarnF("[Banner] Failed to send the start banner: %v", err)
+		return s.SendText(text, markup)
+	}
+
+	if err := s.SendMedia(banner, "", nil); err != nil {
+		gologging.WarnF("[Banner] Failed to send the start banner: %v", err)
+	}
+	return s.SendText(text, markup)
+}
+
+// messageSender sends start messages as replies to a message.
+type messageSender struct {
+	m *telegram.NewMessage
+}
+
+func (s messageSender) SendText(text string, markup telegram.ReplyMarkup) error {
+	_, err := s.m.Reply(text, telegram.SendOptions{ReplyMarkup: markup})
+	return err
+}
+
+func (s messageSender) SendMedia(path, caption string, markup telegram.ReplyMarkup) error {
+	_, err := s.m.ReplyMedia(path, telegram.MediaOptions{Caption: caption, ReplyMarkup: markup})
+	return err
+}
+
+// callbackSender sends start messages to the chat of a callback query.
+type callbackSender struct {
+	cb *telegram.CallbackQuery
+}
+
+func (s callbackSender) SendText(text string, markup telegram.ReplyMarkup) error {
+	_, err := s.cb.Respond(text, &telegram.SendOptions{ReplyMarkup: markup})
+	return err
+}
+
+func (s callbackSender) SendMedia(path, caption string, markup telegram.ReplyMarkup) error {
+	_, err := s.cb.RespondMedia(path, &telegram.MediaOptions{Caption: caption, ReplyMarkup: markup})
+	return err
+}
+
+// setBannerHandler handles the /setbanner command.
+// It saves the replied-to photo or video as the banner sent with the start and help messages.
+func setBannerHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	if !m.IsReply() {
+		_, err := m.Reply(lang.GetString(langCode, "banner_set_usage"))
+		return err
+	}
+	reply, err := m.GetReplyMessage()
+	if err != nil || reply.File == nil || (reply.Photo() == nil && reply.Video() == nil) {
+		_, err = m.Reply(lang.GetString(langCode, "banner_set_usage"))
+		return err
+	}
+	if reply.File.Size > maxBannerSize {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "banner_too_large"), maxBannerSize/(1024*1024)))
+		return err
+	}
+
+	if err = os.MkdirAll(bannerDir, 0o755); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "banner_error"), err.Error()))
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(reply.File.Name))
+	if ext == "" {
+		ext = ".jpg"
+		if reply.Video() != nil {
+			ext = ".mp4"
+		}
+	}
+	tmp, err := reply.Download(&telegram.DownloadOptions{FileName: filepath.Join(bannerDir, "banner.tmp"+ext)})
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "banner_error"), err.Error()))
+		return err
+	}
+
+	botID := m.Client.Me().ID
+	old := db.Instance.GetBanner(ctx, botID)
+	path := filepath.Join(bannerDir, "banner"+ext)
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "banner_error"), err.Error()))
+		return err
+	}
+	if old != "" && old != path {
+		_ = os.Remove(old)
+	}
+	if err = db.Instance.SetBanner(ctx, botID, path); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "banner_error"), err.Error()))
+		return err
+	}
+
+	_, err = m.Reply(lang.GetString(langCode, "banner_saved"))
+	return err
+}
+
+// delBannerHandler handles the /delbanner command.
+// It removes the banner, so the start and help messages are sent as plain text again.
+func delBannerHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	botID := m.Client.Me().ID
+	path := db.Instance.GetBanner(ctx, botID)
+	if path == "" {
+		_, err := m.Reply(lang.GetString(langCode, "banner_none"))
+		return err
+	}
+	if err := db.Instance.SetBanner(ctx, botID, ""); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "banner_error"), err.Error()))
+		return err
+	}
+	_ = os.Remove(path)
+
+	_, err := m.Reply(lang.GetString(langCode, "banner_deleted"))
+	return err
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/zuchzub/Go/pkg/core"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// fakeSender records what sendStart sends, as "text" or "media" with the caption, and fails media when mediaErr is set.
+type fakeSender struct {
+	sent     []string
+	markups  []bool // markups records whether each message carried the keyboard.
+	mediaErr error
+}
+
+func (s *fakeSender) SendText(text string, markup telegram.ReplyMarkup) error {
+	s.sent = append(s.sent, "text:"+text)
+	s.markups = append(s.markups, markup != nil)
+	return nil
+}
+
+func (s *fakeSender) SendMedia(_, caption string, markup telegram.ReplyMarkup) error {
+	if s.mediaErr != nil {
+		return s.mediaErr
+	}
+	s.sent = append(s.sent, "media:"+caption)
+	s.markups = append(s.markups, markup != nil)
+	return nil
+}
+
+func TestSendStart(t *testing.T) {
+	banner := filepath.Join(t.TempDir(), "banner.jpg")
+	if err := os.WriteFile(banner, []byte("jpeg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	short := "Welcome!"
+	fits := strings.Repeat("é", core.MaxCaptionLength) // Counted in characters, not bytes.
+	long := strings.Repeat("a", core.MaxCaptionLength+1)
+	markup := telegram.NewKeyboard().AddRow(core.CloseBtn).Build()
+
+	tests := []struct {
+		name        string
+		banner      string
+		text        string
+		mediaErr    error
+		want        []string
+		wantMarkups []bool
+	}{
+		{"no banner", "", short, nil, []string{"text:" + short}, []bool{true}},
+		{"banner file missing", filepath.Join(t.TempDir(), "gone.jpg"), short, nil, []string{"text:" + short}, []bool{true}},
+		{"caption", banner, short, nil, []string{"media:" + short}, []bool{true}},
+		{"caption at the limit", banner, fits, nil, []string{"media:" + fits}, []bool{true}},
+		{"text over the caption limit", banner, long, nil, []string{"media:", "text:" + long}, []bool{false, true}},
+		{"banner fails", banner, short, errors.New("PHOTO_INVALID"), []string{"text:" + short}, []bool{true}},
+		{"banner fails with a long text", banner, long, errors.New("PHOTO_INVALID"), []string{"text:" + long}, []bool{true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &fakeSender{mediaErr: tt.mediaErr}
+			if err := sendStart(s, tt.banner, tt.text, markup); err != nil {
+				t.Fatalf("sendStart() = %v", err)
+			}
+			if !reflect.DeepEqual(s.sent, tt.want) {
+				t.Errorf("sent %.60q, want %.60q", s.sent, tt.want)
+			}
+			if !reflect.DeepEqual(s.markups, tt.wantMarkups) {
+				t.Errorf("keyboards %v, want %v", s.markups, tt.wantMarkups)
+			}
+		})
+	}
+}
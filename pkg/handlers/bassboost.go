@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// bassBoostHandler handles the /bassboost command.
+// It sets the chat's bass boost preset, kept for later tracks; without arguments, it shows the current one.
+func bassBoostHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	preset := strings.ToLower(strings.TrimSpace(m.Args()))
+	if preset == "" || !vc.IsAudioPreset(preset) {
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "bassboost_usage"), db.Instance.GetAudioPreset(ctx, chatID)))
+		return err
+	}
+
+	if err := vc.Calls.ApplyAudioPreset(chatID, preset); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "bassboost_error"), err.Error()))
+		return err
+	}
+
+	if preset == vc.PresetOff {
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "bassboost_off"), actorName(m)))
+		return err
+	}
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "bassboost_set"), preset, actorName(m)))
+	return err
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
 	"github.com/zuchzub/Go/pkg/vc"
-	"strings"
+	"strconv"
 
 	"github.com/Laky-64/gologging"
 	"github.com/amarnathcjd/gogram/telegram"
@@ -17,12 +17,13 @@ import (
 // It takes a telegram.CallbackQuery object as input.
 // It returns an error if any.
 func playCallbackHandler(cb *telegram.CallbackQuery) error {
-	data := cb.DataString()
-	if strings.Contains(data, "settings_") {
+	_, fields := core.DecodeCallback(cb.DataString())
+	if len(fields) == 0 {
 		return nil
 	}
+	action := fields[0]
 
-	chatID, _ := getPeerId(cb.Client, cb.ChatID)
+	chatID, _ := musicChatID(cb)
 	ctx, cancel := db.Ctx()
 	defer cancel()
 	langCode := db.Instance.GetLang(ctx, chatID)
@@ -40,19 +41,11 @@ func playCallbackHandler(cb *telegram.CallbackQuery) error {
 		return nil
 	}
 
-	buildTrackMessage := func(status, emoji string) string {
-		return fmt.Sprintf(
-			lang.GetString(langCode, "track_message"),
-			emoji, status,
-			currentTrack.URL, currentTrack.Name,
-			cache.SecToMin(currentTrack.Duration),
-			currentTrack.User,
-		)
-	}
-
-	switch {
-	case strings.Contains(data, "play_skip"):
-		if err := vc.Calls.PlayNext(chatID); err != nil {
+	switch action {
+	case "skip":
+		cache.ChatCache.SetLoopCount(chatID, 0)
+		cache.RecordQueueStat(chatID, cache.QueueSkipped, 1)
+		if err := vc.Calls.Skip(chatID); err != nil {
 			_, _ = cb.Answer(lang.GetString(langCode, "skip_fail"), &telegram.CallbackOptions{Alert: true})
 			_, _ = cb.Edit(lang.GetString(langCode, "skip_fail"), &telegram.SendOptions{ReplyMarkup: core.ControlButtons("")})
 			return nil
@@ -61,63 +54,100 @@ func playCallbackHandler(cb *telegram.CallbackQuery) error {
 		_, _ = cb.Delete()
 		return nil
 
-	case strings.Contains(data, "play_stop"):
-		if err := vc.Calls.Stop(chatID); err != nil {
+	case "jump":
+		if len(fields) < 2 {
+			return nil
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil
+		}
+		skipped, err := jumpTo(chatID, n)
+		if skipped < 0 || err != nil {
+			_, _ = cb.Answer(lang.GetString(langCode, "skip_fail"), &telegram.CallbackOptions{Alert: true})
+			return nil
+		}
+		_, _ = cb.Answer(fmt.Sprintf(lang.GetString(langCode, "jump_success"), n, skipped, cb.Sender.FirstName), &telegram.CallbackOptions{Alert: true})
+		_, _ = cb.Delete()
+		return nil
+
+	case "stop":
+		hc := newHandlerContext(cb.Client)
+		if pending := hc.stopNeedsConfirm(chatID); pending > 0 {
+			text, opts, err := stopConfirmPrompt(langCode, chatID, cb.SenderID, pending)
+			if err != nil {
+				return err
+			}
+			_, _ = cb.Answer("")
+			_, err = cb.Respond(text, &opts)
+			return err
+		}
+		if err := hc.stopChat(chatID); err != nil {
 			_, _ = cb.Answer(lang.GetString(langCode, "stop_fail"), &telegram.CallbackOptions{Alert: true})
 			_, _ = cb.Edit(lang.GetString(langCode, "stop_fail"), &telegram.SendOptions{ReplyMarkup: core.ControlButtons("")})
 			return nil
 		}
-		msg := fmt.Sprintf(lang.GetString(langCode, "playback_stopped"), cb.Sender.FirstName)
+		msg := fmt.Sprintf(lang.GetString(langCode, "playback_stopped"), cb.Sender.FirstName) + undoHint(langCode)
 		_, _ = cb.Answer(lang.GetString(langCode, "track_stopped"), &telegram.CallbackOptions{Alert: true})
 		_, err := cb.Edit(msg, &telegram.SendOptions{ReplyMarkup: core.ControlButtons("")})
 		return err
 
-	case strings.Contains(data, "play_pause"):
+	case "audioonly":
+		// The button may be pressed again after the track already started as audio.
+		if !currentTrack.IsVideo {
+			_, _ = cb.Delete()
+			return nil
+		}
+		_, _ = cb.Answer(lang.GetString(langCode, "video_slots_audio_only"))
+		_, _ = cb.Delete()
+		return vc.Calls.PlayAudioOnly(chatID)
+
+	case "pause":
 		if _, err := vc.Calls.Pause(chatID); err != nil {
 			_, _ = cb.Answer(lang.GetString(langCode, "pause_fail"), &telegram.CallbackOptions{Alert: true})
 			_, _ = cb.Edit(lang.GetString(langCode, "pause_fail"), &telegram.SendOptions{ReplyMarkup: core.ControlButtons("")})
 			return nil
 		}
 		_, _ = cb.Answer(lang.GetString(langCode, "track_paused"), &telegram.CallbackOptions{Alert: true})
-		text := buildTrackMessage(lang.GetString(langCode, "paused"), "⏸") + fmt.Sprintf(lang.GetString(langCode, "paused_by"), cb.Sender.FirstName)
+		text := buildTrackMessage(langCode, currentTrack, lang.GetString(langCode, "paused"), "⏸") + fmt.Sprintf(lang.GetString(langCode, "paused_by"), cb.Sender.FirstName)
 		_, _ = cb.Edit(text, &telegram.SendOptions{ReplyMarkup: core.ControlButtons("pause")})
 		return nil
 
-	case strings.Contains(data, "play_resume"):
+	case "resume":
 		if _, err := vc.Calls.Resume(chatID); err != nil {
 			_, _ = cb.Answer(lang.GetString(langCode, "resume_fail"), &telegram.CallbackOptions{Alert: true})
 			_, _ = cb.Edit(lang.GetString(langCode, "resume_fail"), &telegram.SendOptions{ReplyMarkup: core.ControlButtons("pause")})
 			return nil
 		}
 		_, _ = cb.Answer(lang.GetString(langCode, "track_resumed"), &telegram.CallbackOptions{Alert: true})
-		text := buildTrackMessage(lang.GetString(langCode, "now_playing"), "🎵") + fmt.Sprintf(lang.GetString(langCode, "resumed_by"), cb.Sender.FirstName)
+		text := buildTrackMessage(langCode, currentTrack, lang.GetString(langCode, "now_playing"), "🎵") + fmt.Sprintf(lang.GetString(langCode, "resumed_by"), cb.Sender.FirstName)
 		_, _ = cb.Edit(text, &telegram.SendOptions{ReplyMarkup: core.ControlButtons("resume")})
 		return nil
 
-	case strings.Contains(data, "play_mute"):
+	case "mute":
 		if _, err := vc.Calls.Mute(chatID); err != nil {
 			_, _ = cb.Answer(lang.GetString(langCode, "mute_fail"), &telegram.CallbackOptions{Alert: true})
 			_, _ = cb.Edit(lang.GetString(langCode, "mute_fail"), &telegram.SendOptions{ReplyMarkup: core.ControlButtons("mute")})
 			return nil
 		}
 		_, _ = cb.Answer(lang.GetString(langCode, "track_muted"), &telegram.CallbackOptions{Alert: true})
-		text := buildTrackMessage(lang.GetString(langCode, "muted"), "🔇") + fmt.Sprintf(lang.GetString(langCode, "muted_by"), cb.Sender.FirstName)
+		text := buildTrackMessage(langCode, currentTrack, lang.GetString(langCode, "muted"), "🔇") + fmt.Sprintf(lang.GetString(langCode, "muted_by"), cb.Sender.FirstName)
 		_, _ = cb.Edit(text, &telegram.SendOptions{ReplyMarkup: core.ControlButtons("mute")})
 		return nil
 
-	case strings.Contains(data, "play_unmute"):
+	case "unmute":
 		if _, err := vc.Calls.Unmute(chatID); err != nil {
 			_, _ = cb.Answer(lang.GetString(langCode, "unmute_fail"), &telegram.CallbackOptions{Alert: true})
 			_, _ = cb.Edit(lang.GetString(langCode, "unmute_fail"), &telegram.SendOptions{ReplyMarkup: core.ControlButtons("unmute")})
 			return nil
 		}
 		_, _ = cb.Answer(lang.GetString(langCode, "track_unmuted"), &telegram.CallbackOptions{Alert: true})
-		text := buildTrackMessage(lang.GetString(langCode, "now_playing"), "🎵") + fmt.Sprintf(lang.GetString(langCode, "unmuted_by"), cb.Sender.FirstName)
+		text := buildTrackMessage(langCode, currentTrack, lang.GetString(langCode, "now_playing"), "🎵") + fmt.Sprintf(lang.GetString(langCode, "unmuted_by"), cb.Sender.FirstName)
 		_, _ = cb.Edit(text, &telegram.SendOptions{ReplyMarkup: core.ControlButtons("unmute")})
 		return nil
 	}
 
-	text := buildTrackMessage(lang.GetString(langCode, "now_playing"), "🎵")
+	text := buildTrackMessage(langCode, currentTrack, lang.GetString(langCode, "now_playing"), "🎵")
 	_, _ = cb.Edit(text, &telegram.SendOptions{ReplyMarkup: core.ControlButtons("resume")})
 	return nil
 }
@@ -131,7 +161,7 @@ func vcPlayHandler(cb *telegram.CallbackQuery) error {
 	defer cancel()
 	langCode := db.Instance.GetLang(ctx, chatID)
 	data := cb.DataString()
-	if strings.Contains(data, "vcplay_close") {
+	if _, fields := core.DecodeCallback(data); len(fields) > 0 && fields[0] == "close" {
 		_, _ = cb.Answer(lang.GetString(langCode, "closed"), &telegram.CallbackOptions{Alert: true})
 		_, _ = cb.Delete()
 		return nil
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/lang"
+	"sync"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	availabilityWorkers = 4
+	availabilityTimeout = 15 * time.Second
+	clearFailedTimeout  = 90 * time.Second
+)
+
+// isTrackUnavailable runs a lightweight metadata lookup to check whether a queued track can no longer be played.
+// Only a definitive answer counts: the platform reporting that the track does not exist, or returning nothing for it.
+// A track whose check fails for any other reason, such as a timeout, a slow API or an unconfigured gateway, is kept.
+// Telegram files are always treated as available.
+func isTrackUnavailable(ctx context.Context, track *cache.CachedTrack) bool {
+	if track.Platform == cache.Telegram {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, availabilityTimeout)
+	defer cancel()
+
+	wrapper := dl.NewDownloaderWrapper(track.URL)
+	if !wrapper.IsValid() {
+		return false
+	}
+
+	info, err := wrapper.GetInfo(ctx)
+	return definitelyUnavailable(info, err)
+}
+
+// definitelyUnavailable reports whether the result of a metadata lookup proves that a track is gone.
+func definitelyUnavailable(info cache.PlatformTracks, err error) bool {
+	if err != nil {
+		return errors.Is(err, dl.ErrTrackUnavailable)
+	}
+	return len(info.Results) == 0
+}
+
+// findUnavailableTracks checks the given tracks concurrently, with a bounded number of workers.
+// It stops scheduling new checks once the context is cancelled, and returns the tracks found to be unavailable.
+func findUnavailableTracks(ctx context.Context, tracks []*cache.CachedTrack) []*cache.CachedTrack {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed []*cache.CachedTrack
+		sem    = make(chan struct{}, availabilityWorkers)
+	)
+
+	for _, track := range tracks {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return failed
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(track *cache.CachedTrack) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if isTrackUnavailable(ctx, track) && ctx.Err() == nil {
+				mu.Lock()
+				failed = append(failed, track)
+				mu.Unlock()
+			}
+		}(track)
+	}
+
+	wg.Wait()
+	return failed
+}
+
+// clearFailedHandler handles the /clearfailed command.
+// It checks every upcoming track in the queue and removes the ones that can no longer be played.
+func clearFailedHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if !cache.ChatCache.IsActive(chatID) {
+		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return err
+	}
+
+	queue := cache.ChatCache.GetQueue(chatID)
+	if len(queue) < 2 {
+		_, err := m.Reply(lang.GetString(langCode, "clear_failed_nothing"))
+		return err
+	}
+
+	upcoming := queue[1:]
+	statusMsg, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "clear_failed_checking"), len(upcoming)))
+	if err != nil {
+		return err
+	}
+
+	checkCtx, checkCancel := context.WithTimeout(context.Background(), clearFailedTimeout)
+	defer checkCancel()
+
+	failed := findUnavailableTracks(checkCtx, upcoming)
+	cache.ChatCache.SnapshotQueue(chatID)
+	removed := cache.ChatCache.RemoveTracks(chatID, failed)
+	text := fmt.Sprintf(lang.GetString(langCode, "clear_failed_done"), removed, len(upcoming), actorName(m))
+	if removed == 0 {
+		cache.ChatCache.DiscardSnapshot(chatID)
+	} else {
+		text += undoHint(langCode)
+	}
+	_, err = statusMsg.Edit(text)
+	return err
+}
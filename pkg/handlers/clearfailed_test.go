@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"testing"
+)
+
+func TestDefinitelyUnavailable(t *testing.T) {
+	found := cache.PlatformTracks{Results: []cache.MusicTrack{{ID: "x"}}}
+	tests := []struct {
+		name string
+		info cache.PlatformTracks
+		err  error
+		want bool
+	}{
+		{"found", found, nil, false},
+		{"no results", cache.PlatformTracks{}, nil, true},
+		{"not found", cache.PlatformTracks{}, fmt.Errorf("%w: 404 Not Found", dl.ErrTrackUnavailable), true},
+		{"timeout", cache.PlatformTracks{}, context.DeadlineExceeded, false},
+		{"server error", cache.PlatformTracks{}, errors.New("unexpected status code while fetching info: 502 Bad Gateway"), false},
+		{"rate limited", cache.PlatformTracks{}, dl.ErrRateLimited, false},
+	}
+	for _, tt := range tests {
+		if got := definitelyUnavailable(tt.info, tt.err); got != tt.want {
+			t.Errorf("%s: definitelyUnavailable() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTelegramTracksAreAvailable(t *testing.T) {
+	track := &cache.CachedTrack{Platform: cache.Telegram, URL: "https://t.me/c/1/2"}
+	if isTrackUnavailable(context.Background(), track) {
+		t.Error("isTrackUnavailable() = true for a Telegram file")
+	}
+}
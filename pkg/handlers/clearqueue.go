@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// clearQueueHandler handles the /clearqueue command.
+// It drops every upcoming track and leaves the current one playing. If nothing is playing, it clears the whole queue,
+// as /stop would.
+func clearQueueHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	if !cache.ChatCache.IsActive(chatID) {
+		cache.ChatCache.ClearChat(chatID, true)
+		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return err
+	}
+
+	cache.ChatCache.SnapshotQueue(chatID)
+	removed := cache.ChatCache.ClearPending(chatID)
+	if removed == 0 {
+		cache.ChatCache.DiscardSnapshot(chatID)
+		_, err := m.Reply(lang.GetString(langCode, "queue_empty"))
+		return err
+	}
+
+	cache.RecordQueueStat(chatID, cache.QueueCleared, removed)
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "clear_queue_success"), removed, actorName(m)) + undoHint(langCode))
+	return err
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	// cmdStatsErrorRate is the share of failed runs from which /cmdstats flags a command.
+	cmdStatsErrorRate = 0.1
+	// maxCmdStatsError bounds the last error shown for each command.
+	maxCmdStatsError = 120
+)
+
+// cmdStatsHandler handles the /cmdstats command.
+// It lists, most used first, how often each command ran since the bot started, how often it failed or was refused,
+// how long it took on average and the last error it returned. Commands failing often are flagged.
+func cmdStatsHandler(m *telegram.NewMessage) error {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, m.ChatID())
+
+	stats := cache.CommandStats()
+	if len(stats) == 0 {
+		_, err := m.Reply(lang.GetString(langCode, "cmdstats_empty"))
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "cmdstats_header"), time.Since(startTime).Round(time.Minute)))
+	for _, stat := range stats {
+		flag := "▫️"
+		if stat.Errors > 0 && stat.ErrorRate() >= cmdStatsErrorRate {
+			flag = "⚠️"
+		}
+		b.WriteString(fmt.Sprintf(lang.GetString(langCode, "cmdstats_line"),
+			flag, stat.Command, stat.Invocations, stat.Errors, stat.ErrorRate()*100, stat.Rejected,
+			stat.AverageLatency().Round(time.Millisecond)))
+		if stat.LastError != "" {
+			b.WriteString(fmt.Sprintf(lang.GetString(langCode, "cmdstats_last_error"),
+				time.Since(stat.LastErrorAt).Round(time.Second), core.TruncateDisplay(stat.LastError, maxCmdStatsError)))
+		}
+	}
+
+	total := cache.CommandTotals()
+	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "cmdstats_total"), total.Invocations, total.Errors, total.Rejected))
+	return core.SendLong(m, b.String())
+}
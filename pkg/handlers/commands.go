@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// registeredCommand is a command registered with onCommand, kept so that it can be run again later.
+type registeredCommand struct {
+	handler func(*telegram.NewMessage) error
+	filter  func(*telegram.NewMessage) bool
+}
+
+// toggleableCommands holds the commands that admins can turn off per chat, filled in by onCommand.
+var toggleableCommands = map[string]registeredCommand{}
+
+// protectedCommands can never be disabled, so a chat can always stop playback and get help.
+var protectedCommands = []string{"stop", "end", "help", "start", "enable", "disable", "settings"}
+
+// onCommand registers a command that admins can disable per chat with /disable.
+// The stale-command check runs first, then the disabled-command check, then filter.
+// Its runs and the uses filter refuses are counted for /cmdstats.
+func onCommand(c handlerRegistry, name string, handler func(*telegram.NewMessage) error, filter func(*telegram.NewMessage) bool) {
+	command := strings.ToLower(name)
+	handler = recordedHandler(command, handler)
+	toggleableCommands[command] = registeredCommand{handler: handler, filter: filter}
+	c.On("command:"+name, handler, telegram.FilterFunc(freshCommand), telegram.FilterFunc(commandEnabled), telegram.FilterFunc(recordedFilter(command, filter)))
+}
+
+// recordedHandler wraps the handler of a command so that each run, its latency and its error are counted for /cmdstats.
+func recordedHandler(command string, handler func(*telegram.NewMessage) error) func(*telegram.NewMessage) error {
+	return func(m *telegram.NewMessage) error {
+		start := time.Now()
+		err := handler(m)
+		cache.RecordCommand(command, time.Since(start), err)
+		return err
+	}
+}
+
+// recordedFilter wraps the permission filter of a command so that the uses it refuses are counted for /cmdstats,
+// apart from the errors of the runs it lets through.
+func recordedFilter(command string, filter func(*telegram.NewMessage) bool) func(*telegram.NewMessage) bool {
+	return func(m *telegram.NewMessage) bool {
+		if filter(m) {
+			return true
+		}
+		cache.RecordCommandRejected(command)
+		return false
+	}
+}
+
+// normalizeCommand turns "/Speed@MyBot" or "speed" into "speed".
+func normalizeCommand(command string) string {
+	command = strings.TrimLeft(strings.TrimSpace(command), "/!.")
+	command, _, _ = strings.Cut(command, "@")
+	return strings.ToLower(command)
+}
+
+// commandEnabled is a filter that blocks commands disabled in the chat.
+// It returns true if the command may run, otherwise false.
+func commandEnabled(m *telegram.NewMessage) bool {
+	if m.IsPrivate() {
+		return true
+	}
+	chatID, err := getPeerId(m.Client, m.ChatID())
+	if err != nil {
+		gologging.WarnF("getPeerId error: %v", err)
+		return false
+	}
+	ctx, cancel := db.Ctx()
+	defer cancel()
+
+	command := normalizeCommand(m.GetCommand())
+	if !db.Instance.IsCommandDisabled(ctx, chatID, command) {
+		return true
+	}
+
+	langCode := db.Instance.GetLang(ctx, chatID)
+	_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "command_disabled"), command))
+	return false
+}
+
+// disableHandler handles the /disable command.
+func disableHandler(m *telegram.NewMessage) error {
+	return toggleCommand(m, false)
+}
+
+// enableHandler handles the /enable command.
+func enableHandler(m *telegram.NewMessage) error {
+	return toggleCommand(m, true)
+}
+
+// toggleCommand enables or disables the command named in the arguments.
+// Without arguments, it lists the commands currently disabled in the chat.
+func toggleCommand(m *telegram.NewMessage, enable bool) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	command := normalizeCommand(m.Args())
+	if command == "" {
+		disabled := db.Instance.GetDisabledCommands(ctx, chatID)
+		if len(disabled) == 0 {
+			_, err := m.Reply(lang.GetString(langCode, "command_toggle_usage"))
+			return err
+		}
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "command_disabled_list"), "/"+strings.Join(disabled, ", /")))
+		return err
+	}
+
+	if slices.Contains(protectedCommands, command) {
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "command_protected"), command))
+		return err
+	}
+
+	if _, ok := toggleableCommands[command]; !ok {
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "command_unknown"), command))
+		return err
+	}
+
+	var err error
+	key := "command_disable_success"
+	if enable {
+		key = "command_enable_success"
+		err = db.Instance.EnableCommand(ctx, chatID, command)
+	} else {
+		err = db.Instance.DisableCommand(ctx, chatID, command)
+	}
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "command_toggle_error"), err.Error()))
+		return err
+	}
+
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, key), command))
+	return err
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// commandStat returns the /cmdstats counters of command, or the zero CommandStat if it was never recorded.
+func commandStat(command string) cache.CommandStat {
+	for _, stat := range cache.CommandStats() {
+		if stat.Command == command {
+			return stat
+		}
+	}
+	return cache.CommandStat{Command: command}
+}
+
+func TestRecordedHandler(t *testing.T) {
+	failed := errors.New("download failed")
+	tests := []struct {
+		name    string
+		err     error
+		runs    int
+		wantErr int64
+	}{
+		{"success", nil, 3, 0},
+		{"handler error", failed, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := "test_recorded_" + t.Name()
+			handler := recordedHandler(command, func(*telegram.NewMessage) error {
+				time.Sleep(time.Millisecond)
+				return tt.err
+			})
+
+			for i := 0; i < tt.runs; i++ {
+				if err := handler(&telegram.NewMessage{}); !errors.Is(err, tt.err) {
+					t.Fatalf("handler() error = %v, want %v", err, tt.err)
+				}
+			}
+
+			stat := commandStat(command)
+			if stat.Invocations != int64(tt.runs) || stat.Errors != tt.wantErr || stat.Rejected != 0 {
+				t.Errorf("stat = %d runs, %d errors, %d rejected, want %d, %d, 0",
+					stat.Invocations, stat.Errors, stat.Rejected, tt.runs, tt.wantErr)
+			}
+			if stat.AverageLatency() < time.Millisecond {
+				t.Errorf("AverageLatency() = %v, want at least the time the handler took", stat.AverageLatency())
+			}
+			if tt.err == nil {
+				if stat.LastError != "" || !stat.LastErrorAt.IsZero() {
+					t.Errorf("last error = %q at %v, want none", stat.LastError, stat.LastErrorAt)
+				}
+				return
+			}
+			if stat.LastError != tt.err.Error() || time.Since(stat.LastErrorAt) > time.Minute {
+				t.Errorf("last error = %q at %v, want %q just now", stat.LastError, stat.LastErrorAt, tt.err)
+			}
+		})
+	}
+}
+
+func TestRecordedFilter(t *testing.T) {
+	command := "test_recorded_filter"
+	allow := true
+	filter := recordedFilter(command, func(*telegram.NewMessage) bool { return allow })
+
+	if !filter(&telegram.NewMessage{}) {
+		t.Fatal("filter() = false, want the wrapped filter's answer")
+	}
+	if stat := commandStat(command); stat.Rejected != 0 {
+		t.Errorf("Rejected = %d after an allowed use, want 0", stat.Rejected)
+	}
+
+	allow = false
+	for i := 0; i < 2; i++ {
+		if filter(&telegram.NewMessage{}) {
+			t.Fatal("filter() = true, want the wrapped filter's answer")
+		}
+	}
+	stat := commandStat(command)
+	if stat.Rejected != 2 {
+		t.Errorf("Rejected = %d, want 2", stat.Rejected)
+	}
+	if stat.Invocations != 0 || stat.Errors != 0 || stat.LastError != "" {
+		t.Errorf("a refusal counted as a run: %d runs, %d errors, last error %q", stat.Invocations, stat.Errors, stat.LastError)
+	}
+}
+
+// fakeRegistry records the patterns handlers are registered for.
+type fakeRegistry struct {
+	patterns []string
+}
+
+func (r *fakeRegistry) On(pattern any, _ any, _ ...telegram.Filter) telegram.Handle {
+	if p, ok := pattern.(string); ok {
+		r.patterns = append(r.patterns, p)
+	}
+	return nil
+}
+
+func (r *fakeRegistry) AddRawHandler(telegram.Update, telegram.RawHandler) telegram.Handle {
+	return nil
+}
+
+func TestCommandsRegistered(t *testing.T) {
+	var r fakeRegistry
+	registerHandlers(&r)
+
+	registered := make(map[string]bool)
+	for _, p := range r.patterns {
+		if name, ok := strings.CutPrefix(p, "command:"); ok {
+			registered[strings.ToLower(name)] = true
+		}
+	}
+	// A protected command cannot be disabled, so it must be there to be used at all.
+	for _, command := range protectedCommands {
+		if !registered[command] {
+			t.Errorf("protected command /%s has no handler registered", command)
+		}
+	}
+	for command := range toggleableCommands {
+		if !registered[command] {
+			t.Errorf("command /%s can be disabled but has no handler registered", command)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/lang"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// remainingTracks returns the tracks of a playlist after the one at lastIndex, the last one that finished playing.
+func remainingTracks(tracks []cache.MusicTrack, lastIndex int) []cache.MusicTrack {
+	if lastIndex < 0 || lastIndex+1 >= len(tracks) {
+		return nil
+	}
+	return tracks[lastIndex+1:]
+}
+
+// continueHandler handles the /continue command.
+// It queues the rest of the playlist the chat was playing most recently, after the last track that finished.
+func continueHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if queueLockedFor(ctx, m, chatID, langCode) {
+		return nil
+	}
+
+	progress, err := db.Instance.GetLatestPlaylistProgress(ctx, chatID)
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "continue_error"), err.Error()))
+		return err
+	}
+	if progress == nil {
+		_, err = m.Reply(lang.GetString(langCode, "continue_none"))
+		return err
+	}
+
+	statusMsg, err := m.Reply(lang.GetString(langCode, "play_searching"))
+	if err != nil {
+		return err
+	}
+	updater := &statusUpdater{NewMessage: statusMsg, lastMessage: lang.GetString(langCode, "play_searching"), lastSent: time.Now()}
+
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer reqCancel()
+	playlist, err := dl.NewDownloaderWrapper(progress.URL).GetInfo(reqCtx)
+	if err != nil {
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_fetch_error"), err.Error()))
+		return err
+	}
+
+	tracks := remainingTracks(playlist.Results, progress.Index)
+	if len(tracks) == 0 {
+		_, err = updater.Edit(lang.GetString(langCode, "continue_finished"))
+		return err
+	}
+
+	_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "continue_resuming"), progress.URL, progress.Index+2, len(playlist.Results)),
+		telegram.SendOptions{LinkPreview: false})
+	return handleMultipleTracks(m, updater, tracks, chatID, false, &playlistSource{url: progress.URL, offset: progress.Index + 1}, 0, langCode)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"testing"
+)
+
+// fakePlaylist returns a playlist of n tracks, as the platforms resolve it.
+func fakePlaylist(n int) cache.PlatformTracks {
+	var p cache.PlatformTracks
+	for i := 0; i < n; i++ {
+		p.Results = append(p.Results, cache.MusicTrack{ID: fmt.Sprintf("t%d", i), Name: fmt.Sprintf("Track %d", i+1)})
+	}
+	return p
+}
+
+func TestRemainingTracks(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		lastIndex int
+		wantFirst string
+		wantLen   int
+	}{
+		{"stopped halfway", 30, 14, "t15", 15},
+		{"first track finished", 30, 0, "t1", 29},
+		{"second to last finished", 30, 28, "t29", 1},
+		{"last track finished", 30, 29, "", 0},
+		{"playlist shrank since", 10, 14, "", 0},
+		{"invalid index", 30, -1, "", 0},
+		{"empty playlist", 0, 0, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := remainingTracks(fakePlaylist(tt.size).Results, tt.lastIndex)
+			if len(got) != tt.wantLen {
+				t.Fatalf("remainingTracks() = %d tracks, want %d", len(got), tt.wantLen)
+			}
+			if tt.wantLen > 0 && got[0].ID != tt.wantFirst {
+				t.Errorf("remainingTracks() starts at %s, want %s", got[0].ID, tt.wantFirst)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"math"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+// progressBarWidth is the number of segments of the progress bars shown by /current and /queue.
+const progressBarWidth = 12
+
+// currentHandler handles the /current and /np commands.
+// It shows the current track with a bar of how far it has played, and the control buttons.
+// A position ntgcalls cannot report is shown as 0:00.
+func currentHandler(hc *handlerContext, m message) error {
+	chatID, _ := getPeerId(hc.client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := hc.store.GetLang(ctx, chatID)
+
+	track := hc.queues.GetPlayingTrack(chatID)
+	if !hc.queues.IsActive(chatID) || track == nil {
+		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return err
+	}
+
+	played := 0
+	if seconds, err := hc.calls.PlayedTime(chatID); err == nil && seconds < math.MaxInt {
+		played = int(seconds)
+	}
+
+	var progress string
+	if track.IsLive {
+		progress = fmt.Sprintf("%s / %s", cache.SecToMin(played), lang.GetString(langCode, "live_badge"))
+	} else {
+		if track.Duration > 0 {
+			played = min(played, track.Duration)
+		}
+		progress = fmt.Sprintf("%s\n%s / %s", cache.ProgressBar(played, track.Duration, progressBarWidth), cache.SecToMin(played), cache.SecToMin(track.Duration))
+	}
+
+	text := fmt.Sprintf(lang.GetString(langCode, "current_text"), core.FormatTrackLine(track, core.TrackLineCompact), track.User, progress)
+	_, err := m.Reply(text, tg.SendOptions{ReplyMarkup: core.ControlButtons("play")})
+	return err
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/vc"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// vcController is the part of vc.Calls that handlers using handlerContext rely on.
+type vcController interface {
+	PlayedTime(chatID int64) (uint64, error)
+	SeekStream(chatID int64, filePath string, toSeek, duration int, isVideo bool) error
+	Stop(chatID int64) error
+}
+
+// storage is the part of db.Instance that handlers using handlerContext rely on.
+type storage interface {
+	GetLang(ctx context.Context, chatID int64) string
+	GetAuthUsers(ctx context.Context, chatID int64) []int64
+	GetVolume(ctx context.Context, chatID int64) int
+	GetAdminMode(ctx context.Context, chatID int64) string
+	GetStopConfirm(ctx context.Context, chatID int64) int
+	IsAdmin(ctx context.Context, chatID, userID int64) bool
+	IsAuthUser(ctx context.Context, chatID, userID int64) bool
+	IsDJ(ctx context.Context, chatID, userID int64) bool
+}
+
+// queueStore is the part of cache.ChatCache that handlers using handlerContext rely on.
+type queueStore interface {
+	IsActive(chatID int64) bool
+	GetQueue(chatID int64) []*cache.CachedTrack
+	GetQueueLength(chatID int64) int
+	GetPlayingTrack(chatID int64) *cache.CachedTrack
+	GetLastTrackRequestedBy(chatID, userID int64) (*cache.CachedTrack, int)
+	GetQueueLock(chatID int64) (cache.QueueLock, bool)
+	GetLoopQueue(chatID int64) bool
+	RemoveTracks(chatID int64, tracks []*cache.CachedTrack) int
+	SnapshotQueue(chatID int64)
+	DiscardSnapshot(chatID int64)
+}
+
+// peerResolver resolves a chat, user or username to its input peer, like *telegram.Client.
+type peerResolver interface {
+	ResolvePeer(peer any) (telegram.InputPeer, error)
+}
+
+// telegramClient is the part of *telegram.Client that handlers using handlerContext rely on.
+type telegramClient interface {
+	peerResolver
+	GetMessageByID(peerID any, msgID int32) (*telegram.NewMessage, error)
+	SendMessage(peerID, message any, opts ...*telegram.SendOptions) (*telegram.NewMessage, error)
+}
+
+// message is the part of a command message that handlers using handlerContext rely on.
+// commandMessage implements it for a *telegram.NewMessage.
+type message interface {
+	ChatID() int64
+	IsPrivate() bool
+	Args() string
+	// ChatTitle returns the title of the group the message was sent in, or "" in a private chat.
+	ChatTitle() string
+	// Actor returns who sent the message; see resolveActor.
+	Actor() (name string, userID int64, anonymousAdmin bool)
+	Reply(text any, opts ...telegram.SendOptions) (*telegram.NewMessage, error)
+	Edit(text any, opts ...telegram.SendOptions) (*telegram.NewMessage, error)
+	Download(opts ...*telegram.DownloadOptions) (string, error)
+}
+
+// callbackQuery is the part of a button press that handlers using handlerContext rely on.
+// buttonPress implements it for a *telegram.CallbackQuery.
+type callbackQuery interface {
+	// ChatID returns the chat the button acts on; see musicChatID.
+	ChatID() int64
+	SenderID() int64
+	SenderName() string
+	DataString() string
+	Answer(text string, opts ...*telegram.CallbackOptions) (bool, error)
+	Edit(text any, opts ...*telegram.SendOptions) (*telegram.NewMessage, error)
+}
+
+// handlerContext carries the services a handler talks to, so that they can be swapped out without a live bot.
+type handlerContext struct {
+	client telegramClient
+	calls  vcController
+	store  storage
+	queues queueStore
+}
+
+// newHandlerContext returns the handlerContext of the running bot.
+// It is built on every call because db.Instance and vc.Calls are only set once the bot has started.
+func newHandlerContext(client *telegram.Client) *handlerContext {
+	return &handlerContext{client: client, calls: vc.Calls, store: db.Instance, queues: cache.ChatCache}
+}
+
+// withContext adapts a handler taking a handlerContext to the signature gogram expects.
+func withContext(handler func(*handlerContext, message) error) func(*telegram.NewMessage) error {
+	return func(m *telegram.NewMessage) error {
+		return handler(newHandlerContext(m.Client), commandMessage{m})
+	}
+}
+
+// withCallbackContext adapts a callback handler taking a handlerContext to the signature gogram expects.
+func withCallbackContext(handler func(*handlerContext, callbackQuery) error) func(*telegram.CallbackQuery) error {
+	return func(cb *telegram.CallbackQuery) error {
+		return handler(newHandlerContext(cb.Client), buttonPress{cb})
+	}
+}
+
+// commandMessage is a message as handlers using handlerContext see it.
+type commandMessage struct {
+	*telegram.NewMessage
+}
+
+func (m commandMessage) ChatTitle() string {
+	switch {
+	case m.Channel != nil:
+		return m.Channel.Title
+	case m.Chat != nil:
+		return m.Chat.Title
+	default:
+		return ""
+	}
+}
+
+func (m commandMessage) Actor() (string, int64, bool) {
+	return resolveActor(m.NewMessage)
+}
+
+// buttonPress is a button press as callback handlers using handlerContext see it.
+type buttonPress struct {
+	cb *telegram.CallbackQuery
+}
+
+func (p buttonPress) ChatID() int64 {
+	chatID, _ := musicChatID(p.cb)
+	return chatID
+}
+
+func (p buttonPress) SenderID() int64 {
+	return p.cb.SenderID
+}
+
+func (p buttonPress) SenderName() string {
+	if p.cb.Sender == nil {
+		return ""
+	}
+	return p.cb.Sender.FirstName
+}
+
+func (p buttonPress) DataString() string {
+	return p.cb.DataString()
+}
+
+func (p buttonPress) Answer(text string, opts ...*telegram.CallbackOptions) (bool, error) {
+	return p.cb.Answer(text, opts...)
+}
+
+func (p buttonPress) Edit(text any, opts ...*telegram.SendOptions) (*telegram.NewMessage, error) {
+	return p.cb.Edit(text, opts...)
+}
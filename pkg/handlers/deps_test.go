@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/handlers/testsupport"
+	"strings"
+	"testing"
+)
+
+var (
+	_ message        = (*testsupport.Message)(nil)
+	_ callbackQuery  = (*testsupport.Callback)(nil)
+	_ telegramClient = (*testsupport.Client)(nil)
+	_ vcController   = (*testsupport.Calls)(nil)
+	_ storage        = (*testsupport.Store)(nil)
+	_ queueStore     = (*cache.ChatCacher)(nil)
+)
+
+// testChatID is the supergroup the handler tests run in.
+const testChatID = -1001234567890
+
+// newTestContext returns a handlerContext backed by fakes, whose chat queue holds tracks.
+// Without tracks, the chat has no session.
+func newTestContext(store *testsupport.Store, calls *testsupport.Calls, tracks ...*cache.CachedTrack) (*handlerContext, *cache.ChatCacher) {
+	queues := cache.NewChatCacher()
+	for _, track := range tracks {
+		queues.AddSong(testChatID, track)
+	}
+	return &handlerContext{client: &testsupport.Client{}, calls: calls, store: store, queues: queues}, queues
+}
+
+// checkReply fails the test unless the last reply of m is the string with key want. The tests run without
+// translations, so a reply is its key followed by any formatting arguments.
+func checkReply(t *testing.T, m *testsupport.Message, want string) {
+	t.Helper()
+	if got := m.LastReply(); got == "" || !strings.HasPrefix(got, want) {
+		t.Errorf("reply = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// loadDevs merges the developers added with /adddev with those of the config into the set isDev checks.
+// If the added developers cannot be read, only those of the config are allowed.
+func loadDevs(c *telegram.Client) error {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	added, err := db.Instance.GetAddedDevs(ctx, c.Me().ID)
+	if err != nil {
+		gologging.WarnF("[Devs] Failed to load the added developers: %v", err)
+	}
+	cache.SetDevs(config.Conf.DEVS, added)
+	return err
+}
+
+// devTarget returns the user a /adddev or /rmdev command is about: the user ID given, or the user replied to or
+// named by username.
+func devTarget(m *telegram.NewMessage, langCode string) (int64, error) {
+	if id, err := strconv.ParseInt(strings.TrimSpace(m.Args()), 10, 64); err == nil && id > 0 {
+		return id, nil
+	}
+	return getTargetUserID(m, langCode)
+}
+
+// addDevHandler handles the /adddev command.
+// It lets a user use the developer commands, until they are removed with /rmdev.
+func addDevHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	userID, err := devTarget(m, langCode)
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "devs_target_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	if cache.IsDev(userID) {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "devs_already"), userID))
+		return err
+	}
+
+	if _, err = db.Instance.AddDev(ctx, m.Client.Me().ID, userID); err == nil {
+		err = loadDevs(m.Client)
+	}
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "devs_error"), err.Error()))
+		return err
+	}
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "devs_added"), userID))
+	return err
+}
+
+// rmDevHandler handles the /rmdev command.
+// It removes a developer added with /adddev. The owner and the developers of the config cannot be removed this way.
+func rmDevHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	userID, err := devTarget(m, langCode)
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "devs_target_error"), html.EscapeString(err.Error())))
+		return err
+	}
+	if refusal := devRemovalRefusal(langCode, userID); refusal != "" {
+		_, err = m.Reply(refusal)
+		return err
+	}
+
+	removed, err := db.Instance.RemoveDev(ctx, m.Client.Me().ID, userID)
+	if err == nil {
+		err = loadDevs(m.Client)
+	}
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "devs_error"), err.Error()))
+		return err
+	}
+	if !removed {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "devs_not_dev"), userID))
+		return err
+	}
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "devs_removed"), userID))
+	return err
+}
+
+// devRemovalRefusal returns why a user cannot be removed with /rmdev, or "" if they can: the owner and the developers
+// of the config stay developers whatever is stored.
+func devRemovalRefusal(langCode string, userID int64) string {
+	switch {
+	case userID == config.Conf.OwnerId:
+		return lang.GetString(langCode, "devs_owner_fixed")
+	case slices.Contains(config.Conf.DEVS, userID):
+		return fmt.Sprintf(lang.GetString(langCode, "devs_config_fixed"), userID)
+	default:
+		return ""
+	}
+}
+
+// devsHandler handles the /devs command.
+// It lists the developers with their names, marking the owner and those added with /adddev.
+func devsHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	var b strings.Builder
+	devs := cache.Devs()
+	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "devs_header"), len(devs)))
+	for _, id := range devs {
+		name := strconv.FormatInt(id, 10)
+		if user, err := m.Client.GetUser(id); err == nil && user != nil {
+			if full := strings.TrimSpace(user.FirstName + " " + user.LastName); full != "" {
+				name = core.TruncateDisplay(full, 40)
+			}
+		}
+
+		note := ""
+		switch {
+		case id == config.Conf.OwnerId:
+			note = lang.GetString(langCode, "devs_owner_note")
+		case !slices.Contains(config.Conf.DEVS, id):
+			note = lang.GetString(langCode, "devs_added_note")
+		}
+		b.WriteString(fmt.Sprintf(lang.GetString(langCode, "devs_item"), name, id, note))
+	}
+	return core.SendLong(m, b.String())
+}
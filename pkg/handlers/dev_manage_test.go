@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"strings"
+	"testing"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// useDevConfig sets the owner and the developers of the config, and the developers added at runtime, restoring the
+// config after the test.
+func useDevConfig(t *testing.T, owner int64, configured, added []int64) {
+	t.Helper()
+	saved := config.Conf
+	config.Conf = &config.BotConfig{OwnerId: owner, DEVS: configured}
+	cache.SetDevs(configured, added)
+	t.Cleanup(func() {
+		config.Conf = saved
+		var savedDevs []int64
+		if saved != nil {
+			savedDevs = saved.DEVS
+		}
+		cache.SetDevs(savedDevs, nil)
+	})
+}
+
+func TestDevCommandFilters(t *testing.T) {
+	const owner, configuredDev, addedDev, user = 10, 20, 30, 40
+	useDevConfig(t, owner, []int64{configuredDev, owner}, []int64{addedDev})
+
+	tests := []struct {
+		name        string
+		sender      int64
+		wantDev     bool
+		wantManages bool
+	}{
+		{"owner", owner, true, true},
+		{"configured developer", configuredDev, true, false},
+		{"added developer", addedDev, true, false},
+		{"user", user, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := groupMessage(1234567890, &telegram.PeerUser{UserID: tt.sender}, &telegram.UserObj{ID: tt.sender})
+			if got := isDev(m); got != tt.wantDev {
+				t.Errorf("isDev() = %v, want %v", got, tt.wantDev)
+			}
+			// /adddev and /rmdev are filtered with isOwner.
+			if got := isOwner(m); got != tt.wantManages {
+				t.Errorf("isOwner() = %v, want %v", got, tt.wantManages)
+			}
+		})
+	}
+}
+
+func TestIsOwnerWithoutOwner(t *testing.T) {
+	useDevConfig(t, 0, []int64{20}, nil)
+	m := groupMessage(1234567890, &telegram.PeerChannel{ChannelID: 1234567890}, nil)
+	if isOwner(m) {
+		t.Error("isOwner() = true for a message without a user sender when no owner is set")
+	}
+}
+
+func TestDevRemovalRefusal(t *testing.T) {
+	const owner, configuredDev, addedDev = 10, 20, 30
+	useDevConfig(t, owner, []int64{configuredDev, owner}, []int64{addedDev})
+
+	tests := []struct {
+		name   string
+		userID int64
+		want   string
+	}{
+		{"owner", owner, "devs_owner_fixed"},
+		{"configured developer", configuredDev, "devs_config_fixed"},
+		{"added developer", addedDev, ""},
+		{"not a developer", 40, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := devRemovalRefusal("en", tt.userID)
+			if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("devRemovalRefusal(%d) = %q, want %q", tt.userID, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,22 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core"
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/core/eventlog"
 	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+	"html"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/amarnathcjd/gogram/telegram"
 )
@@ -26,6 +37,9 @@ func activeVcHandler(m *telegram.NewMessage) error {
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "active_chats_header"), len(activeChats)))
+	if videoChats, slots := vc.Calls.VideoSlots(); slots > 0 {
+		sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "active_chats_video_slots"), len(videoChats), slots))
+	}
 
 	for _, chatID := range activeChats {
 		queueLength := cache.ChatCache.GetQueueLength(chatID)
@@ -35,13 +49,15 @@ func activeVcHandler(m *telegram.NewMessage) error {
 		if currentSong != nil {
 			songInfo = fmt.Sprintf(
 				lang.GetString(langCode, "now_playing_devs"),
-				currentSong.URL,
-				currentSong.Name,
+				core.FormatTrackLine(currentSong, core.TrackLineCompact),
 				currentSong.Duration,
 			)
 		} else {
 			songInfo = lang.GetString(langCode, "no_song_playing")
 		}
+		if vc.Calls.HasVideoSlot(chatID) {
+			songInfo += lang.GetString(langCode, "active_chat_video")
+		}
 
 		sb.WriteString(fmt.Sprintf(
 			lang.GetString(langCode, "chat_info"),
@@ -51,15 +67,229 @@ func activeVcHandler(m *telegram.NewMessage) error {
 		))
 	}
 
-	text := sb.String()
-	if len(text) > 4096 {
-		text = fmt.Sprintf(lang.GetString(langCode, "active_chats_header_short"), len(activeChats))
+	return core.SendLong(m, sb.String(), telegram.SendOptions{LinkPreview: false})
+}
+
+// forceResetHandler handles the /forcereset command.
+// It tears down every piece of state held for a chat and reports the outcome of each step.
+// It returns an error if any.
+func forceResetHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	targetID := chatID
+	if args := strings.TrimSpace(m.Args()); args != "" {
+		id, err := strconv.ParseInt(args, 10, 64)
+		if err != nil {
+			_, err = m.Reply(lang.GetString(langCode, "force_reset_invalid_chat"))
+			return err
+		}
+		targetID = id
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "force_reset_header"), targetID))
+	for _, step := range vc.Calls.ForceReset(targetID) {
+		if step.Err != nil {
+			sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "force_reset_step_fail"), step.Name, step.Err.Error()))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "force_reset_step_ok"), step.Name))
+	}
+
+	_, err := m.Reply(sb.String())
+	return err
+}
+
+// eventsHandler handles the /events command.
+// It replies with the most recent event log lines for the given chat.
+// It returns an error if any.
+func eventsHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	targetID := chatID
+	if args := strings.TrimSpace(m.Args()); args != "" {
+		id, err := strconv.ParseInt(args, 10, 64)
+		if err != nil {
+			_, err = m.Reply(lang.GetString(langCode, "events_invalid_chat"))
+			return err
+		}
+		targetID = id
+	}
+
+	lines, err := eventlog.Tail(targetID, 30)
+	if errors.Is(err, eventlog.ErrDisabled) {
+		_, err = m.Reply(lang.GetString(langCode, "events_disabled"))
+		return err
+	}
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "events_error"), err.Error()))
+		return err
+	}
+	if len(lines) == 0 {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "events_none"), targetID))
+		return err
+	}
+
+	body := html.EscapeString(strings.Join(lines, "\n"))
+	for len(body) > 3800 {
+		_, body, _ = strings.Cut(body, "\n")
+	}
+
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "events_header"), targetID, eventlog.Dropped(), body))
+	return err
+}
+
+// purgeCacheHandler handles the /purgecache command.
+// It clears the in-memory database caches, and the admin cache when "admins" is passed.
+// It returns an error if any.
+func purgeCacheHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	db.Instance.ClearCaches()
+	if !strings.EqualFold(strings.TrimSpace(m.Args()), "admins") {
+		_, err := m.Reply(lang.GetString(langCode, "purge_cache_done"))
+		return err
+	}
+
+	cache.AdminCache.Clear()
+	_, err := m.Reply(lang.GetString(langCode, "purge_cache_done_admins"))
+	return err
+}
+
+// redactKey hides all but the first few characters of a secret.
+func redactKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + strings.Repeat("*", 8)
+}
+
+// apiTestHandler handles the /apitest command.
+// It checks the API gateway connectivity and key, and reports the status and latency.
+// It returns an error if any.
+func apiTestHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer reqCancel()
+	health := dl.CheckApi(reqCtx)
+
+	var verdict string
+	switch {
+	case health.Err != nil:
+		verdict = fmt.Sprintf(lang.GetString(langCode, "api_test_unreachable"), health.Err.Error())
+	case !health.KeyAccepted:
+		verdict = lang.GetString(langCode, "api_test_bad_key")
+	case health.StatusCode == http.StatusTooManyRequests:
+		verdict = lang.GetString(langCode, "api_test_rate_limited")
+	case health.StatusCode >= 500:
+		verdict = lang.GetString(langCode, "api_test_gateway_down")
+	case health.StatusCode != 200:
+		verdict = lang.GetString(langCode, "api_test_unexpected")
+	default:
+		verdict = lang.GetString(langCode, "api_test_ok")
+	}
+
+	status := health.Status
+	if status == "" {
+		status = "-"
+	}
+	if cooldown := dl.ApiCooldown(); cooldown > 0 {
+		verdict += "\n" + fmt.Sprintf(lang.GetString(langCode, "api_test_cooldown"), cooldown.Round(time.Second))
 	}
 
+	text := fmt.Sprintf(
+		lang.GetString(langCode, "api_test_result"),
+		health.URL,
+		redactKey(config.Conf.ApiKey),
+		status,
+		health.Latency.Round(time.Millisecond),
+		verdict,
+	)
 	_, err := m.Reply(text, telegram.SendOptions{LinkPreview: false})
+	return err
+}
+
+// assistantPoolText lists the available and disabled assistants.
+func assistantPoolText(langCode string) string {
+	available, disabled := vc.Calls.AssistantPool()
+	none := lang.GetString(langCode, "assistant_pool_none")
+	return fmt.Sprintf(lang.GetString(langCode, "assistant_pool"), coalesce(strings.Join(available, ", "), none), coalesce(strings.Join(disabled, ", "), none))
+}
+
+// assistantPoolError returns the message for an error from DisableAssistant or EnableAssistant.
+func assistantPoolError(langCode, name string, err error) string {
+	switch {
+	case errors.Is(err, vc.ErrUnknownAssistant):
+		return fmt.Sprintf(lang.GetString(langCode, "assistant_unknown"), html.EscapeString(name)) + "\n\n" + assistantPoolText(langCode)
+	case errors.Is(err, vc.ErrAssistantDisabled):
+		return fmt.Sprintf(lang.GetString(langCode, "assistant_already_disabled"), name)
+	case errors.Is(err, vc.ErrAssistantEnabled):
+		return fmt.Sprintf(lang.GetString(langCode, "assistant_already_enabled"), name)
+	case errors.Is(err, vc.ErrLastAssistant):
+		return lang.GetString(langCode, "assistant_last")
+	default:
+		return fmt.Sprintf(lang.GetString(langCode, "assistant_pool_error"), err.Error())
+	}
+}
+
+// disableAssistantHandler handles the /disableassistant command.
+// It takes an assistant out of the pool and moves the chats playing on it to other assistants.
+// It returns an error if any.
+func disableAssistantHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	name := strings.TrimSpace(m.Args())
+	if name == "" {
+		_, err := m.Reply(lang.GetString(langCode, "disable_assistant_usage") + "\n\n" + assistantPoolText(langCode))
+		return err
+	}
+
+	moved, err := vc.Calls.DisableAssistant(name)
 	if err != nil {
+		_, err = m.Reply(assistantPoolError(langCode, name, err))
+		return err
+	}
+
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "assistant_disabled"), name, moved) + "\n\n" + assistantPoolText(langCode))
+	return err
+}
+
+// enableAssistantHandler handles the /enableassistant command.
+// It puts an assistant back into the pool.
+// It returns an error if any.
+func enableAssistantHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	name := strings.TrimSpace(m.Args())
+	if name == "" {
+		_, err := m.Reply(lang.GetString(langCode, "enable_assistant_usage") + "\n\n" + assistantPoolText(langCode))
+		return err
+	}
+
+	if err := vc.Calls.EnableAssistant(name); err != nil {
+		_, err = m.Reply(assistantPoolError(langCode, name, err))
 		return err
 	}
 
-	return nil
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "assistant_enabled"), name) + "\n\n" + assistantPoolText(langCode))
+	return err
 }
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// djHandler handles the /dj command.
+// DJs may use the playback controls (skip, stop, pause, ...) without being administrators or authorized users.
+// It returns an error if any.
+func djHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	action, target, _ := strings.Cut(strings.TrimSpace(m.Args()), " ")
+	switch strings.ToLower(action) {
+	case "list":
+		djs := db.Instance.GetDJs(ctx, chatID)
+		if len(djs) == 0 {
+			_, err := m.Reply(lang.GetString(langCode, "dj_list_empty"))
+			return err
+		}
+		var b strings.Builder
+		b.WriteString(lang.GetString(langCode, "dj_list"))
+		for _, uid := range djs {
+			b.WriteString(fmt.Sprintf("• <code>%d</code>\n", uid))
+		}
+		return core.SendLong(m, b.String())
+
+	case "add", "remove", "rm", "del":
+		userID, err := resolveTargetUser(m, strings.TrimSpace(target), langCode)
+		if err != nil {
+			_, _ = m.Reply(err.Error())
+			return nil
+		}
+
+		isDJ := db.Instance.IsDJ(ctx, chatID, userID)
+		if strings.EqualFold(action, "add") {
+			if isDJ {
+				_, err = m.Reply(lang.GetString(langCode, "dj_already_added"))
+				return err
+			}
+			if err = db.Instance.AddDJ(ctx, chatID, userID); err != nil {
+				gologging.Error("Failed to add DJ:", err)
+				_, err = m.Reply(lang.GetString(langCode, "dj_error"))
+				return err
+			}
+			_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "dj_added"), userID))
+			return err
+		}
+
+		if !isDJ {
+			_, err = m.Reply(lang.GetString(langCode, "dj_not_dj"))
+			return err
+		}
+		if err = db.Instance.RemoveDJ(ctx, chatID, userID); err != nil {
+			gologging.Error("Failed to remove DJ:", err)
+			_, err = m.Reply(lang.GetString(langCode, "dj_error"))
+			return err
+		}
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "dj_removed"), userID))
+		return err
+
+	default:
+		_, err := m.Reply(lang.GetString(langCode, "dj_usage"))
+		return err
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// renderDownloads builds the /downloads view from a snapshot of the downloads in progress, with a cancel button for each.
+func renderDownloads(langCode string) (string, *telegram.ReplyInlineMarkup) {
+	downloads := dl.ActiveDownloads()
+	keyboard := telegram.NewKeyboard()
+	if len(downloads) == 0 {
+		return lang.GetString(langCode, "downloads_none"), keyboard.AddRow(downloadsRefreshBtn(langCode), core.CloseBtn).Build()
+	}
+
+	var b strings.Builder
+	now := time.Now()
+	for _, d := range downloads {
+		progress := lang.GetString(langCode, "downloads_progress_unknown")
+		switch {
+		case d.Total > 0:
+			progress = fmt.Sprintf("%d%% (%s / %s)", d.Done*100/d.Total, humanBytes(uint64(d.Done)), humanBytes(uint64(d.Total)))
+		case d.Done > 0:
+			progress = humanBytes(uint64(d.Done))
+		}
+		deadline := "-"
+		if !d.Deadline.IsZero() {
+			deadline = d.Deadline.Sub(now).Round(time.Second).String()
+		}
+
+		b.WriteString(fmt.Sprintf(lang.GetString(langCode, "downloads_entry"),
+			d.ID, core.TruncateDisplay(d.Name, 40), d.Platform, d.ChatID, now.Sub(d.Started).Round(time.Second), progress, deadline))
+		b.WriteString("\n")
+		keyboard.AddRow(telegram.Button.Data(fmt.Sprintf(lang.GetString(langCode, "downloads_cancel_button"), d.ID), fmt.Sprintf("downloads_cancel_%d", d.ID)))
+	}
+	keyboard.AddRow(downloadsRefreshBtn(langCode), core.CloseBtn)
+
+	return fmt.Sprintf(lang.GetString(langCode, "downloads_header"), len(downloads), b.String()), keyboard.Build()
+}
+
+// downloadsRefreshBtn returns the button that renders the /downloads view again.
+func downloadsRefreshBtn(langCode string) telegram.KeyboardButton {
+	return telegram.Button.Data(lang.GetString(langCode, "downloads_refresh_button"), "downloads_refresh")
+}
+
+// downloadsHandler handles the /downloads command.
+// It lists the downloads in progress, oldest first.
+func downloadsHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	text, keyboard := renderDownloads(langCode)
+	_, err := m.Reply(text, telegram.SendOptions{ReplyMarkup: keyboard})
+	return err
+}
+
+// downloadsCallbackHandler handles the cancel and refresh buttons of /downloads.
+func downloadsCallbackHandler(cb *telegram.CallbackQuery) error {
+	chatID, _ := getPeerId(cb.Client, cb.ChatID)
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	if !cache.IsDev(cb.SenderID) {
+		_, _ = cb.Answer(lang.GetString(langCode, "downloads_not_dev"), &telegram.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	answer := ""
+	if idText, ok := strings.CutPrefix(cb.DataString(), "downloads_cancel_"); ok {
+		id, _ := strconv.ParseUint(idText, 10, 64)
+		answer = lang.GetString(langCode, "downloads_cancelled")
+		if !dl.CancelDownload(id) {
+			answer = lang.GetString(langCode, "downloads_gone")
+		}
+	}
+
+	_, _ = cb.Answer(answer)
+	text, keyboard := renderDownloads(langCode)
+	_, _ = cb.Edit(text, &telegram.SendOptions{ReplyMarkup: keyboard})
+	return nil
+}
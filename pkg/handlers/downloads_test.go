@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// callbackData returns the data of every callback button of a keyboard, row by row.
+func callbackData(keyboard *telegram.ReplyInlineMarkup) []string {
+	var data []string
+	for _, row := range keyboard.Rows {
+		for _, button := range row.Buttons {
+			if cb, ok := button.(*telegram.KeyboardButtonCallback); ok {
+				data = append(data, string(cb.Data))
+			}
+		}
+	}
+	return data
+}
+
+func TestRenderDownloads(t *testing.T) {
+	text, keyboard := renderDownloads("en")
+	if text != "downloads_none" || !reflect.DeepEqual(callbackData(keyboard), []string{"downloads_refresh", "vcplay|close"}) {
+		t.Errorf("renderDownloads() with no downloads = %q with buttons %v, want downloads_none with refresh and close", text, callbackData(keyboard))
+	}
+
+	ctx, done := dl.StartDownload(context.Background(), -6001, "song", "youtube")
+	defer done()
+	dl.SetDownloadProgress(ctx, 50, 200)
+	id := dl.ActiveDownloads()[0].ID
+
+	text, keyboard = renderDownloads("en")
+	for _, want := range []string{"25% (50 B / 200 B)", "song", "youtube"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("renderDownloads() = %q, want it to contain %q", text, want)
+		}
+	}
+	want := []string{"downloads_cancel_" + strconv.FormatUint(id, 10), "downloads_refresh", "vcplay|close"}
+	if got := callbackData(keyboard); !reflect.DeepEqual(got, want) {
+		t.Errorf("renderDownloads() buttons = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// CapabilitiesSchemaVersion is the version of the Capabilities layout. It is raised whenever a field is renamed,
+// removed or changes meaning, so that integrators can tell which layout they are reading; new fields do not raise it.
+const CapabilitiesSchemaVersion = 1
+
+// Capabilities describes what this instance supports, for tools built around the bot.
+// It holds nothing secret: no tokens, keys, URLs or chat IDs.
+type Capabilities struct {
+	SchemaVersion      int             `json:"schema_version"`
+	Version            string          `json:"version"`
+	Platforms          []string        `json:"platforms"`
+	DefaultService     string          `json:"default_service"`
+	APIGateway         bool            `json:"api_gateway"`
+	MaxFileSize        int64           `json:"max_file_size"`
+	MaxDuration        int             `json:"max_duration"`
+	MaxPodcastDuration int             `json:"max_podcast_duration"`
+	MaxPlaylistTracks  int             `json:"max_playlist_tracks"`
+	VideoMaxHeight     int             `json:"video_max_height"`
+	AudioSampleRate    int             `json:"audio_sample_rate"`
+	MaxVideoSessions   int             `json:"max_video_sessions"`
+	Assistants         int             `json:"assistants"`
+	Languages          []string        `json:"languages"`
+	Maintenance        bool            `json:"maintenance"`
+	Features           map[string]bool `json:"features"`
+}
+
+// GetCapabilities assembles the capabilities of the instance from its configuration and current state.
+func GetCapabilities() Capabilities {
+	return Capabilities{
+		SchemaVersion:      CapabilitiesSchemaVersion,
+		Version:            config.Conf.Version,
+		Platforms:          dl.EnabledPlatforms(),
+		DefaultService:     config.Conf.DefaultService,
+		APIGateway:         config.Conf.ApiUrl != "" && config.Conf.ApiKey != "",
+		MaxFileSize:        config.Conf.MaxFileSize,
+		MaxDuration:        config.Conf.MaxDuration,
+		MaxPodcastDuration: config.Conf.MaxPodcastDuration,
+		MaxPlaylistTracks:  config.Conf.MaxPlaylistTracks,
+		VideoMaxHeight:     config.Conf.VideoMaxHeight,
+		AudioSampleRate:    config.Conf.AudioSampleRate,
+		MaxVideoSessions:   config.Conf.MaxVideoSessions,
+		Assistants:         len(vc.Calls.JoinedCalls()),
+		Languages:          lang.GetAvailableLangs(),
+		Maintenance:        cache.IsMaintenance(),
+		Features: map[string]bool{
+			"adaptive_audio":  config.Conf.AdaptiveAudio,
+			"auto_reconnect":  config.Conf.AutoReconnect,
+			"autoplay":        true,
+			"download_checks": config.Conf.ValidateDownloads,
+			"event_log":       config.Conf.EventLogPath != "",
+			"incoming_calls":  config.Conf.IncomingCallMode != "off",
+			"silence_check":   config.Conf.SilenceCheck,
+			"telemetry":       config.Conf.TelemetryURL != "",
+			"video":           true,
+			"webhooks":        false,
+		},
+	}
+}
+
+// ServeCapabilities serves GET /api/capabilities with the capabilities of the instance as JSON.
+// It needs no authentication, as the capabilities hold nothing sensitive.
+func ServeCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(GetCapabilities()); err != nil {
+		gologging.WarnF("[Capabilities] Failed to write the response: %v", err)
+	}
+}
+
+// featuresHandler handles the /features command.
+// It describes in private what this instance supports, the readable form of /api/capabilities.
+func featuresHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if !m.IsPrivate() {
+		_, err := m.Reply(lang.GetString(langCode, "features_private_only"))
+		return err
+	}
+
+	caps := GetCapabilities()
+	yesNo := func(ok bool) string {
+		if ok {
+			return lang.GetString(langCode, "features_on")
+		}
+		return lang.GetString(langCode, "features_off")
+	}
+	limit := func(n int, format func(int) string) string {
+		if n <= 0 {
+			return lang.GetString(langCode, "features_unlimited")
+		}
+		return format(n)
+	}
+
+	var features strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(caps.Features)) {
+		features.WriteString(fmt.Sprintf(lang.GetString(langCode, "features_flag"), name, yesNo(caps.Features[name])))
+	}
+
+	text := fmt.Sprintf(lang.GetString(langCode, "features_text"),
+		caps.Version,
+		strings.Join(caps.Platforms, ", "),
+		yesNo(caps.APIGateway),
+		caps.MaxFileSize/(1024*1024),
+		limit(caps.MaxDuration, cache.SecToMin),
+		limit(caps.MaxPodcastDuration, cache.SecToMin),
+		caps.MaxPlaylistTracks,
+		caps.VideoMaxHeight,
+		limit(caps.MaxVideoSessions, func(n int) string { return fmt.Sprint(n) }),
+		caps.Assistants,
+		strings.Join(caps.Languages, ", "),
+		yesNo(caps.Maintenance),
+		features.String(),
+	)
+	_, err := m.Reply(text)
+	return err
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/zuchzub/Go/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// capabilityConfig lists the configuration fields that Capabilities is derived from, some only as a yes or no.
+var capabilityConfig = []string{
+	"AdaptiveAudio", "ApiKey", "ApiUrl", "AudioSampleRate", "AutoReconnect", "DefaultService", "EventLogPath",
+	"IncomingCallMode", "MaxDuration", "MaxFileSize", "MaxPlaylistTracks", "MaxPodcastDuration", "MaxVideoSessions",
+	"SilenceCheck", "TelemetryURL", "ValidateDownloads", "Version", "VideoMaxHeight",
+}
+
+// privateConfig lists, with the reason, the configuration fields that Capabilities leaves out on purpose.
+// A new configuration field has to be added to one of the two lists, so that the capabilities stay a reviewed choice.
+var privateConfig = map[string]string{
+	"ApiId":                 "credential",
+	"ApiHash":               "credential",
+	"Token":                 "credential",
+	"SessionStrings":        "credential",
+	"MongoUri":              "credential",
+	"SessionKey":            "credential",
+	"DbName":                "deployment detail",
+	"OwnerId":               "user ID",
+	"DEVS":                  "user IDs",
+	"LoggerId":              "chat ID",
+	"SupportGroup":          "shown by /start already",
+	"SupportChannel":        "shown by /start already",
+	"Proxy":                 "deployment detail",
+	"Port":                  "deployment detail",
+	"PublicURL":             "deployment detail",
+	"DownloadsDir":          "host path",
+	"CookiesPath":           "host path",
+	"FFmpegPath":            "host path",
+	"FFprobePath":           "host path",
+	"FFmpegExtraArgs":       "host tuning",
+	"EventLogMaxSize":       "host tuning",
+	"EventLogKeep":          "host tuning",
+	"NetworkFamily":         "host tuning",
+	"MinMemberCount":        "internal policy",
+	"DownloadRetries":       "internal tuning",
+	"RetryStatusCodes":      "internal tuning",
+	"DownloadStrategies":    "internal tuning",
+	"SilenceCheckPlatforms": "covered by the silence_check feature",
+	"IncomingCallMedia":     "covered by the incoming_calls feature",
+	"ReconnectRetries":      "covered by the auto_reconnect feature",
+	"PodcastEpisodes":       "internal tuning",
+	"ShutdownDrain":         "internal tuning",
+	"ShutdownDrainTimeout":  "internal tuning",
+	"AssistantRejoinGrace":  "internal tuning",
+	"PlaybackStartTimeout":  "internal tuning",
+	"StaleCommandAge":       "internal tuning",
+	"CommandStatsFlush":     "internal tuning",
+}
+
+func TestCapabilitiesCoverConfig(t *testing.T) {
+	exposed := make(map[string]bool, len(capabilityConfig))
+	for _, name := range capabilityConfig {
+		exposed[name] = true
+	}
+
+	fields := make(map[string]bool)
+	typ := reflect.TypeOf(config.BotConfig{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fields[field.Name] = true
+		_, private := privateConfig[field.Name]
+		switch {
+		case exposed[field.Name] && private:
+			t.Errorf("config field %s is listed as both exposed and private", field.Name)
+		case !exposed[field.Name] && !private:
+			t.Errorf("config field %s is new: expose it in Capabilities or add it to privateConfig with the reason", field.Name)
+		}
+	}
+
+	for name := range exposed {
+		if !fields[name] {
+			t.Errorf("capabilityConfig lists %s, which is not a config field", name)
+		}
+	}
+	for name := range privateConfig {
+		if !fields[name] {
+			t.Errorf("privateConfig lists %s, which is not a config field", name)
+		}
+	}
+}
+
+func TestServeCapabilities(t *testing.T) {
+	saved := config.Conf
+	config.Conf = &config.BotConfig{Version: "1.2.3", MaxFileSize: 500 * 1024 * 1024, ApiKey: "secret-key", ApiUrl: "https://gateway.example"}
+	defer func() { config.Conf = saved }()
+
+	rec := httptest.NewRecorder()
+	ServeCapabilities(rec, httptest.NewRequest(http.MethodGet, "/api/capabilities", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("GET = %d with Content-Type %q, want 200 and JSON", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var caps Capabilities
+	if err := json.Unmarshal(rec.Body.Bytes(), &caps); err != nil {
+		t.Fatalf("the response is not JSON: %v", err)
+	}
+	if caps.SchemaVersion != CapabilitiesSchemaVersion || caps.Version != "1.2.3" || caps.MaxFileSize != 500*1024*1024 || !caps.APIGateway {
+		t.Errorf("capabilities = %+v, want the schema version and the configured values", caps)
+	}
+	for _, secret := range []string{"secret-key", "gateway.example"} {
+		if strings.Contains(rec.Body.String(), secret) {
+			t.Errorf("the capabilities reveal %q", secret)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	ServeCapabilities(rec, httptest.NewRequest(http.MethodPost, "/api/capabilities", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST = %d with Allow %q, want 405 and GET, HEAD", rec.Code, rec.Header().Get("Allow"))
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"github.com/zuchzub/Go/pkg/config"
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
@@ -15,12 +16,12 @@ import (
 // It takes a telegram.NewMessage object as input.
 // It returns true if the user is a developer, otherwise false.
 func isDev(m *telegram.NewMessage) bool {
-	for _, dev := range config.Conf.DEVS {
-		if dev == m.SenderID() {
-			return true
-		}
-	}
-	return false
+	return cache.IsDev(m.SenderID())
+}
+
+// isOwner checks if the message is from the owner of the bot.
+func isOwner(m *telegram.NewMessage) bool {
+	return config.Conf.OwnerId != 0 && m.SenderID() == config.Conf.OwnerId
 }
 
 // adminMode checks if the bot is an admin in the chat.
@@ -29,6 +30,17 @@ func isDev(m *telegram.NewMessage) bool {
 // Handle Admin Mode
 // It returns true if the bot is an admin, otherwise false.
 func adminMode(m *telegram.NewMessage) bool {
+	return checkAdminMode(m, false)
+}
+
+// controlMode is adminMode for playback controls such as /skip, /stop and /pause,
+// which the chat's DJs may use as well as the users adminMode lets through.
+func controlMode(m *telegram.NewMessage) bool {
+	return checkAdminMode(m, true)
+}
+
+// checkAdminMode implements adminMode and controlMode. allowDJ lets the chat's DJs through in the admins and auth modes.
+func checkAdminMode(m *telegram.NewMessage, allowDJ bool) bool {
 	if m.IsPrivate() {
 		return false
 	}
@@ -62,35 +74,56 @@ func adminMode(m *telegram.NewMessage) bool {
 		_, _ = m.Reply(lang.GetString(langCode, "filter_bot_no_invite_permission"))
 		return false
 	}
-	userID := m.SenderID()
+	_, userID, anonymousAdmin := resolveActor(m)
+	if key := adminModeRefusal(ctx, db.Instance, chatID, userID, anonymousAdmin, allowDJ); key != "" {
+		_, _ = m.Reply(lang.GetString(langCode, key))
+		return false
+	}
+	return true
+}
 
-	getAdminMode := db.Instance.GetAdminMode(ctx, chatID)
-	if getAdminMode == cache.Everyone {
-		return true
+// adminModeRefusal decides whether the actor of a command passes the chat's admin mode. It returns the key of the
+// message refusing them, or "" if they pass. An anonymous admin always passes; see resolveActor.
+func adminModeRefusal(ctx context.Context, store storage, chatID, userID int64, anonymousAdmin, allowDJ bool) string {
+	if anonymousAdmin {
+		return ""
 	}
 
-	if getAdminMode == cache.Admins {
-		if db.Instance.IsAdmin(ctx, chatID, userID) {
-			return true
+	switch store.GetAdminMode(ctx, chatID) {
+	case cache.Everyone:
+		return ""
+	case cache.Admins:
+		if store.IsAdmin(ctx, chatID, userID) || (allowDJ && store.IsDJ(ctx, chatID, userID)) {
+			return ""
+		}
+		return "filter_not_admin"
+	case cache.Auth:
+		if store.IsAuthUser(ctx, chatID, userID) || (allowDJ && store.IsDJ(ctx, chatID, userID)) {
+			return ""
 		}
-		_, _ = m.Reply(lang.GetString(langCode, "filter_not_admin"))
-		return false
 	}
+	return "filter_not_authorized"
+}
 
-	if getAdminMode == cache.Auth {
-		if db.Instance.IsAuthUser(ctx, chatID, userID) {
-			return true
-		}
-		_, _ = m.Reply(lang.GetString(langCode, "filter_not_authorized"))
+// canManageQueue reports whether a user passes the chat's admin mode, or is one of its DJs, without replying.
+// It is used by handlers registered under playMode that give admins more rights than other users.
+func (hc *handlerContext) canManageQueue(ctx context.Context, chatID, userID int64) bool {
+	switch hc.store.GetAdminMode(ctx, chatID) {
+	case cache.Everyone:
+		return true
+	case cache.Admins:
+		return hc.store.IsAdmin(ctx, chatID, userID) || hc.store.IsDJ(ctx, chatID, userID)
+	case cache.Auth:
+		return hc.store.IsAuthUser(ctx, chatID, userID) || hc.store.IsDJ(ctx, chatID, userID)
+	default:
 		return false
 	}
-
-	_, _ = m.Reply(lang.GetString(langCode, "filter_not_authorized"))
-	return false
 }
 
+// adminModeCB is controlMode for the playback control buttons.
+// Buttons are always pressed by a user, never by an anonymous admin, so unlike adminMode it has no such case.
 func adminModeCB(cb *telegram.CallbackQuery) bool {
-	chatID, err := getPeerId(cb.Client, cb.ChatID)
+	chatID, err := musicChatID(cb)
 	if err != nil {
 		gologging.WarnF("getPeerId error: %v", err)
 		return false
@@ -130,7 +163,7 @@ func adminModeCB(cb *telegram.CallbackQuery) bool {
 	}
 
 	if getAdminMode == cache.Admins {
-		if db.Instance.IsAdmin(ctx, chatID, userID) {
+		if db.Instance.IsAdmin(ctx, chatID, userID) || db.Instance.IsDJ(ctx, chatID, userID) {
 			return true
 		}
 		_, _ = cb.Answer(lang.GetString(langCode, "filter_not_admin"), opts)
@@ -138,7 +171,7 @@ func adminModeCB(cb *telegram.CallbackQuery) bool {
 	}
 
 	if getAdminMode == cache.Auth {
-		if db.Instance.IsAuthUser(ctx, chatID, userID) {
+		if db.Instance.IsAuthUser(ctx, chatID, userID) || db.Instance.IsDJ(ctx, chatID, userID) {
 			return true
 		}
 		_, _ = cb.Answer(lang.GetString(langCode, "filter_not_authorized"), opts)
@@ -149,6 +182,19 @@ func adminModeCB(cb *telegram.CallbackQuery) bool {
 	return false
 }
 
+// songMode is the filter of /song, which works in private chats as well as in groups.
+// It only refuses requests while the bot is in maintenance, like the playback commands.
+func songMode(m *telegram.NewMessage) bool {
+	if !cache.IsMaintenance() {
+		return true
+	}
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	_, _ = m.Reply(maintenanceMessage(db.Instance.GetLang(ctx, chatID)))
+	return false
+}
+
 func playMode(m *telegram.NewMessage) bool {
 	if m.IsPrivate() {
 		return false
@@ -161,6 +207,11 @@ func playMode(m *telegram.NewMessage) bool {
 	ctx, cancel := db.Ctx()
 	defer cancel()
 	langCode := db.Instance.GetLang(ctx, chatID)
+	if cache.IsMaintenance() {
+		_, _ = m.Reply(maintenanceMessage(langCode))
+		return false
+	}
+
 	botStatus, err := cache.GetUserAdmin(m.Client, chatID, m.Client.Me().ID, false)
 	if err != nil {
 		if strings.Contains(err.Error(), "is not an admin in chat") {
@@ -182,34 +233,47 @@ func playMode(m *telegram.NewMessage) bool {
 		_, _ = m.Reply(lang.GetString(langCode, "filter_bot_no_invite_permission"))
 		return false
 	}
-	getPlayMode := db.Instance.GetPlayMode(ctx, chatID)
-	if getPlayMode != cache.Everyone {
+	_, userID, anonymousAdmin := resolveActor(m)
+	isAdmin := func() (bool, error) {
 		admins, err := cache.GetAdmins(m.Client, chatID, false)
 		if err != nil {
-			gologging.WarnF("getAdmins error: %v", err)
-			return false
+			return false, err
 		}
-
-		var isAdmin bool
 		for _, admin := range admins {
-			if admin.User.ID == m.Sender.ID {
-				isAdmin = true
-				break
-			}
-		}
-
-		if !isAdmin {
-			if getPlayMode == cache.Auth {
-				if !db.Instance.IsAuthUser(ctx, chatID, m.Sender.ID) {
-					_, _ = m.Reply(lang.GetString(langCode, "filter_not_authorized_command"))
-					return false
-				}
-			} else {
-				_, _ = m.Reply(lang.GetString(langCode, "filter_not_authorized_command"))
-				return false
+			if admin.User.ID == userID {
+				return true, nil
 			}
 		}
+		return false, nil
 	}
 
+	key, err := playModeRefusal(ctx, db.Instance, chatID, userID, db.Instance.GetPlayMode(ctx, chatID), anonymousAdmin, isAdmin)
+	if err != nil {
+		gologging.WarnF("getAdmins error: %v", err)
+		return false
+	}
+	if key != "" {
+		_, _ = m.Reply(lang.GetString(langCode, key))
+		return false
+	}
 	return true
 }
+
+// playModeRefusal decides whether the actor of a command passes the chat's play mode. It returns the key of the
+// message refusing them, or "" if they pass. isAdmin reports whether the actor is an admin of the chat, and is only
+// called when that matters. An anonymous admin always passes; see resolveActor.
+func playModeRefusal(ctx context.Context, store storage, chatID, userID int64, playMode string, anonymousAdmin bool,
+	isAdmin func() (bool, error)) (string, error) {
+	if playMode == cache.Everyone || anonymousAdmin {
+		return "", nil
+	}
+
+	admin, err := isAdmin()
+	if err != nil {
+		return "", err
+	}
+	if admin || (playMode == cache.Auth && store.IsAuthUser(ctx, chatID, userID)) {
+		return "", nil
+	}
+	return "filter_not_authorized_command", nil
+}
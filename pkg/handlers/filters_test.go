@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/handlers/testsupport"
+	"testing"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// groupMessage fabricates a message in the supergroup with channel ID groupID, sent by from.
+func groupMessage(groupID int64, from telegram.Peer, sender *telegram.UserObj) *telegram.NewMessage {
+	return &telegram.NewMessage{
+		Message: &telegram.MessageObj{PeerID: &telegram.PeerChannel{ChannelID: groupID}, FromID: from},
+		Sender:  sender,
+	}
+}
+
+func TestResolveActor(t *testing.T) {
+	const groupID = 1234567890
+	tests := []struct {
+		name          string
+		m             *telegram.NewMessage
+		wantName      string
+		wantUserID    int64
+		wantAnonymous bool
+	}{
+		{
+			"user", groupMessage(groupID, &telegram.PeerUser{UserID: 11}, &telegram.UserObj{ID: 11, FirstName: "Ann"}),
+			"Ann", 11, false,
+		},
+		{
+			"anonymous admin", groupMessage(groupID, &telegram.PeerChannel{ChannelID: groupID}, nil),
+			anonymousAdminName, 0, true,
+		},
+		{
+			// Some updates carry the group as the sender too; the actor is still no user.
+			"anonymous admin with the group as sender", groupMessage(groupID, &telegram.PeerChannel{ChannelID: groupID}, &telegram.UserObj{ID: groupID}),
+			anonymousAdminName, 0, true,
+		},
+		{
+			"posted as a linked channel", groupMessage(groupID, &telegram.PeerChannel{ChannelID: 999}, nil),
+			"", 999, false,
+		},
+		{
+			"user without sender details", groupMessage(groupID, &telegram.PeerUser{UserID: 12}, nil),
+			"", 12, false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, userID, anonymous := resolveActor(tt.m)
+			if name != tt.wantName || userID != tt.wantUserID || anonymous != tt.wantAnonymous {
+				t.Errorf("resolveActor() = %q, %d, %t; want %q, %d, %t", name, userID, anonymous, tt.wantName, tt.wantUserID, tt.wantAnonymous)
+			}
+		})
+	}
+}
+
+func TestAdminModeRefusal(t *testing.T) {
+	const admin, auth, dj, user = 1, 2, 3, 4
+	tests := []struct {
+		name      string
+		mode      string
+		userID    int64
+		anonymous bool
+		allowDJ   bool
+		want      string
+	}{
+		{"everyone", cache.Everyone, user, false, false, ""},
+		{"admins mode, admin", cache.Admins, admin, false, false, ""},
+		{"admins mode, user", cache.Admins, user, false, false, "filter_not_admin"},
+		{"admins mode, anonymous admin", cache.Admins, 0, true, false, ""},
+		{"admins mode, DJ of a control", cache.Admins, dj, false, true, ""},
+		{"admins mode, DJ of another command", cache.Admins, dj, false, false, "filter_not_admin"},
+		{"auth mode, auth user", cache.Auth, auth, false, false, ""},
+		{"auth mode, user", cache.Auth, user, false, false, "filter_not_authorized"},
+		{"auth mode, anonymous admin", cache.Auth, 0, true, false, ""},
+		{"auth mode, DJ of a control", cache.Auth, dj, false, true, ""},
+		{"unknown mode", "nobody", admin, false, false, "filter_not_authorized"},
+		{"unknown mode, anonymous admin", "nobody", 0, true, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &testsupport.Store{AdminMode: tt.mode, Admins: []int64{admin}, AuthUsers: []int64{auth}, DJs: []int64{dj}}
+			if got := adminModeRefusal(context.Background(), store, testChatID, tt.userID, tt.anonymous, tt.allowDJ); got != tt.want {
+				t.Errorf("adminModeRefusal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlayModeRefusal(t *testing.T) {
+	const admin, auth, user = 1, 2, 4
+	adminsErr := errors.New("CHAT_ADMIN_REQUIRED")
+	tests := []struct {
+		name      string
+		mode      string
+		userID    int64
+		anonymous bool
+		adminsErr error
+		want      string
+		wantErr   bool
+		wantAsked bool // wantAsked is whether the admin list had to be fetched.
+	}{
+		{"everyone", cache.Everyone, user, false, nil, "", false, false},
+		{"admins mode, admin", cache.Admins, admin, false, nil, "", false, true},
+		{"admins mode, auth user", cache.Admins, auth, false, nil, "filter_not_authorized_command", false, true},
+		{"admins mode, anonymous admin", cache.Admins, 0, true, nil, "", false, false},
+		{"auth mode, auth user", cache.Auth, auth, false, nil, "", false, true},
+		{"auth mode, user", cache.Auth, user, false, nil, "filter_not_authorized_command", false, true},
+		{"auth mode, anonymous admin", cache.Auth, 0, true, adminsErr, "", false, false},
+		{"admin list unavailable", cache.Admins, admin, false, adminsErr, "", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &testsupport.Store{AuthUsers: []int64{auth}}
+			asked := false
+			isAdmin := func() (bool, error) {
+				asked = true
+				return tt.userID == admin, tt.adminsErr
+			}
+
+			got, err := playModeRefusal(context.Background(), store, testChatID, tt.userID, tt.mode, tt.anonymous, isAdmin)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("playModeRefusal() = %q, %v; want %q, error %t", got, err, tt.want, tt.wantErr)
+			}
+			if asked != tt.wantAsked {
+				t.Errorf("admin list fetched = %t, want %t", asked, tt.wantAsked)
+			}
+		})
+	}
+}
+
+func TestMayAnswerPrompt(t *testing.T) {
+	const owner, admin, user = 11, 12, 13
+	store := &testsupport.Store{Admins: []int64{admin}}
+	tests := []struct {
+		name    string
+		ownerID int64
+		sender  int64
+		want    bool
+	}{
+		{"owner", owner, owner, true},
+		{"admin answering a user's prompt", owner, admin, false},
+		{"other user", owner, user, false},
+		{"admin answering an anonymous admin's prompt", 0, admin, true},
+		{"user answering an anonymous admin's prompt", 0, user, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mayAnswerPrompt(context.Background(), store, testChatID, tt.ownerID, tt.sender); got != tt.want {
+				t.Errorf("mayAnswerPrompt(%d, %d) = %v, want %v", tt.ownerID, tt.sender, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
 	"github.com/zuchzub/Go/pkg/core"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
+	"runtime"
 	"strings"
 
 	"github.com/amarnathcjd/gogram/telegram"
@@ -57,21 +59,27 @@ func helpCallbackHandler(cb *telegram.CallbackQuery) error {
 	if strings.Contains(data, "help_all") {
 		_, _ = cb.Answer(lang.GetString(langCode, "opening_help_menu"), &telegram.CallbackOptions{Alert: true})
 		response := fmt.Sprintf(lang.GetString(langCode, "start_text"), cb.Sender.FirstName, cb.Client.Me().FirstName)
-		_, _ = cb.Edit(response, &telegram.SendOptions{ReplyMarkup: core.HelpMenuKeyboard()})
+		editHelp(cb, response, core.HelpMenuKeyboard())
 		return nil
 	}
 
 	if strings.Contains(data, "help_back") {
 		_, _ = cb.Answer(lang.GetString(langCode, "returning_to_home"), &telegram.CallbackOptions{Alert: true})
 		response := fmt.Sprintf(lang.GetString(langCode, "start_text"), cb.Sender.FirstName, cb.Client.Me().FirstName)
-		_, _ = cb.Edit(response, &telegram.SendOptions{ReplyMarkup: core.AddMeMarkup(cb.Client.Me().Username)})
+		markup := core.AddMeMarkup(cb.Client.Me().Username)
+		if banner := db.Instance.GetBanner(ctx, cb.Client.Me().ID); banner != "" {
+			// A text message cannot be edited into a media one, so the home message is sent again with the banner.
+			_, _ = cb.Delete()
+			return sendStart(callbackSender{cb}, banner, response, markup)
+		}
+		_, _ = cb.Edit(response, &telegram.SendOptions{ReplyMarkup: markup})
 		return nil
 	}
 
 	if category, ok := helpCategories[data]; ok {
 		_, _ = cb.Answer(fmt.Sprintf(lang.GetString(langCode, "opening_category"), category.Title), &telegram.CallbackOptions{Alert: true})
 		text := fmt.Sprintf(lang.GetString(langCode, "help_category_text"), category.Title, category.Content)
-		_, _ = cb.Edit(text, &telegram.SendOptions{ReplyMarkup: category.Markup})
+		editHelp(cb, text, category.Markup)
 		return nil
 	}
 
@@ -79,6 +87,17 @@ func helpCallbackHandler(cb *telegram.CallbackQuery) error {
 	return nil
 }
 
+// editHelp shows text in the help message. A start message sent with the banner has a caption, which cannot hold
+// the longer help texts, so if the edit fails the message is replaced with a plain one.
+func editHelp(cb *telegram.CallbackQuery, text string, markup telegram.ReplyMarkup) {
+	_, err := cb.Edit(text, &telegram.SendOptions{ReplyMarkup: markup})
+	if err == nil || strings.Contains(err.Error(), "MESSAGE_NOT_MODIFIED") {
+		return
+	}
+	_, _ = cb.Delete()
+	_, _ = cb.Respond(text, &telegram.SendOptions{ReplyMarkup: markup})
+}
+
 // privacyHandler handles the /privacy command.
 // It takes a telegram.NewMessage object as input.
 // It returns an error if any.
@@ -94,3 +113,15 @@ func privacyHandler(m *telegram.NewMessage) error {
 	_, err := m.Reply(text, telegram.SendOptions{LinkPreview: false})
 	return err
 }
+
+// versionHandler handles the /version command.
+// It replies with the build version of the bot and the Go version it was built with.
+func versionHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "version_info"), config.Conf.Version, runtime.Version()))
+	return err
+}
@@ -2,6 +2,16 @@ package handlers
 
 import (
 	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/core/musicfeed"
+	"github.com/zuchzub/Go/pkg/core/timeparse"
+	"github.com/zuchzub/Go/pkg/lang"
+	"net/url"
+	"slices"
+	"strings"
 
 	"github.com/Laky-64/gologging"
 	"github.com/amarnathcjd/gogram/telegram"
@@ -10,7 +20,7 @@ import (
 // getPeerId gets the peer ID from a chat ID.
 // It takes a telegram client and a chat ID as input.
 // It returns the peer ID and an error if any.
-func getPeerId(c *telegram.Client, chatId any) (int64, error) {
+func getPeerId(c peerResolver, chatId any) (int64, error) {
 	peer, err := c.ResolvePeer(chatId)
 	if err != nil {
 		gologging.WarnF("failed to resolve Peer for %d", chatId)
@@ -29,6 +39,79 @@ func getPeerId(c *telegram.Client, chatId any) (int64, error) {
 	}
 }
 
+// formatDuration formats a track duration for display.
+// It returns the live badge instead of a duration for livestreams.
+func formatDuration(langCode string, seconds int, isLive bool) string {
+	if isLive {
+		return lang.GetString(langCode, "live_badge")
+	}
+	return cache.SecToMin(seconds)
+}
+
+// durationLimit returns the longest playable duration in seconds for a content type, or 0 if there is no limit.
+// Podcasts have their own, higher ceiling.
+func durationLimit(contentType string) int {
+	if contentType == cache.ContentPodcast {
+		return config.Conf.MaxPodcastDuration
+	}
+	return config.Conf.MaxDuration
+}
+
+// exceedsDurationLimit reports whether a track is longer than its content type allows.
+// Livestreams and tracks of unknown duration are never rejected.
+func exceedsDurationLimit(duration int, isLive bool, contentType string) bool {
+	limit := durationLimit(contentType)
+	return !isLive && limit > 0 && duration > limit
+}
+
+// failureNote returns a "⚠️ reason (×n)" note, with the reason shortened to maxLen, for a track whose download has failed.
+// It returns an empty string for a track that has not failed.
+func failureNote(track *cache.CachedTrack, maxLen int) string {
+	if track.FailCount == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" ⚠️ %s (×%d)", core.TruncateDisplay(track.LastError, maxLen), track.FailCount)
+}
+
+// buildTrackMessage formats the now-playing message for a track under the given status line.
+func buildTrackMessage(langCode string, track *cache.CachedTrack, status, emoji string) string {
+	return fmt.Sprintf(
+		lang.GetString(langCode, "track_message"),
+		emoji, status,
+		core.FormatTrackLine(track, core.TrackLineCompact),
+		formatDuration(langCode, track.Duration, track.IsLive),
+		track.User,
+	)
+}
+
+// updateNowPlaying edits the chat's stored now-playing message with the given text and control buttons.
+// If that message can no longer be edited, it sends a fresh one and remembers its ID instead.
+func updateNowPlaying(m *telegram.NewMessage, chatID int64, text, state string) {
+	// The message is in the chat's music feed, if it has one.
+	peer, topicID := musicfeed.Target(chatID)
+	opts := &telegram.SendOptions{ReplyMarkup: core.ControlButtons(state), TopicID: topicID}
+	if msgID := cache.ChatCache.GetNowPlayingMessage(chatID); msgID != 0 {
+		_, err := m.Client.EditMessage(peer, msgID, text, opts)
+		if err == nil || strings.Contains(err.Error(), "MESSAGE_NOT_MODIFIED") {
+			return
+		}
+		gologging.InfoF("[updateNowPlaying] Failed to edit message %d: %v", msgID, err)
+	}
+
+	msg, err := m.Client.SendMessage(peer, text, opts)
+	if err != nil && (peer != chatID || topicID != 0) && musicfeed.Unpostable(err) {
+		musicfeed.Revert(m.Client, chatID, err)
+		opts.TopicID = 0
+		msg, err = m.Client.SendMessage(chatID, text, opts)
+	}
+	if err != nil {
+		gologging.WarnF("[updateNowPlaying] Failed to send the now-playing message: %v", err)
+		return
+	}
+	cache.ChatCache.SetNowPlayingMessage(chatID, msg.ID)
+}
+
 // getUrl gets a URL from a message.
 // It takes a telegram.NewMessage object and a boolean indicating whether it is a reply.
 // It returns the URL from the message.
@@ -62,6 +145,56 @@ func getUrl(m *telegram.NewMessage, isReply bool) string {
 	return ""
 }
 
+// isYouTubeURL reports whether rawURL, with or without its scheme, is on a YouTube host.
+func isYouTubeURL(rawURL string) bool {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	return err == nil && dl.IsYouTubeHost(u.Hostname())
+}
+
+// startParams are the URL query parameters that carry a start offset, as in YouTube's "?t=90" or "&start=1m30s".
+var startParams = []string{"t", "start"}
+
+// stripStartParam removes the start offset parameters from a YouTube URL, keeping the order of the other parameters
+// so that the platform regexes still match. It returns the cleaned URL and the offset in seconds (0 if absent or invalid).
+// Other URLs are returned unchanged: on a CDN or direct media link, "t" or "start" may be part of a signature.
+func stripStartParam(rawURL string) (string, int) {
+	base, query, ok := strings.Cut(rawURL, "?")
+	if !ok || !isYouTubeURL(base) {
+		return rawURL, 0
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+
+	var kept []string
+	var startAt int
+	stripped := false
+	for _, param := range strings.Split(query, "&") {
+		key, value, _ := strings.Cut(param, "=")
+		if !slices.Contains(startParams, key) {
+			kept = append(kept, param)
+			continue
+		}
+		stripped = true
+		if d, err := timeparse.ParseDuration(value); err == nil && startAt == 0 {
+			startAt = int(d.Seconds())
+		}
+	}
+	if !stripped {
+		return rawURL, 0
+	}
+
+	cleaned := base
+	if len(kept) > 0 {
+		cleaned += "?" + strings.Join(kept, "&")
+	}
+	if hasFragment {
+		cleaned += "#" + fragment
+	}
+	return cleaned, startAt
+}
+
 // isValidMedia checks if a message contains valid media.
 // It takes a telegram.NewMessage object as input.
 // It returns true if the message contains valid media, otherwise false.
@@ -77,6 +210,21 @@ func isValidMedia(reply *telegram.NewMessage) bool {
 	return true
 }
 
+// batchLinks returns the links of a /play argument made only of two or more links, separated by spaces or new lines.
+// It returns nil for anything else, which is played as a single link or search.
+func batchLinks(args string) []string {
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		return nil
+	}
+	for _, field := range fields {
+		if !strings.HasPrefix(field, "https://") && !strings.HasPrefix(field, "http://") {
+			return nil
+		}
+	}
+	return fields
+}
+
 // coalesce returns the first non-empty string.
 // It takes two strings as input.
 // It returns the first non-empty string.
@@ -86,13 +234,3 @@ func coalesce(a, b string) string {
 	}
 	return b
 }
-
-// truncate truncates a string to a maximum length.
-// It takes a string and a maximum length as input.
-// It returns the truncated string.
-func truncate(s string, max int) string {
-	if len(s) <= max {
-		return s
-	}
-	return s[:max]
-}
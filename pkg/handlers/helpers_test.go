@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"strings"
+	"testing"
+)
+
+func TestStripStartParam(t *testing.T) {
+	tests := []struct {
+		url, wantURL string
+		wantStartAt  int
+	}{
+		{"https://youtu.be/dQw4w9WgXcQ?t=90", "https://youtu.be/dQw4w9WgXcQ", 90},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=1m30s", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", 90},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&start=75", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", 75},
+		{"https://www.youtube.com/watch?t=90&v=dQw4w9WgXcQ&list=PL1", "https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PL1", 90},
+		{"https://youtu.be/dQw4w9WgXcQ?t=1h2m3s#comments", "https://youtu.be/dQw4w9WgXcQ#comments", 3723},
+		{"https://youtu.be/dQw4w9WgXcQ?t=90&start=10", "https://youtu.be/dQw4w9WgXcQ", 90},
+		{"https://youtu.be/dQw4w9WgXcQ?t=soon", "https://youtu.be/dQw4w9WgXcQ", 0},
+		{"https://youtu.be/dQw4w9WgXcQ?si=abc", "https://youtu.be/dQw4w9WgXcQ?si=abc", 0},
+		{"https://youtu.be/dQw4w9WgXcQ?tt=90", "https://youtu.be/dQw4w9WgXcQ?tt=90", 0},
+		{"never gonna give you up", "never gonna give you up", 0},
+		{"youtu.be/dQw4w9WgXcQ?t=90", "youtu.be/dQw4w9WgXcQ", 90},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ&t=30", "https://music.youtube.com/watch?v=dQw4w9WgXcQ", 30},
+		{"https://cdn.example.com/audio.mp3?start=10&t=1700000000&sig=abc", "https://cdn.example.com/audio.mp3?start=10&t=1700000000&sig=abc", 0},
+		{"https://example.com/watch?v=dQw4w9WgXcQ&t=90", "https://example.com/watch?v=dQw4w9WgXcQ&t=90", 0},
+		{"https://notyoutube.com/watch?v=dQw4w9WgXcQ&t=90", "https://notyoutube.com/watch?v=dQw4w9WgXcQ&t=90", 0},
+		{"what is t=90 and start=5? nothing", "what is t=90 and start=5? nothing", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			gotURL, gotStartAt := stripStartParam(tt.url)
+			if gotURL != tt.wantURL || gotStartAt != tt.wantStartAt {
+				t.Errorf("stripStartParam(%q) = %q, %d; want %q, %d", tt.url, gotURL, gotStartAt, tt.wantURL, tt.wantStartAt)
+			}
+		})
+	}
+}
+
+func TestStripStartParamKeepsVideoURLsValid(t *testing.T) {
+	saved := config.Conf
+	config.Conf = &config.BotConfig{}
+	defer func() { config.Conf = saved }()
+
+	// With t= first, the unstripped URL would not match the youtube.com/watch?v= pattern.
+	for _, url := range []string{
+		"https://www.youtube.com/watch?t=90&v=dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ?start=1m",
+		"https://youtube.com/shorts/dQw4w9WgXcQ?t=5",
+	} {
+		stripped, _ := stripStartParam(url)
+		if !dl.NewYouTubeData(stripped).IsValid() {
+			t.Errorf("stripStartParam(%q) = %q, which is not a valid YouTube URL", url, stripped)
+		}
+	}
+}
+
+func TestParseStartAt(t *testing.T) {
+	tests := []struct {
+		args, wantRest string
+		wantStartAt    int
+		wantErr        bool
+	}{
+		{"song name", "song name", 0, false},
+		{"song name at=1:30", "song name", 90, false},
+		{"at=90 song name", "song name", 90, false},
+		{"song AT=2m name", "song name", 120, false},
+		{"song name at=later", "song name", 0, true},
+		{"song name flat=90", "song name flat=90", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			rest, startAt, err := parseStartAt(tt.args)
+			if rest != tt.wantRest || startAt != tt.wantStartAt || (err != nil) != tt.wantErr {
+				t.Errorf("parseStartAt(%q) = %q, %d, %v; want %q, %d, error %t", tt.args, rest, startAt, err, tt.wantRest, tt.wantStartAt, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStartAtError(t *testing.T) {
+	tests := []struct {
+		name  string
+		track cache.CachedTrack
+		want  string
+	}{
+		{"no offset", cache.CachedTrack{Duration: 100}, ""},
+		{"within the track", cache.CachedTrack{Duration: 100, StartAt: 90}, ""},
+		{"past the end", cache.CachedTrack{Duration: 100, StartAt: 100}, "play_start_out_of_range"},
+		{"unknown duration", cache.CachedTrack{StartAt: 5000}, ""},
+		{"live", cache.CachedTrack{IsLive: true, StartAt: 10}, "seek_live_unsupported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := startAtError(&tt.track, "en")
+			if (got == "") != (tt.want == "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("startAtError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureNote(t *testing.T) {
+	tests := []struct {
+		name  string
+		track *cache.CachedTrack
+		want  string
+	}{
+		{"not failed", &cache.CachedTrack{LastError: "stale"}, ""},
+		{"failed once", &cache.CachedTrack{FailCount: 1, LastError: "timeout"}, " ⚠️ timeout (×1)"},
+		{"escaped", &cache.CachedTrack{FailCount: 2, LastError: "bad <format> & codec"}, " ⚠️ bad &lt;format&gt; &amp; codec (×2)"},
+		{"shortened", &cache.CachedTrack{FailCount: 3, LastError: "connection reset by peer while reading the body"}, " ⚠️ connection reset by… (×3)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureNote(tt.track, 20); got != tt.want {
+				t.Errorf("failureNote() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	// exportHistoryDefaultDays is the period /exporthistory covers when no number of days is given.
+	exportHistoryDefaultDays = 30
+	// exportHistoryMaxRows is the most tracks an export lists; beyond it, only the most recent are kept.
+	exportHistoryMaxRows = 5000
+)
+
+// writeHistoryCSV writes history entries as CSV with a header row. Quoting is left to encoding/csv, so names
+// containing commas, quotes or line breaks stay in their column.
+func writeHistoryCSV(w io.Writer, entries []db.HistoryEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"timestamp", "track", "url", "platform", "duration_seconds", "requester"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{
+			e.FinishedAt.UTC().Format(time.RFC3339),
+			e.Name,
+			e.URL,
+			e.Platform,
+			strconv.Itoa(e.Duration),
+			e.Requester,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportHistoryHandler handles the /exporthistory command.
+// It sends the tracks that finished playing in the chat over the last days as a CSV document.
+func exportHistoryHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	days := exportHistoryDefaultDays
+	if args := strings.TrimSpace(m.Args()); args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 || n > db.HistoryMaxDays {
+			_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "export_history_usage"), db.HistoryMaxDays))
+			return err
+		}
+		days = n
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	entries, err := db.Instance.GetHistory(ctx, chatID, since, exportHistoryMaxRows)
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "export_history_error"), err.Error()))
+		return err
+	}
+	if len(entries) == 0 {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "export_history_empty"), days))
+		return err
+	}
+
+	file, err := os.CreateTemp("", "history-*.csv")
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "export_history_error"), err.Error()))
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	err = writeHistoryCSV(file, entries)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "export_history_error"), err.Error()))
+		return err
+	}
+
+	caption := fmt.Sprintf(lang.GetString(langCode, "export_history_caption"), len(entries), days)
+	if len(entries) == exportHistoryMaxRows {
+		caption += "\n" + fmt.Sprintf(lang.GetString(langCode, "export_history_truncated"), exportHistoryMaxRows)
+	}
+	_, err = m.ReplyMedia(file.Name(), telegram.MediaOptions{
+		FileName:      fmt.Sprintf("history_%d_%s.csv", chatID, time.Now().UTC().Format("2006-01-02")),
+		ForceDocument: true,
+		Caption:       caption,
+	})
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "export_history_error"), err.Error()))
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHistoryCSV(t *testing.T) {
+	finished := time.Date(2026, 3, 1, 20, 15, 0, 0, time.FixedZone("IST", 5*60*60+30*60))
+	titles := []string{
+		"Plain Title",
+		"Simon, Garfunkel",
+		`12" Mix "Extended"`,
+		"Line one\nLine two",
+		"=HYPERLINK(\"x\")",
+		"नमस्ते 🎵",
+		"",
+	}
+	entries := make([]db.HistoryEntry, len(titles))
+	for i, title := range titles {
+		entries[i] = db.HistoryEntry{
+			FinishedAt: finished,
+			Name:       title,
+			URL:        "https://example.com/?a=1,b=2",
+			Platform:   "youtube",
+			Duration:   215,
+			Requester:  `Ann "DJ" Lee`,
+		}
+	}
+
+	var b strings.Builder
+	if err := writeHistoryCSV(&b, entries); err != nil {
+		t.Fatalf("writeHistoryCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("the export is not valid CSV: %v\n%s", err, b.String())
+	}
+	if want := []string{"timestamp", "track", "url", "platform", "duration_seconds", "requester"}; !reflect.DeepEqual(records[0], want) {
+		t.Errorf("header = %q, want %q", records[0], want)
+	}
+	if len(records) != len(titles)+1 {
+		t.Fatalf("%d rows, want a header and %d tracks", len(records), len(titles))
+	}
+	for i, title := range titles {
+		want := []string{"2026-03-01T14:45:00Z", title, "https://example.com/?a=1,b=2", "youtube", "215", `Ann "DJ" Lee`}
+		if got := records[i+1]; !reflect.DeepEqual(got, want) {
+			t.Errorf("row %d = %q, want %q", i+1, got, want)
+		}
+	}
+
+	if !strings.Contains(b.String(), `"12"" Mix ""Extended"""`) {
+		t.Errorf("quotes in a title are not doubled:\n%s", b.String())
+	}
+}
+
+func TestWriteHistoryCSVEmpty(t *testing.T) {
+	var b strings.Builder
+	if err := writeHistoryCSV(&b, nil); err != nil {
+		t.Fatalf("writeHistoryCSV() error = %v", err)
+	}
+	if got, want := b.String(), "timestamp,track,url,platform,duration_seconds,requester\n"; got != want {
+		t.Errorf("writeHistoryCSV(nil) = %q, want only the header %q", got, want)
+	}
+}
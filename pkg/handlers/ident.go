@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	// maxIdentDuration is the longest clip, in seconds, accepted as a station ident.
+	maxIdentDuration = 15
+	// maxIdentSize is the largest file accepted as a station ident.
+	maxIdentSize = 5 * 1024 * 1024
+)
+
+// identDir is where station idents are kept, one file per chat.
+var identDir = filepath.Join("database", "idents")
+
+// setIdentHandler handles the /setident command.
+// It saves the replied-to audio clip as the chat's station ident, played between tracks while /ident is on.
+func setIdentHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	if !m.IsReply() {
+		_, err := m.Reply(lang.GetString(langCode, "ident_set_usage"))
+		return err
+	}
+	reply, err := m.GetReplyMessage()
+	if err != nil || reply.File == nil || (reply.Audio() == nil && reply.Voice() == nil && reply.Document() == nil) {
+		_, err = m.Reply(lang.GetString(langCode, "ident_set_usage"))
+		return err
+	}
+	if reply.File.Size > maxIdentSize {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "ident_too_large"), maxIdentSize/(1024*1024)))
+		return err
+	}
+
+	if err = os.MkdirAll(identDir, 0o755); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "ident_error"), err.Error()))
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(reply.File.Name))
+	if ext == "" {
+		ext = ".ogg"
+	}
+	tmp := filepath.Join(identDir, fmt.Sprintf("%d.tmp%s", chatID, ext))
+	tmp, err = reply.Download(&telegram.DownloadOptions{FileName: tmp})
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "ident_error"), err.Error()))
+		return err
+	}
+
+	dur := cache.GetFileDuration(tmp)
+	if dur <= 0 || dur > maxIdentDuration {
+		_ = os.Remove(tmp)
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "ident_too_long"), maxIdentDuration))
+		return err
+	}
+
+	old := db.Instance.GetIdent(ctx, chatID)
+	path := filepath.Join(identDir, fmt.Sprintf("%d%s", chatID, ext))
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "ident_error"), err.Error()))
+		return err
+	}
+	if old != "" && old != path {
+		_ = os.Remove(old)
+	}
+	if err = db.Instance.SetIdent(ctx, chatID, path); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "ident_error"), err.Error()))
+		return err
+	}
+
+	key := "ident_saved"
+	if db.Instance.GetIdentEnabled(ctx, chatID) {
+		key = "ident_saved_enabled"
+	}
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, key), dur))
+	return err
+}
+
+// delIdentHandler handles the /delident command.
+// It removes the chat's station ident.
+func delIdentHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	path := db.Instance.GetIdent(ctx, chatID)
+	if path == "" {
+		_, err := m.Reply(lang.GetString(langCode, "ident_none"))
+		return err
+	}
+	if err := db.Instance.SetIdent(ctx, chatID, ""); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "ident_error"), err.Error()))
+		return err
+	}
+	_ = os.Remove(path)
+
+	_, err := m.Reply(lang.GetString(langCode, "ident_deleted"))
+	return err
+}
+
+// identHandler handles the /ident command.
+// It sets whether the chat's station ident is played between tracks.
+func identHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	var enabled bool
+	switch strings.ToLower(m.Args()) {
+	case "on", "enable":
+		enabled = true
+	case "off", "disable":
+		enabled = false
+	default:
+		status := lang.GetString(langCode, "ident_status_off")
+		if db.Instance.GetIdentEnabled(ctx, chatID) {
+			status = lang.GetString(langCode, "ident_status_on")
+		}
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "ident_usage"), status))
+		return err
+	}
+
+	if enabled && db.Instance.GetIdent(ctx, chatID) == "" {
+		_, err := m.Reply(lang.GetString(langCode, "ident_none"))
+		return err
+	}
+	if err := db.Instance.SetIdentEnabled(ctx, chatID, enabled); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "ident_error"), err.Error()))
+		return err
+	}
+
+	key := "ident_disabled"
+	if enabled {
+		key = "ident_enabled"
+	}
+	_, err := m.Reply(lang.GetString(langCode, key))
+	return err
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+	"strconv"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// jumpTo skips to the track at position n of a chat's queue, dropping the tracks queued before it without
+// downloading them. It returns the number of tracks skipped, or -1 if n is not an upcoming position.
+func jumpTo(chatID int64, n int) (int, error) {
+	dropped := cache.ChatCache.DropUpTo(chatID, n)
+	if dropped < 0 {
+		return -1, nil
+	}
+	cache.ChatCache.SetLoopCount(chatID, 0)
+	cache.RecordQueueStat(chatID, cache.QueueSkipped, dropped+1)
+	return dropped + 1, vc.Calls.Skip(chatID)
+}
+
+// jumpHandler handles the /jump command.
+// It plays the track at the given position of /queue at once, skipping the current track and those before it.
+func jumpHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if !cache.ChatCache.IsActive(chatID) {
+		_, _ = m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(m.Args()))
+	if err != nil {
+		_, err = m.Reply(lang.GetString(langCode, "jump_usage"))
+		return err
+	}
+
+	skipped, err := jumpTo(chatID, n)
+	if skipped < 0 {
+		upcoming := cache.ChatCache.GetQueueLength(chatID) - 1
+		if upcoming < 1 {
+			_, err = m.Reply(lang.GetString(langCode, "jump_no_upcoming"))
+			return err
+		}
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "jump_invalid_position"), upcoming))
+		return err
+	}
+	if err != nil {
+		_, _ = m.Reply(lang.GetString(langCode, "skip_fail"))
+		return err
+	}
+
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "jump_success"), n, skipped, actorName(m)))
+	return err
+}
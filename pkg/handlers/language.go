@@ -6,7 +6,6 @@ import (
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
-	"strings"
 
 	"github.com/amarnathcjd/gogram/telegram"
 )
@@ -23,11 +22,11 @@ func langHandler(m *telegram.NewMessage) error {
 }
 
 func setLangCallbackHandler(c *telegram.CallbackQuery) error {
-	parts := strings.SplitN(c.DataString(), "_", 2)
-	if len(parts) < 2 {
+	_, fields := core.DecodeCallback(c.DataString())
+	if len(fields) == 0 {
 		return nil
 	}
-	langCode := parts[1]
+	langCode := fields[0]
 
 	// Validate that the language code is supported
 	supportedLangs := lang.GetAvailableLangs()
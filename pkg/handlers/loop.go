@@ -6,11 +6,13 @@ import (
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
 	"strconv"
+	"strings"
 
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
 // loopHandler handles the /loop command.
+// /loop <count> repeats the current track; /loop queue repeats the whole queue until /loop queue off.
 func loopHandler(m *telegram.NewMessage) error {
 	chatID, _ := getPeerId(m.Client, m.ChatID())
 	ctx, cancel := db.Ctx()
@@ -27,6 +29,10 @@ func loopHandler(m *telegram.NewMessage) error {
 		return err
 	}
 
+	if fields := strings.Fields(strings.ToLower(args)); len(fields) > 0 && fields[0] == "queue" {
+		return loopQueue(m, chatID, langCode, fields[1:])
+	}
+
 	argsInt, err := strconv.Atoi(args)
 	if err != nil {
 		_, _ = m.Reply(lang.GetString(langCode, "loop_invalid_count"))
@@ -46,6 +52,43 @@ func loopHandler(m *telegram.NewMessage) error {
 		action = fmt.Sprintf(lang.GetString(langCode, "loop_set"), argsInt)
 	}
 
-	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "loop_status_changed"), action, m.Sender.FirstName))
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "loop_status_changed"), action, actorName(m)))
+	return err
+}
+
+// loopQueueHandler handles the /loopqueue command, a shorthand for /loop queue [on|off].
+func loopQueueHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if !cache.ChatCache.IsActive(chatID) {
+		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return err
+	}
+
+	return loopQueue(m, chatID, langCode, strings.Fields(strings.ToLower(m.Args())))
+}
+
+// loopQueue handles /loop queue [on|off], which turns looping of the whole queue on or off.
+func loopQueue(m *telegram.NewMessage, chatID int64, langCode string, args []string) error {
+	on := true
+	if len(args) > 0 {
+		switch args[0] {
+		case "on":
+		case "off":
+			on = false
+		default:
+			_, err := m.Reply(lang.GetString(langCode, "loop_usage"))
+			return err
+		}
+	}
+
+	cache.ChatCache.SetLoopQueue(chatID, on)
+	action := lang.GetString(langCode, "loop_queue_disabled")
+	if on {
+		action = lang.GetString(langCode, "loop_queue_enabled")
+	}
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "loop_status_changed"), action, actorName(m)))
 	return err
 }
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	// lyricsContext is how many lines are shown before and after the current line of synced lyrics.
+	lyricsContext = 2
+	// maxPlainLyrics bounds plain lyrics edited into a single message, so that it stays within Telegram's length limit.
+	maxPlainLyrics = 3500
+	// lyricsFetchTimeout bounds fetching the lyrics of a track that was queued without them.
+	lyricsFetchTimeout = 20 * time.Second
+)
+
+// lyricsKeyboard returns the keyboard of the synced lyrics view, with a button to move it to the current line.
+func lyricsKeyboard() *telegram.ReplyInlineMarkup {
+	return telegram.NewKeyboard().
+		AddRow(telegram.Button.Data("🔄", "lyrics_refresh"), core.CloseBtn).
+		Build()
+}
+
+// renderLyrics builds the lyrics view for the track playing in a chat.
+// Synced lyrics show the line at the current playback position with a few lines around it;
+// plain lyrics are shown in full. It reports whether the lyrics are synced, and so can be refreshed.
+func renderLyrics(chatID int64, langCode string, track *cache.CachedTrack) (string, bool) {
+	if strings.TrimSpace(track.Lyrics) == "" {
+		return lang.GetString(langCode, "lyrics_not_found"), false
+	}
+
+	name := core.TruncateDisplay(track.Name, 50)
+	lines := cache.ParseSyncedLyrics(track.Lyrics)
+	if len(lines) == 0 {
+		text := []rune(track.Lyrics)
+		if len(text) > maxPlainLyrics {
+			text = append(text[:maxPlainLyrics-1], '…')
+		}
+		return fmt.Sprintf(lang.GetString(langCode, "lyrics_plain"), name, html.EscapeString(string(text))), false
+	}
+
+	played, err := vc.Calls.PlayedTime(chatID)
+	if err != nil {
+		return lang.GetString(langCode, "lyrics_position_error"), false
+	}
+
+	current := cache.CurrentLyricLine(lines, int(played)*1000)
+	var b strings.Builder
+	for i := max(current-lyricsContext, 0); i <= min(current+lyricsContext, len(lines)-1); i++ {
+		text := html.EscapeString(lines[i].Text)
+		if text == "" {
+			text = "♪"
+		}
+		if i == current {
+			b.WriteString("<b>▶ " + text + "</b>\n")
+		} else {
+			b.WriteString("<i>" + text + "</i>\n")
+		}
+	}
+
+	return fmt.Sprintf(lang.GetString(langCode, "lyrics_synced"), name, cache.SecToMin(int(played)), b.String()), true
+}
+
+// fetchLyrics fills in the lyrics of a track that was queued without them, by fetching the track again from its
+// platform. Lyrics found are kept on the track, so later calls do not fetch them again.
+func fetchLyrics(track *cache.CachedTrack) {
+	if strings.TrimSpace(track.Lyrics) != "" || track.Platform == cache.Telegram || track.URL == "" {
+		return
+	}
+	wrapper := dl.NewDownloaderWrapper(track.URL)
+	if !wrapper.IsValid() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lyricsFetchTimeout)
+	defer cancel()
+	info, err := wrapper.GetTrack(ctx)
+	if err != nil {
+		gologging.InfoF("[Lyrics] Failed to fetch the lyrics of %s: %v", track.URL, err)
+		return
+	}
+	if info.Lyrics != "" {
+		track.Lyrics = info.Lyrics
+	}
+}
+
+// lyricsHandler handles the /lyrics command.
+// It shows the lyrics of the track playing in the chat, fetching them first if the track was queued without them.
+func lyricsHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	track := cache.ChatCache.GetPlayingTrack(chatID)
+	if !cache.ChatCache.IsActive(chatID) || track == nil {
+		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return err
+	}
+
+	fetchLyrics(track)
+	if strings.TrimSpace(track.Lyrics) != "" && len(cache.ParseSyncedLyrics(track.Lyrics)) == 0 {
+		// Plain lyrics are sent in full, over several messages if they are long.
+		name := core.TruncateDisplay(track.Name, 50)
+		return core.SendLong(m, fmt.Sprintf(lang.GetString(langCode, "lyrics_plain"), name, html.EscapeString(track.Lyrics)))
+	}
+
+	text, synced := renderLyrics(chatID, langCode, track)
+	if !synced {
+		_, err := m.Reply(text)
+		return err
+	}
+	_, err := m.Reply(text, telegram.SendOptions{ReplyMarkup: lyricsKeyboard()})
+	return err
+}
+
+// lyricsCallbackHandler moves a synced lyrics view to the line currently being sung.
+func lyricsCallbackHandler(cb *telegram.CallbackQuery) error {
+	chatID, _ := getPeerId(cb.Client, cb.ChatID)
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	track := cache.ChatCache.GetPlayingTrack(chatID)
+	if !cache.ChatCache.IsActive(chatID) || track == nil {
+		text := lang.GetString(langCode, "no_track_playing")
+		_, _ = cb.Answer(text, &telegram.CallbackOptions{Alert: true})
+		_, _ = cb.Edit(text, &telegram.SendOptions{ReplyMarkup: core.ControlButtons("")})
+		return nil
+	}
+
+	text, synced := renderLyrics(chatID, langCode, track)
+	markup := core.ControlButtons("")
+	if synced {
+		markup = lyricsKeyboard()
+	}
+	_, _ = cb.Answer("")
+	_, _ = cb.Edit(text, &telegram.SendOptions{ReplyMarkup: markup})
+	return nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"html"
+	"strings"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// loadMaintenanceState restores the persisted maintenance state into the in-memory flag.
+// It takes a telegram client as input.
+func loadMaintenanceState(c *telegram.Client) {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	enabled, eta := db.Instance.GetMaintenance(ctx, c.Me().ID)
+	cache.SetMaintenance(enabled, eta)
+	if enabled {
+		gologging.Info("Maintenance mode is on; new playback requests will be rejected.")
+	}
+}
+
+// maintenanceMessage builds the localized message shown when a request is rejected during maintenance.
+// The ETA is free text from /maintenance, so it is escaped.
+func maintenanceMessage(langCode string) string {
+	text := lang.GetString(langCode, "maintenance_active")
+	if eta := cache.MaintenanceETA(); eta != "" {
+		text += fmt.Sprintf(lang.GetString(langCode, "maintenance_eta"), html.EscapeString(eta))
+	}
+	return text
+}
+
+// maintenanceHandler handles the /maintenance command.
+// It turns maintenance mode on, with an optional ETA, or off.
+// It returns an error if any.
+func maintenanceHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	mode, eta, _ := strings.Cut(strings.TrimSpace(m.Args()), " ")
+	eta = strings.TrimSpace(eta)
+
+	switch strings.ToLower(mode) {
+	case "on", "enable":
+		if err := db.Instance.SetMaintenance(ctx, m.Client.Me().ID, true, eta); err != nil {
+			_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "maintenance_update_failed"), err.Error()))
+			return err
+		}
+		cache.SetMaintenance(true, eta)
+		_, err := m.Reply(lang.GetString(langCode, "maintenance_enabled"))
+		return err
+	case "off", "disable":
+		if err := db.Instance.SetMaintenance(ctx, m.Client.Me().ID, false, ""); err != nil {
+			_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "maintenance_update_failed"), err.Error()))
+			return err
+		}
+		cache.SetMaintenance(false, "")
+		_, err := m.Client.SendMessage(config.Conf.LoggerId, lang.GetString(langCode, "maintenance_ready_logger"))
+		if err != nil {
+			gologging.WarnF("[maintenance.go] Failed to notify the logger chat: %v", err)
+		}
+		_, err = m.Reply(lang.GetString(langCode, "maintenance_disabled"))
+		return err
+	default:
+		status := lang.GetString(langCode, "maintenance_status_off")
+		if cache.IsMaintenance() {
+			status = lang.GetString(langCode, "maintenance_status_on")
+		}
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "maintenance_usage"), status))
+		return err
+	}
+}
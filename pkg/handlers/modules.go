@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"github.com/zuchzub/Go/pkg/core"
 	"time"
 
 	"github.com/Laky-64/gologging"
@@ -9,52 +10,128 @@ import (
 
 var startTime = time.Now()
 
+// handlerRegistry is the part of *telegram.Client that handlers are registered with.
+type handlerRegistry interface {
+	On(pattern any, handler any, filters ...telegram.Filter) telegram.Handle
+	AddRawHandler(updateType telegram.Update, handler telegram.RawHandler) telegram.Handle
+}
+
 // LoadModules loads all the handlers.
 // It takes a telegram client as input.
 func LoadModules(c *telegram.Client) {
 	_, _ = c.UpdatesGetState()
+	registerHandlers(c)
+	loadMaintenanceState(c)
+	_ = loadDevs(c)
+	gologging.Debug("Handlers loaded successfully.")
+}
 
+// registerHandlers registers the handlers of every command, callback and update with c.
+func registerHandlers(c handlerRegistry) {
 	c.On("command:ping", pingHandler)
 	c.On("command:start", startHandler)
 	c.On("command:help", startHandler)
 	c.On("command:lang", langHandler)
 	c.On("command:reload", reloadAdminCacheHandler)
 	c.On("command:privacy", privacyHandler)
+	c.On("command:version", versionHandler)
+	c.On("command:features", featuresHandler)
+	c.On("command:link", linkHandler)
+	c.On("command:unlink", unlinkHandler)
+	c.On("command:myplaylists", myPlaylistsHandler)
+
+	onCommand(c, "play", playHandler, playMode)
+	onCommand(c, "vPlay", vPlayHandler, playMode)
+	onCommand(c, "playmine", playMineHandler, playMode)
+	onCommand(c, "song", songHandler, songMode)
 
-	c.On("command:play", playHandler, telegram.FilterFunc(playMode))
-	c.On("command:vPlay", vPlayHandler, telegram.FilterFunc(playMode))
-
-	c.On("command:loop", loopHandler, telegram.FilterFunc(adminMode))
-	c.On("command:remove", removeHandler, telegram.FilterFunc(adminMode))
-	c.On("command:skip", skipHandler, telegram.FilterFunc(adminMode))
-	c.On("command:stop", stopHandler, telegram.FilterFunc(adminMode))
-	c.On("command:end", stopHandler, telegram.FilterFunc(adminMode))
-	c.On("command:mute", muteHandler, telegram.FilterFunc(adminMode))
-	c.On("command:unmute", unmuteHandler, telegram.FilterFunc(adminMode))
-	c.On("command:pause", pauseHandler, telegram.FilterFunc(adminMode))
-	c.On("command:resume", resumeHandler, telegram.FilterFunc(adminMode))
-	c.On("command:queue", queueHandler, telegram.FilterFunc(adminMode))
-	c.On("command:seek", seekHandler, telegram.FilterFunc(adminMode))
-	c.On("command:speed", speedHandler, telegram.FilterFunc(adminMode))
-	c.On("command:authList", authListHandler, telegram.FilterFunc(adminMode))
+	onCommand(c, "loop", loopHandler, controlMode)
+	onCommand(c, "loopqueue", loopQueueHandler, controlMode)
+	onCommand(c, "remove", withContext(removeHandler), playMode)
+	onCommand(c, "move", moveHandler, adminMode)
+	onCommand(c, "startat", startAtHandler, adminMode)
+	onCommand(c, "clearqueue", clearQueueHandler, adminMode)
+	onCommand(c, "clearfailed", clearFailedHandler, adminMode)
+	onCommand(c, "undo", undoHandler, adminMode)
+	onCommand(c, "resume_session", resumeSessionHandler, adminMode)
+	onCommand(c, "continue", continueHandler, playMode)
+	onCommand(c, "skip", skipHandler, controlMode)
+	onCommand(c, "voteskip", voteSkipHandler, playMode)
+	onCommand(c, "setvoteskip", setVoteSkipHandler, adminMode)
+	onCommand(c, "jump", jumpHandler, controlMode)
+	c.On("command:stop", withContext(stopHandler), telegram.FilterFunc(freshCommand), telegram.FilterFunc(controlMode))
+	c.On("command:end", withContext(stopHandler), telegram.FilterFunc(freshCommand), telegram.FilterFunc(controlMode))
+	onCommand(c, "stopconfirm", stopConfirmHandler, adminMode)
+	onCommand(c, "mute", muteHandler, controlMode)
+	onCommand(c, "unmute", unmuteHandler, controlMode)
+	onCommand(c, "pause", pauseHandler, controlMode)
+	onCommand(c, "resume", resumeHandler, controlMode)
+	onCommand(c, "queue", withContext(queueHandler), adminMode)
+	onCommand(c, "current", withContext(currentHandler), playMode)
+	onCommand(c, "np", withContext(currentHandler), playMode)
+	onCommand(c, "queuestats", queueStatsHandler, adminMode)
+	onCommand(c, "exporthistory", exportHistoryHandler, adminMode)
+	onCommand(c, "lyrics", lyricsHandler, playMode)
+	onCommand(c, "seek", withContext(seekHandler), controlMode)
+	onCommand(c, "seekback", withContext(seekBackHandler), controlMode)
+	onCommand(c, "speed", speedHandler, controlMode)
+	onCommand(c, "volume", volumeHandler, controlMode)
+	onCommand(c, "bassboost", bassBoostHandler, controlMode)
+	onCommand(c, "normalize", normalizeHandler, adminMode)
+	onCommand(c, "stayinvc", stayInVCHandler, adminMode)
+	onCommand(c, "stayonempty", stayOnEmptyHandler, adminMode)
+	onCommand(c, "ident", identHandler, adminMode)
+	onCommand(c, "setident", setIdentHandler, adminMode)
+	onCommand(c, "delident", delIdentHandler, adminMode)
+	onCommand(c, "autoplay", autoplayHandler, adminMode)
+	onCommand(c, "reordernotify", reorderNotifyHandler, adminMode)
+	onCommand(c, "setmusicfeed", setMusicFeedHandler, adminMode)
+	onCommand(c, "delmusicfeed", delMusicFeedHandler, adminMode)
+	c.On("command:lockqueue", lockQueueHandler, telegram.FilterFunc(adminMode))
+	c.On("command:unlockqueue", unlockQueueHandler, telegram.FilterFunc(adminMode))
+	onCommand(c, "announcements", announcementsHandler, adminMode)
+	c.On("command:authList", withContext(authListHandler), telegram.FilterFunc(adminMode))
 	c.On("command:addAuth", addAuthHandler, telegram.FilterFunc(adminMode))
 	c.On("command:auth", addAuthHandler, telegram.FilterFunc(adminMode))
 	c.On("command:removeAuth", removeAuthHandler, telegram.FilterFunc(adminMode))
 	c.On("command:unAuth", removeAuthHandler, telegram.FilterFunc(adminMode))
 	c.On("command:rmAuth", removeAuthHandler, telegram.FilterFunc(adminMode))
+	c.On("command:dj", djHandler, telegram.FilterFunc(adminMode))
+	c.On("command:disable", disableHandler, telegram.FilterFunc(adminMode))
+	c.On("command:enable", enableHandler, telegram.FilterFunc(adminMode))
 
 	c.On("command:active_vc", activeVcHandler, telegram.FilterFunc(isDev))
 	c.On("command:av", activeVcHandler, telegram.FilterFunc(isDev))
 	c.On("command:stats", sysStatsHandler, telegram.FilterFunc(isDev))
+	c.On("command:forcereset", forceResetHandler, telegram.FilterFunc(isDev))
+	c.On("command:purgecache", purgeCacheHandler, telegram.FilterFunc(isDev))
+	c.On("command:maintenance", maintenanceHandler, telegram.FilterFunc(isDev))
+	c.On("command:apitest", apiTestHandler, telegram.FilterFunc(isDev))
+	c.On("command:events", eventsHandler, telegram.FilterFunc(isDev))
+	c.On("command:cmdstats", cmdStatsHandler, telegram.FilterFunc(isDev))
+	c.On("command:disableassistant", disableAssistantHandler, telegram.FilterFunc(isDev))
+	c.On("command:enableassistant", enableAssistantHandler, telegram.FilterFunc(isDev))
+	c.On("command:announce", announceHandler, telegram.FilterFunc(isDev))
+	c.On("command:downloads", downloadsHandler, telegram.FilterFunc(isDev))
+	c.On("command:setbanner", setBannerHandler, telegram.FilterFunc(isDev))
+	c.On("command:delbanner", delBannerHandler, telegram.FilterFunc(isDev))
+	c.On("command:devs", devsHandler, telegram.FilterFunc(isDev))
+	c.On("command:adddev", addDevHandler, telegram.FilterFunc(isOwner))
+	c.On("command:rmdev", rmDevHandler, telegram.FilterFunc(isOwner))
 
-	c.On("command:settings", settingsHandler, telegram.FilterFunc(adminMode))
-	c.On("callback:play_\\w+", playCallbackHandler, telegram.FilterFuncCallback(adminModeCB))
-	c.On("callback:vcplay_\\w+", vcPlayHandler)
+	onCommand(c, "settings", settingsHandler, adminMode)
+	c.On("callback:"+core.CallbackPattern("play"), playCallbackHandler, telegram.FilterFuncCallback(adminModeCB))
+	c.On("callback:"+core.CallbackPattern("vcplay"), vcPlayHandler)
+	c.On("callback:ytlist_\\w+", listChoiceCallbackHandler)
+	c.On("callback:lyrics_\\w+", lyricsCallbackHandler)
+	c.On("callback:announce_\\w+", announceCallbackHandler)
+	c.On("callback:downloads_\\w+", downloadsCallbackHandler)
+	c.On("callback:stop_\\w+", withCallbackContext(stopConfirmCallbackHandler))
+	c.On("callback:stale_\\w+", staleCommandCallbackHandler)
 	c.On("callback:help_\\w+", helpCallbackHandler)
-	c.On("callback:settings_\\w+", settingsCallbackHandler)
-	c.On("callback:setlang_\\w+", setLangCallbackHandler)
+	c.On("callback:"+core.CallbackPattern("settings"), settingsCallbackHandler)
+	c.On("callback:"+core.CallbackPattern("setlang"), setLangCallbackHandler)
 
 	c.On(telegram.OnParticipant, handleParticipant)
 	c.AddRawHandler(&telegram.UpdateNewChannelMessage{}, handleVoiceChat)
-	gologging.Debug("Handlers loaded successfully.")
 }
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// moveHandler handles the /move command.
+// It moves an upcoming track to another position in the queue; the track playing now cannot be moved.
+func moveHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if !cache.ChatCache.IsActive(chatID) {
+		_, _ = m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return nil
+	}
+
+	args := strings.Fields(m.Args())
+	if len(args) != 2 {
+		_, err := m.Reply(lang.GetString(langCode, "move_usage"))
+		return err
+	}
+	from, errFrom := strconv.Atoi(args[0])
+	to, errTo := strconv.Atoi(args[1])
+	if errFrom != nil || errTo != nil {
+		_, err := m.Reply(lang.GetString(langCode, "move_usage"))
+		return err
+	}
+
+	before := cache.ChatCache.GetQueue(chatID)
+	if len(before) < 3 {
+		_, err := m.Reply(lang.GetString(langCode, "move_not_enough"))
+		return err
+	}
+	if !cache.ChatCache.MoveTrack(chatID, from, to) {
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "move_out_of_range"), len(before)-1))
+		return err
+	}
+
+	track := before[from]
+	notifyDisplaced(m.Client, chatID, before, cache.ChatCache.GetQueue(chatID))
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "move_success"), html.EscapeString(track.Name), from, to, actorName(m)))
+	return err
+}
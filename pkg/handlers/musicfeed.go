@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/musicfeed"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// musicFeedNoteTTL is how long the short note left in a chat for a request confirmed in its music feed stays there.
+const musicFeedNoteTTL = 10 * time.Second
+
+// setMusicFeedHandler handles the /setmusicfeed command.
+// It sets where the chat's queue confirmations and now-playing messages are posted: a channel, given by username or
+// ID, or a topic of the chat, given by its ID. The bot must be able to post there, and a channel can only be chosen by
+// one of its admins.
+func setMusicFeedHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	arg := strings.TrimSpace(m.Args())
+	if arg == "" {
+		_, err := m.Reply(lang.GetString(langCode, "musicfeed_usage"))
+		return err
+	}
+
+	var feed db.MusicFeed
+	if topicID, err := strconv.ParseInt(arg, 10, 32); err == nil && topicID > 0 {
+		feed = db.MusicFeed{ChatID: chatID, TopicID: int32(topicID)}
+	} else {
+		feedID, err := getPeerId(m.Client, strings.TrimPrefix(arg, "https://t.me/"))
+		if err != nil || feedID > -1000000000000 {
+			_, err = m.Reply(lang.GetString(langCode, "musicfeed_invalid"))
+			return err
+		}
+		if feedID == chatID {
+			_, err = m.Reply(lang.GetString(langCode, "musicfeed_same_chat"))
+			return err
+		}
+		if owner, ok := db.Instance.MusicFeedOwner(feedID); ok && owner != chatID {
+			_, err = m.Reply(lang.GetString(langCode, "musicfeed_taken"))
+			return err
+		}
+		if userID := actorID(m); userID == 0 {
+			_, err = m.Reply(lang.GetString(langCode, "musicfeed_not_admin"))
+			return err
+		} else if _, err = cache.GetUserAdmin(m.Client, feedID, userID, false); err != nil {
+			_, err = m.Reply(lang.GetString(langCode, "musicfeed_not_admin"))
+			return err
+		}
+		feed = db.MusicFeed{ChatID: feedID}
+	}
+
+	// Posting a first message to the feed checks that the bot can post there.
+	if _, err := m.Client.SendMessage(feed.ChatID, lang.GetString(langCode, "musicfeed_connected"), &telegram.SendOptions{TopicID: feed.TopicID}); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "musicfeed_cannot_post"), err.Error()))
+		return err
+	}
+	if err := db.Instance.SetMusicFeed(ctx, chatID, feed); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "musicfeed_error"), err.Error()))
+		return err
+	}
+	_, err := m.Reply(lang.GetString(langCode, "musicfeed_set"))
+	return err
+}
+
+// delMusicFeedHandler handles the /delmusicfeed command.
+// It removes the chat's music feed, so its music messages are posted in the chat again.
+func delMusicFeedHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	removed, err := db.Instance.DeleteMusicFeed(ctx, chatID)
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "musicfeed_error"), err.Error()))
+		return err
+	}
+	key := "musicfeed_removed"
+	if !removed {
+		key = "musicfeed_none"
+	}
+	_, err = m.Reply(lang.GetString(langCode, key))
+	return err
+}
+
+// confirm shows the final message of a request: in the chat's music feed if it has one, leaving a short note that
+// deletes itself in the status message, or in the status message otherwise.
+// It returns the message that holds the text.
+func (su *statusUpdater) confirm(chatID int64, langCode, text string, opts telegram.SendOptions) (*telegram.NewMessage, error) {
+	if msg, ok := musicfeed.Send(su.Client, chatID, text, &opts); ok {
+		_, err := su.Edit(lang.GetString(langCode, "musicfeed_queued"))
+		time.AfterFunc(musicFeedNoteTTL, func() { _, _ = su.Delete() })
+		return msg, err
+	}
+	return su.Edit(text, opts)
+}
+
+// musicChatID returns the chat a callback acts on: the chat its button was pressed in, or the chat whose music feed
+// that is.
+func musicChatID(cb *telegram.CallbackQuery) (int64, error) {
+	chatID, err := getPeerId(cb.Client, cb.ChatID)
+	if err != nil {
+		return 0, err
+	}
+	return musicfeed.ChatOf(chatID), nil
+}
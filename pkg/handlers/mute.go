@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"fmt"
-	"github.com/zuchzub/Go/pkg/core"
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
@@ -27,7 +26,12 @@ func muteHandler(m *telegram.NewMessage) error {
 		return err
 	}
 
-	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "mute_success"), m.Sender.FirstName), telegram.SendOptions{ReplyMarkup: core.ControlButtons("mute")})
+	if track := cache.ChatCache.GetPlayingTrack(chatID); track != nil {
+		text := buildTrackMessage(langCode, track, lang.GetString(langCode, "muted"), "🔇") + fmt.Sprintf(lang.GetString(langCode, "muted_by"), actorName(m))
+		updateNowPlaying(m, chatID, text, "mute")
+	}
+
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "mute_success"), actorName(m)))
 	return err
 }
 
@@ -47,6 +51,11 @@ func unmuteHandler(m *telegram.NewMessage) error {
 		return err
 	}
 
-	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "unmute_success"), m.Sender.FirstName), telegram.SendOptions{ReplyMarkup: core.ControlButtons("unmute")})
+	if track := cache.ChatCache.GetPlayingTrack(chatID); track != nil {
+		text := buildTrackMessage(langCode, track, lang.GetString(langCode, "now_playing"), "🎵") + fmt.Sprintf(lang.GetString(langCode, "unmuted_by"), actorName(m))
+		updateNowPlaying(m, chatID, text, "unmute")
+	}
+
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "unmute_success"), actorName(m)))
 	return err
 }
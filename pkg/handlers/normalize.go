@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// normalizeHandler handles the /normalize command.
+// It toggles loudness normalization for the chat; the change applies from the next track.
+func normalizeHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	var enabled bool
+	switch strings.ToLower(m.Args()) {
+	case "on", "enable":
+		enabled = true
+	case "off", "disable":
+		enabled = false
+	default:
+		status := lang.GetString(langCode, "normalize_status_off")
+		if db.Instance.GetNormalize(ctx, chatID) {
+			status = lang.GetString(langCode, "normalize_status_on")
+		}
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "normalize_usage"), status))
+		return err
+	}
+
+	if err := db.Instance.SetNormalize(ctx, chatID, enabled); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "normalize_error"), err.Error()))
+		return err
+	}
+
+	key := "normalize_disabled"
+	if enabled {
+		key = "normalize_enabled"
+	}
+	_, err := m.Reply(lang.GetString(langCode, key))
+	return err
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"fmt"
-	"github.com/zuchzub/Go/pkg/core"
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
@@ -27,7 +26,12 @@ func pauseHandler(m *telegram.NewMessage) error {
 		return nil
 	}
 
-	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "pause_success"), m.Sender.FirstName), telegram.SendOptions{ReplyMarkup: core.ControlButtons("pause")})
+	if track := cache.ChatCache.GetPlayingTrack(chatID); track != nil {
+		text := buildTrackMessage(langCode, track, lang.GetString(langCode, "paused"), "⏸") + fmt.Sprintf(lang.GetString(langCode, "paused_by"), actorName(m))
+		updateNowPlaying(m, chatID, text, "pause")
+	}
+
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "pause_success"), actorName(m)))
 	return err
 }
 
@@ -52,6 +56,11 @@ func resumeHandler(m *telegram.NewMessage) error {
 		return nil
 	}
 
-	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "resume_success"), m.Sender.FirstName), telegram.SendOptions{ReplyMarkup: core.ControlButtons("resume")})
+	if track := cache.ChatCache.GetPlayingTrack(chatID); track != nil {
+		text := buildTrackMessage(langCode, track, lang.GetString(langCode, "now_playing"), "🎵") + fmt.Sprintf(lang.GetString(langCode, "resumed_by"), actorName(m))
+		updateNowPlaying(m, chatID, text, "resume")
+	}
+
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "resume_success"), actorName(m)))
 	return err
 }
@@ -2,15 +2,18 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/zuchzub/Go/pkg/config"
 	"github.com/zuchzub/Go/pkg/core"
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/core/storagehealth"
+	"github.com/zuchzub/Go/pkg/core/timeparse"
 	"github.com/zuchzub/Go/pkg/lang"
 	"github.com/zuchzub/Go/pkg/vc"
-	"path/filepath"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -50,6 +53,40 @@ func (su *statusUpdater) Edit(text string, opts ...telegram.SendOptions) (*teleg
 	return msg, err
 }
 
+// startAtRegex matches the optional at=<time> argument of /play.
+var startAtRegex = regexp.MustCompile(`(?i)(?:^|\s)at=(\S+)`)
+
+// parseStartAt extracts an at=<time> argument from args.
+// It returns the remaining arguments and the start offset in seconds.
+func parseStartAt(args string) (string, int, error) {
+	match := startAtRegex.FindStringSubmatchIndex(args)
+	if match == nil {
+		return args, 0, nil
+	}
+
+	rest := strings.Join(strings.Fields(args[:match[0]]+" "+args[match[1]:]), " ")
+	startAt, err := timeparse.ParseDuration(args[match[2]:match[3]])
+	if err != nil {
+		return rest, 0, err
+	}
+	return rest, int(startAt.Seconds()), nil
+}
+
+// startAtError validates the start offset of a track against its duration.
+// An offset on a track whose duration is not known yet is accepted; it is checked again once the track is downloaded.
+// It returns the error message to show, or an empty string if the offset can be used.
+func startAtError(track *cache.CachedTrack, langCode string) string {
+	switch {
+	case track.StartAt <= 0:
+		return ""
+	case track.IsLive:
+		return lang.GetString(langCode, "seek_live_unsupported")
+	case track.Duration > 0 && track.StartAt >= track.Duration:
+		return fmt.Sprintf(lang.GetString(langCode, "play_start_out_of_range"), cache.SecToMin(track.StartAt), cache.SecToMin(track.Duration))
+	}
+	return ""
+}
+
 // playHandler handles the /play command.
 func playHandler(m *telegram.NewMessage) error {
 	return handlePlay(m, false)
@@ -66,16 +103,31 @@ func handlePlay(m *telegram.NewMessage, isVideo bool) error {
 	ctx, cancel := db.Ctx()
 	defer cancel()
 	langCode := db.Instance.GetLang(ctx, chatID)
+	if storagehealth.Degraded() {
+		_, err := m.Reply(lang.GetString(langCode, "play_storage_unavailable"))
+		return err
+	}
+	if queueLockedFor(ctx, m, chatID, langCode) {
+		return nil
+	}
 	if queue := cache.ChatCache.GetQueue(chatID); len(queue) > 10 {
+		cache.RecordQueueStat(chatID, cache.QueueRejected, 1)
 		_, err := m.Reply(lang.GetString(langCode, "play_queue_full"))
 		return err
 	}
 
 	isReply := m.IsReply()
 	url := getUrl(m, isReply)
-	args := m.Args()
+	args, startAt, err := parseStartAt(m.Args())
+	if err != nil {
+		_, err = m.Reply(lang.GetString(langCode, "play_start_invalid"))
+		return err
+	}
 	rMsg := m
-	var err error
+
+	if links := batchLinks(args); links != nil && !isReply {
+		return handleBatch(m, links, chatID, isVideo, langCode)
+	}
 
 	parseTelegramURL := func(input string) (string, int, bool) {
 		re := regexp.MustCompile(`^https://t\.me/([a-zA-Z0-9_]{4,})/(\d+)$`)
@@ -90,7 +142,10 @@ func handlePlay(m *telegram.NewMessage, isVideo bool) error {
 		return matches[1], id, true
 	}
 
-	input := coalesce(url, args)
+	input, urlStartAt := stripStartParam(coalesce(url, args))
+	if startAt == 0 {
+		startAt = urlStartAt
+	}
 	if username, msgID, ok := parseTelegramURL(input); ok {
 		rMsg, err = m.Client.GetMessageByID(username, int32(msgID))
 		if err != nil {
@@ -123,7 +178,7 @@ func handlePlay(m *telegram.NewMessage, isVideo bool) error {
 	updater := &statusUpdater{NewMessage: statusMsg, lastMessage: lang.GetString(langCode, "play_searching"), lastSent: time.Now()}
 
 	if isReply && isValidMedia(rMsg) {
-		return handleMedia(m, updater, rMsg, chatID, isVideo, langCode)
+		return handleMedia(m, updater, rMsg, chatID, isVideo, startAt, langCode)
 	}
 
 	wrapper := dl.NewDownloaderWrapper(input)
@@ -132,6 +187,9 @@ func handlePlay(m *telegram.NewMessage, isVideo bool) error {
 			_, err = updater.Edit(lang.GetString(langCode, "play_invalid_url"), telegram.SendOptions{ReplyMarkup: core.SupportKeyboard()})
 			return err
 		}
+		if link := dl.ParseYouTubeURL(input); link.Kind == dl.YouTubeVideoInPlaylist {
+			return handleVideoInPlaylist(m, updater, link, chatID, isVideo, startAt, langCode)
+		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
@@ -144,16 +202,16 @@ func handlePlay(m *telegram.NewMessage, isVideo bool) error {
 			_, err = updater.Edit(lang.GetString(langCode, "play_no_tracks_found"))
 			return err
 		}
-		return handleUrl(m, updater, trackInfo, chatID, isVideo, langCode)
+		return handleUrl(m, updater, trackInfo, input, chatID, isVideo, startAt, langCode)
 	}
 
 	ctx2, cancel2 := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel2()
-	return handleTextSearch(m, updater, wrapper, chatID, isVideo, ctx2, langCode)
+	return handleTextSearch(m, updater, wrapper, chatID, isVideo, startAt, ctx2, langCode)
 }
 
 // handleMedia handles playing media from a message.
-func handleMedia(m *telegram.NewMessage, updater *statusUpdater, dlMsg *telegram.NewMessage, chatId int64, isVideo bool, langCode string) error {
+func handleMedia(m *telegram.NewMessage, updater *statusUpdater, dlMsg *telegram.NewMessage, chatId int64, isVideo bool, startAt int, langCode string) error {
 	if dlMsg.File.Size > config.Conf.MaxFileSize {
 		_, err := updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_file_too_large"), config.Conf.MaxFileSize/(1024*1024)))
 		if err != nil {
@@ -176,14 +234,14 @@ func handleMedia(m *telegram.NewMessage, updater *statusUpdater, dlMsg *telegram
 	dur := cache.GetFileDur(dlMsg)
 	if cache.ChatCache.IsActive(chatId) {
 		saveCache := cache.CachedTrack{
-			URL: dlMsg.Link(), Name: fileName, User: m.Sender.FirstName, TrackID: fileId,
+			URL: dlMsg.Link(), Name: fileName, User: actorName(m), UserID: actorID(m), TrackID: fileId,
 			Duration: dur, IsVideo: isVideo, Platform: cache.Telegram,
 		}
 		queue := cache.ChatCache.GetQueue(chatId)
 		cache.ChatCache.AddSong(chatId, &saveCache)
 		queueInfo := fmt.Sprintf(
 			lang.GetString(langCode, "play_added_to_queue"),
-			len(queue), saveCache.URL, saveCache.Name, cache.SecToMin(saveCache.Duration), saveCache.User,
+			len(queue), core.FormatTrackLine(&saveCache, core.TrackLineCompact), cache.SecToMin(saveCache.Duration), saveCache.User,
 		)
 		_, err := updater.Edit(queueInfo, telegram.SendOptions{ReplyMarkup: core.ControlButtons("play")})
 		if err != nil {
@@ -192,8 +250,9 @@ func handleMedia(m *telegram.NewMessage, updater *statusUpdater, dlMsg *telegram
 		return nil
 	}
 
-	filePath, err := dlMsg.Download(&telegram.DownloadOptions{FileName: filepath.Join(config.Conf.DownloadsDir, fileName)})
+	filePath, err := downloadMedia(dlMsg, chatId, fileName)
 	if err != nil {
+		storagehealth.Report(err)
 		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_download_failed"), err.Error()))
 		return err
 	}
@@ -206,11 +265,29 @@ func handleMedia(m *telegram.NewMessage, updater *statusUpdater, dlMsg *telegram
 	track := cache.MusicTrack{
 		Name: fileName, Duration: dur, URL: dlMsg.Link(), ID: fileId, Platform: cache.Telegram,
 	}
-	return handleSingleTrack(m, updater, track, filePath, chatId, isVideo, langCode)
+	return handleSingleTrack(m, updater, track, filePath, chatId, isVideo, startAt, langCode)
+}
+
+// downloadMedia downloads the media of a message to the downloads directory, listing it in /downloads meanwhile.
+// Telegram downloads cannot be interrupted, so one cancelled from /downloads is discarded once it finishes.
+func downloadMedia(msg *telegram.NewMessage, chatId int64, fileName string) (string, error) {
+	ctx, done := dl.StartDownload(context.Background(), chatId, fileName, cache.Telegram)
+	defer done()
+
+	download := func(opts *telegram.DownloadOptions) (string, error) { return msg.Download(opts) }
+	filePath, err := dl.DownloadTelegram(ctx, download, fileName, msg.File.Size)
+	if err != nil {
+		return "", err
+	}
+	if err = dl.DownloadCancelled(ctx); err != nil {
+		_ = os.Remove(filePath)
+		return "", err
+	}
+	return filePath, nil
 }
 
 // handleTextSearch handles a text search for a song.
-func handleTextSearch(m *telegram.NewMessage, updater *statusUpdater, wrapper *dl.DownloaderWrapper, chatId int64, isVideo bool, ctx context.Context, langCode string) error {
+func handleTextSearch(m *telegram.NewMessage, updater *statusUpdater, wrapper *dl.DownloaderWrapper, chatId int64, isVideo bool, startAt int, ctx context.Context, langCode string) error {
 	searchResult, err := wrapper.Search(ctx)
 	if err != nil {
 		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_search_failed"), err.Error()))
@@ -228,41 +305,110 @@ func handleTextSearch(m *telegram.NewMessage, updater *statusUpdater, wrapper *d
 		return err
 	}
 
-	return handleSingleTrack(m, updater, song, "", chatId, isVideo, langCode)
+	return handleSingleTrack(m, updater, song, "", chatId, isVideo, startAt, langCode)
 }
 
 // handleUrl handles a URL search for a song.
-func handleUrl(m *telegram.NewMessage, updater *statusUpdater, trackInfo cache.PlatformTracks, chatId int64, isVideo bool, langCode string) error {
+// sourceURL is the URL the tracks were fetched from, remembered on the tracks when it is a playlist so that /continue can resume it.
+func handleUrl(m *telegram.NewMessage, updater *statusUpdater, trackInfo cache.PlatformTracks, sourceURL string, chatId int64, isVideo bool, startAt int, langCode string) error {
 	if len(trackInfo.Results) == 1 {
 		track := trackInfo.Results[0]
 		if _track := cache.ChatCache.GetTrackIfExists(chatId, track.ID); _track != nil {
 			_, err := updater.Edit(lang.GetString(langCode, "play_track_already_in_queue"))
 			return err
 		}
-		return handleSingleTrack(m, updater, track, "", chatId, isVideo, langCode)
+		return handleSingleTrack(m, updater, track, "", chatId, isVideo, startAt, langCode)
+	}
+	return handleMultipleTracks(m, updater, trackInfo.Results, chatId, isVideo, &playlistSource{url: sourceURL}, 0, langCode)
+}
+
+// handleBatch queues the tracks of several links sent in one /play, up to MaxPlaylistTracks tracks.
+// Links that are invalid or cannot be fetched are skipped and counted in the summary.
+func handleBatch(m *telegram.NewMessage, links []string, chatId int64, isVideo bool, langCode string) error {
+	statusMsg, err := m.Reply(lang.GetString(langCode, "play_searching"))
+	if err != nil {
+		gologging.WarnF("failed to send message: %v", err)
+		return err
+	}
+	updater := &statusUpdater{NewMessage: statusMsg, lastMessage: lang.GetString(langCode, "play_searching"), lastSent: time.Now()}
+
+	var tracks []cache.MusicTrack
+	skipped := 0
+	for i, link := range links {
+		if len(tracks) >= config.Conf.MaxPlaylistTracks {
+			skipped += len(links) - i
+			break
+		}
+
+		link, _ = stripStartParam(link)
+		wrapper := dl.NewDownloaderWrapper(link)
+		if !wrapper.IsValid() {
+			skipped++
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		trackInfo, err := wrapper.GetInfo(ctx)
+		cancel()
+		if err != nil || len(trackInfo.Results) == 0 {
+			gologging.DebugF("[play.go - handleBatch] Skipping %s: %v", link, err)
+			skipped++
+			continue
+		}
+
+		for _, track := range trackInfo.Results {
+			if cache.ChatCache.GetTrackIfExists(chatId, track.ID) == nil {
+				tracks = append(tracks, track)
+			}
+		}
+	}
+	tracks = tracks[:min(len(tracks), config.Conf.MaxPlaylistTracks)]
+
+	if len(tracks) == 0 {
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_batch_none"), skipped))
+		return err
 	}
-	return handleMultipleTracks(m, updater, trackInfo.Results, chatId, isVideo, langCode)
+	return handleMultipleTracks(m, updater, tracks, chatId, isVideo, nil, skipped, langCode)
 }
 
 // handleSingleTrack handles a single track.
-func handleSingleTrack(m *telegram.NewMessage, updater *statusUpdater, song cache.MusicTrack, filePath string, chatId int64, isVideo bool, langCode string) error {
+// startAt is stored on the track, which starts playing from that offset whether it plays now or later from the queue.
+func handleSingleTrack(m *telegram.NewMessage, updater *statusUpdater, song cache.MusicTrack, filePath string, chatId int64, isVideo bool, startAt int, langCode string) error {
 	saveCache := cache.CachedTrack{
-		URL: song.URL, Name: song.Name, User: m.Sender.FirstName, FilePath: filePath,
+		URL: song.URL, Name: song.Name, User: actorName(m), UserID: actorID(m), FilePath: filePath,
 		Thumbnail: song.Cover, TrackID: song.ID, Duration: song.Duration,
-		IsVideo: isVideo, Platform: song.Platform,
+		IsVideo: isVideo, Platform: song.Platform, IsLive: song.IsLive, ContentType: song.ContentType,
+		StartAt: startAt,
 	}
 
-	if cache.ChatCache.IsActive(chatId) {
+	if exceedsDurationLimit(saveCache.Duration, saveCache.IsLive, saveCache.ContentType) {
+		cache.RecordQueueStat(chatId, cache.QueueRejected, 1)
+		_, err := updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_too_long"), cache.SecToMin(durationLimit(saveCache.ContentType))))
+		return err
+	}
+
+	if msg := startAtError(&saveCache, langCode); msg != "" {
+		_, err := updater.Edit(msg)
+		return err
+	}
+
+	// Claiming the chat before the download makes a concurrent /play in the same idle chat queue its track behind this one
+	// instead of starting playback a second time.
+	if !cache.ChatCache.StartIfIdle(chatId, &saveCache) {
 		queue := cache.ChatCache.GetQueue(chatId)
 		cache.ChatCache.AddSong(chatId, &saveCache)
 		queueInfo := fmt.Sprintf(
 			lang.GetString(langCode, "play_added_to_queue"),
-			len(queue), saveCache.URL, saveCache.Name, cache.SecToMin(saveCache.Duration), saveCache.User,
+			len(queue), core.FormatTrackLine(&saveCache, core.TrackLineCompact), formatDuration(langCode, max(saveCache.Duration-saveCache.StartAt, 0), saveCache.IsLive), saveCache.User,
 		)
-		_, err := updater.Edit(queueInfo, telegram.SendOptions{ReplyMarkup: core.ControlButtons("play")})
+		if saveCache.StartAt > 0 {
+			queueInfo += fmt.Sprintf(lang.GetString(langCode, "play_start_offset"), cache.SecToMin(saveCache.StartAt))
+		}
+		_, err := updater.confirm(chatId, langCode, queueInfo, telegram.SendOptions{ReplyMarkup: core.ControlButtons("play")})
 		if err != nil {
 			gologging.WarnF("[play.go - handleSingleTrack] Edit message failed: %v", err)
 		}
+		vc.Calls.PrefetchNext(chatId)
 		return nil
 	}
 
@@ -274,8 +420,10 @@ func handleSingleTrack(m *telegram.NewMessage, updater *statusUpdater, song cach
 
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
 		defer cancel()
-		dlResult, trackInfo, err := vc.DownloadSong(ctx, &saveCache, m.Client)
+		dlResult, trackInfo, err := vc.DownloadSong(ctx, chatId, &saveCache, m.Client)
 		if err != nil {
+			cache.RecordQueueStat(chatId, cache.QueueFailed, 1)
+			abortStart(chatId)
 			_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_song_download_failed"), err.Error()))
 			return err
 		}
@@ -286,58 +434,143 @@ func handleSingleTrack(m *telegram.NewMessage, updater *statusUpdater, song cach
 			if song.Duration == 0 {
 				saveCache.Duration = trackInfo.Duration
 			}
+			if trackInfo.IsLive {
+				saveCache.IsLive = true
+				saveCache.Duration = 0
+			}
+			if trackInfo.ContentType != "" {
+				saveCache.ContentType = trackInfo.ContentType
+			}
+		}
+
+		if exceedsDurationLimit(saveCache.Duration, saveCache.IsLive, saveCache.ContentType) {
+			cache.RecordQueueStat(chatId, cache.QueueRejected, 1)
+			abortStart(chatId)
+			// A stream is played from its URL, so only a downloaded file is left to remove.
+			if !strings.HasPrefix(dlResult, "https://") && !strings.HasPrefix(dlResult, "http://") {
+				removeUnqueuedFile(dlResult)
+			}
+			_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_too_long"), cache.SecToMin(durationLimit(saveCache.ContentType))))
+			return err
 		}
 	}
 
-	cache.ChatCache.SetActive(chatId, true)
-	cache.ChatCache.AddSong(chatId, &saveCache)
+	if msg := startAtError(&saveCache, langCode); msg != "" {
+		abortStart(chatId)
+		_, err := updater.Edit(msg)
+		return err
+	}
 
-	if err := vc.Calls.PlayMedia(chatId, saveCache.FilePath, saveCache.IsVideo, ""); err != nil {
+	var err error
+	joined := vc.Calls.OnJoining(chatId, func() { _, _ = updater.Edit(lang.GetString(langCode, "assistant_joining")) })
+	if saveCache.StartAt > 0 {
+		err = vc.Calls.SeekStream(chatId, saveCache.FilePath, saveCache.StartAt, saveCache.Duration, saveCache.IsVideo)
+	} else {
+		err = vc.Calls.PlayMedia(chatId, saveCache.FilePath, saveCache.IsVideo, "")
+	}
+	joined()
+	if err != nil {
+		// The track stays claimed as the current one, so that the buttons can play it as audio only or skip it.
+		var full *vc.VideoSlotsFullError
+		if errors.As(err, &full) {
+			text, opts := vc.VideoSlotsFullReply(langCode, &saveCache, full)
+			_, err = updater.Edit(text, opts)
+			return err
+		}
+		cache.RecordQueueStat(chatId, cache.QueueFailed, 1)
+		abortStart(chatId)
 		_, err = updater.Edit(err.Error())
 		return err
 	}
+	vc.Calls.WaitForPlayback(chatId)
 
 	nowPlaying := fmt.Sprintf(
 		lang.GetString(langCode, "play_now_playing"),
-		saveCache.URL, saveCache.Name, cache.SecToMin(song.Duration), saveCache.User,
+		core.FormatTrackLine(&saveCache, core.TrackLineCompact), formatDuration(langCode, max(saveCache.Duration-saveCache.StartAt, 0), saveCache.IsLive), saveCache.User,
 	)
-	_, err := updater.Edit(nowPlaying, telegram.SendOptions{ReplyMarkup: core.ControlButtons("play")})
+	if saveCache.StartAt > 0 {
+		nowPlaying += fmt.Sprintf(lang.GetString(langCode, "play_start_offset"), cache.SecToMin(saveCache.StartAt))
+	}
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	nowPlaying += vc.VolumeNote(langCode, db.Instance.GetVolume(ctx, chatId))
+	msg, err := updater.confirm(chatId, langCode, nowPlaying, telegram.SendOptions{ReplyMarkup: core.ControlButtons("play")})
 	if err != nil {
 		gologging.WarnF("[play.go - handleSingleTrack] Edit message failed: %v", err)
 	}
+	if msg == nil {
+		msg = updater.NewMessage
+	}
+	cache.ChatCache.SetNowPlayingMessage(chatId, msg.ID)
+	vc.Calls.PrefetchNext(chatId)
 	return nil
 }
 
+// abortStart releases a chat claimed by handleSingleTrack whose track could not be started.
+// Tracks queued behind it by other requests in the meantime are played instead of being dropped.
+func abortStart(chatId int64) {
+	if cache.ChatCache.GetUpcomingTrack(chatId) != nil {
+		_ = vc.Calls.PlayNext(chatId)
+		return
+	}
+	cache.ChatCache.ClearChat(chatId, false)
+}
+
+// playlistSource is the playlist that the tracks passed to handleMultipleTracks come from.
+type playlistSource struct {
+	url    string
+	offset int // offset is the index in the playlist of the first track passed.
+}
+
 // handleMultipleTracks handles multiple tracks.
-func handleMultipleTracks(m *telegram.NewMessage, updater *statusUpdater, tracks []cache.MusicTrack, chatId int64, isVideo bool, langCode string) error {
+// source is the playlist the tracks come from, or nil if they do not come from a single one.
+// skippedLinks is the number of links of a batch that could not be queued, reported in the summary.
+func handleMultipleTracks(m *telegram.NewMessage, updater *statusUpdater, tracks []cache.MusicTrack, chatId int64, isVideo bool, source *playlistSource, skippedLinks int, langCode string) error {
 	isActive := cache.ChatCache.IsActive(chatId)
 	queue := cache.ChatCache.GetQueue(chatId)
 	queueHeader := lang.GetString(langCode, "play_added_to_queue_header")
 	var queueItems []string
+	var skipped, totalDuration int
 
 	for i, track := range tracks {
-		position := len(queue) + i
+		if exceedsDurationLimit(track.Duration, track.IsLive, track.ContentType) {
+			skipped++
+			continue
+		}
+
+		position := len(queue) + len(queueItems)
 		saveCache := cache.CachedTrack{
 			Name: track.Name, TrackID: track.ID, Duration: track.Duration,
-			Thumbnail: track.Cover, User: m.Sender.FirstName, Platform: track.Platform,
-			IsVideo: isVideo, URL: track.URL,
+			Thumbnail: track.Cover, User: actorName(m), UserID: actorID(m), Platform: track.Platform,
+			IsVideo: isVideo, URL: track.URL, IsLive: track.IsLive, ContentType: track.ContentType,
+		}
+		if source != nil && source.url != "" {
+			saveCache.SourceURL, saveCache.SourceIndex = source.url, source.offset+i
 		}
-		if !isActive && i == 0 {
+		if !isActive && len(queueItems) == 0 {
 			saveCache.Loop = 1
 		}
 		cache.ChatCache.AddSong(chatId, &saveCache)
-		queueItems = append(queueItems, fmt.Sprintf(lang.GetString(langCode, "play_queue_item"), position, track.Name, cache.SecToMin(track.Duration)))
+		queueItems = append(queueItems, fmt.Sprintf(lang.GetString(langCode, "play_queue_item"), position, track.Name, formatDuration(langCode, track.Duration, track.IsLive)))
+		totalDuration += track.Duration
 	}
 
-	totalDuration := 0
-	for _, t := range tracks {
-		totalDuration += t.Duration
+	cache.RecordQueueStat(chatId, cache.QueueRejected, skipped)
+	if len(queueItems) == 0 {
+		_, err := updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_too_long"), cache.SecToMin(durationLimit(tracks[0].ContentType))))
+		return err
 	}
 
 	queueSummary := fmt.Sprintf(
 		lang.GetString(langCode, "play_queue_summary"),
-		len(cache.ChatCache.GetQueue(chatId)), cache.SecToMin(totalDuration), m.Sender.FirstName,
+		len(cache.ChatCache.GetQueue(chatId)), cache.SecToMin(totalDuration), actorName(m),
 	)
+	if skipped > 0 {
+		queueSummary += fmt.Sprintf(lang.GetString(langCode, "play_skipped_too_long"), skipped)
+	}
+	if skippedLinks > 0 {
+		queueSummary += fmt.Sprintf(lang.GetString(langCode, "play_skipped_links"), skippedLinks)
+	}
 	fullMessage := queueHeader + strings.Join(queueItems, "\n") + queueSummary
 	if len(fullMessage) > 4096 {
 		fullMessage = queueSummary
@@ -345,9 +578,11 @@ func handleMultipleTracks(m *telegram.NewMessage, updater *statusUpdater, tracks
 
 	if !isActive {
 		_ = vc.Calls.PlayNext(chatId)
+	} else {
+		vc.Calls.PrefetchNext(chatId)
 	}
 
-	_, err := updater.Edit(fullMessage, telegram.SendOptions{ReplyMarkup: core.ControlButtons("play")})
+	_, err := updater.confirm(chatId, langCode, fullMessage, telegram.SendOptions{ReplyMarkup: core.ControlButtons("play")})
 	if err != nil {
 		gologging.WarnF("[play.go - handleMultipleTracks] Edit message failed: %v", err)
 	}
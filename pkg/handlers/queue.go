@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
@@ -9,51 +10,63 @@ import (
 	"math"
 	"strconv"
 	"strings"
-
-	tg "github.com/amarnathcjd/gogram/telegram"
 )
 
 // queueHandler displays the current playback queue with detailed information.
-func queueHandler(m *tg.NewMessage) error {
-	chatID, _ := getPeerId(m.Client, m.ChatID())
+func queueHandler(hc *handlerContext, m message) error {
+	chatID, _ := getPeerId(hc.client, m.ChatID())
 	ctx, cancel := db.Ctx()
 	defer cancel()
-	langCode := db.Instance.GetLang(ctx, chatID)
-	chat := m.Channel
-	queue := cache.ChatCache.GetQueue(chatID)
+	langCode := hc.store.GetLang(ctx, chatID)
+	queue := hc.queues.GetQueue(chatID)
 	if len(queue) == 0 {
 		_, _ = m.Reply(lang.GetString(langCode, "queue_empty"))
 		return nil
 	}
 
-	if !cache.ChatCache.IsActive(chatID) {
+	if !hc.queues.IsActive(chatID) {
 		_, _ = m.Reply(lang.GetString(langCode, "queue_no_session"))
 		return nil
 	}
 
 	current := queue[0]
-	playedTime, _ := vc.Calls.PlayedTime(chatID)
+	playedTime, _ := hc.calls.PlayedTime(chatID)
 
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_header"), chat.Title))
+	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_header"), m.ChatTitle()))
+	if _, locked := hc.queues.GetQueueLock(chatID); locked {
+		b.WriteString(lang.GetString(langCode, "queue_locked_header"))
+	}
 
 	b.WriteString(lang.GetString(langCode, "queue_now_playing"))
-	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_track_title"), truncate(current.Name, 45)))
+	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_track_title"), core.FormatTrackLine(current, core.TrackLineCompact)))
 	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_requested_by"), current.User))
-	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_duration"), cache.SecToMin(current.Duration)))
+	if current.IsLive {
+		b.WriteString(lang.GetString(langCode, "queue_live"))
+	} else {
+		b.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_duration"), cache.SecToMin(current.Duration)))
+	}
 	b.WriteString(lang.GetString(langCode, "queue_loop"))
-	if current.Loop > 0 {
+	if hc.queues.GetLoopQueue(chatID) {
+		b.WriteString(lang.GetString(langCode, "queue_loop_queue"))
+	} else if current.Loop > 0 {
 		b.WriteString(lang.GetString(langCode, "queue_loop_on"))
 	} else {
 		b.WriteString(lang.GetString(langCode, "queue_loop_off"))
 	}
-	b.WriteString(lang.GetString(langCode, "queue_progress"))
+	played := 0
 	if playedTime > 0 && playedTime < math.MaxInt {
-		b.WriteString(cache.SecToMin(int(playedTime)))
-	} else {
-		b.WriteString("0:00")
+		played = int(playedTime)
 	}
-	b.WriteString(" min\n")
+	b.WriteString(lang.GetString(langCode, "queue_progress"))
+	b.WriteString(cache.SecToMin(played))
+	b.WriteString(" min")
+	if !current.IsLive {
+		b.WriteString("\n")
+		b.WriteString(cache.ProgressBar(played, current.Duration, progressBarWidth))
+	}
+	b.WriteString(vc.VolumeNote(langCode, hc.store.GetVolume(ctx, chatID)))
+	b.WriteString("\n")
 
 	if len(queue) > 1 {
 		b.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_next_up"), len(queue)-1))
@@ -63,11 +76,10 @@ func queueHandler(m *tg.NewMessage) error {
 				break
 			}
 			b.WriteString(strconv.Itoa(i + 1))
-			b.WriteString(". <code>")
-			b.WriteString(truncate(song.Name, 45))
-			b.WriteString("</code> | ")
-			b.WriteString(cache.SecToMin(song.Duration))
-			b.WriteString(" min\n")
+			b.WriteString(". ")
+			b.WriteString(core.FormatTrackLine(song, core.TrackLineQueue))
+			b.WriteString(failureNote(song, 40))
+			b.WriteString("\n")
 		}
 
 		if len(queue) > 15 {
@@ -77,17 +89,5 @@ func queueHandler(m *tg.NewMessage) error {
 
 	b.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_total"), len(queue)))
 
-	text := b.String()
-	if len(text) > 4096 {
-		var sb strings.Builder
-		progress := "0:00"
-		if playedTime > 0 && playedTime < math.MaxInt {
-			progress = cache.SecToMin(int(playedTime))
-		}
-		sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "queue_short_summary"), chat.Title, truncate(current.Name, 45), progress, cache.SecToMin(current.Duration), len(queue)))
-		text = sb.String()
-	}
-
-	_, err := m.Reply(text)
-	return err
+	return core.SendLong(m, b.String())
 }
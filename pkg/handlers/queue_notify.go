@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"html"
+	"strings"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// notifyDisplaced tells the requesters of the tracks that a reorder of a chat's queue pushed back, in one message.
+// before and after are the queue around a single reorder done by a command; the queue advancing must not be passed.
+// Nothing is sent unless the chat turned /reordernotify on.
+func notifyDisplaced(client *telegram.Client, chatID int64, before, after []*cache.CachedTrack) {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	if !db.Instance.GetReorderNotify(ctx, chatID) {
+		return
+	}
+	displaced := cache.DisplacedTracks(before, after)
+	if len(displaced) == 0 {
+		return
+	}
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	var b strings.Builder
+	for _, d := range displaced {
+		mention := html.EscapeString(d.Track.User)
+		if d.Track.UserID != 0 {
+			mention = fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, d.Track.UserID, mention)
+		}
+		b.WriteString(fmt.Sprintf(lang.GetString(langCode, "reorder_notify_line"),
+			mention, core.TruncateDisplay(d.Track.Name, 40), d.From, d.To, cache.SecToMin(d.Delay)))
+		b.WriteString("\n")
+	}
+
+	text := fmt.Sprintf(lang.GetString(langCode, "reorder_notify_message"), b.String())
+	if _, err := client.SendMessage(chatID, text); err != nil {
+		gologging.DebugF("[Reorder] Failed to notify requesters in chat %d: %v", chatID, err)
+	}
+}
+
+// reorderNotifyHandler handles the /reordernotify command.
+// It sets whether requesters are told when an admin reorders the queue and their tracks are pushed back.
+func reorderNotifyHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	var enabled bool
+	switch strings.ToLower(m.Args()) {
+	case "on", "enable":
+		enabled = true
+	case "off", "disable":
+		enabled = false
+	default:
+		status := lang.GetString(langCode, "reorder_notify_status_off")
+		if db.Instance.GetReorderNotify(ctx, chatID) {
+			status = lang.GetString(langCode, "reorder_notify_status_on")
+		}
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "reorder_notify_usage"), status))
+		return err
+	}
+
+	if err := db.Instance.SetReorderNotify(ctx, chatID, enabled); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "reorder_notify_error"), err.Error()))
+		return err
+	}
+
+	key := "reorder_notify_disabled"
+	if enabled {
+		key = "reorder_notify_enabled"
+	}
+	_, err := m.Reply(lang.GetString(langCode, key))
+	return err
+}
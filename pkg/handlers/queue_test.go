@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/handlers/testsupport"
+	"strings"
+	"testing"
+)
+
+func TestQueueHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		tracks   []*cache.CachedTrack
+		inactive bool
+		locked   bool
+		want     string
+		contains []string
+	}{
+		{name: "empty", want: "queue_empty"},
+		{name: "no session", tracks: []*cache.CachedTrack{{TrackID: "a", Name: "A"}}, inactive: true, want: "queue_no_session"},
+		{
+			name:     "playing",
+			tracks:   []*cache.CachedTrack{{TrackID: "a", Name: "First", Duration: 60}, {TrackID: "b", Name: "Second", Duration: 90}},
+			want:     "queue_header",
+			contains: []string{"First", "Second", "queue_next_up", "queue_total"},
+		},
+		{
+			name: "failed track",
+			tracks: []*cache.CachedTrack{
+				{TrackID: "a", Name: "First", Duration: 60},
+				{TrackID: "b", Name: "Second", Duration: 90, FailCount: 2, LastError: "timeout"},
+			},
+			want:     "queue_header",
+			contains: []string{"1. 🎧 Second | 1:30 ⚠️ timeout (×2)\n"},
+		},
+		{
+			name:     "locked",
+			tracks:   []*cache.CachedTrack{{TrackID: "a", Name: "First", Duration: 60}},
+			locked:   true,
+			want:     "queue_header",
+			contains: []string{"queue_locked_header"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, queues := newTestContext(&testsupport.Store{}, &testsupport.Calls{Played: 30}, tt.tracks...)
+			if tt.inactive {
+				queues.SetActive(testChatID, false)
+			}
+			if tt.locked {
+				queues.LockQueue(testChatID, false, 0)
+			}
+			m := &testsupport.Message{Chat: testChatID, Title: "Music"}
+
+			if err := queueHandler(hc, m); err != nil {
+				t.Fatalf("queueHandler() = %v", err)
+			}
+			checkReply(t, m, tt.want)
+			for _, want := range tt.contains {
+				if !strings.Contains(m.LastReply(), want) {
+					t.Errorf("reply %q does not contain %q", m.LastReply(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// queueStatsDays is how many days, today included, the weekly summary of /queuestats covers.
+const queueStatsDays = 7
+
+// formatQueueStats renders the counts of a period, with the share of accepted tracks that failed or were cleared.
+func formatQueueStats(langCode, title string, counts cache.QueueCounts) string {
+	percent := func(n int64) int64 {
+		if counts[cache.QueueEnqueued] == 0 {
+			return 0
+		}
+		return n * 100 / counts[cache.QueueEnqueued]
+	}
+	return fmt.Sprintf(lang.GetString(langCode, "queuestats_period"),
+		title,
+		counts[cache.QueueEnqueued],
+		counts[cache.QueueCompleted], percent(counts[cache.QueueCompleted]),
+		counts[cache.QueueSkipped], percent(counts[cache.QueueSkipped]),
+		counts[cache.QueueFailed], percent(counts[cache.QueueFailed]),
+		counts[cache.QueueCleared], percent(counts[cache.QueueCleared]),
+		counts[cache.QueueRejected],
+	)
+}
+
+// queueStatsHandler handles the /queuestats command.
+// It shows what happened to the tracks requested in the chat today and over the last week.
+func queueStatsHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	now := time.Now()
+	today := cache.StatsDay(now)
+	since := cache.StatsDay(now.AddDate(0, 0, -(queueStatsDays - 1)))
+	stats, err := db.Instance.GetQueueStats(ctx, chatID, since)
+	if err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "queuestats_error"), err.Error()))
+		return err
+	}
+
+	var todayCounts, weekCounts cache.QueueCounts
+	add := func(day string, counts cache.QueueCounts) {
+		if day < since {
+			return
+		}
+		weekCounts.Add(counts)
+		if day == today {
+			todayCounts.Add(counts)
+		}
+	}
+	for day, counts := range stats {
+		add(day, counts)
+	}
+	for day, counts := range cache.PendingQueueStats(chatID) {
+		add(day, counts)
+	}
+
+	text := lang.GetString(langCode, "queuestats_header") +
+		formatQueueStats(langCode, lang.GetString(langCode, "queuestats_today"), todayCounts) + "\n" +
+		formatQueueStats(langCode, fmt.Sprintf(lang.GetString(langCode, "queuestats_week"), queueStatsDays), weekCounts)
+	_, err = m.Reply(text)
+	return err
+}
@@ -5,46 +5,78 @@ import (
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
+	"html"
 	"strconv"
-
-	"github.com/amarnathcjd/gogram/telegram"
+	"strings"
 )
 
 // removeHandler handles the /remove command.
-func removeHandler(m *telegram.NewMessage) error {
-	chatID, _ := getPeerId(m.Client, m.ChatID())
+// It takes a track number, "next" for the track that plays next, or "last" for the final track in the queue.
+// The command is registered under playMode: users who pass the chat's admin mode can remove any track,
+// while anyone else can only remove the last track, and only if they queued it.
+func removeHandler(hc *handlerContext, m message) error {
+	chatID, _ := getPeerId(hc.client, m.ChatID())
 	ctx, cancel := db.Ctx()
 	defer cancel()
-	langCode := db.Instance.GetLang(ctx, chatID)
-	if !cache.ChatCache.IsActive(chatID) {
+	langCode := hc.store.GetLang(ctx, chatID)
+	if !hc.queues.IsActive(chatID) {
 		_, _ = m.Reply(lang.GetString(langCode, "no_track_playing"))
 		return nil
 	}
 
-	queue := cache.ChatCache.GetQueue(chatID)
-	if len(queue) == 0 {
+	// Index 0 is the track playing now, which /remove leaves alone.
+	queue := hc.queues.GetQueue(chatID)
+	if len(queue) < 2 {
 		_, _ = m.Reply(lang.GetString(langCode, "queue_empty"))
 		return nil
 	}
 
-	args := m.Args()
+	args := strings.ToLower(m.Args())
 	if args == "" {
 		_, _ = m.Reply(lang.GetString(langCode, "remove_usage"))
 		return nil
 	}
 
-	trackNum, err := strconv.Atoi(args)
-	if err != nil {
-		_, _ = m.Reply(lang.GetString(langCode, "remove_invalid_number"))
+	name, userID, anonymousAdmin := m.Actor()
+	privileged := anonymousAdmin || hc.canManageQueue(ctx, chatID, userID)
+	var trackNum int
+	switch args {
+	case "next":
+		trackNum = 1
+	case "last":
+		trackNum = len(queue) - 1
+		if !privileged {
+			if _, index := hc.queues.GetLastTrackRequestedBy(chatID, userID); index != trackNum {
+				_, _ = m.Reply(lang.GetString(langCode, "remove_last_not_owner"))
+				return nil
+			}
+			privileged = true
+		}
+	default:
+		var err error
+		if trackNum, err = strconv.Atoi(args); err != nil {
+			_, _ = m.Reply(lang.GetString(langCode, "remove_invalid_number"))
+			return nil
+		}
+	}
+
+	if !privileged {
+		_, _ = m.Reply(lang.GetString(langCode, "filter_not_authorized"))
 		return nil
 	}
 
-	if trackNum <= 0 || trackNum > len(queue) {
-		_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "remove_out_of_range"), len(queue)))
+	if trackNum <= 0 || trackNum >= len(queue) {
+		_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "remove_out_of_range"), len(queue)-1))
 		return nil
 	}
 
-	cache.ChatCache.RemoveTrack(chatID, trackNum)
-	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "remove_success"), trackNum, m.Sender.FirstName))
+	track := queue[trackNum]
+	hc.queues.SnapshotQueue(chatID)
+	if hc.queues.RemoveTracks(chatID, []*cache.CachedTrack{track}) == 0 {
+		hc.queues.DiscardSnapshot(chatID)
+		_, _ = m.Reply(lang.GetString(langCode, "queue_empty"))
+		return nil
+	}
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "remove_success"), trackNum, html.EscapeString(track.Name), name) + undoHint(langCode))
 	return err
 }
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/handlers/testsupport"
+	"testing"
+)
+
+func TestRemoveHandler(t *testing.T) {
+	const owner, other = 11, 22
+	queue := func() []*cache.CachedTrack {
+		return []*cache.CachedTrack{
+			{TrackID: "now", Name: "Now"},
+			{TrackID: "next", Name: "Next", UserID: other},
+			{TrackID: "last", Name: "Last", UserID: owner},
+		}
+	}
+	tests := []struct {
+		name        string
+		args        string
+		tracks      []*cache.CachedTrack
+		store       testsupport.Store
+		sender      int64
+		anonymous   bool
+		want        string
+		wantRemoved string
+	}{
+		{name: "no session", args: "1", want: "no_track_playing"},
+		{name: "nothing upcoming", args: "1", tracks: []*cache.CachedTrack{{TrackID: "now"}}, want: "queue_empty"},
+		{name: "no argument", tracks: queue(), want: "remove_usage"},
+		{name: "not a number", args: "two", tracks: queue(), want: "remove_invalid_number"},
+		{name: "out of range", args: "3", tracks: queue(), want: "remove_out_of_range"},
+		{name: "the playing track", args: "0", tracks: queue(), want: "remove_out_of_range"},
+		{name: "by number", args: "1", tracks: queue(), sender: other, want: "remove_success", wantRemoved: "next"},
+		{name: "next", args: "next", tracks: queue(), sender: other, want: "remove_success", wantRemoved: "next"},
+		{
+			name: "not authorized", args: "1", tracks: queue(), sender: other,
+			store: testsupport.Store{AdminMode: cache.Admins}, want: "filter_not_authorized",
+		},
+		{
+			name: "last by its owner", args: "last", tracks: queue(), sender: owner,
+			store: testsupport.Store{AdminMode: cache.Admins}, want: "remove_success", wantRemoved: "last",
+		},
+		{
+			name: "last by someone else", args: "last", tracks: queue(), sender: other,
+			store: testsupport.Store{AdminMode: cache.Admins}, want: "remove_last_not_owner",
+		},
+		{
+			name: "admin", args: "1", tracks: queue(), sender: other,
+			store: testsupport.Store{AdminMode: cache.Admins, Admins: []int64{other}}, want: "remove_success", wantRemoved: "next",
+		},
+		{
+			name: "DJ in auth mode", args: "1", tracks: queue(), sender: other,
+			store: testsupport.Store{AdminMode: cache.Auth, DJs: []int64{other}}, want: "remove_success", wantRemoved: "next",
+		},
+		{
+			name: "anonymous admin", args: "1", tracks: queue(), anonymous: true,
+			store: testsupport.Store{AdminMode: cache.Auth}, want: "remove_success", wantRemoved: "next",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, queues := newTestContext(&tt.store, &testsupport.Calls{}, tt.tracks...)
+			m := &testsupport.Message{Chat: testChatID, Text: tt.args, Sender: tt.sender, AnonymousAdmin: tt.anonymous}
+
+			if err := removeHandler(hc, m); err != nil {
+				t.Fatalf("removeHandler() = %v", err)
+			}
+			checkReply(t, m, tt.want)
+
+			left := queues.GetQueue(testChatID)
+			if removed := len(tt.tracks) - len(left); tt.wantRemoved == "" && removed != 0 || tt.wantRemoved != "" && removed != 1 {
+				t.Fatalf("removed %d tracks, want %q removed", removed, tt.wantRemoved)
+			}
+			for _, track := range left {
+				if track.TrackID == tt.wantRemoved {
+					t.Errorf("track %q is still queued", tt.wantRemoved)
+				}
+			}
+		})
+	}
+}
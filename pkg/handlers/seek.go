@@ -4,64 +4,136 @@ import (
 	"fmt"
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/timeparse"
 	"github.com/zuchzub/Go/pkg/lang"
-	"github.com/zuchzub/Go/pkg/vc"
-	"strconv"
-
-	"github.com/amarnathcjd/gogram/telegram"
+	"strings"
 )
 
+// seekEndMargin is how close to the end of a track /seek can go, so that a seek never lands past the last frame.
+const seekEndMargin = 5
+
+// parseSeekTarget parses the argument of /seek.
+// A value signed with + or - is an offset from the current position, returned with relative set; an unsigned value,
+// in seconds ("90"), clock form ("1:23", "1:02:03") or units ("1m30s"), is a position from the start of the track.
+func parseSeekTarget(args string) (seconds int, relative bool, err error) {
+	s := strings.TrimSpace(args)
+	sign := 1
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		relative = true
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+
+	d, err := timeparse.ParseDuration(s)
+	if err != nil {
+		return 0, false, err
+	}
+	return sign * int(d.Seconds()), relative, nil
+}
+
+// clampSeek limits a seek position to the range from the start of a track to seekEndMargin seconds before its end.
+func clampSeek(position, duration int) int {
+	return max(0, min(position, duration-seekEndMargin))
+}
+
 // seekHandler handles the /seek command.
-func seekHandler(m *telegram.NewMessage) error {
-	chatID, _ := getPeerId(m.Client, m.ChatID())
+// It jumps to a position of the playing track, or by an offset from the current one; see parseSeekTarget.
+func seekHandler(hc *handlerContext, m message) error {
+	chatID, _ := getPeerId(hc.client, m.ChatID())
 	ctx, cancel := db.Ctx()
 	defer cancel()
-	langCode := db.Instance.GetLang(ctx, chatID)
-	if !cache.ChatCache.IsActive(chatID) {
+	langCode := hc.store.GetLang(ctx, chatID)
+	if !hc.queues.IsActive(chatID) {
 		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
 		return err
 	}
 
-	playingSong := cache.ChatCache.GetPlayingTrack(chatID)
+	playingSong := hc.queues.GetPlayingTrack(chatID)
 	if playingSong == nil {
 		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
 		return err
 	}
 
+	if playingSong.IsLive {
+		_, err := m.Reply(lang.GetString(langCode, "seek_live_unsupported"))
+		return err
+	}
+
 	args := m.Args()
 	if args == "" {
 		_, _ = m.Reply(lang.GetString(langCode, "seek_usage"))
 		return nil
 	}
 
-	seekTime, err := strconv.Atoi(args)
+	target, relative, err := parseSeekTarget(args)
 	if err != nil {
 		_, _ = m.Reply(lang.GetString(langCode, "seek_invalid_time"))
 		return nil
 	}
 
-	if seekTime < 0 || seekTime < 20 {
-		_, _ = m.Reply(lang.GetString(langCode, "seek_min_time"))
-		return nil
+	toSeek := target
+	if relative {
+		currDur, err := hc.calls.PlayedTime(chatID)
+		if err != nil {
+			_, _ = m.Reply(lang.GetString(langCode, "seek_fetch_duration_error"))
+			return nil
+		}
+		toSeek += int(currDur)
 	}
+	toSeek = clampSeek(toSeek, playingSong.Duration)
 
-	currDur, err := vc.Calls.PlayedTime(chatID)
-	if err != nil {
-		_, _ = m.Reply(lang.GetString(langCode, "seek_fetch_duration_error"))
+	if err = hc.calls.SeekStream(chatID, playingSong.FilePath, toSeek, playingSong.Duration, playingSong.IsVideo); err != nil {
+		_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "seek_error"), err.Error()))
 		return nil
 	}
 
-	toSeek := int(currDur) + seekTime
-	if toSeek >= playingSong.Duration {
-		_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "seek_beyond_duration"), cache.SecToMin(playingSong.Duration)))
-		return nil
+	_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "seek_success"), cache.SecToMin(toSeek)))
+	return nil
+}
+
+// seekBackHandler handles the /seekback command.
+// It rewinds the playing track by the given time, in any unsigned form parseSeekTarget accepts, stopping at its start.
+func seekBackHandler(hc *handlerContext, m message) error {
+	chatID, _ := getPeerId(hc.client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := hc.store.GetLang(ctx, chatID)
+	playingSong := hc.queues.GetPlayingTrack(chatID)
+	if !hc.queues.IsActive(chatID) || playingSong == nil {
+		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return err
+	}
+	if playingSong.IsLive {
+		_, err := m.Reply(lang.GetString(langCode, "seek_live_unsupported"))
+		return err
 	}
 
-	if err = vc.Calls.SeekStream(chatID, playingSong.FilePath, toSeek, playingSong.Duration, playingSong.IsVideo); err != nil {
-		_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "seek_error"), err.Error()))
-		return nil
+	args := m.Args()
+	if args == "" {
+		_, err := m.Reply(lang.GetString(langCode, "seekback_usage"))
+		return err
+	}
+	seconds, relative, err := parseSeekTarget(args)
+	if err != nil || relative {
+		_, err = m.Reply(lang.GetString(langCode, "seekback_usage"))
+		return err
 	}
 
-	_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "seek_success"), cache.SecToMin(toSeek)))
-	return nil
+	// A stream whose position ntgcalls cannot report, such as one played straight from a URL, cannot be rewound from it.
+	played, err := hc.calls.PlayedTime(chatID)
+	if err != nil {
+		_, err = m.Reply(lang.GetString(langCode, "seekback_position_unknown"))
+		return err
+	}
+	toSeek := clampSeek(int(played)-seconds, playingSong.Duration)
+
+	if err = hc.calls.SeekStream(chatID, playingSong.FilePath, toSeek, playingSong.Duration, playingSong.IsVideo); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "seek_error"), err.Error()))
+		return err
+	}
+
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "seekback_success"), cache.SecToMin(toSeek)))
+	return err
 }
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/handlers/testsupport"
+	"testing"
+)
+
+func TestSeekHandler(t *testing.T) {
+	song := func() *cache.CachedTrack {
+		return &cache.CachedTrack{TrackID: "a", FilePath: "/tmp/a.mp3", Duration: 300}
+	}
+	tests := []struct {
+		name     string
+		args     string
+		tracks   []*cache.CachedTrack
+		calls    testsupport.Calls
+		want     string
+		wantSeek int // wantSeek is the position seeked to, or -1 if there is no seek.
+	}{
+		{name: "no session", args: "30", want: "no_track_playing", wantSeek: -1},
+		{name: "live stream", args: "30", tracks: []*cache.CachedTrack{{TrackID: "live", IsLive: true}}, want: "seek_live_unsupported", wantSeek: -1},
+		{name: "no argument", tracks: []*cache.CachedTrack{song()}, want: "seek_usage", wantSeek: -1},
+		{name: "invalid time", args: "soon", tracks: []*cache.CachedTrack{song()}, want: "seek_invalid_time", wantSeek: -1},
+		{name: "absolute", args: "1:30", tracks: []*cache.CachedTrack{song()}, want: "seek_success", wantSeek: 90},
+		{name: "relative", args: "+30", tracks: []*cache.CachedTrack{song()}, calls: testsupport.Calls{Played: 100}, want: "seek_success", wantSeek: 130},
+		{name: "past the end", args: "10m", tracks: []*cache.CachedTrack{song()}, want: "seek_success", wantSeek: 300 - seekEndMargin},
+		{name: "position unknown", args: "-10", tracks: []*cache.CachedTrack{song()}, calls: testsupport.Calls{PlayedErr: errors.New("no stream")}, want: "seek_fetch_duration_error", wantSeek: -1},
+		{name: "seek fails", args: "30", tracks: []*cache.CachedTrack{song()}, calls: testsupport.Calls{SeekErr: errors.New("ffmpeg")}, want: "seek_error", wantSeek: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, _ := newTestContext(&testsupport.Store{}, &tt.calls, tt.tracks...)
+			m := &testsupport.Message{Chat: testChatID, Text: tt.args}
+
+			if err := seekHandler(hc, m); err != nil {
+				t.Fatalf("seekHandler() = %v", err)
+			}
+			checkReply(t, m, tt.want)
+			switch {
+			case tt.wantSeek < 0 && len(tt.calls.Seeks) > 0:
+				t.Errorf("seeked to %v", tt.calls.Seeks)
+			case tt.wantSeek >= 0 && (len(tt.calls.Seeks) != 1 || tt.calls.Seeks[0].ToSeek != tt.wantSeek):
+				t.Errorf("seeks = %v, want one to %d", tt.calls.Seeks, tt.wantSeek)
+			}
+		})
+	}
+}
+
+func TestSeekBackHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		played   uint64
+		want     string
+		wantSeek int
+	}{
+		{name: "no argument", want: "seekback_usage", wantSeek: -1},
+		{name: "signed", args: "+10", want: "seekback_usage", wantSeek: -1},
+		{name: "rewind", args: "30", played: 100, want: "seekback_success", wantSeek: 70},
+		{name: "before the start", args: "1m", played: 20, want: "seekback_success", wantSeek: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := &testsupport.Calls{Played: tt.played}
+			hc, _ := newTestContext(&testsupport.Store{}, calls, &cache.CachedTrack{TrackID: "a", Duration: 300})
+			m := &testsupport.Message{Chat: testChatID, Text: tt.args}
+
+			if err := seekBackHandler(hc, m); err != nil {
+				t.Fatalf("seekBackHandler() = %v", err)
+			}
+			checkReply(t, m, tt.want)
+			if tt.wantSeek >= 0 && (len(calls.Seeks) != 1 || calls.Seeks[0].ToSeek != tt.wantSeek) {
+				t.Errorf("seeks = %v, want one to %d", calls.Seeks, tt.wantSeek)
+			}
+		})
+	}
+}
+
+func TestParseSeekTarget(t *testing.T) {
+	tests := []struct {
+		args         string
+		wantSeconds  int
+		wantRelative bool
+		wantErr      bool
+	}{
+		{args: "90", wantSeconds: 90},
+		{args: "5", wantSeconds: 5},
+		{args: "0", wantSeconds: 0},
+		{args: "1:23", wantSeconds: 83},
+		{args: "1:02:03", wantSeconds: 3723},
+		{args: "1m30s", wantSeconds: 90},
+		{args: " 45 ", wantSeconds: 45},
+		{args: "+30", wantSeconds: 30, wantRelative: true},
+		{args: "-30", wantSeconds: -30, wantRelative: true},
+		{args: "-1:00", wantSeconds: -60, wantRelative: true},
+		{args: "+", wantErr: true},
+		{args: "soon", wantErr: true},
+		{args: "1:75", wantErr: true},
+		{args: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			seconds, relative, err := parseSeekTarget(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSeekTarget(%q) error = %v, want error %t", tt.args, err, tt.wantErr)
+			}
+			if !tt.wantErr && (seconds != tt.wantSeconds || relative != tt.wantRelative) {
+				t.Errorf("parseSeekTarget(%q) = %d, %t; want %d, %t", tt.args, seconds, relative, tt.wantSeconds, tt.wantRelative)
+			}
+		})
+	}
+}
+
+func TestClampSeek(t *testing.T) {
+	tests := []struct {
+		position, duration, want int
+	}{
+		{90, 300, 90},
+		{-20, 300, 0},
+		{299, 300, 300 - seekEndMargin},
+		{10, 3, 0}, // A track shorter than the margin can only restart.
+	}
+	for _, tt := range tests {
+		if got := clampSeek(tt.position, tt.duration); got != tt.want {
+			t.Errorf("clampSeek(%d, %d) = %d, want %d", tt.position, tt.duration, got, tt.want)
+		}
+	}
+}
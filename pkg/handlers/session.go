@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// resumeSessionHandler handles the /resume_session command.
+// It restores the queue saved for the chat when the bot last shut down and resumes playback.
+// Like the other commands that start playback, it is refused while the bot is in maintenance.
+func resumeSessionHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if cache.IsMaintenance() {
+		_, err := m.Reply(maintenanceMessage(langCode))
+		return err
+	}
+
+	statusMsg, err := m.Reply(lang.GetString(langCode, "session_resuming"))
+	if err != nil {
+		return err
+	}
+
+	report, err := vc.Calls.ResumeSession(chatID)
+	switch {
+	case errors.Is(err, vc.ErrSessionActive):
+		_, err = statusMsg.Edit(lang.GetString(langCode, "session_active"))
+		return err
+	case errors.Is(err, vc.ErrNoSession):
+		_, err = statusMsg.Edit(lang.GetString(langCode, "session_none"))
+		return err
+	case err != nil:
+		_, err = statusMsg.Edit(fmt.Sprintf(lang.GetString(langCode, "session_resume_failed"), err.Error()))
+		return err
+	}
+
+	_, err = statusMsg.Edit(fmt.Sprintf(
+		lang.GetString(langCode, "session_resumed"),
+		report.Tracks, report.Redownload, cache.SecToMin(report.Position),
+	))
+	return err
+}
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"github.com/zuchzub/Go/pkg/core"
 	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
 	"strings"
+	"sync"
 
 	"github.com/Laky-64/gologging"
 	"github.com/amarnathcjd/gogram/telegram"
@@ -27,9 +29,9 @@ func settingsHandler(m *telegram.NewMessage) error {
 	}
 
 	// Check if user is admin
-	var isAdmin bool
+	_, userID, isAdmin := resolveActor(m)
 	for _, admin := range admins {
-		if admin.User.ID == m.Sender.ID {
+		if admin.User.ID == userID {
 			isAdmin = true
 			break
 		}
@@ -39,14 +41,13 @@ func settingsHandler(m *telegram.NewMessage) error {
 	}
 	langCode := db.Instance.GetLang(ctx, chatID)
 	// Get current settings
-	getPlayMode := db.Instance.GetPlayMode(ctx, chatID)
-	getAdminMode := db.Instance.GetAdminMode(ctx, chatID)
+	values := storedSettings(ctx, chatID)
 
 	text := fmt.Sprintf(lang.GetString(langCode, "settings_header"),
-		m.Chat.Title, getPlayMode, getAdminMode)
+		m.Chat.Title, values.playMode, values.adminMode)
 
 	_, err = m.Reply(text, telegram.SendOptions{
-		ReplyMarkup: core.SettingsKeyboard(getPlayMode, getAdminMode),
+		ReplyMarkup: core.SettingsKeyboard(values.playMode, values.adminMode, values.autoplay),
 	})
 	return err
 }
@@ -81,14 +82,15 @@ func settingsCallbackHandler(c *telegram.CallbackQuery) error {
 	}
 
 	// Process the callback data
-	parts := strings.Split(c.DataString(), "_")
-	if len(parts) < 3 {
+	_, fields := core.DecodeCallback(c.DataString())
+	if len(fields) < 2 {
+		_, _ = c.Answer(lang.GetString(langCode, "settings_update_prompt"), &telegram.CallbackOptions{Alert: true})
 		return nil
 	}
 
 	// Update the appropriate setting
-	settingType := parts[1]
-	settingValue := parts[2]
+	settingType := fields[0]
+	settingValue := fields[1]
 
 	// Validate the setting value
 	validValues := map[string]bool{
@@ -97,42 +99,194 @@ func settingsCallbackHandler(c *telegram.CallbackQuery) error {
 		cache.Everyone: true,
 	}
 
-	if !validValues[settingValue] {
-		_, _ = c.Answer(lang.GetString(langCode, "settings_update_invalid"), &telegram.CallbackOptions{Alert: true})
-		return nil
-	}
-
 	switch settingType {
-	case "play":
-		_ = db.Instance.SetPlayMode(ctx, chatID, settingValue)
-	case "admin":
-		_ = db.Instance.SetAdminMode(ctx, chatID, settingValue)
+	case "play", "admin":
+	case "autoplay":
+		validValues = map[string]bool{"on": true, "off": true}
 	default:
 		_, _ = c.Answer(lang.GetString(langCode, "settings_update_prompt"), &telegram.CallbackOptions{Alert: true})
 		return nil
 	}
 
-	// Get updated settings
-	getPlayMode := db.Instance.GetPlayMode(ctx, chatID)
-	getAdminMode := db.Instance.GetAdminMode(ctx, chatID)
+	if !validValues[settingValue] {
+		_, _ = c.Answer(lang.GetString(langCode, "settings_update_invalid"), &telegram.CallbackOptions{Alert: true})
+		return nil
+	}
+
 	chat, err := c.GetChannel()
 	if err != nil {
 		gologging.WarnF("Failed to get chat: %v", err)
 		return nil
 	}
 
-	text := fmt.Sprintf(lang.GetString(langCode, "settings_header"),
-		chat.Title, getPlayMode, getAdminMode)
+	// The keyboard is shown with the new value at once; the write runs in the background and only a failure
+	// re-renders it, from the values stored in the database.
+	_, _ = c.Answer(lang.GetString(langCode, "settings_saving"))
+	values := settingsWrites.values(ctx, chatID)
+	values.set(settingType, settingValue)
+	renderSettings(c, langCode, chat.Title, values, "")
 
-	_, err = c.Edit(text, &telegram.SendOptions{
-		ReplyMarkup: core.SettingsKeyboard(getPlayMode, getAdminMode),
+	settingsWrites.submit(chatID, settingType, settingValue, func(err error) {
+		gologging.WarnF("[Settings] Failed to save the %s setting of chat %d: %v", settingType, chatID, err)
+		ctx, cancel := db.Ctx()
+		defer cancel()
+		renderSettings(c, langCode, chat.Title, settingsWrites.values(ctx, chatID), lang.GetString(langCode, "settings_save_failed"))
 	})
-	if err != nil {
-		gologging.WarnF("Failed to edit message: %v", err)
-		return err
+	return nil
+}
+
+// chatSettings holds the values of the settings menu: the play and admin modes, and autoplay as "on" or "off".
+type chatSettings struct {
+	playMode  string
+	adminMode string
+	autoplay  string
+}
+
+// storedSettings returns a chat's settings as stored in the database.
+func storedSettings(ctx context.Context, chatID int64) chatSettings {
+	autoplay := "off"
+	if db.Instance.GetAutoplay(ctx, chatID) {
+		autoplay = "on"
 	}
+	return chatSettings{
+		playMode:  db.Instance.GetPlayMode(ctx, chatID),
+		adminMode: db.Instance.GetAdminMode(ctx, chatID),
+		autoplay:  autoplay,
+	}
+}
 
-	_, _ = c.Answer(lang.GetString(langCode, "settings_updated"), &telegram.CallbackOptions{Alert: false})
-	_, _ = c.Edit(lang.GetString(langCode, "settings_updated"))
+// saveSetting stores one of a chat's settings in the database, named as in the settings menu's callback data.
+func saveSetting(ctx context.Context, chatID int64, setting, value string) error {
+	switch setting {
+	case "play":
+		return db.Instance.SetPlayMode(ctx, chatID, value)
+	case "admin":
+		return db.Instance.SetAdminMode(ctx, chatID, value)
+	case "autoplay":
+		return db.Instance.SetAutoplay(ctx, chatID, value == "on")
+	}
 	return nil
 }
+
+// set sets the value of a setting, named as in the settings menu's callback data.
+func (s *chatSettings) set(setting, value string) {
+	switch setting {
+	case "play":
+		s.playMode = value
+	case "admin":
+		s.adminMode = value
+	case "autoplay":
+		s.autoplay = value
+	}
+}
+
+// renderSettings edits the settings message to show the given values, followed by note if it is not empty.
+func renderSettings(c *telegram.CallbackQuery, langCode, title string, values chatSettings, note string) {
+	text := fmt.Sprintf(lang.GetString(langCode, "settings_header"), title, values.playMode, values.adminMode)
+	if note != "" {
+		text += "\n\n" + note
+	}
+
+	_, err := c.Edit(text, &telegram.SendOptions{ReplyMarkup: core.SettingsKeyboard(values.playMode, values.adminMode, values.autoplay)})
+	if err != nil && !strings.Contains(err.Error(), "MESSAGE_NOT_MODIFIED") {
+		gologging.WarnF("Failed to edit message: %v", err)
+	}
+}
+
+// settingsWriter runs the settings writes of each chat one at a time in the background.
+// While a write runs, further taps only replace the value waiting to be written for their setting, so rapid taps
+// never race each other and the last value tapped is the one stored.
+type settingsWriter struct {
+	mu      sync.Mutex
+	pending map[int64]map[string]pendingSetting // pending holds the values waiting to be written, by chat and setting.
+	writing map[int64]map[string]string         // writing holds the values being written, by chat and setting.
+
+	load func(ctx context.Context, chatID int64) chatSettings                 // load reads a chat's stored settings.
+	save func(ctx context.Context, chatID int64, setting, value string) error // save stores one setting of a chat.
+}
+
+// pendingSetting is a settings value waiting to be written, with the function called if writing it fails.
+type pendingSetting struct {
+	value  string
+	onFail func(error)
+}
+
+// settingsWrites is the settingsWriter of the settings menu.
+var settingsWrites = newSettingsWriter(storedSettings, saveSetting)
+
+// newSettingsWriter returns a settingsWriter that reads and stores settings with load and save.
+func newSettingsWriter(load func(context.Context, int64) chatSettings, save func(context.Context, int64, string, string) error) *settingsWriter {
+	return &settingsWriter{
+		pending: make(map[int64]map[string]pendingSetting),
+		writing: make(map[int64]map[string]string),
+		load:    load,
+		save:    save,
+	}
+}
+
+// submit queues a settings value to be written for a chat, and starts writing the chat's values if no write is
+// running. onFail is called from the background if the write fails.
+func (w *settingsWriter) submit(chatID int64, setting, value string, onFail func(error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pending[chatID] == nil {
+		w.pending[chatID] = make(map[string]pendingSetting)
+	}
+	w.pending[chatID][setting] = pendingSetting{value: value, onFail: onFail}
+	if w.writing[chatID] == nil {
+		w.writing[chatID] = make(map[string]string)
+		go w.run(chatID)
+	}
+}
+
+// run writes a chat's pending values one at a time until none are left.
+func (w *settingsWriter) run(chatID int64) {
+	for {
+		w.mu.Lock()
+		setting, next, ok := firstPending(w.pending[chatID])
+		if !ok {
+			delete(w.pending, chatID)
+			delete(w.writing, chatID)
+			w.mu.Unlock()
+			return
+		}
+		delete(w.pending[chatID], setting)
+		w.writing[chatID][setting] = next.value
+		w.mu.Unlock()
+
+		ctx, cancel := db.Ctx()
+		err := w.save(ctx, chatID, setting, next.value)
+		cancel()
+
+		w.mu.Lock()
+		delete(w.writing[chatID], setting)
+		w.mu.Unlock()
+		if err != nil {
+			next.onFail(err)
+		}
+	}
+}
+
+// firstPending returns one of a chat's pending settings, and false if there are none.
+func firstPending(pending map[string]pendingSetting) (string, pendingSetting, bool) {
+	for setting, p := range pending {
+		return setting, p, true
+	}
+	return "", pendingSetting{}, false
+}
+
+// values returns a chat's settings as they will be once the writes in progress and pending are done.
+func (w *settingsWriter) values(ctx context.Context, chatID int64) chatSettings {
+	values := w.load(ctx, chatID)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for setting, v := range w.writing[chatID] {
+		values.set(setting, v)
+	}
+	for setting, p := range w.pending[chatID] {
+		values.set(setting, p.value)
+	}
+	return values
+}
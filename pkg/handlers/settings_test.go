@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowSettings is a settings store whose writes wait until released, and then fail with failErr if it is set.
+type slowSettings struct {
+	mu      sync.Mutex
+	stored  chatSettings
+	writes  []string      // writes lists the values written, in order.
+	active  int           // active is the number of writes in progress.
+	overlap bool          // overlap is set if two writes ever ran at once.
+	release chan struct{} // release lets one write finish.
+	failErr error
+}
+
+func newSlowSettings() *slowSettings {
+	return &slowSettings{
+		stored:  chatSettings{playMode: "everyone", adminMode: "everyone", autoplay: "off"},
+		release: make(chan struct{}),
+	}
+}
+
+func (s *slowSettings) load(context.Context, int64) chatSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stored
+}
+
+func (s *slowSettings) save(_ context.Context, _ int64, setting, value string) error {
+	s.mu.Lock()
+	s.active++
+	s.overlap = s.overlap || s.active > 1
+	s.writes = append(s.writes, setting+"="+value)
+	s.mu.Unlock()
+
+	<-s.release
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.failErr != nil {
+		return s.failErr
+	}
+	s.stored.set(setting, value)
+	return nil
+}
+
+// waitWrites waits until n writes have started.
+func (s *slowSettings) waitWrites(t *testing.T, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		s.mu.Lock()
+		started := len(s.writes)
+		s.mu.Unlock()
+		if started >= n {
+			return
+		}
+	}
+	t.Fatalf("%d writes did not start", n)
+}
+
+func TestSettingsWriterReconcilesOnFailure(t *testing.T) {
+	store := newSlowSettings()
+	store.failErr = errors.New("write conflict")
+	w := newSettingsWriter(store.load, store.save)
+
+	failed := make(chan chatSettings, 1)
+	w.submit(1, "play", "admins", func(err error) {
+		if !errors.Is(err, store.failErr) {
+			t.Errorf("onFail() got %v, want the write's error", err)
+		}
+		failed <- w.values(context.Background(), 1)
+	})
+	store.waitWrites(t, 1)
+
+	if got := w.values(context.Background(), 1).playMode; got != "admins" {
+		t.Errorf("play mode while the write runs = %q, want the value being written", got)
+	}
+
+	close(store.release)
+	select {
+	case values := <-failed:
+		if values.playMode != "everyone" {
+			t.Errorf("play mode after the write failed = %q, want the stored %q", values.playMode, "everyone")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("onFail was not called after the write failed")
+	}
+}
+
+func TestSettingsWriterSerializesTaps(t *testing.T) {
+	store := newSlowSettings()
+	w := newSettingsWriter(store.load, store.save)
+	fail := func(err error) { t.Errorf("onFail(%v) called for a write that succeeded", err) }
+
+	w.submit(1, "play", "admins", fail)
+	store.waitWrites(t, 1)
+
+	// Taps during the write only replace the value waiting to be written.
+	w.submit(1, "play", "auth", fail)
+	w.submit(1, "play", "everyone", fail)
+	w.submit(1, "autoplay", "on", fail)
+	if got := w.values(context.Background(), 1); got.playMode != "everyone" || got.autoplay != "on" {
+		t.Errorf("values() during the write = %+v, want the last values tapped", got)
+	}
+
+	store.release <- struct{}{}
+	store.waitWrites(t, 2)
+	store.release <- struct{}{}
+	store.waitWrites(t, 3)
+	store.release <- struct{}{}
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		w.mu.Lock()
+		_, busy := w.writing[1]
+		w.mu.Unlock()
+		if !busy {
+			break
+		}
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.overlap {
+		t.Error("two writes for the chat ran at once")
+	}
+	if len(store.writes) != 3 || store.writes[0] != "play=admins" {
+		t.Errorf("writes = %v, want play=admins followed by the last play and autoplay values", store.writes)
+	}
+	if store.stored.playMode != "everyone" || store.stored.autoplay != "on" {
+		t.Errorf("stored settings = %+v, want the last values tapped", store.stored)
+	}
+}
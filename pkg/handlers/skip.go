@@ -20,6 +20,8 @@ func skipHandler(m *telegram.NewMessage) error {
 		return nil
 	}
 
-	_ = vc.Calls.PlayNext(chatID)
+	cache.ChatCache.SetLoopCount(chatID, 0)
+	cache.RecordQueueStat(chatID, cache.QueueSkipped, 1)
+	_ = vc.Calls.Skip(chatID)
 	return nil
 }
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/core/storagehealth"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// maxCoverSize is the largest cover sent as the thumbnail of a /song upload; Telegram refuses larger thumbnails.
+const maxCoverSize = 200 * 1024
+
+// songHandler handles the /song command.
+// It finds a track by name or link, downloads it and sends the audio file to the chat, without playing it in a voice
+// chat. The downloaded file is deleted once sent, unless a queue is using it.
+// Tracks longer than the duration limit of /play are refused.
+func songHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if storagehealth.Degraded() {
+		_, err := m.Reply(lang.GetString(langCode, "play_storage_unavailable"))
+		return err
+	}
+
+	input, _ := stripStartParam(coalesce(getUrl(m, false), strings.TrimSpace(m.Args())))
+	if input == "" {
+		_, err := m.Reply(lang.GetString(langCode, "song_usage"))
+		return err
+	}
+
+	statusMsg, err := m.Reply(lang.GetString(langCode, "play_searching"))
+	if err != nil {
+		gologging.WarnF("failed to send message: %v", err)
+		return err
+	}
+	updater := &statusUpdater{NewMessage: statusMsg, lastMessage: lang.GetString(langCode, "play_searching"), lastSent: time.Now()}
+
+	song, text := findSong(input, langCode)
+	if text != "" {
+		_, err = updater.Edit(text)
+		return err
+	}
+	if song.IsLive {
+		_, err = updater.Edit(lang.GetString(langCode, "song_live"))
+		return err
+	}
+	if exceedsDurationLimit(song.Duration, false, song.ContentType) {
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_too_long"), cache.SecToMin(durationLimit(song.ContentType))))
+		return err
+	}
+
+	_, _ = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "downloading"), song.Name))
+	track := cache.CachedTrack{
+		URL: song.URL, Name: song.Name, TrackID: song.ID, Thumbnail: song.Cover, Duration: song.Duration,
+		Platform: song.Platform, ContentType: song.ContentType, User: actorName(m), UserID: actorID(m),
+	}
+	dlCtx, dlCancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer dlCancel()
+	filePath, trackInfo, err := vc.DownloadSong(dlCtx, chatID, &track, m.Client)
+	if err != nil {
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_song_download_failed"), err.Error()))
+		return err
+	}
+	defer removeUnqueuedFile(filePath)
+
+	if trackInfo != nil {
+		if track.Duration == 0 {
+			track.Duration = trackInfo.Duration
+		}
+		track.Thumbnail = coalesce(track.Thumbnail, trackInfo.Cover)
+	}
+	if track.Duration == 0 {
+		track.Duration = cache.GetFileDuration(filePath)
+	}
+	// A track whose duration was unknown until it was downloaded is checked again, so that it is not uploaded.
+	if exceedsDurationLimit(track.Duration, false, track.ContentType) {
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_too_long"), cache.SecToMin(durationLimit(track.ContentType))))
+		return err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_song_download_failed"), err.Error()))
+		return err
+	}
+	if info.Size() > config.Conf.MaxFileSize {
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_file_too_large"), config.Conf.MaxFileSize/(1024*1024)))
+		return err
+	}
+
+	_, _ = updater.Edit(lang.GetString(langCode, "song_uploading"))
+	opts := telegram.MediaOptions{
+		Caption:  fmt.Sprintf(lang.GetString(langCode, "song_caption"), core.TruncateDisplay(track.Name, 100), cache.SecToMin(track.Duration)),
+		FileName: songFileName(track.Name, filePath),
+		Attributes: []telegram.DocumentAttribute{
+			&telegram.DocumentAttributeAudio{Title: track.Name, Duration: int32(track.Duration)},
+		},
+	}
+	if cover := fetchCover(track.Thumbnail); cover != nil {
+		opts.Thumb = cover
+	}
+	if _, err = m.ReplyMedia(filePath, opts); err != nil {
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "song_upload_failed"), err.Error()))
+		return err
+	}
+
+	_, _ = updater.Delete()
+	return nil
+}
+
+// findSong resolves the input of /song to a single track: the first track of a link, or the first result of a search.
+// If no track is found, it returns the message to tell the user instead.
+func findSong(input, langCode string) (cache.MusicTrack, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	wrapper := dl.NewDownloaderWrapper(input)
+	var (
+		tracks cache.PlatformTracks
+		err    error
+	)
+	if wrapper.IsValid() {
+		tracks, err = wrapper.GetInfo(ctx)
+		if err != nil {
+			return cache.MusicTrack{}, fmt.Sprintf(lang.GetString(langCode, "play_fetch_error"), err.Error())
+		}
+	} else {
+		tracks, err = wrapper.Search(ctx)
+		if err != nil {
+			return cache.MusicTrack{}, fmt.Sprintf(lang.GetString(langCode, "play_search_failed"), err.Error())
+		}
+	}
+	if len(tracks.Results) == 0 {
+		return cache.MusicTrack{}, lang.GetString(langCode, "play_no_results")
+	}
+	return tracks.Results[0], ""
+}
+
+// songFileName returns the name a /song upload is sent with: the track's name, with the extension of the downloaded file.
+func songFileName(name, filePath string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		return filepath.Base(filePath)
+	}
+	return name + filepath.Ext(filePath)
+}
+
+// fetchCover downloads the cover of a track to send as the thumbnail of its file.
+// It returns nil if there is no cover, or if it cannot be fetched or is too large for a thumbnail.
+func fetchCover(url string) []byte {
+	if !strings.HasPrefix(url, "http") {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	cover, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverSize+1))
+	if err != nil || len(cover) > maxCoverSize {
+		return nil
+	}
+	return cover
+}
+
+// removeUnqueuedFile deletes a file downloaded for /song, unless a track in a chat's queue is using it.
+// Downloads are shared by path, so the track may be queued or playing elsewhere.
+func removeUnqueuedFile(filePath string) {
+	for _, chatID := range cache.ChatCache.GetActiveChats() {
+		for _, track := range cache.ChatCache.GetQueue(chatID) {
+			if track.FilePath == filePath {
+				return
+			}
+		}
+	}
+	_ = os.Remove(filePath)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// staleConfirmTTL is how long the buttons asking whether to run a stale command keep working.
+const staleConfirmTTL = 5 * time.Minute
+
+// commandKind tells how a command that arrives late is handled.
+type commandKind int
+
+const (
+	commandOther    commandKind = iota // commandOther runs as usual, however old it is.
+	commandPlayback                    // commandPlayback starts playback; a stale one is run only once its sender confirms.
+	commandControl                     // commandControl acts on what is playing now; a stale one is dropped.
+)
+
+// commandKinds classifies the commands whose effect depends on when they are run. Commands not listed are
+// commandOther.
+var commandKinds = map[string]commandKind{
+	"play":     commandPlayback,
+	"vplay":    commandPlayback,
+	"playmine": commandPlayback,
+	"skip":     commandControl,
+	"voteskip": commandControl,
+	"jump":     commandControl,
+	"stop":     commandControl,
+	"end":      commandControl,
+	"pause":    commandControl,
+	"resume":   commandControl,
+	"mute":     commandControl,
+	"unmute":   commandControl,
+	"seek":     commandControl,
+	"seekback": commandControl,
+	"speed":    commandControl,
+}
+
+// staleCommand is a stale playback command waiting for its sender to confirm it.
+// The message is kept rather than fetched again, so it can be run even if it has since been deleted.
+type staleCommand struct {
+	m       *telegram.NewMessage
+	command string
+}
+
+// staleCommands holds the stale commands waiting for confirmation by token.
+var staleCommands = cache.NewCache[staleCommand](staleConfirmTTL)
+
+// commandAge returns how long ago a message was sent, if it was sent before the bot started and is older than
+// StaleCommandAge. Telegram delivers the messages missed while the bot was down once it reconnects; it returns 0 for
+// any other message.
+func commandAge(m *telegram.NewMessage) time.Duration {
+	if config.Conf.StaleCommandAge <= 0 {
+		return 0
+	}
+	sent := time.Unix(int64(m.Date()), 0)
+	if !sent.Before(startTime) {
+		return 0
+	}
+	if age := time.Since(sent); age > config.Conf.StaleCommandAge {
+		return age
+	}
+	return 0
+}
+
+// freshCommand is a filter that holds back commands sent while the bot was down.
+// A stale playback command is only run once its sender confirms it with a button, a stale control command is dropped
+// with a note, and other commands run as usual.
+func freshCommand(m *telegram.NewMessage) bool {
+	age := commandAge(m)
+	if age == 0 {
+		return true
+	}
+	command := normalizeCommand(m.GetCommand())
+	kind := commandKinds[command]
+	if kind == commandOther {
+		return true
+	}
+
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	minutes := int(age.Minutes())
+
+	if kind == commandControl {
+		_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "stale_command_dropped"), command, minutes))
+		return false
+	}
+
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		gologging.WarnF("[stale] Failed to create a token: %v", err)
+		return false
+	}
+	token := hex.EncodeToString(b[:])
+	staleCommands.Set(token, staleCommand{m: m, command: command})
+
+	keyboard := telegram.NewKeyboard().AddRow(
+		telegram.Button.Data(lang.GetString(langCode, "stale_command_run_button"), "stale_run_"+token),
+		telegram.Button.Data(lang.GetString(langCode, "stale_command_drop_button"), "stale_drop_"+token),
+	).Build()
+	query := strings.TrimSpace(m.Args())
+	if query == "" {
+		query = "/" + command
+	}
+	text := fmt.Sprintf(lang.GetString(langCode, "stale_command_prompt"), minutes, core.TruncateDisplay(query, 60))
+	_, _ = m.Reply(text, telegram.SendOptions{ReplyMarkup: keyboard})
+	return false
+}
+
+// staleCommandCallbackHandler handles the Run and Dismiss buttons asking whether to run a stale playback command.
+// Only its sender can answer, and the command goes through the checks it would have met when it arrived.
+func staleCommandCallbackHandler(cb *telegram.CallbackQuery) error {
+	chatID, _ := getPeerId(cb.Client, cb.ChatID)
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	data := cb.DataString()
+	run := strings.HasPrefix(data, "stale_run_")
+	token := strings.TrimPrefix(strings.TrimPrefix(data, "stale_run_"), "stale_drop_")
+
+	pending, ok := staleCommands.Get(token)
+	if !ok {
+		_, _ = cb.Answer(lang.GetString(langCode, "stale_command_expired"), &telegram.CallbackOptions{Alert: true})
+		_, _ = cb.Delete()
+		return nil
+	}
+	if !mayAnswerPrompt(ctx, db.Instance, chatID, actorID(pending.m), cb.SenderID) {
+		_, _ = cb.Answer(lang.GetString(langCode, "stale_command_not_yours"), &telegram.CallbackOptions{Alert: true})
+		return nil
+	}
+	staleCommands.Delete(token)
+	_, _ = cb.Answer("")
+	_, _ = cb.Delete()
+	if !run {
+		return nil
+	}
+
+	registered, ok := toggleableCommands[pending.command]
+	if !ok || !commandEnabled(pending.m) || !registered.filter(pending.m) {
+		return nil
+	}
+	return registered.handler(pending.m)
+}
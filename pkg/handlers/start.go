@@ -53,10 +53,7 @@ func startHandler(m *telegram.NewMessage) error {
 	defer cancel()
 	langCode := db.Instance.GetLang(ctx, chatID)
 
-	response := fmt.Sprintf(lang.GetString(langCode, "start_text"), m.Sender.FirstName, bot.FirstName)
-	_, err := m.Reply(response, telegram.SendOptions{
-		ReplyMarkup: core.AddMeMarkup(m.Client.Me().Username),
-	})
-
-	return err
+	response := fmt.Sprintf(lang.GetString(langCode, "start_text"), actorName(m), bot.FirstName)
+	banner := db.Instance.GetBanner(ctx, bot.ID)
+	return sendStart(messageSender{m}, banner, response, core.AddMeMarkup(m.Client.Me().Username))
 }
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/timeparse"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strconv"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// startAtHandler handles the /startat command.
+// It sets the offset that a queued track starts playing from; an offset of 0 plays it from the beginning.
+func startAtHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	if !cache.ChatCache.IsActive(chatID) {
+		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return err
+	}
+
+	queue := cache.ChatCache.GetQueue(chatID)
+	if len(queue) < 2 {
+		_, err := m.Reply(lang.GetString(langCode, "startat_no_pending"))
+		return err
+	}
+
+	args := strings.Fields(m.Args())
+	if len(args) != 2 {
+		_, err := m.Reply(lang.GetString(langCode, "startat_usage"))
+		return err
+	}
+
+	position, err := strconv.Atoi(args[0])
+	if err != nil || position < 1 || position >= len(queue) {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "startat_invalid_position"), len(queue)-1))
+		return err
+	}
+
+	offset, err := timeparse.ParseDuration(args[1])
+	if err != nil {
+		_, err = m.Reply(lang.GetString(langCode, "play_start_invalid"))
+		return err
+	}
+
+	track := *queue[position]
+	track.StartAt = int(offset.Seconds())
+	if msg := startAtError(&track, langCode); msg != "" {
+		_, err = m.Reply(msg)
+		return err
+	}
+
+	if !cache.ChatCache.SetStartAt(chatID, position, track.StartAt) {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "startat_invalid_position"), cache.ChatCache.GetQueueLength(chatID)-1))
+		return err
+	}
+
+	if track.StartAt == 0 {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "startat_cleared"), position, track.Name))
+		return err
+	}
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "startat_success"), position, track.Name, cache.SecToMin(track.StartAt)))
+	return err
+}
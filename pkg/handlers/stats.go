@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+	"maps"
 	"os"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -151,9 +155,27 @@ func sysStatsHandler(msg *telegram.NewMessage) error {
 		sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_mem"), info.MemUsed, info.MemPerc))
 	}
 	sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_goroutines"), info.NumGoroutines))
+	commands := cache.CommandTotals()
+	sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_commands"), commands.Invocations, commands.Errors, commands.Rejected))
 	sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_db"), len(chats), len(users)))
 	sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_go_version"), info.GoVersion))
 	sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_platform"), info.OS, info.Arch))
+	joinedCalls := vc.Calls.JoinedCalls()
+	for _, name := range slices.Sorted(maps.Keys(joinedCalls)) {
+		sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_assistant_calls"), name, joinedCalls[name]))
+	}
+	joinStats := vc.Calls.JoinStats()
+	for _, name := range slices.Sorted(maps.Keys(joinStats)) {
+		stat := joinStats[name]
+		sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_assistant_joins"),
+			name, stat.Count, stat.Average().Round(time.Millisecond), stat.Max.Round(time.Millisecond)))
+	}
+	if videoChats, slots := vc.Calls.VideoSlots(); slots > 0 {
+		sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_video_slots"), len(videoChats), slots))
+	}
+	if cache.IsMaintenance() {
+		sb.WriteString(lang.GetString(langCode, "stats_maintenance_on"))
+	}
 
 	sb.WriteString(lang.GetString(langCode, "stats_server_header"))
 	sb.WriteString(fmt.Sprintf(lang.GetString(langCode, "stats_server_cpu"), info.SystemCPUUsage))
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// stayInVCHandler handles the /stayinvc command.
+// It sets whether the assistant stays in the voice chat after playback stops, instead of leaving it.
+func stayInVCHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	var enabled bool
+	switch strings.ToLower(m.Args()) {
+	case "on", "enable":
+		enabled = true
+	case "off", "disable":
+		enabled = false
+	default:
+		status := lang.GetString(langCode, "stayinvc_status_off")
+		if db.Instance.GetStayInVC(ctx, chatID) {
+			status = lang.GetString(langCode, "stayinvc_status_on")
+		}
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "stayinvc_usage"), status))
+		return err
+	}
+
+	if err := db.Instance.SetStayInVC(ctx, chatID, enabled); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "stayinvc_error"), err.Error()))
+		return err
+	}
+
+	key := "stayinvc_disabled"
+	if enabled {
+		key = "stayinvc_enabled"
+	}
+	_, err := m.Reply(lang.GetString(langCode, key))
+	return err
+}
+
+// stayOnEmptyHandler handles the /stayonempty command.
+// It sets whether the assistant stays in the voice chat when the queue runs out, ready for the next request.
+// Unlike /stayinvc, it does not keep the assistant in the voice chat after /stop.
+func stayOnEmptyHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	var enabled bool
+	switch strings.ToLower(m.Args()) {
+	case "on", "enable":
+		enabled = true
+	case "off", "disable":
+		enabled = false
+	default:
+		status := lang.GetString(langCode, "stayonempty_status_off")
+		if db.Instance.GetStayOnEmpty(ctx, chatID) {
+			status = lang.GetString(langCode, "stayonempty_status_on")
+		}
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "stayonempty_usage"), status))
+		return err
+	}
+
+	if err := db.Instance.SetStayOnEmpty(ctx, chatID, enabled); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "stayonempty_error"), err.Error()))
+		return err
+	}
+
+	key := "stayonempty_disabled"
+	if enabled {
+		key = "stayonempty_enabled"
+	}
+	_, err := m.Reply(lang.GetString(langCode, key))
+	return err
+}
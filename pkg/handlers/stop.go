@@ -2,30 +2,41 @@ package handlers
 
 import (
 	"fmt"
-	"github.com/zuchzub/Go/pkg/core/cache"
 	"github.com/zuchzub/Go/pkg/core/db"
 	"github.com/zuchzub/Go/pkg/lang"
-	"github.com/zuchzub/Go/pkg/vc"
-
-	"github.com/amarnathcjd/gogram/telegram"
+	"strings"
 )
 
 // stopHandler handles the /stop command.
-func stopHandler(m *telegram.NewMessage) error {
-	chatID, _ := getPeerId(m.Client, m.ChatID())
+// If the queue holds more upcoming tracks than the chat's threshold, it asks for confirmation first, unless it is
+// given "force".
+func stopHandler(hc *handlerContext, m message) error {
+	chatID, _ := getPeerId(hc.client, m.ChatID())
 	ctx, cancel := db.Ctx()
 	defer cancel()
-	langCode := db.Instance.GetLang(ctx, chatID)
-	if !cache.ChatCache.IsActive(chatID) {
+	langCode := hc.store.GetLang(ctx, chatID)
+	if !hc.queues.IsActive(chatID) {
 		_, _ = m.Reply(lang.GetString(langCode, "no_track_playing"))
 		return nil
 	}
 
-	if err := vc.Calls.Stop(chatID); err != nil {
+	name, userID, _ := m.Actor()
+	if !strings.EqualFold(strings.TrimSpace(m.Args()), "force") {
+		if pending := hc.stopNeedsConfirm(chatID); pending > 0 {
+			text, opts, err := stopConfirmPrompt(langCode, chatID, userID, pending)
+			if err != nil {
+				return err
+			}
+			_, err = m.Reply(text, opts)
+			return err
+		}
+	}
+
+	if err := hc.stopChat(chatID); err != nil {
 		_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "stop_error"), err.Error()))
 		return err
 	}
 
-	_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "stop_success"), m.Sender.FirstName))
+	_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "stop_success"), name) + undoHint(langCode))
 	return nil
 }
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// stopConfirmTTL is how long the buttons of a /stop confirmation keep working.
+const stopConfirmTTL = 30 * time.Second
+
+// stopRequest is a stop that waits for its confirmation.
+type stopRequest struct {
+	chatID int64
+	userID int64
+}
+
+// stopRequests holds the pending stop confirmations by token.
+var stopRequests = cache.NewCache[stopRequest](stopConfirmTTL)
+
+// stopNeedsConfirm returns the number of upcoming tracks a stop would discard if that is more than the chat's
+// threshold, or 0 if the chat can be stopped without asking.
+func (hc *handlerContext) stopNeedsConfirm(chatID int64) int {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+
+	threshold := hc.store.GetStopConfirm(ctx, chatID)
+	pending := hc.queues.GetQueueLength(chatID) - 1
+	if threshold <= 0 || pending <= threshold {
+		return 0
+	}
+	return pending
+}
+
+// stopConfirmPrompt records a stop request by a user and returns the question to ask with its Confirm and Cancel
+// buttons.
+func stopConfirmPrompt(langCode string, chatID, userID int64, pending int) (string, telegram.SendOptions, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", telegram.SendOptions{}, err
+	}
+	token := hex.EncodeToString(b[:])
+	stopRequests.Set(token, stopRequest{chatID: chatID, userID: userID})
+
+	keyboard := telegram.NewKeyboard().AddRow(
+		telegram.Button.Data(lang.GetString(langCode, "stop_confirm_button"), "stop_confirm_"+token),
+		telegram.Button.Data(lang.GetString(langCode, "stop_cancel_button"), "stop_cancel_"+token),
+	).Build()
+	text := fmt.Sprintf(lang.GetString(langCode, "stop_confirm_prompt"), pending, int(stopConfirmTTL.Seconds()))
+	return text, telegram.SendOptions{ReplyMarkup: keyboard}, nil
+}
+
+// stopChat stops playback in a chat and clears its queue, keeping a snapshot for /undo.
+func (hc *handlerContext) stopChat(chatID int64) error {
+	hc.queues.SnapshotQueue(chatID)
+	if err := hc.calls.Stop(chatID); err != nil {
+		hc.queues.DiscardSnapshot(chatID)
+		return err
+	}
+	return nil
+}
+
+// stopConfirmCallbackHandler handles the Confirm and Cancel buttons of a /stop confirmation.
+// The keyboard can outlive a change of rights, so whoever presses a button must still be allowed to stop playback.
+func stopConfirmCallbackHandler(hc *handlerContext, cb callbackQuery) error {
+	chatID := cb.ChatID()
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := hc.store.GetLang(ctx, chatID)
+
+	data := cb.DataString()
+	confirm := strings.HasPrefix(data, "stop_confirm_")
+	token := strings.TrimPrefix(strings.TrimPrefix(data, "stop_confirm_"), "stop_cancel_")
+
+	req, ok := stopRequests.Get(token)
+	if !ok || req.chatID != chatID {
+		_, _ = cb.Answer(lang.GetString(langCode, "stop_confirm_expired"), &telegram.CallbackOptions{Alert: true})
+		_, _ = cb.Edit(lang.GetString(langCode, "stop_confirm_expired"))
+		return nil
+	}
+
+	if cb.SenderID() != req.userID && !hc.store.IsAdmin(ctx, chatID, cb.SenderID()) {
+		_, _ = cb.Answer(lang.GetString(langCode, "stop_confirm_not_yours"), &telegram.CallbackOptions{Alert: true})
+		return nil
+	}
+	if !hc.canManageQueue(ctx, chatID, cb.SenderID()) {
+		_, _ = cb.Answer(lang.GetString(langCode, "filter_not_authorized"), &telegram.CallbackOptions{Alert: true})
+		return nil
+	}
+	stopRequests.Delete(token)
+
+	if !confirm {
+		_, _ = cb.Answer(lang.GetString(langCode, "stop_confirm_cancelled"))
+		_, err := cb.Edit(lang.GetString(langCode, "stop_confirm_cancelled"))
+		return err
+	}
+
+	if !hc.queues.IsActive(chatID) {
+		_, err := cb.Edit(lang.GetString(langCode, "no_track_playing"))
+		return err
+	}
+	if err := hc.stopChat(chatID); err != nil {
+		_, _ = cb.Edit(fmt.Sprintf(lang.GetString(langCode, "stop_error"), err.Error()))
+		return err
+	}
+	_, _ = cb.Answer(lang.GetString(langCode, "track_stopped"))
+	_, err := cb.Edit(fmt.Sprintf(lang.GetString(langCode, "stop_success"), cb.SenderName()) + undoHint(langCode))
+	return err
+}
+
+// stopConfirmHandler handles the /stopconfirm command.
+// It sets how many upcoming tracks the queue can have before /stop asks for confirmation, or turns the question off.
+func stopConfirmHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	args := strings.ToLower(strings.TrimSpace(m.Args()))
+	threshold, err := strconv.Atoi(args)
+	switch {
+	case args == "off" || args == "disable":
+		threshold = 0
+	case err != nil || threshold < 1:
+		status := lang.GetString(langCode, "stop_confirm_status_off")
+		if current := db.Instance.GetStopConfirm(ctx, chatID); current > 0 {
+			status = fmt.Sprintf(lang.GetString(langCode, "stop_confirm_status_on"), current)
+		}
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "stop_confirm_usage"), status))
+		return err
+	}
+
+	if err = db.Instance.SetStopConfirm(ctx, chatID, threshold); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "stop_confirm_error"), err.Error()))
+		return err
+	}
+
+	if threshold == 0 {
+		_, err = m.Reply(lang.GetString(langCode, "stop_confirm_disabled"))
+		return err
+	}
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "stop_confirm_set"), threshold))
+	return err
+}
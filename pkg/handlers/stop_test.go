@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/handlers/testsupport"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// queueOf returns a queue of n tracks.
+func queueOf(n int) []*cache.CachedTrack {
+	tracks := make([]*cache.CachedTrack, n)
+	for i := range tracks {
+		tracks[i] = &cache.CachedTrack{TrackID: string(rune('a' + i))}
+	}
+	return tracks
+}
+
+func TestStopHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        string
+		tracks      int
+		stopConfirm int
+		stopErr     error
+		want        string
+		wantStopped bool
+	}{
+		{name: "no session", want: "no_track_playing"},
+		{name: "stop", tracks: 3, want: "stop_success", wantStopped: true},
+		{name: "below the threshold", tracks: 3, stopConfirm: 2, want: "stop_success", wantStopped: true},
+		{name: "needs confirmation", tracks: 4, stopConfirm: 2, want: "stop_confirm_prompt"},
+		{name: "forced", args: "force", tracks: 4, stopConfirm: 2, want: "stop_success", wantStopped: true},
+		{name: "stop fails", tracks: 1, stopErr: errors.New("not in a call"), want: "stop_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := &testsupport.Calls{StopErr: tt.stopErr}
+			hc, _ := newTestContext(&testsupport.Store{StopConfirm: tt.stopConfirm}, calls, queueOf(tt.tracks)...)
+			m := &testsupport.Message{Chat: testChatID, Text: tt.args, Sender: 11}
+
+			err := stopHandler(hc, m)
+			if (err != nil) != (tt.stopErr != nil) {
+				t.Fatalf("stopHandler() = %v", err)
+			}
+			checkReply(t, m, tt.want)
+			if stopped := len(calls.Stopped) > 0; stopped != tt.wantStopped {
+				t.Errorf("stopped = %t, want %t", stopped, tt.wantStopped)
+			}
+		})
+	}
+}
+
+func TestStopConfirmCallbackHandler(t *testing.T) {
+	const requester, other, admin = 11, 22, 33
+	tests := []struct {
+		name        string
+		pressedBy   int64
+		action      string
+		token       string // token is the token of the button, if it is not the one of the request.
+		store       testsupport.Store
+		want        string
+		wantStopped bool
+	}{
+		{name: "confirm", pressedBy: requester, action: "stop_confirm_", want: "track_stopped", wantStopped: true},
+		{name: "cancel", pressedBy: requester, action: "stop_cancel_", want: "stop_confirm_cancelled"},
+		{name: "expired", pressedBy: requester, action: "stop_confirm_", token: "0000", want: "stop_confirm_expired"},
+		{name: "someone else", pressedBy: other, action: "stop_confirm_", want: "stop_confirm_not_yours"},
+		{name: "an admin", pressedBy: admin, action: "stop_confirm_", store: testsupport.Store{Admins: []int64{admin}}, want: "track_stopped", wantStopped: true},
+		{
+			name: "requester lost their rights", pressedBy: requester, action: "stop_confirm_",
+			store: testsupport.Store{AdminMode: cache.Auth}, want: "filter_not_authorized",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := &testsupport.Calls{}
+			hc, _ := newTestContext(&tt.store, calls, queueOf(3)...)
+			stopRequests.Set("abcd", stopRequest{chatID: testChatID, userID: requester})
+			defer stopRequests.Delete("abcd")
+			token := tt.token
+			if token == "" {
+				token = "abcd"
+			}
+			cb := &testsupport.Callback{Chat: testChatID, Sender: tt.pressedBy, Data: tt.action + token}
+
+			if err := stopConfirmCallbackHandler(hc, cb); err != nil {
+				t.Fatalf("stopConfirmCallbackHandler() = %v", err)
+			}
+			if len(cb.Answers) == 0 || cb.Answers[0] != tt.want {
+				t.Errorf("answers = %q, want %q", cb.Answers, tt.want)
+			}
+			if stopped := len(calls.Stopped) > 0; stopped != tt.wantStopped {
+				t.Errorf("stopped = %t, want %t", stopped, tt.wantStopped)
+			}
+		})
+	}
+}
+
+func TestStopConfirmPrompt(t *testing.T) {
+	const requester = 11
+	text, opts, err := stopConfirmPrompt("en", testChatID, requester, 7)
+	if err != nil {
+		t.Fatalf("stopConfirmPrompt() error = %v", err)
+	}
+	if !strings.HasPrefix(text, "stop_confirm_prompt") {
+		t.Errorf("stopConfirmPrompt() = %q, want the stop_confirm_prompt question", text)
+	}
+
+	data := callbackData(opts.ReplyMarkup.(*telegram.ReplyInlineMarkup))
+	if len(data) != 2 || !strings.HasPrefix(data[0], "stop_confirm_") || !strings.HasPrefix(data[1], "stop_cancel_") {
+		t.Fatalf("buttons = %v, want Confirm and Cancel", data)
+	}
+	token := strings.TrimPrefix(data[0], "stop_confirm_")
+	defer stopRequests.Delete(token)
+	if req, ok := stopRequests.Get(token); !ok || req != (stopRequest{chatID: testChatID, userID: requester}) {
+		t.Errorf("recorded request = %+v, %t; want the requester's stop of the chat", req, ok)
+	}
+}
+
+func TestStopConfirmExpires(t *testing.T) {
+	const requester = 11
+	tests := []struct {
+		name  string
+		setup func(token string)
+	}{
+		{"after the TTL", func(token string) {
+			stopRequests.SetWithTTL(token, stopRequest{chatID: testChatID, userID: requester}, time.Millisecond)
+			time.Sleep(5 * time.Millisecond)
+		}},
+		{"already answered", func(token string) {
+			stopRequests.Set(token, stopRequest{chatID: testChatID, userID: requester})
+			hc, _ := newTestContext(&testsupport.Store{}, &testsupport.Calls{}, queueOf(3)...)
+			_ = stopConfirmCallbackHandler(hc, &testsupport.Callback{Chat: testChatID, Sender: requester, Data: "stop_cancel_" + token})
+		}},
+		{"for another chat", func(token string) {
+			stopRequests.Set(token, stopRequest{chatID: testChatID - 1, userID: requester})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const token = "ef01"
+			defer stopRequests.Delete(token)
+			tt.setup(token)
+
+			calls := &testsupport.Calls{}
+			hc, _ := newTestContext(&testsupport.Store{}, calls, queueOf(3)...)
+			cb := &testsupport.Callback{Chat: testChatID, Sender: requester, Data: "stop_confirm_" + token}
+			if err := stopConfirmCallbackHandler(hc, cb); err != nil {
+				t.Fatalf("stopConfirmCallbackHandler() = %v", err)
+			}
+			if len(cb.Answers) == 0 || cb.Answers[0] != "stop_confirm_expired" {
+				t.Errorf("answers = %q, want stop_confirm_expired", cb.Answers)
+			}
+			if len(calls.Stopped) > 0 {
+				t.Error("an expired confirmation stopped playback")
+			}
+		})
+	}
+}
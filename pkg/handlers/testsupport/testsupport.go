@@ -0,0 +1,208 @@
+// Package testsupport provides fakes of the Telegram messages, client and services that handlers talk to through a
+// handlerContext, so that handlers can be tested without a live bot. The queue needs no fake: tests use a
+// cache.NewChatCacher.
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"slices"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// Message is a fake command message. It records the replies and edits sent through it.
+type Message struct {
+	Chat           int64
+	Text           string // Text is the argument of the command, as returned by Args.
+	Private        bool
+	Title          string
+	SenderName     string
+	Sender         int64
+	AnonymousAdmin bool
+	File           string // File is the path Download returns; without one, the message has no media.
+
+	Replies []string
+	Edits   []string
+}
+
+func (m *Message) ChatID() int64     { return m.Chat }
+func (m *Message) IsPrivate() bool   { return m.Private }
+func (m *Message) Args() string      { return m.Text }
+func (m *Message) ChatTitle() string { return m.Title }
+
+func (m *Message) Actor() (string, int64, bool) {
+	if m.AnonymousAdmin {
+		return "Anonymous admin", 0, true
+	}
+	return m.SenderName, m.Sender, false
+}
+
+func (m *Message) Reply(text any, _ ...telegram.SendOptions) (*telegram.NewMessage, error) {
+	m.Replies = append(m.Replies, fmt.Sprint(text))
+	return &telegram.NewMessage{}, nil
+}
+
+func (m *Message) Edit(text any, _ ...telegram.SendOptions) (*telegram.NewMessage, error) {
+	m.Edits = append(m.Edits, fmt.Sprint(text))
+	return &telegram.NewMessage{}, nil
+}
+
+func (m *Message) Download(...*telegram.DownloadOptions) (string, error) {
+	if m.File == "" {
+		return "", errors.New("the message has no media")
+	}
+	return m.File, nil
+}
+
+// LastReply returns the last reply sent to the message, or "" if there was none.
+func (m *Message) LastReply() string {
+	if len(m.Replies) == 0 {
+		return ""
+	}
+	return m.Replies[len(m.Replies)-1]
+}
+
+// Callback is a fake button press. It records the answers and edits sent through it.
+type Callback struct {
+	Chat        int64
+	Sender      int64
+	SenderFirst string
+	Data        string
+
+	Answers []string
+	Edits   []string
+}
+
+func (c *Callback) ChatID() int64      { return c.Chat }
+func (c *Callback) SenderID() int64    { return c.Sender }
+func (c *Callback) SenderName() string { return c.SenderFirst }
+func (c *Callback) DataString() string { return c.Data }
+
+func (c *Callback) Answer(text string, _ ...*telegram.CallbackOptions) (bool, error) {
+	c.Answers = append(c.Answers, text)
+	return true, nil
+}
+
+func (c *Callback) Edit(text any, _ ...*telegram.SendOptions) (*telegram.NewMessage, error) {
+	c.Edits = append(c.Edits, fmt.Sprint(text))
+	return &telegram.NewMessage{}, nil
+}
+
+// Client is a fake Telegram client. Chat IDs resolve to the peer they stand for, as the bot API numbers them.
+type Client struct {
+	Messages map[int32]*telegram.NewMessage
+
+	Sent []Sent
+}
+
+// Sent is a message sent with Client.SendMessage.
+type Sent struct {
+	PeerID any
+	Text   string
+}
+
+func (c *Client) ResolvePeer(peer any) (telegram.InputPeer, error) {
+	id, ok := peer.(int64)
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("cannot resolve %v", peer)
+	case id > 0:
+		return &telegram.InputPeerUser{UserID: id}, nil
+	case id < -1000000000000:
+		return &telegram.InputPeerChannel{ChannelID: -1000000000000 - id}, nil
+	default:
+		return &telegram.InputPeerChat{ChatID: -id}, nil
+	}
+}
+
+func (c *Client) GetMessageByID(_ any, msgID int32) (*telegram.NewMessage, error) {
+	if m, ok := c.Messages[msgID]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("message %d not found", msgID)
+}
+
+func (c *Client) SendMessage(peerID, message any, _ ...*telegram.SendOptions) (*telegram.NewMessage, error) {
+	c.Sent = append(c.Sent, Sent{PeerID: peerID, Text: fmt.Sprint(message)})
+	return &telegram.NewMessage{}, nil
+}
+
+// Seek is a call of Calls.SeekStream.
+type Seek struct {
+	ChatID   int64
+	FilePath string
+	ToSeek   int
+}
+
+// Calls is a fake of vc.Calls.
+type Calls struct {
+	Played    uint64
+	PlayedErr error
+	SeekErr   error
+	StopErr   error
+
+	Seeks   []Seek
+	Stopped []int64
+}
+
+func (c *Calls) PlayedTime(int64) (uint64, error) { return c.Played, c.PlayedErr }
+
+func (c *Calls) SeekStream(chatID int64, filePath string, toSeek, _ int, _ bool) error {
+	if c.SeekErr != nil {
+		return c.SeekErr
+	}
+	c.Seeks = append(c.Seeks, Seek{ChatID: chatID, FilePath: filePath, ToSeek: toSeek})
+	return nil
+}
+
+func (c *Calls) Stop(chatID int64) error {
+	if c.StopErr != nil {
+		return c.StopErr
+	}
+	c.Stopped = append(c.Stopped, chatID)
+	return nil
+}
+
+// Store is a fake of db.Instance. Its zero value is a chat in English with the default settings, whose admin mode
+// lets everyone manage the queue.
+type Store struct {
+	AdminMode   string
+	StopConfirm int
+	Volume      int
+	AuthUsers   []int64
+	Admins      []int64
+	DJs         []int64
+}
+
+func (s *Store) GetLang(context.Context, int64) string       { return "en" }
+func (s *Store) GetAuthUsers(context.Context, int64) []int64 { return s.AuthUsers }
+func (s *Store) GetStopConfirm(context.Context, int64) int   { return s.StopConfirm }
+
+func (s *Store) GetVolume(context.Context, int64) int {
+	if s.Volume == 0 {
+		return 100
+	}
+	return s.Volume
+}
+
+func (s *Store) GetAdminMode(context.Context, int64) string {
+	if s.AdminMode == "" {
+		return cache.Everyone
+	}
+	return s.AdminMode
+}
+
+func (s *Store) IsAdmin(_ context.Context, _, userID int64) bool {
+	return slices.Contains(s.Admins, userID)
+}
+
+func (s *Store) IsAuthUser(_ context.Context, _, userID int64) bool {
+	return slices.Contains(s.AuthUsers, userID)
+}
+
+func (s *Store) IsDJ(_ context.Context, _, userID int64) bool {
+	return slices.Contains(s.DJs, userID)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// undoHint returns the line added to the reply of a destructive command to say that it can be undone.
+func undoHint(langCode string) string {
+	return fmt.Sprintf(lang.GetString(langCode, "undo_hint"), int(cache.UndoWindow.Minutes()))
+}
+
+// undoHandler handles the /undo command.
+// It puts back the tracks removed by the last /stop, /clearqueue, /remove or /clearfailed in the chat, if that was
+// less than cache.UndoWindow ago. If the queue had been stopped, playback starts again from the beginning of the track
+// that was playing.
+func undoHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	restored, restart := cache.ChatCache.RestoreSnapshot(chatID)
+	if restored == 0 {
+		_, err := m.Reply(lang.GetString(langCode, "undo_nothing"))
+		return err
+	}
+
+	if restart {
+		// PlayNext replays the current track while its loop count is above zero, the same way a new queue starts.
+		cache.ChatCache.SetLoopCount(chatID, cache.ChatCache.GetLoopCount(chatID)+1)
+		if err := vc.Calls.PlayNext(chatID); err != nil {
+			_, _ = m.Reply(fmt.Sprintf(lang.GetString(langCode, "undo_play_error"), restored, err.Error()))
+			return err
+		}
+	}
+
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "undo_success"), restored, actorName(m)))
+	return err
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+	"strconv"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// volumeHandler handles the /volume command.
+// It sets the chat's playback volume, kept for later sessions; without arguments, it shows the current one.
+func volumeHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	args := strings.TrimSuffix(strings.TrimSpace(m.Args()), "%")
+	if args == "" {
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "volume_usage"), db.Instance.GetVolume(ctx, chatID), vc.MaxVolume))
+		return err
+	}
+
+	volume, err := strconv.Atoi(args)
+	if err != nil || volume < 0 || volume > vc.MaxVolume {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "volume_invalid"), vc.MaxVolume))
+		return err
+	}
+
+	if err = vc.Calls.SetVolume(chatID, volume); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "volume_error"), err.Error()))
+		return err
+	}
+
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "volume_set"), volume, actorName(m)))
+	return err
+}
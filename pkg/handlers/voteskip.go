@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc"
+	"strconv"
+	"strings"
+
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// skipVotesNeeded returns how many votes skip a track in a voice chat with the given number of listeners, when
+// percent of them must vote. At least one vote is always needed.
+func skipVotesNeeded(listeners, percent int) int {
+	return max(1, (listeners*percent+99)/100)
+}
+
+// voteSkipHandler handles the /voteskip command.
+// It counts the sender's vote to skip the current track, once per user and track, and skips it once the share of the
+// voice chat's listeners set with /setvoteskip have voted. Unlike /skip, anyone in the voice chat can use it; only the
+// votes of users still in the voice chat are counted.
+func voteSkipHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	track := cache.ChatCache.GetPlayingTrack(chatID)
+	if !cache.ChatCache.IsActive(chatID) || track == nil {
+		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return err
+	}
+
+	// An anonymous admin has no user to count a vote for, and can skip with /skip anyway.
+	userID := actorID(m)
+	if userID == 0 {
+		_, err := m.Reply(lang.GetString(langCode, "voteskip_anonymous"))
+		return err
+	}
+
+	listeners := vc.Calls.ListenerIDs(chatID)
+	if listeners == nil {
+		_, err := m.Reply(lang.GetString(langCode, "voteskip_no_listeners"))
+		return err
+	}
+	if _, listening := listeners[userID]; !listening {
+		_, err := m.Reply(lang.GetString(langCode, "voteskip_not_listening"))
+		return err
+	}
+	needed := skipVotesNeeded(len(listeners), db.Instance.GetVoteSkip(ctx, chatID))
+
+	votes, added := cache.ChatCache.AddSkipVote(chatID, track.TrackID, userID, listeners)
+	switch {
+	case votes < 0:
+		_, err := m.Reply(lang.GetString(langCode, "no_track_playing"))
+		return err
+	case !added:
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "voteskip_already"), votes, needed))
+		return err
+	case votes < needed:
+		_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "voteskip_counted"), votes, needed))
+		return err
+	}
+
+	cache.ChatCache.SetLoopCount(chatID, 0)
+	cache.RecordQueueStat(chatID, cache.QueueSkipped, 1)
+	if err := vc.Calls.Skip(chatID); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "voteskip_error"), err.Error()))
+		return err
+	}
+	_, err := m.Reply(fmt.Sprintf(lang.GetString(langCode, "voteskip_passed"), votes, needed))
+	return err
+}
+
+// setVoteSkipHandler handles the /setvoteskip command.
+// It sets the percentage of the voice chat's listeners that must vote with /voteskip to skip a track.
+func setVoteSkipHandler(m *telegram.NewMessage) error {
+	chatID, _ := getPeerId(m.Client, m.ChatID())
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	percent, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(m.Args()), "%"))
+	if err != nil || percent < 1 || percent > 100 {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "voteskip_usage"), db.Instance.GetVoteSkip(ctx, chatID)))
+		return err
+	}
+
+	if err = db.Instance.SetVoteSkip(ctx, chatID, percent); err != nil {
+		_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "voteskip_set_error"), err.Error()))
+		return err
+	}
+	_, err = m.Reply(fmt.Sprintf(lang.GetString(langCode, "voteskip_set"), percent))
+	return err
+}
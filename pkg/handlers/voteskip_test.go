@@ -0,0 +1,24 @@
+package handlers
+
+import "testing"
+
+func TestSkipVotesNeeded(t *testing.T) {
+	tests := []struct {
+		listeners, percent, want int
+	}{
+		{0, 50, 1},
+		{1, 50, 1},
+		{2, 50, 1},
+		{3, 50, 2},
+		{4, 50, 2},
+		{10, 1, 1},
+		{10, 100, 10},
+		{7, 33, 3},
+		{200, 50, 100},
+	}
+	for _, tt := range tests {
+		if got := skipVotesNeeded(tt.listeners, tt.percent); got != tt.want {
+			t.Errorf("skipVotesNeeded(%d, %d) = %d, want %d", tt.listeners, tt.percent, got, tt.want)
+		}
+	}
+}
@@ -130,12 +130,18 @@ func handleJoin(client *telegram.Client, chatID, userID, ubID int64) error {
 		gologging.InfoF("bot joined chat %d. Initializing...", chatID)
 	}
 
+	gologging.DebugF("User %d joined chat %d", userID, chatID)
+	updateUbStatusCache(chatID, userID, telegram.Member)
+
 	if userID == ubID {
 		gologging.InfoF("UB joined chat %d. Initializing...", chatID)
+		if vc.Calls.AssistantRejoined(chatID) {
+			ctx, cancel := db.Ctx()
+			defer cancel()
+			langCode := db.Instance.GetLang(ctx, chatID)
+			_, _ = client.SendMessage(chatID, lang.GetString(langCode, "watcher_assistant_rejoined"))
+		}
 	}
-
-	gologging.DebugF("User %d joined chat %d", userID, chatID)
-	updateUbStatusCache(chatID, userID, telegram.Member)
 	return nil
 }
 
@@ -146,7 +152,7 @@ func handleLeaveOrKick(client *telegram.Client, chatID, userID, ubId int64) erro
 	gologging.DebugF("User %d left or was kicked from %d", userID, chatID)
 	if userID == ubId {
 		gologging.InfoF("UB left chat %d. Stopping call...", chatID)
-		cache.ChatCache.ClearChat(chatID, true)
+		vc.Calls.AssistantLeft(chatID)
 	}
 
 	if userID == client.Me().ID {
@@ -168,7 +174,7 @@ func handleBan(client *telegram.Client, chatID, userID, ubId int64) error {
 	langCode := db.Instance.GetLang(ctx, chatID)
 	if userID == ubId {
 		gologging.InfoF("The bot (assistant) was banned in chat %d. Stopping any active calls and clearing cache...", chatID)
-		cache.ChatCache.ClearChat(chatID, true)
+		vc.Calls.AssistantLeft(chatID)
 
 		_, err := client.SendMessage(chatID, fmt.Sprintf(lang.GetString(langCode, "watcher_assistant_banned"),
 			ubId,
@@ -209,6 +215,9 @@ func updateUbStatusCache(chatId, userId int64, status string) {
 	ubId := call.App.Me().ID
 	if userId == ubId {
 		vc.Calls.UpdateMembership(chatId, userId, status)
+		if status != telegram.Member && status != telegram.Admin && status != telegram.Creator {
+			vc.Calls.InvalidateJoin(chatId)
+		}
 	}
 }
 
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/lang"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Laky-64/gologging"
+	"github.com/amarnathcjd/gogram/telegram"
+)
+
+// listChoiceTTL is how long the "just this video or the whole playlist" question can be answered.
+const listChoiceTTL = 5 * time.Minute
+
+// listChoiceKey identifies a pending question by the status message that asks it.
+type listChoiceKey struct {
+	chatID int64
+	msgID  int32
+}
+
+// listChoice is a /play of a video opened from a playlist, waiting for the user to pick what to queue.
+// Both options are fetched before asking, so answering only queues them.
+type listChoice struct {
+	m        *telegram.NewMessage
+	updater  *statusUpdater
+	video    cache.PlatformTracks
+	playlist cache.PlatformTracks
+	listURL  string
+	isVideo  bool
+	startAt  int
+	created  time.Time
+}
+
+var (
+	listChoiceMu sync.Mutex
+	listChoices  = make(map[listChoiceKey]*listChoice)
+)
+
+// fetchTracks gets the tracks of a link, within the same timeout as other /play lookups.
+func fetchTracks(link string) (cache.PlatformTracks, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return dl.NewDownloaderWrapper(link).GetInfo(ctx)
+}
+
+// handleVideoInPlaylist handles a /play of a link carrying both a video and a playlist.
+// If the playlist has more than one track, the user is asked whether to queue the video or the whole playlist;
+// otherwise, or if the playlist cannot be fetched, the video is played.
+func handleVideoInPlaylist(m *telegram.NewMessage, updater *statusUpdater, link dl.YouTubeURL, chatId int64, isVideo bool, startAt int, langCode string) error {
+	video, err := fetchTracks(link.VideoURL())
+	if err != nil || len(video.Results) == 0 {
+		if err == nil {
+			_, err = updater.Edit(lang.GetString(langCode, "play_no_tracks_found"))
+			return err
+		}
+		_, err = updater.Edit(fmt.Sprintf(lang.GetString(langCode, "play_fetch_error"), err.Error()))
+		return err
+	}
+
+	playlist, err := fetchTracks(link.PlaylistURL())
+	if err != nil || len(playlist.Results) <= 1 {
+		if err != nil {
+			gologging.DebugF("[ytlist] Failed to fetch playlist %s, playing the video only: %v", link.ListID, err)
+		}
+		return handleUrl(m, updater, video, "", chatId, isVideo, startAt, langCode)
+	}
+
+	keyboard := telegram.NewKeyboard().AddRow(
+		telegram.Button.Data(lang.GetString(langCode, "play_list_video_button"), "ytlist_video"),
+	).AddRow(
+		telegram.Button.Data(fmt.Sprintf(lang.GetString(langCode, "play_list_all_button"), len(playlist.Results)), "ytlist_all"),
+	).Build()
+	if _, err = updater.Edit(lang.GetString(langCode, "play_list_choice"), telegram.SendOptions{ReplyMarkup: keyboard}); err != nil {
+		return err
+	}
+
+	listChoiceMu.Lock()
+	defer listChoiceMu.Unlock()
+	for key, choice := range listChoices {
+		if time.Since(choice.created) > listChoiceTTL {
+			delete(listChoices, key)
+		}
+	}
+	listChoices[listChoiceKey{chatID: chatId, msgID: updater.ID}] = &listChoice{
+		m: m, updater: updater, video: video, playlist: playlist, listURL: link.PlaylistURL(), isVideo: isVideo, startAt: startAt, created: time.Now(),
+	}
+	return nil
+}
+
+// listChoiceCallbackHandler queues the video or the whole playlist, as picked by the user who sent the /play, or by any
+// admin if an anonymous admin sent it.
+func listChoiceCallbackHandler(cb *telegram.CallbackQuery) error {
+	chatID, _ := getPeerId(cb.Client, cb.ChatID)
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+
+	key := listChoiceKey{chatID: chatID, msgID: cb.MessageID}
+	listChoiceMu.Lock()
+	choice, ok := listChoices[key]
+	listChoiceMu.Unlock()
+	if ok && !mayAnswerPrompt(ctx, db.Instance, chatID, actorID(choice.m), cb.SenderID) {
+		_, _ = cb.Answer(lang.GetString(langCode, "play_list_not_yours"), &telegram.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	// The admin check is made without the lock, so the choice may have been taken by another tap in the meantime.
+	listChoiceMu.Lock()
+	if listChoices[key] != choice {
+		ok = false
+	}
+	delete(listChoices, key)
+	listChoiceMu.Unlock()
+
+	if !ok || time.Since(choice.created) > listChoiceTTL {
+		_, _ = cb.Answer(lang.GetString(langCode, "play_list_expired"), &telegram.CallbackOptions{Alert: true})
+		_, _ = cb.Delete()
+		return nil
+	}
+
+	_, _ = cb.Answer("")
+	tracks, source := choice.video, ""
+	if strings.Contains(cb.DataString(), "ytlist_all") {
+		tracks, source = choice.playlist, choice.listURL
+	}
+	return handleUrl(choice.m, choice.updater, tracks, source, chatID, choice.isVideo, choice.startAt, langCode)
+}
@@ -1,14 +1,37 @@
 package pkg
 
 import (
+	"context"
+	"fmt"
 	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/core/eventlog"
+	"github.com/zuchzub/Go/pkg/core/storagehealth"
+	"github.com/zuchzub/Go/pkg/core/telemetry"
 "github.com/zuchzub/Go/pkg/handlers"
+"github.com/zuchzub/Go/pkg/lang"
 "github.com/zuchzub/Go/pkg/vc"
+	"html"
+	"net/http"
 
+	"github.com/Laky-64/gologging"
 	tg "github.com/amarnathcjd/gogram/telegram"
 )
 
 func Init(client *tg.Client) error {
+	ntgVersion, err := vc.CheckNtgCalls()
+	if err != nil {
+		return err
+	}
+	gologging.InfoF("Using ntgcalls %s.", ntgVersion)
+
+	go dl.ProbeNetwork(context.Background())
+
+	if err := eventlog.Open(config.Conf.EventLogPath, config.Conf.EventLogMaxSize, config.Conf.EventLogKeep); err != nil {
+		return err
+	}
+
 	for _, session := range config.Conf.SessionStrings {
 		_, err := vc.Calls.StartClient(config.Conf.ApiId, config.Conf.ApiHash, session)
 		if err != nil {
@@ -16,7 +39,49 @@ func Init(client *tg.Client) error {
 		}
 	}
 
+	storagehealth.Start(config.Conf.DownloadsDir, func(err error) {
+		storageAlert(client, err)
+	})
+
+	if n, err := db.Instance.LoadMusicFeeds(context.Background()); err != nil {
+		gologging.WarnF("Failed to load the music feeds: %v", err)
+	} else if n > 0 {
+		gologging.InfoF("Loaded %d music feeds.", n)
+	}
+	vc.Calls.LoadCaches()
 	vc.Calls.RegisterHandlers(client)
+	vc.Calls.StartCacheSaver()
+	db.Instance.StartQueueStatsFlusher()
+	db.Instance.StartCommandStatsFlusher()
+	db.Instance.StartQueueFlusher()
 	handlers.LoadModules(client)
+	http.HandleFunc("/api/capabilities", handlers.ServeCapabilities)
+	http.HandleFunc("/api/link/callback", handlers.LinkCallback(client))
+	telemetry.Start(config.Conf.TelemetryURL, config.Conf.Version, func(ctx context.Context) (string, error) {
+		return db.Instance.GetInstanceID(ctx, client.Me().ID)
+	}, telemetryCounts)
 	return nil
 }
+
+// storageAlert tells the logger chat that downloads fail because the downloads directory cannot be written to.
+func storageAlert(client *tg.Client, err error) {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, config.Conf.LoggerId)
+	text := fmt.Sprintf(lang.GetString(langCode, "storage_unavailable_logger"), config.Conf.DownloadsDir, html.EscapeString(err.Error()))
+	if _, err := client.SendMessage(config.Conf.LoggerId, text); err != nil {
+		gologging.WarnF("[Storage] Failed to alert the logger chat: %v", err)
+	}
+}
+
+// telemetryCounts gathers the usage figures reported in the telemetry ping.
+func telemetryCounts(ctx context.Context) telemetry.Counts {
+	chats, _ := db.Instance.GetAllChats(ctx)
+	users, _ := db.Instance.GetAllUsers(ctx)
+	return telemetry.Counts{
+		Chats:      len(chats),
+		Users:      len(users),
+		Assistants: len(vc.Calls.JoinedCalls()),
+		Plays24h:   telemetry.Plays24h(),
+	}
+}
@@ -0,0 +1,98 @@
+package vc
+
+import (
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/eventlog"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// pendingLeave is a chat whose assistant was removed and whose queue is kept until the grace period ends.
+type pendingLeave struct {
+	position int
+	timer    *time.Timer
+}
+
+// AssistantLeft is called when the assistant leaves, is kicked, or is banned from a chat.
+// If the chat is playing and a grace period is configured, its queue is kept for that long so that an accidental kick
+// can be undone by adding the assistant back; otherwise, or once the period ends, the chat is stopped.
+func (c *TelegramCalls) AssistantLeft(chatID int64) {
+	grace := config.Conf.AssistantRejoinGrace
+	if grace <= 0 || !cache.ChatCache.IsActive(chatID) {
+		cache.ChatCache.ClearChat(chatID, true)
+		c.releaseVideoSlot(chatID)
+		return
+	}
+
+	position := 0
+	if played, err := c.PlayedTime(chatID); err == nil {
+		position = int(played)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pending, ok := c.assistantLeft[chatID]; ok {
+		pending.timer.Stop()
+		position = pending.position
+	}
+	c.assistantLeft[chatID] = &pendingLeave{
+		position: position,
+		timer:    time.AfterFunc(grace, func() { c.expireAssistantLeft(chatID) }),
+	}
+	eventlog.Emit(chatID, "assistant_left", trackID(chatID), grace.String())
+	gologging.InfoF("[AssistantLeft] Keeping the queue of chat %d for %s", chatID, grace)
+}
+
+// AssistantRejoined is called when the assistant is added back to a chat.
+// If the chat was within its grace period, playback of the current track resumes from where it stopped.
+// It reports whether the chat was waiting for the assistant.
+func (c *TelegramCalls) AssistantRejoined(chatID int64) bool {
+	c.mu.Lock()
+	pending, ok := c.assistantLeft[chatID]
+	if ok {
+		pending.timer.Stop()
+		delete(c.assistantLeft, chatID)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	track := cache.ChatCache.GetPlayingTrack(chatID)
+	if track == nil || !cache.ChatCache.IsActive(chatID) {
+		return true
+	}
+
+	eventlog.Emit(chatID, "assistant_rejoined", track.TrackID, "")
+	go func() {
+		if err := c.rejoin(chatID, track, pending.position); err != nil {
+			gologging.WarnF("[AssistantRejoined] Failed to resume chat %d: %v", chatID, err)
+			_ = c.Stop(chatID)
+		}
+	}()
+	return true
+}
+
+// awaitingAssistant reports whether a chat is within the grace period after its assistant was removed.
+func (c *TelegramCalls) awaitingAssistant(chatID int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.assistantLeft[chatID]
+	return ok
+}
+
+// expireAssistantLeft stops a chat whose assistant was not added back within the grace period.
+func (c *TelegramCalls) expireAssistantLeft(chatID int64) {
+	c.mu.Lock()
+	_, ok := c.assistantLeft[chatID]
+	delete(c.assistantLeft, chatID)
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	gologging.InfoF("[AssistantLeft] The assistant was not added back to chat %d; clearing the queue", chatID)
+	_ = c.Stop(chatID)
+}
@@ -0,0 +1,117 @@
+package vc
+
+import (
+	"errors"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/eventlog"
+	"slices"
+	"sort"
+
+	"github.com/Laky-64/gologging"
+)
+
+var (
+	// ErrUnknownAssistant is returned when no assistant has the given name.
+	ErrUnknownAssistant = errors.New("no assistant has this name")
+	// ErrAssistantDisabled is returned by DisableAssistant when the assistant is already out of the pool.
+	ErrAssistantDisabled = errors.New("the assistant is already disabled")
+	// ErrAssistantEnabled is returned by EnableAssistant when the assistant is already in the pool.
+	ErrAssistantEnabled = errors.New("the assistant is already enabled")
+	// ErrLastAssistant is returned by DisableAssistant when the assistant is the only one left in the pool.
+	ErrLastAssistant = errors.New("the last available assistant cannot be disabled")
+)
+
+// AssistantPool returns the names of the assistants that new chats can be assigned to, and of those taken out of the pool.
+func (c *TelegramCalls) AssistantPool() (available, disabled []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	available = slices.Clone(c.availableClients)
+	for name := range c.clients {
+		if !slices.Contains(c.availableClients, name) {
+			disabled = append(disabled, name)
+		}
+	}
+	sort.Strings(available)
+	sort.Strings(disabled)
+	return available, disabled
+}
+
+// DisableAssistant takes an assistant out of the pool without stopping its client, e.g. for maintenance of the account.
+// Chats playing on it are moved to another assistant and resume from where they were; other chats assigned to it
+// are reassigned the next time they play. It returns the number of chats moved.
+func (c *TelegramCalls) DisableAssistant(name string) (int, error) {
+	c.mu.RLock()
+	_, exists := c.clients[name]
+	available := slices.Contains(c.availableClients, name)
+	remaining := len(c.availableClients)
+	c.mu.RUnlock()
+	switch {
+	case !exists:
+		return 0, ErrUnknownAssistant
+	case !available:
+		return 0, ErrAssistantDisabled
+	case remaining == 1:
+		return 0, ErrLastAssistant
+	}
+
+	// Positions are read while the chats still resolve to the assistant being disabled.
+	ctx, cancel := db.Ctx()
+	positions := make(map[int64]int)
+	for _, chatID := range cache.ChatCache.GetActiveChats() {
+		if assistant, _ := db.Instance.GetAssistant(ctx, chatID); assistant != name {
+			continue
+		}
+		position := 0
+		if played, err := c.PlayedTime(chatID); err == nil {
+			position = int(played)
+		}
+		positions[chatID] = position
+	}
+	cancel()
+
+	c.mu.Lock()
+	if len(c.availableClients) == 1 {
+		c.mu.Unlock()
+		return 0, ErrLastAssistant
+	}
+	c.availableClients = slices.DeleteFunc(c.availableClients, func(n string) bool { return n == name })
+	call := c.uBContext[name]
+	c.mu.Unlock()
+	gologging.InfoF("[TelegramCalls] Assistant %s has been disabled; moving %d active chat(s).", name, len(positions))
+
+	moved := 0
+	for chatID, position := range positions {
+		_ = call.Stop(chatID)
+		track := cache.ChatCache.GetPlayingTrack(chatID)
+		if track == nil {
+			continue
+		}
+		// The chat's assignment no longer matches an available assistant, so rejoin picks a new one.
+		if err := c.rejoin(chatID, track, position); err != nil {
+			gologging.WarnF("[TelegramCalls] Failed to move chat %d off assistant %s: %v", chatID, name, err)
+			_ = c.Stop(chatID)
+			continue
+		}
+		eventlog.Emit(chatID, "assistant_moved", track.TrackID, name)
+		moved++
+	}
+	return moved, nil
+}
+
+// EnableAssistant puts an assistant taken out with DisableAssistant back into the pool.
+func (c *TelegramCalls) EnableAssistant(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.clients[name]; !ok {
+		return ErrUnknownAssistant
+	}
+	if slices.Contains(c.availableClients, name) {
+		return ErrAssistantEnabled
+	}
+	c.availableClients = append(c.availableClients, name)
+	gologging.InfoF("[TelegramCalls] Assistant %s has been enabled.", name)
+	return nil
+}
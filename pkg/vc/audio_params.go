@@ -0,0 +1,149 @@
+package vc
+
+import (
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/vc/ubot"
+	"strings"
+
+	"github.com/Laky-64/gologging"
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+const (
+	// lowListenerThreshold is the listener count below which the low sample rate is used.
+	lowListenerThreshold = 5
+	// lowSampleRate is the sample rate used for voice chats with few listeners.
+	lowSampleRate = 48000
+)
+
+// AudioParams describes how the audio of a stream is encoded and filtered.
+type AudioParams struct {
+	SampleRate   int
+	ChannelCount int
+	Listeners    int    // Listeners is the listener estimate the parameters were chosen for, or -1 if unknown.
+	Normalize    bool   // Normalize applies loudnessFilter to the audio.
+	Volume       int    // Volume is the playback volume in percent; 100 leaves the audio as it is.
+	Preset       string // Preset is the audio preset applied to the audio, or PresetOff.
+	SpeedFilters string // SpeedFilters are the ffmpeg filters /speed set for the current track, if any.
+}
+
+// audioParamsFor maps a listener estimate to the audio parameters used for the next track.
+// An unknown estimate (negative) keeps the configured sample rate.
+func audioParamsFor(listeners int) AudioParams {
+	params := AudioParams{
+		SampleRate:   config.Conf.AudioSampleRate,
+		ChannelCount: 2,
+		Listeners:    listeners,
+		Volume:       db.DefaultVolume,
+		Preset:       PresetOff,
+	}
+
+	if config.Conf.AdaptiveAudio && listeners >= 0 && listeners < lowListenerThreshold {
+		params.SampleRate = min(lowSampleRate, params.SampleRate)
+	}
+	return params
+}
+
+// estimateListeners returns the number of group call participants other than the assistant, or -1 if it cannot be determined.
+func estimateListeners(call *ubot.Context, chatID int64) int {
+	if chatID > 0 {
+		return -1
+	}
+
+	participants, err := call.GetParticipants(chatID)
+	if err != nil {
+		gologging.DebugF("[AudioParams] Failed to get participants for chat %d: %v", chatID, err)
+		return -1
+	}
+
+	me := call.App.Me().ID
+	listeners := 0
+	for _, participant := range participants {
+		if peer, ok := participant.Peer.(*tg.PeerUser); ok && peer.UserID == me {
+			continue
+		}
+		listeners++
+	}
+	return listeners
+}
+
+// ListenerIDs returns the users in the voice chat of a chat, not counting the assistant, or nil if they cannot be
+// determined.
+func (c *TelegramCalls) ListenerIDs(chatID int64) map[int64]struct{} {
+	call, err := c.GetGroupAssistant(chatID)
+	if err != nil || chatID > 0 {
+		return nil
+	}
+
+	participants, err := call.GetParticipants(chatID)
+	if err != nil {
+		gologging.DebugF("[ListenerIDs] Failed to get participants for chat %d: %v", chatID, err)
+		return nil
+	}
+
+	me := call.App.Me().ID
+	ids := make(map[int64]struct{}, len(participants))
+	for _, participant := range participants {
+		if peer, ok := participant.Peer.(*tg.PeerUser); ok && peer.UserID != me {
+			ids[peer.UserID] = struct{}{}
+		}
+	}
+	return ids
+}
+
+// selectAudioParams re-evaluates the audio parameters of a chat from its current listener estimate.
+// It is only called at track boundaries, so a running stream never changes format.
+func (c *TelegramCalls) selectAudioParams(call *ubot.Context, chatID int64) AudioParams {
+	listeners := -1
+	if config.Conf.AdaptiveAudio {
+		listeners = estimateListeners(call, chatID)
+	}
+
+	params := audioParamsFor(listeners)
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	params.Normalize = db.Instance.GetNormalize(ctx, chatID)
+	params.Volume = db.Instance.GetVolume(ctx, chatID)
+	params.Preset = db.Instance.GetAudioPreset(ctx, chatID)
+
+	c.mu.Lock()
+	c.audioParams[chatID] = params
+	c.mu.Unlock()
+	return params
+}
+
+// currentAudioParams returns the audio parameters of the track playing in a chat.
+// If none were chosen yet, they are selected now.
+func (c *TelegramCalls) currentAudioParams(call *ubot.Context, chatID int64) AudioParams {
+	c.mu.RLock()
+	params, ok := c.audioParams[chatID]
+	c.mu.RUnlock()
+	if ok {
+		return params
+	}
+	return c.selectAudioParams(call, chatID)
+}
+
+// clearAudioParams forgets the audio parameters of a chat so that its next session selects them afresh.
+func (c *TelegramCalls) clearAudioParams(chatID int64) {
+	c.mu.Lock()
+	delete(c.audioParams, chatID)
+	c.mu.Unlock()
+}
+
+// rememberSpeed records the /speed filters a chat's stream was started with, so a restart for another change of its
+// audio parameters keeps the speed. A stream started with a seek or without parameters plays at the normal speed.
+func (c *TelegramCalls) rememberSpeed(chatID int64, ffmpegParameters string) {
+	speedFilters := ""
+	if strings.Contains(ffmpegParameters, "filter:") {
+		speedFilters = ffmpegParameters
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if params, ok := c.audioParams[chatID]; ok {
+		params.SpeedFilters = speedFilters
+		c.audioParams[chatID] = params
+	}
+}
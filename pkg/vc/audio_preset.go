@@ -0,0 +1,55 @@
+package vc
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/db"
+)
+
+// PresetOff is the audio preset that leaves the audio as it is.
+const PresetOff = "off"
+
+// bassBoostGains maps the bass boost presets to the gain, in dB, ffmpeg's bass filter applies.
+var bassBoostGains = map[string]int{
+	"low":  5,
+	"mid":  10,
+	"high": 15,
+}
+
+// IsAudioPreset reports whether preset is a known audio preset, PresetOff included.
+func IsAudioPreset(preset string) bool {
+	_, ok := bassBoostGains[preset]
+	return ok || preset == PresetOff
+}
+
+// presetFilter returns the ffmpeg filter of an audio preset, or an empty string for PresetOff or an unknown preset.
+// It is appended to the -filter:a chain, so it runs after the atempo filters of a speed change.
+func presetFilter(preset string) string {
+	gain, ok := bassBoostGains[preset]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("bass=g=%d", gain)
+}
+
+// ApplyAudioPreset stores the audio preset of a chat and applies it to the track playing there.
+// The stream is rebuilt with the preset in its filter chain, keeping a speed set with /speed; later tracks pick the
+// stored preset up when their audio parameters are selected.
+func (c *TelegramCalls) ApplyAudioPreset(chatID int64, preset string) error {
+	if !IsAudioPreset(preset) {
+		return fmt.Errorf("unknown audio preset %q", preset)
+	}
+
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	if err := db.Instance.SetAudioPreset(ctx, chatID, preset); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if params, ok := c.audioParams[chatID]; ok {
+		params.Preset = preset
+		c.audioParams[chatID] = params
+	}
+	c.mu.Unlock()
+	return c.restartWithFilters(chatID)
+}
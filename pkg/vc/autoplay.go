@@ -0,0 +1,83 @@
+package vc
+
+import (
+	"context"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/core/eventlog"
+	"github.com/zuchzub/Go/pkg/lang"
+	"slices"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+const (
+	// autoplayBatch is how many related tracks are queued each time the queue runs out.
+	autoplayBatch = 5
+	// autoplayHistory is how many recently autoplayed track IDs are remembered per chat, to avoid replaying them.
+	autoplayHistory = 50
+	// autoplayTimeout bounds the lookup of related tracks.
+	autoplayTimeout = time.Minute
+)
+
+// queueRelated queues tracks related to the finished track when the chat has autoplay enabled.
+// It reports whether any track was queued.
+func (c *TelegramCalls) queueRelated(chatID int64, last *cache.CachedTrack) bool {
+	ctx, cancel := db.Ctx()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	enabled := db.Instance.GetAutoplay(ctx, chatID)
+	cancel()
+	if !enabled {
+		return false
+	}
+
+	lookupCtx, lookupCancel := context.WithTimeout(context.Background(), autoplayTimeout)
+	defer lookupCancel()
+	tracks, err := dl.RelatedTracks(lookupCtx, last, autoplayBatch*2)
+	if err != nil {
+		gologging.WarnF("[Autoplay] Failed to find tracks related to %s in chat %d: %v", last.Name, chatID, err)
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	history := append(c.autoplayed[chatID], last.TrackID)
+
+	queued := 0
+	for _, track := range tracks {
+		if queued == autoplayBatch {
+			break
+		}
+		if slices.Contains(history, track.ID) || (config.Conf.MaxDuration > 0 && track.Duration > config.Conf.MaxDuration) {
+			continue
+		}
+
+		cache.ChatCache.AddSong(chatID, &cache.CachedTrack{
+			URL: track.URL, Name: track.Name, User: lang.GetString(langCode, "autoplay_user"),
+			Thumbnail: track.Cover, TrackID: track.ID, Duration: track.Duration,
+			IsVideo: last.IsVideo, Platform: track.Platform, ContentType: cache.ContentMusic,
+		})
+		history = append(history, track.ID)
+		queued++
+	}
+
+	if len(history) > autoplayHistory {
+		history = history[len(history)-autoplayHistory:]
+	}
+	c.autoplayed[chatID] = history
+
+	if queued > 0 {
+		eventlog.Emit(chatID, "autoplay", last.TrackID, "")
+	}
+	return queued > 0
+}
+
+// clearAutoplayHistory forgets the tracks autoplayed in a chat.
+func (c *TelegramCalls) clearAutoplayHistory(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.autoplayed, chatID)
+}
@@ -0,0 +1,71 @@
+package vc
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// cacheSaveInterval is how often the membership and invite link caches are saved while the bot runs.
+const cacheSaveInterval = 5 * time.Minute
+
+// cacheDir is where the membership and invite link caches are saved between runs.
+var cacheDir = filepath.Join("database", "cache")
+
+// persistedCache is a cache kept between runs, such as a *cache.Cache.
+type persistedCache interface {
+	SaveFile(path string) error
+	LoadFile(path string) (int, error)
+}
+
+// persistedCaches returns the caches kept between runs by file name.
+func (c *TelegramCalls) persistedCaches() map[string]persistedCache {
+	return map[string]persistedCache{
+		"status.json": c.statusCache,
+		"invite.json": c.inviteCache,
+	}
+}
+
+// SaveCaches saves the assistants' membership statuses and the chats' invite links, so that a restart does not have to
+// ask Telegram for them again in every chat.
+func (c *TelegramCalls) SaveCaches() error {
+	var errs []error
+	for name, pc := range c.persistedCaches() {
+		if err := pc.SaveFile(filepath.Join(cacheDir, name)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadCaches restores the caches saved by SaveCaches, skipping entries that have expired since.
+// The restored entries are hints: a membership found to be wrong when playing is dropped and checked again, as it is
+// for entries cached during the run. A file that cannot be read is logged and its cache starts empty.
+func (c *TelegramCalls) LoadCaches() {
+	for name, pc := range c.persistedCaches() {
+		n, err := pc.LoadFile(filepath.Join(cacheDir, name))
+		if err != nil {
+			gologging.WarnF("[TelegramCalls] Ignoring the saved cache %s: %v", name, err)
+			continue
+		}
+		if n > 0 {
+			gologging.InfoF("[TelegramCalls] Restored %d entries from the saved cache %s", n, name)
+		}
+	}
+}
+
+// StartCacheSaver saves the caches every cacheSaveInterval, so that they survive a crash as well as a shutdown.
+func (c *TelegramCalls) StartCacheSaver() {
+	go func() {
+		ticker := time.NewTicker(cacheSaveInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := c.SaveCaches(); err != nil {
+				gologging.WarnF("[TelegramCalls] Failed to save the caches: %v", err)
+			}
+		}
+	}()
+}
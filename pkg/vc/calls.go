@@ -20,13 +20,14 @@ import (
     "github.com/zuchzub/Go/pkg/core"
     "github.com/zuchzub/Go/pkg/core/cache"
     "github.com/zuchzub/Go/pkg/core/db"
-    "github.com/zuchzub/Go/pkg/core/dl"
+    "github.com/zuchzub/Go/pkg/core/eventlog"
+    "github.com/zuchzub/Go/pkg/core/musicfeed"
+    "github.com/zuchzub/Go/pkg/core/telemetry"
     "github.com/zuchzub/Go/pkg/lang"
     "github.com/zuchzub/Go/pkg/vc/ntgcalls"
     "github.com/zuchzub/Go/pkg/vc/ubot"
 	"math/rand"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -155,33 +156,39 @@ func (c *TelegramCalls) StopAllClients() {
 	}
 }
 
+// JoinedCalls returns how many group calls each assistant is currently joined to, keyed by assistant name.
+func (c *TelegramCalls) JoinedCalls() map[string]int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	counts := make(map[string]int, len(c.uBContext))
+	for name, call := range c.uBContext {
+		counts[name] = call.JoinedCalls()
+	}
+	return counts
+}
+
 // PlayMedia starts playing a media file in a voice chat. It handles joining the assistant to the chat if necessary
 // and sends a log message if logging is enabled.
+// Voice chats only exist in groups and channels, so a positive (user) chat ID is rejected with a localized error.
 func (c *TelegramCalls) PlayMedia(chatID int64, filePath string, video bool, ffmpegParameters string) error {
-	call, err := c.GetGroupAssistant(chatID)
-	if err != nil {
-		return err
-	}
-	ctx, cancel := db.Ctx()
-	defer cancel()
-
-	if chatID < 0 {
-		if err := c.joinAssistant(chatID, call.App.Me().ID); err != nil {
-			cache.ChatCache.ClearChat(chatID, true)
+	if video {
+		if err := c.acquireVideoSlot(chatID); err != nil {
 			return err
 		}
 	} else {
-		_, _ = call.App.ResolvePeer(chatID)
+		c.releaseVideoSlot(chatID)
 	}
 
-	gologging.InfoF("Playing media in chat %d: %s", chatID, filePath)
-	mediaDesc := getMediaDescription(filePath, video, ffmpegParameters)
-	if err := call.Play(chatID, mediaDesc); err != nil {
-		gologging.ErrorF("Failed to play the media: %v", err)
-		cache.ChatCache.ClearChat(chatID, true)
-		return fmt.Errorf("playback failed: %w", err)
+	if err := c.startStream(chatID, filePath, video, ffmpegParameters); err != nil {
+		return err
 	}
+	c.rememberSpeed(chatID, ffmpegParameters)
+	eventlog.Emit(chatID, "play", trackID(chatID), ffmpegParameters)
+	telemetry.RecordPlay()
 
+	ctx, cancel := db.Ctx()
+	defer cancel()
 	if db.Instance.GetLoggerStatus(ctx, c.bot.Me().ID) {
 		go sendLogger(c.bot, chatID, cache.ChatCache.GetPlayingTrack(chatID))
 	}
@@ -189,65 +196,135 @@ func (c *TelegramCalls) PlayMedia(chatID int64, filePath string, video bool, ffm
 	return nil
 }
 
+// startStream joins the assistant to a chat's voice chat if needed and streams a media file there.
+// Unlike PlayMedia, it does not count the play or log it, so it is also used for station idents.
+func (c *TelegramCalls) startStream(chatID int64, filePath string, video bool, ffmpegParameters string) error {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	if chatID > 0 {
+		cache.ChatCache.ClearChat(chatID, false)
+		return errors.New(lang.GetString(db.Instance.GetLang(ctx, chatID), "play_private_unsupported"))
+	}
+
+	call, err := c.GetGroupAssistant(chatID)
+	if err != nil {
+		return err
+	}
+
+	// The membership check is done once per session; the watcher invalidates it when the assistant leaves.
+	ubID := call.App.Me().ID
+	joined, err := joinOnce(chatID, ubID, c.ensureJoined)
+	if err != nil {
+		cache.ChatCache.ClearChat(chatID, true)
+		return err
+	}
+
+	audio := c.currentAudioParams(call, chatID)
+	gologging.InfoF("Playing media in chat %d: %s (listeners=%d sample_rate=%d channels=%d)", chatID, filePath, audio.Listeners, audio.SampleRate, audio.ChannelCount)
+	mediaDesc := getMediaDescription(filePath, video, ffmpegParameters, audio)
+	c.armFirstFrame(chatID)
+	c.startGeneration(chatID)
+	err = c.playJoined(chatID, ubID, joined, c.ensureJoined, func() error { return call.Play(chatID, mediaDesc) })
+	if err != nil {
+		gologging.ErrorF("Failed to play the media: %v", err)
+		eventlog.Emit(chatID, "play_failed", trackID(chatID), err.Error())
+		cache.ChatCache.ClearChat(chatID, true)
+		return fmt.Errorf("playback failed: %w", err)
+	}
+	return nil
+}
+
 // downloadAndPrepareSong handles the download and preparation of a song for playback.
 // It returns an error if the download or preparation fails.
-func (c *TelegramCalls) downloadAndPrepareSong(song *cache.CachedTrack, reply *tg.NewMessage) error {
+func (c *TelegramCalls) downloadAndPrepareSong(chatID int64, song *cache.CachedTrack, reply *tg.NewMessage) error {
+	// A prefetch of the song that is still running is waited for rather than downloading the song a second time.
+	c.waitForPrefetch(song)
 	if song.FilePath != "" {
-		return nil
+		if _, err := os.Stat(song.FilePath); err == nil || urlRegex.MatchString(song.FilePath) {
+			return nil
+		}
+		gologging.InfoF("[downloadAndPrepareSong] %s is missing, downloading it again", song.FilePath)
+		song.FilePath = ""
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
 	defer cancel()
 
-	chatID := config.Conf.LoggerId
 	dbCtx, dbCancel := db.Ctx()
 	defer dbCancel()
-	langCode := db.Instance.GetLang(dbCtx, chatID)
+	langCode := db.Instance.GetLang(dbCtx, config.Conf.LoggerId)
 
-	dlPath, trackInfo, err := DownloadSong(ctx, song, c.bot)
+	ctx, stopProgress := showDownloadProgress(ctx, reply, db.Instance.GetLang(dbCtx, chatID), song.Name)
+	dlPath, trackInfo, err := DownloadSong(ctx, chatID, song, c.bot)
+	stopProgress()
 	if err != nil {
+		cache.ChatCache.RecordFailure(chatID, song, err)
 		_, _ = reply.Edit(fmt.Sprintf(lang.GetString(langCode, "download_failed_skip"), err))
 		return err
 	}
-
-	song.FilePath = dlPath
-	if trackInfo != nil && trackInfo.Duration > 0 {
-		song.Duration = trackInfo.Duration
-	}
-
-	if song.FilePath == "" {
+	if dlPath == "" {
 		_, _ = reply.Edit(lang.GetString(langCode, "download_failed_empty"))
 		return errors.New("download failed due to an empty file path")
 	}
+	if !cache.ChatCache.SetDownload(chatID, song, dlPath, trackInfo) {
+		return errors.New("the track left the queue while it was downloading")
+	}
 
 	return nil
 }
 
 // PlayNext plays the next song in the queue, handles looping, and notifies the chat when the queue is finished.
 func (c *TelegramCalls) PlayNext(chatID int64) error {
+	eventlog.Emit(chatID, "next", trackID(chatID), "")
+	// Skipping during an ident skips only the ident.
+	if c.finishIdent(chatID) {
+		return nil
+	}
 	loop := cache.ChatCache.GetLoopCount(chatID)
 	if loop > 0 {
 		cache.ChatCache.SetLoopCount(chatID, loop-1)
 		if currentsSong := cache.ChatCache.GetPlayingTrack(chatID); currentsSong != nil {
-			return c.playSong(chatID, currentsSong)
+			return c.player.playSong(chatID, currentsSong)
 		}
 	}
 
-	if nextSong := cache.ChatCache.GetUpcomingTrack(chatID); nextSong != nil {
+	nextSong := cache.ChatCache.GetUpcomingTrack(chatID)
+	if nextSong == nil && cache.ChatCache.GetLoopQueue(chatID) {
+		// The current track is kept for the next pass when it leaves the queue, so it is removed first.
 		cache.ChatCache.RemoveCurrentSong(chatID, true)
-		return c.playSong(chatID, nextSong)
+		if cache.ChatCache.RequeueLooped(chatID) > 0 {
+			first := cache.ChatCache.GetPlayingTrack(chatID)
+			if started, err := c.playIdent(chatID, first); started || err != nil {
+				return err
+			}
+			return c.player.playSong(chatID, first)
+		}
+		return c.player.handleNoSong(chatID)
+	}
+	if nextSong == nil {
+		if last := cache.ChatCache.GetPlayingTrack(chatID); last != nil && c.queueRelated(chatID, last) {
+			nextSong = cache.ChatCache.GetUpcomingTrack(chatID)
+		}
+	}
+	if nextSong != nil {
+		cache.ChatCache.RemoveCurrentSong(chatID, true)
+		if started, err := c.playIdent(chatID, nextSong); started || err != nil {
+			return err
+		}
+		return c.player.playSong(chatID, nextSong)
 	}
 
 	cache.ChatCache.RemoveCurrentSong(chatID, true)
-	return c.handleNoSong(chatID)
+	return c.player.handleNoSong(chatID)
 }
 
 // handleNoSong manages the situation where there are no more songs in the queue by stopping the playback
 // and sending a notification to the chat.
 func (c *TelegramCalls) handleNoSong(chatID int64) error {
-	_ = c.Stop(chatID)
+	eventlog.Emit(chatID, "queue_end", "", "")
 	ctx, cancel := db.Ctx()
 	defer cancel()
+	_ = c.stop(chatID, db.Instance.GetStayInVC(ctx, chatID) || db.Instance.GetStayOnEmpty(ctx, chatID))
 	langCode := db.Instance.GetLang(ctx, chatID)
 	_, _ = c.bot.SendMessage(chatID, lang.GetString(langCode, "queue_finished"))
 	return nil
@@ -259,49 +336,133 @@ func (c *TelegramCalls) playSong(chatID int64, song *cache.CachedTrack) error {
 	ctx, cancel := db.Ctx()
 	defer cancel()
 	langCode := db.Instance.GetLang(ctx, chatID)
-	reply, err := c.bot.SendMessage(chatID, fmt.Sprintf(lang.GetString(langCode, "downloading"), song.Name))
+	// The message becomes the now-playing message, so it is posted to the chat's music feed if it has one.
+	downloading := fmt.Sprintf(lang.GetString(langCode, "downloading"), song.Name)
+	var err error
+	reply, inFeed := musicfeed.Send(c.bot, chatID, downloading, nil)
+	if !inFeed {
+		reply, err = c.bot.SendMessage(chatID, downloading)
+	}
 	if err != nil {
 		gologging.InfoF("[playSong] Failed to send message: %v", err)
 		return err
 	}
 
-	if err := c.downloadAndPrepareSong(song, reply); err != nil {
-		return c.PlayNext(chatID)
+	if err := c.downloadAndPrepareSong(chatID, song, reply); err != nil {
+		return c.songFailed(chatID, reply, err)
+	}
+
+	if !song.IsLive && song.Duration == 0 {
+		song.Duration = cache.GetFileDuration(song.FilePath)
+	}
+
+	startAt := song.StartAt
+	if song.IsLive || song.Duration > 0 && startAt >= song.Duration {
+		startAt = 0
 	}
 
-	if err := c.PlayMedia(chatID, song.FilePath, song.IsVideo, ""); err != nil {
+	c.clearAudioParams(chatID)
+	joined := c.OnJoining(chatID, func() { _, _ = reply.Edit(lang.GetString(langCode, "assistant_joining")) })
+	if startAt > 0 {
+		err = c.SeekStream(chatID, song.FilePath, startAt, song.Duration, song.IsVideo)
+	} else {
+		err = c.PlayMedia(chatID, song.FilePath, song.IsVideo, "")
+	}
+	joined()
+	if err != nil {
+		var full *VideoSlotsFullError
+		if errors.As(err, &full) {
+			text, opts := VideoSlotsFullReply(langCode, song, full)
+			_, err = reply.Edit(text, opts)
+			return err
+		}
 		_, err := reply.Edit(err.Error())
 		return err
 	}
+	c.WaitForPlayback(chatID)
 
-	if song.Duration == 0 {
-		song.Duration = cache.GetFileDuration(song.FilePath)
+	duration := lang.GetString(langCode, "live_badge")
+	if !song.IsLive {
+		duration = cache.SecToMin(song.Duration - startAt)
 	}
 	text := fmt.Sprintf(
 		lang.GetString(langCode, "now_playing_details"),
-		song.URL,
-		song.Name,
-		cache.SecToMin(song.Duration),
+		core.FormatTrackLine(song, core.TrackLineCompact),
+		duration,
 		song.User,
 	)
+	if startAt > 0 {
+		text += fmt.Sprintf(lang.GetString(langCode, "play_start_offset"), cache.SecToMin(startAt))
+	}
+	text += VolumeNote(langCode, db.Instance.GetVolume(ctx, chatID))
 
 	_, err = reply.Edit(text, tg.SendOptions{ReplyMarkup: core.ControlButtons("play")})
 	if err != nil {
 		gologging.InfoF("[playSong] Failed to edit message: %v", err)
+		c.PrefetchNext(chatID)
 		return nil
 	}
+	cache.ChatCache.SetNowPlayingMessage(chatID, reply.ID)
+	c.PrefetchNext(chatID)
 
 	return nil
 }
 
+// songFailed is called when the current track could not be downloaded. It cancels the loop of the track, telling
+// the chat why in reply, if there is one, and plays the next track.
+func (c *TelegramCalls) songFailed(chatID int64, reply *tg.NewMessage, err error) error {
+	c.cancelLoop(chatID, reply, err)
+	return c.PlayNext(chatID)
+}
+
+// cancelLoop clears the remaining loop count of the current track after it failed to play,
+// so that PlayNext advances to the next track instead of retrying the broken one.
+func (c *TelegramCalls) cancelLoop(chatID int64, reply *tg.NewMessage, reason error) {
+	if cache.ChatCache.GetLoopCount(chatID) == 0 {
+		return
+	}
+
+	cache.ChatCache.SetLoopCount(chatID, 0)
+	gologging.WarnF("[playSong] Cancelled the loop in chat %d: %v", chatID, reason)
+	if reply == nil {
+		return
+	}
+
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	_, _ = reply.Edit(fmt.Sprintf(lang.GetString(langCode, "loop_cancelled"), reason))
+}
+
 // Stop halts media playback in a voice chat and clears the chat's cache.
+// The assistant also leaves the group call, unless the chat has asked it to stay in the voice chat.
 func (c *TelegramCalls) Stop(chatId int64) error {
+	ctx, cancel := db.Ctx()
+	stay := db.Instance.GetStayInVC(ctx, chatId)
+	cancel()
+	return c.stop(chatId, stay)
+}
+
+// stop halts media playback in a voice chat and clears the chat's cache.
+// If stay is true the assistant stays joined to the group call with nothing playing; otherwise it leaves.
+func (c *TelegramCalls) stop(chatId int64, stay bool) error {
+	c.releaseVideoSlot(chatId)
 	call, err := c.GetGroupAssistant(chatId)
 	if err != nil {
 		return err
 	}
+	eventlog.Emit(chatId, "stop", trackID(chatId), "")
+	cache.RecordQueueStat(chatId, cache.QueueCleared, cache.ChatCache.GetQueueLength(chatId))
 	cache.ChatCache.ClearChat(chatId, true)
-	err = call.Stop(chatId)
+	c.clearAudioParams(chatId)
+	c.clearAutoplayHistory(chatId)
+	c.clearIdent(chatId)
+
+	if stay {
+		err = call.StopBinding(chatId)
+	} else {
+		err = call.Stop(chatId)
+	}
 	if err != nil {
 		gologging.InfoF("[Stop] Failed to stop the call: %v", err)
 		// For now, we will ignore the error.
@@ -310,6 +471,97 @@ func (c *TelegramCalls) Stop(chatId int64) error {
 	return nil
 }
 
+// ForceReset unconditionally tears down all state held for a chat, across both the vc and ubot layers.
+// Every step is attempted regardless of whether an earlier one failed, and the outcome of each is returned.
+func (c *TelegramCalls) ForceReset(chatId int64) []ResetStep {
+	var steps []ResetStep
+
+	cache.RecordQueueStat(chatId, cache.QueueCleared, cache.ChatCache.GetQueueLength(chatId))
+	cache.ChatCache.UnlockQueue(chatId)
+	cache.ChatCache.ClearChat(chatId, true)
+	steps = append(steps, ResetStep{Name: "queue"}, ResetStep{Name: "queue lock"})
+
+	c.mu.RLock()
+	for _, ctx := range c.uBContext {
+		if me := ctx.App.Me(); me != nil {
+			c.statusCache.Delete(fmt.Sprintf("%d:%d", chatId, me.ID))
+		}
+	}
+	c.mu.RUnlock()
+	steps = append(steps, ResetStep{Name: "status cache"})
+
+	c.inviteCache.Delete(fmt.Sprintf("%d", chatId))
+	steps = append(steps, ResetStep{Name: "invite cache"})
+
+	c.mu.Lock()
+	delete(c.reconnecting, chatId)
+	delete(c.audioParams, chatId)
+	c.mu.Unlock()
+	steps = append(steps, ResetStep{Name: "reconnect latch"})
+
+	steps = append(steps, c.resetChatState(chatId)...)
+
+	c.releaseVideoSlot(chatId)
+	steps = append(steps, ResetStep{Name: "video slot"})
+
+	call, err := c.GetGroupAssistant(chatId)
+	if err != nil {
+		steps = append(steps, ResetStep{Name: "binding", Err: err}, ResetStep{Name: "leave call", Err: err})
+		return steps
+	}
+
+	steps = append(steps, ResetStep{Name: "binding", Err: call.StopBinding(chatId)})
+	steps = append(steps, ResetStep{Name: "leave call", Err: call.LeaveCall(chatId)})
+	return steps
+}
+
+// resetChatState forgets the per-chat playback state kept by the other features, for ForceReset.
+// Pending stream ends are invalidated rather than forgotten, so that a late end of the old stream cannot match the
+// generation of a stream started after the reset.
+func (c *TelegramCalls) resetChatState(chatId int64) []ResetStep {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pending, ok := c.assistantLeft[chatId]; ok {
+		pending.timer.Stop()
+		delete(c.assistantLeft, chatId)
+	}
+	delete(c.joins, chatId)
+	delete(c.joinNotify, chatId)
+
+	gen := c.streamGens[chatId]
+	gen.id++
+	gen.consumed = true
+	c.streamGens[chatId] = gen
+
+	if started, ok := c.firstFrame[chatId]; ok {
+		close(started)
+		delete(c.firstFrame, chatId)
+	}
+	delete(c.identPending, chatId)
+	if c.draining != nil {
+		delete(c.draining, chatId)
+	}
+
+	for _, flight := range c.prefetches {
+		if flight.chatID == chatId {
+			flight.cancel(errTrackDequeued)
+		}
+	}
+	delete(c.autoplayed, chatId)
+
+	return []ResetStep{
+		{Name: "rejoin grace timer"},
+		{Name: "join in progress"},
+		{Name: "stream generation"},
+		{Name: "playback start wait"},
+		{Name: "ident"},
+		{Name: "restart drain"},
+		{Name: "prefetch"},
+		{Name: "autoplay history"},
+	}
+}
+
 // Pause temporarily stops media playback in a voice chat.
 // It returns true if the operation was successful, and an error otherwise.
 func (c *TelegramCalls) Pause(chatId int64) (bool, error) {
@@ -366,26 +618,33 @@ func (c *TelegramCalls) PlayedTime(chatId int64) (uint64, error) {
 var urlRegex = regexp.MustCompile(`^https?://`)
 
 // SeekStream jumps to a specific time in the current media stream.
+// A duration of 0 means it is not known, as for some direct links: playback then runs to the end of the media.
 func (c *TelegramCalls) SeekStream(chatID int64, filePath string, toSeek, duration int, isVideo bool) error {
 	ctx, cancel := db.Ctx()
 	defer cancel()
 	langCode := db.Instance.GetLang(ctx, chatID)
-	if toSeek < 0 || duration <= 0 {
+	if toSeek < 0 || duration < 0 {
 		return errors.New(lang.GetString(langCode, "invalid_seek"))
 	}
 
+	return c.PlayMedia(chatID, filePath, isVideo, seekParameters(filePath, toSeek, duration))
+}
+
+// seekParameters builds the ffmpeg parameters that start playback of filePath at toSeek seconds.
+// With a duration of 0, the end of the media is not given.
+func seekParameters(filePath string, toSeek, duration int) string {
 	isURL := urlRegex.MatchString(filePath)
 	_, err := os.Stat(filePath)
 	isFile := err == nil
 
-	var ffmpegParams string
+	params := fmt.Sprintf("-ss %d", toSeek)
 	if isURL || !isFile {
-		ffmpegParams = fmt.Sprintf("-ss %d -i %s -to %d", toSeek, filePath, duration)
-	} else {
-		ffmpegParams = fmt.Sprintf("-ss %d -to %d", toSeek, duration)
+		params += " -i " + shellQuote(filePath)
 	}
-
-	return c.PlayMedia(chatID, filePath, isVideo, ffmpegParams)
+	if duration > 0 {
+		params += fmt.Sprintf(" -to %d", duration)
+	}
+	return params
 }
 
 // ChangeSpeed modifies the playback speed of the current stream.
@@ -402,6 +661,11 @@ func (c *TelegramCalls) ChangeSpeed(chatID int64, speed float64) error {
 		return errors.New(lang.GetString(langCode, "no_song_playing"))
 	}
 
+	return c.PlayMedia(chatID, playingSong.FilePath, playingSong.IsVideo, speedParameters(speed))
+}
+
+// speedParameters builds the ffmpeg filters that play a stream at speed times its normal rate.
+func speedParameters(speed float64) string {
 	videoPTS := 1 / speed
 
 	audioFilters := make([]string, 0)
@@ -417,9 +681,7 @@ func (c *TelegramCalls) ChangeSpeed(chatID int64, speed float64) error {
 	audioFilters = append(audioFilters, fmt.Sprintf("atempo=%f", remaining))
 	audioFilter := strings.Join(audioFilters, ",")
 
-	ffmpegFilters := fmt.Sprintf("-filter:v setpts=%f*PTS -filter:a %s", videoPTS, audioFilter)
-
-	return c.PlayMedia(chatID, playingSong.FilePath, playingSong.IsVideo, ffmpegFilters)
+	return fmt.Sprintf("-filter:v setpts=%f*PTS -filter:a %s", videoPTS, audioFilter)
 }
 
 // RegisterHandlers sets up the event handlers for the voice call client.
@@ -430,46 +692,38 @@ func (c *TelegramCalls) RegisterHandlers(client *tg.Client) {
 		//_, _ = call.App.UpdatesGetState()
 
 		call.OnStreamEnd(func(chatID int64, streamType ntgcalls.StreamType, device ntgcalls.StreamDevice) {
-			gologging.InfoF("[TelegramCalls] The stream has ended in chat %d (type=%v, device=%v)", chatID, streamType, device)
+			gen := c.currentGeneration(chatID)
+			gologging.InfoF("[TelegramCalls] The stream has ended in chat %d (type=%v, device=%v, generation=%d)", chatID, streamType, device, gen)
 			if streamType == ntgcalls.VideoStream {
 				gologging.DebugF("Ignoring video stream end for chat %d", chatID)
 				return
 			}
-
-			if err := c.PlayNext(chatID); err != nil {
-				gologging.WarnF("[OnStreamEnd] Failed to play the song: %v", err)
-			}
-		})
-
-		call.OnIncomingCall(func(ub *ubot.Context, chatID int64) {
-			ctx, cancel := db.Ctx()
-			defer cancel()
-			langCode := db.Instance.GetLang(ctx, chatID)
-			_, _ = ub.App.SendMessage(chatID, lang.GetString(langCode, "incoming_call"))
-			msg, err := dl.GetMessage(c.bot, "https://t.me/FallenSongs/1295")
-			if err != nil {
-				gologging.InfoF("[OnIncomingCall] Failed to get the message: %v", err)
+			if !c.consumeGeneration(chatID, gen) {
 				return
 			}
-
-			filePath, err := msg.Download(&tg.DownloadOptions{FileName: filepath.Join(config.Conf.DownloadsDir, msg.File.Name)})
-			if err != nil {
-				gologging.InfoF("[OnIncomingCall] Failed to download the message: %v", err)
+			if c.finishIdent(chatID) {
 				return
 			}
+			cache.RecordQueueStat(chatID, cache.QueueCompleted, 1)
+			c.recordPlaylistProgress(chatID)
+			c.recordHistory(chatID)
 
-			err = c.PlayMedia(chatID, filePath, false, "")
-			if err != nil {
-
-				gologging.InfoF("[OnIncomingCall] Failed to play the media: %v", err)
+			if c.finishDrained(chatID) {
+				gologging.InfoF("[OnStreamEnd] Chat %d finished its track while draining", chatID)
 				return
 			}
 
-			return
+			if err := c.PlayNext(chatID); err != nil {
+				gologging.WarnF("[OnStreamEnd] Failed to play the song: %v", err)
+			}
 		})
 
+		call.OnIncomingCall(c.handleIncomingCall)
+
+		call.OnConnectionDrop(c.handleConnectionDrop)
+
 		call.OnFrame(func(chatId int64, mode ntgcalls.StreamMode, device ntgcalls.StreamDevice, frames []ntgcalls.Frame) {
-			gologging.DebugF("Received frames for chatId: %d, mode: %v, device: %v", chatId, mode, device)
+			c.markFirstFrame(chatId)
 		})
 
 		_, _ = call.App.SendMessage(client.Me().Username, "/start")
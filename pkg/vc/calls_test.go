@@ -0,0 +1,133 @@
+package vc
+
+import (
+	"context"
+	"errors"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestForceResetClearsChatState(t *testing.T) {
+	const chatID, otherID = -2001, -2002
+	track := &cache.CachedTrack{TrackID: "a"}
+	cache.ChatCache.AddSong(chatID, track)
+	cache.ChatCache.LockQueue(chatID, false, 0)
+
+	pending := &pendingLeave{timer: time.AfterFunc(time.Hour, func() {})}
+	started := make(chan struct{})
+	_, cancel := context.WithCancelCause(context.Background())
+	cancelled := make(chan error, 1)
+	flight := &prefetchFlight{chatID: chatID, done: make(chan struct{}), cancel: func(err error) {
+		cancelled <- err
+		cancel(err)
+	}}
+	other := &prefetchFlight{chatID: otherID, done: make(chan struct{}), cancel: func(error) {
+		t.Error("ForceReset() cancelled the prefetch of another chat")
+	}}
+
+	Calls.mu.Lock()
+	Calls.assistantLeft[chatID] = pending
+	Calls.joins[chatID] = &joinFlight{done: make(chan struct{})}
+	Calls.joinNotify[chatID] = func() {}
+	Calls.streamGens[chatID] = streamGen{id: 3}
+	Calls.firstFrame[chatID] = started
+	Calls.identPending[chatID] = track
+	Calls.autoplayed[chatID] = []string{"b"}
+	Calls.prefetches[track] = flight
+	Calls.prefetches[&cache.CachedTrack{TrackID: "c"}] = other
+	Calls.mu.Unlock()
+	defer func() {
+		Calls.mu.Lock()
+		for song, f := range Calls.prefetches {
+			if f == flight || f == other {
+				delete(Calls.prefetches, song)
+			}
+		}
+		Calls.mu.Unlock()
+	}()
+
+	steps := Calls.ForceReset(chatID)
+
+	Calls.mu.RLock()
+	_, leaving := Calls.assistantLeft[chatID]
+	_, joining := Calls.joins[chatID]
+	_, notified := Calls.joinNotify[chatID]
+	_, waiting := Calls.firstFrame[chatID]
+	_, ident := Calls.identPending[chatID]
+	_, autoplayed := Calls.autoplayed[chatID]
+	gen := Calls.streamGens[chatID]
+	Calls.mu.RUnlock()
+	if leaving || joining || notified || waiting || ident || autoplayed {
+		t.Errorf("ForceReset() kept state: assistantLeft %t, joins %t, joinNotify %t, firstFrame %t, identPending %t, autoplayed %t",
+			leaving, joining, notified, waiting, ident, autoplayed)
+	}
+	if Calls.consumeGeneration(chatID, 3) {
+		t.Error("a stream end of the generation before ForceReset() was accepted")
+	}
+	if gen.id == 3 || !gen.consumed {
+		t.Errorf("ForceReset() left the stream generation at %+v", gen)
+	}
+
+	select {
+	case <-started:
+	default:
+		t.Error("ForceReset() did not release the wait for the first frame")
+	}
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, errTrackDequeued) {
+			t.Errorf("the prefetch was cancelled with %v, want errTrackDequeued", err)
+		}
+	default:
+		t.Error("ForceReset() did not cancel the prefetch of the chat")
+	}
+	if cache.ChatCache.GetQueueLength(chatID) != 0 {
+		t.Error("ForceReset() kept the queue")
+	}
+	if _, locked := cache.ChatCache.GetQueueLock(chatID); locked {
+		t.Error("ForceReset() kept the queue lock")
+	}
+
+	names := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		names[step.Name] = true
+	}
+	for _, want := range []string{"queue", "rejoin grace timer", "join in progress", "stream generation",
+		"queue lock", "playback start wait", "ident", "restart drain", "prefetch", "autoplay history", "binding", "leave call"} {
+		if !names[want] {
+			t.Errorf("ForceReset() did not report the %q step", want)
+		}
+	}
+	if pending.timer.Stop() {
+		t.Error("ForceReset() did not stop the grace timer")
+	}
+}
+
+func TestSeekParameters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "song.mp3")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		path     string
+		toSeek   int
+		duration int
+		want     string
+	}{
+		{"file", file, 90, 200, "-ss 90 -to 200"},
+		{"file of unknown duration", file, 90, 0, "-ss 90"},
+		{"url", "https://example.com/a.mp3", 30, 200, "-ss 30 -i 'https://example.com/a.mp3' -to 200"},
+		{"url of unknown duration", "https://example.com/a.mp3", 30, 0, "-ss 30 -i 'https://example.com/a.mp3'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seekParameters(tt.path, tt.toSeek, tt.duration); got != tt.want {
+				t.Errorf("seekParameters() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package vc
+
+import (
+	"context"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/lang"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+// downloadProgressInterval is how often the status message of a download is updated with its progress. Telegram
+// limits how often a message can be edited, so it is not updated on every report.
+const downloadProgressInterval = 5 * time.Second
+
+// showDownloadProgress returns a context whose download updates reply with its progress, and a function that stops
+// the updates. The stop function must be called once the download has ended, and waits for any edit in flight.
+// The message is only edited when the percentage has changed, and not at all while the size is unknown.
+func showDownloadProgress(ctx context.Context, reply *tg.NewMessage, langCode, name string) (context.Context, func()) {
+	var done, total atomic.Int64
+	ctx = dl.WithProgress(ctx, func(d, t int64) {
+		done.Store(d)
+		total.Store(t)
+	})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(downloadProgressInterval)
+		defer ticker.Stop()
+
+		name = core.TruncateDisplay(name, 60)
+		lastPercent := -1
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			t := total.Load()
+			if t <= 0 {
+				continue
+			}
+			percent := int(min(done.Load()*100/t, 100))
+			if percent == lastPercent {
+				continue
+			}
+			lastPercent = percent
+			_, _ = reply.Edit(fmt.Sprintf(lang.GetString(langCode, "downloading_progress"), name, percent))
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			close(stop)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package vc
+
+import (
+	"context"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// Drain winds down active chats before shutdown.
+// In "finish" mode, the current track of every active chat is allowed to end (bounded by timeout, or until ctx is done)
+// without the queue advancing; chats still playing afterwards are notified. In "notify" mode, every active chat is told
+// that playback is paused for a restart. Any other mode does nothing.
+func (c *TelegramCalls) Drain(ctx context.Context, mode string, timeout time.Duration) {
+	active := cache.ChatCache.GetActiveChats()
+	if len(active) == 0 {
+		return
+	}
+
+	switch mode {
+	case "finish":
+		remaining := c.waitForTracks(ctx, active, timeout)
+		gologging.InfoF("[Drain] %d of %d chats finished their track", len(active)-len(remaining), len(active))
+		c.notifyRestart(remaining)
+	case "notify":
+		c.notifyRestart(active)
+	}
+}
+
+// waitForTracks stops the queues of the given chats from advancing and waits for their current tracks to end.
+// It returns the chats whose track was still playing when the wait ended.
+func (c *TelegramCalls) waitForTracks(ctx context.Context, chats []int64, timeout time.Duration) []int64 {
+	c.mu.Lock()
+	c.draining = make(map[int64]struct{}, len(chats))
+	for _, chatID := range chats {
+		c.draining[chatID] = struct{}{}
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		c.mu.RLock()
+		pending := len(c.draining)
+		c.mu.RUnlock()
+		if pending == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			c.mu.RLock()
+			defer c.mu.RUnlock()
+			remaining := make([]int64, 0, len(c.draining))
+			for chatID := range c.draining {
+				remaining = append(remaining, chatID)
+			}
+			return remaining
+		case <-ticker.C:
+		}
+	}
+}
+
+// finishDrained is called when a stream ends. If the chat is being drained, it drops the finished track,
+// marks the chat as done, and reports true so the queue does not advance.
+func (c *TelegramCalls) finishDrained(chatID int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.draining == nil {
+		return false
+	}
+
+	delete(c.draining, chatID)
+	cache.ChatCache.RemoveCurrentSong(chatID, true)
+	return true
+}
+
+// notifyRestart tells each chat that playback is paused because the bot is restarting.
+func (c *TelegramCalls) notifyRestart(chats []int64) {
+	for _, chatID := range chats {
+		ctx, cancel := db.Ctx()
+		langCode := db.Instance.GetLang(ctx, chatID)
+		cancel()
+		if _, err := c.bot.SendMessage(chatID, lang.GetString(langCode, "shutdown_paused")); err != nil {
+			gologging.DebugF("[Drain] Failed to notify chat %d: %v", chatID, err)
+		}
+	}
+}
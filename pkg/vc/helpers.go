@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"github.com/zuchzub/Go/pkg/config"
 "github.com/zuchzub/Go/pkg/core/cache"
+"github.com/zuchzub/Go/pkg/core/db"
 "github.com/zuchzub/Go/pkg/core/dl"
+"github.com/zuchzub/Go/pkg/core/storagehealth"
 "github.com/zuchzub/Go/pkg/vc/ntgcalls"
-	"path/filepath"
+	"os"
 	"regexp"
 	"strings"
 
@@ -16,19 +18,19 @@ import (
 	"github.com/amarnathcjd/gogram/telegram"
 )
 
-// getMediaDescription creates a media description for ntgcalls based on the provided file path, video status, and ffmpeg parameters.
-func getMediaDescription(filePath string, isVideo bool, ffmpegParameters string) ntgcalls.MediaDescription {
+// getMediaDescription creates a media description for ntgcalls based on the provided file path, video status, ffmpeg parameters, and audio parameters.
+func getMediaDescription(filePath string, isVideo bool, ffmpegParameters string, audio AudioParams) ntgcalls.MediaDescription {
 	audioDescription := &ntgcalls.AudioDescription{
 		MediaSource:  ntgcalls.MediaSourceShell,
-		SampleRate:   96000,
-		ChannelCount: 2,
+		SampleRate:   uint32(audio.SampleRate),
+		ChannelCount: uint8(audio.ChannelCount),
 	}
 
-	quotedPath := fmt.Sprintf("\"%s\"", filePath)
+	quotedPath := shellQuote(filePath)
 	isURL := regexp.MustCompile(`^https?://`).MatchString(filePath)
 
 	var audioCmd strings.Builder
-	audioCmd.WriteString("ffmpeg ")
+	audioCmd.WriteString(ffmpegCommand())
 	if isURL {
 		audioCmd.WriteString("-reconnect 1 -reconnect_at_eof 1 -reconnect_streamed 1 -reconnect_delay_max 2 ")
 	}
@@ -46,9 +48,20 @@ func getMediaDescription(filePath string, isVideo bool, ffmpegParameters string)
 		audioCmd.WriteString(seekFlags + " ")
 	}
 
+	audioFilterFlags := filterFlags
+	if filter := presetFilter(audio.Preset); filter != "" {
+		audioFilterFlags = appendAudioFilter(audioFilterFlags, filter)
+	}
+	if audio.Normalize {
+		audioFilterFlags = appendAudioFilter(audioFilterFlags, loudnessFilter)
+	}
+	if audio.Volume != db.DefaultVolume {
+		audioFilterFlags = appendAudioFilter(audioFilterFlags, volumeFilter(audio.Volume))
+	}
+
 	audioCmd.WriteString("-i " + quotedPath + " ")
-	if filterFlags != "" {
-		audioCmd.WriteString(filterFlags + " ")
+	if audioFilterFlags != "" {
+		audioCmd.WriteString(audioFilterFlags + " ")
 	}
 
 	audioCmd.WriteString(fmt.Sprintf("-f s16le -ac %d -ar %d -v quiet pipe:1",
@@ -71,7 +84,7 @@ func getMediaDescription(filePath string, isVideo bool, ffmpegParameters string)
 	}
 
 	var videoCmd strings.Builder
-	videoCmd.WriteString("ffmpeg ")
+	videoCmd.WriteString(ffmpegCommand())
 
 	if isURL {
 		videoCmd.WriteString("-reconnect 1 -reconnect_at_eof 1 -reconnect_streamed 1 -reconnect_delay_max 2 ")
@@ -99,6 +112,55 @@ func getMediaDescription(filePath string, isVideo bool, ffmpegParameters string)
 	}
 }
 
+// shellQuote quotes s as a single word of the shell command ntgcalls runs, so that no character of it, such as $ or `,
+// is interpreted by the shell. File names and URLs can come from users, and must always be quoted this way.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ffmpegCommand returns the start of the ffmpeg shell commands: the configured binary and its extra global flags.
+// Both are checked against a conservative pattern when the config is loaded, so they need no quoting.
+func ffmpegCommand() string {
+	parts := append([]string{config.Conf.FFmpegPath}, config.Conf.FFmpegExtraArgs...)
+	return strings.Join(parts, " ") + " "
+}
+
+// trackID returns the ID of the track playing in a chat, or an empty string if there is none.
+func trackID(chatID int64) string {
+	if track := cache.ChatCache.GetPlayingTrack(chatID); track != nil {
+		return track.TrackID
+	}
+	return ""
+}
+
+// loudnessFilter is the ffmpeg filter used for loudness normalization.
+// It runs loudnorm in single-pass mode, which costs noticeably more CPU per stream than plain decoding.
+const loudnessFilter = "loudnorm=I=-16:TP=-1.5:LRA=11"
+
+// volumeFilter returns the ffmpeg filter that sets the audio to volume percent of its level.
+// It runs after loudnessFilter, so a normalized track is scaled from the normalized level.
+func volumeFilter(volume int) string {
+	return fmt.Sprintf("volume=%.2f", float64(volume)/100)
+}
+
+// appendAudioFilter adds filter to the end of the -filter:a chain in flags, creating the chain if there is none.
+// Filters already in the chain, such as the atempo filters used for speed changes, run before it.
+func appendAudioFilter(flags, filter string) string {
+	const audioFlag = "-filter:a "
+	i := strings.Index(flags, audioFlag)
+	if i < 0 {
+		return strings.TrimSpace(flags + " " + audioFlag + filter)
+	}
+
+	end := i + len(audioFlag)
+	if n := strings.IndexByte(flags[end:], ' '); n >= 0 {
+		end += n
+	} else {
+		end = len(flags)
+	}
+	return flags[:end] + "," + filter + flags[end:]
+}
+
 // decodePyrogramSessionString decodes a Pyrogram-generated session string into a gogram-compatible session object.
 // It returns an error if the decoding fails or the data is malformed.
 func decodePyrogramSessionString(encodedString string) (*telegram.Session, error) {
@@ -136,16 +198,35 @@ func decodePyrogramSessionString(encodedString string) (*telegram.Session, error
 	}, nil
 }
 
-// DownloadSong downloads a song using the provided cached track information.
+// DownloadSong downloads a song for a chat using the provided cached track information.
+// The download is listed by /downloads while it runs, and can be cancelled from there.
 // It returns the file path, track information, and an error if the download fails.
-func DownloadSong(ctx context.Context, song *cache.CachedTrack, bot *telegram.Client) (string, *cache.TrackInfo, error) {
+// A failure to write the file marks storage as degraded.
+func DownloadSong(ctx context.Context, chatID int64, song *cache.CachedTrack, bot *telegram.Client) (string, *cache.TrackInfo, error) {
+	ctx, done := dl.StartDownload(ctx, chatID, song.Name, song.Platform)
+	defer done()
+
+	filePath, trackInfo, err := downloadSong(ctx, song, bot)
+	if cancelled := dl.DownloadCancelled(ctx); cancelled != nil {
+		if err == nil && filePath != "" {
+			_ = os.Remove(filePath)
+		}
+		return "", trackInfo, cancelled
+	}
+	storagehealth.Report(err)
+	return filePath, trackInfo, err
+}
+
+// downloadSong downloads a song for DownloadSong.
+func downloadSong(ctx context.Context, song *cache.CachedTrack, bot *telegram.Client) (string, *cache.TrackInfo, error) {
 	if song.Platform == cache.Telegram {
 		file, err := telegram.ResolveBotFileID(song.TrackID)
 		if err != nil {
 			return "", nil, err
 		}
 
-		filePath, err := bot.DownloadMedia(file, &telegram.DownloadOptions{FileName: filepath.Join(config.Conf.DownloadsDir, song.Name)})
+		download := func(opts *telegram.DownloadOptions) (string, error) { return bot.DownloadMedia(file, opts) }
+		filePath, err := dl.DownloadTelegram(ctx, download, song.Name, 0)
 		return filePath, nil, err
 	}
 
@@ -167,8 +248,8 @@ func DownloadSong(ctx context.Context, song *cache.CachedTrack, bot *telegram.Cl
 				return "", &trackInfo, fmt.Errorf("failed to get the message for %s: %w", trackInfo.Name, err)
 			}
 
-			fileName := msg.File.Name
-			download, err := msg.Download(&telegram.DownloadOptions{FileName: filepath.Join(config.Conf.DownloadsDir, fileName)})
+			download := func(opts *telegram.DownloadOptions) (string, error) { return msg.Download(opts) }
+			downloaded, err := dl.DownloadTelegram(ctx, download, msg.File.Name, msg.File.Size)
 			if err != nil {
 				return "", &trackInfo, fmt.Errorf("failed to download %s: %w", trackInfo.Name, err)
 			}
@@ -177,7 +258,7 @@ func DownloadSong(ctx context.Context, song *cache.CachedTrack, bot *telegram.Cl
 				trackInfo.Duration = cache.GetFileDur(msg)
 			}
 
-			return download, &trackInfo, nil
+			return downloaded, &trackInfo, nil
 		}
 
 		return filePath, &trackInfo, err
@@ -0,0 +1,82 @@
+package vc
+
+import (
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	for _, s := range []string{
+		"plain.mp3",
+		"with space.mp3",
+		"$(touch /tmp/pwned).mp3",
+		"`id`.mp3",
+		"it's.mp3",
+		`"quoted";echo hi&`,
+		"https://example.com/a?b=1&c=$(id)",
+		"",
+	} {
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(s)).Output()
+		if err != nil {
+			t.Fatalf("sh -c with %q: %v", s, err)
+		}
+		if string(out) != s {
+			t.Errorf("shellQuote(%q) reached the shell as %q", s, out)
+		}
+	}
+}
+
+func TestGetMediaDescriptionFFmpegOverrides(t *testing.T) {
+	saved := config.Conf
+	defer func() { config.Conf = saved }()
+	audio := AudioParams{SampleRate: 48000, ChannelCount: 2, Volume: db.DefaultVolume, Preset: PresetOff}
+
+	tests := []struct {
+		name       string
+		conf       *config.BotConfig
+		file       string
+		params     string
+		wantPrefix string
+	}{
+		{
+			name:       "defaults",
+			conf:       &config.BotConfig{FFmpegPath: "ffmpeg"},
+			file:       "/downloads/a.mp3",
+			wantPrefix: "ffmpeg -i '/downloads/a.mp3' ",
+		},
+		{
+			name:       "custom binary",
+			conf:       &config.BotConfig{FFmpegPath: "/opt/ffmpeg/bin/ffmpeg"},
+			file:       "/downloads/a.mp3",
+			wantPrefix: "/opt/ffmpeg/bin/ffmpeg -i '/downloads/a.mp3' ",
+		},
+		{
+			name:       "extra args before the input options",
+			conf:       &config.BotConfig{FFmpegPath: "ffmpeg", FFmpegExtraArgs: []string{"-hwaccel", "vaapi", "-threads", "2"}},
+			file:       "/downloads/a.mp3",
+			params:     "-ss 30 -to 90",
+			wantPrefix: "ffmpeg -hwaccel vaapi -threads 2 -ss 30 -to 90 -i '/downloads/a.mp3' ",
+		},
+		{
+			name:       "extra args before the reconnect flags of a URL",
+			conf:       &config.BotConfig{FFmpegPath: "/usr/local/bin/ffmpeg", FFmpegExtraArgs: []string{"-hwaccel", "auto"}},
+			file:       "https://example.com/a.mp3",
+			wantPrefix: "/usr/local/bin/ffmpeg -hwaccel auto -reconnect 1 ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Conf = tt.conf
+			desc := getMediaDescription(tt.file, true, tt.params, audio)
+			if got := desc.Microphone.Input; !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("audio command = %q, want it to start with %q", got, tt.wantPrefix)
+			}
+			if got := desc.Camera.Input; !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("video command = %q, want it to start with %q", got, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package vc
+
+import (
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// recordHistory adds the track that just finished in a chat to its play history, read by /exporthistory.
+func (c *TelegramCalls) recordHistory(chatID int64) {
+	track := cache.ChatCache.GetPlayingTrack(chatID)
+	if track == nil {
+		return
+	}
+
+	entry := db.HistoryEntry{
+		ChatID:      chatID,
+		FinishedAt:  time.Now().UTC(),
+		Name:        track.Name,
+		URL:         track.URL,
+		Platform:    track.Platform,
+		Duration:    track.Duration,
+		Requester:   track.User,
+		RequesterID: track.UserID,
+	}
+	go func() {
+		ctx, cancel := db.Ctx()
+		defer cancel()
+		if err := db.Instance.AddHistory(ctx, entry); err != nil {
+			gologging.WarnF("[History] Failed to record the track that finished in chat %d: %v", chatID, err)
+		}
+	}()
+}
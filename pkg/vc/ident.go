@@ -0,0 +1,64 @@
+package vc
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/eventlog"
+	"os"
+
+	"github.com/Laky-64/gologging"
+)
+
+// playIdent plays the chat's station ident, if it has one and it is turned on, before next.
+// The ident is marked so that its end starts next instead of advancing the queue; it is not counted as a play.
+// It reports whether the ident started; if not, and there is no error, the caller plays next itself.
+// A failure to stream clears the chat like any other failed play, and is returned.
+func (c *TelegramCalls) playIdent(chatID int64, next *cache.CachedTrack) (bool, error) {
+	ctx, cancel := db.Ctx()
+	enabled := db.Instance.GetIdentEnabled(ctx, chatID)
+	path := db.Instance.GetIdent(ctx, chatID)
+	cancel()
+	if !enabled || path == "" {
+		return false, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		gologging.WarnF("[Ident] The ident of chat %d is missing: %v", chatID, err)
+		return false, nil
+	}
+
+	c.mu.Lock()
+	c.identPending[chatID] = next
+	c.mu.Unlock()
+
+	if err := c.startStream(chatID, path, false, ""); err != nil {
+		c.clearIdent(chatID)
+		return false, fmt.Errorf("failed to play the ident: %w", err)
+	}
+	eventlog.Emit(chatID, "ident", next.TrackID, "")
+	return true, nil
+}
+
+// finishIdent is called when a stream ends. If the stream was a station ident, it plays the track that waited for it
+// and reports true, so the queue does not advance.
+func (c *TelegramCalls) finishIdent(chatID int64) bool {
+	c.mu.Lock()
+	next, ok := c.identPending[chatID]
+	delete(c.identPending, chatID)
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	if err := c.playSong(chatID, next); err != nil {
+		gologging.WarnF("[Ident] Failed to play the track after the ident in chat %d: %v", chatID, err)
+	}
+	return true
+}
+
+// clearIdent forgets an ident playing in a chat, so that its end is treated as the end of a normal track.
+func (c *TelegramCalls) clearIdent(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.identPending, chatID)
+}
@@ -0,0 +1,85 @@
+package vc
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/core/dl"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc/ubot"
+	"path/filepath"
+	"regexp"
+
+	"github.com/Laky-64/gologging"
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+var tgMessageLinkRegex = regexp.MustCompile(`^https?://t\.me/\w+/\d+$`)
+
+// handleIncomingCall reacts to a private call made to an assistant according to the configured IncomingCallMode.
+// "off" ignores the call, "message" replies with a text, and "play" also answers it by streaming IncomingCallMedia.
+func (c *TelegramCalls) handleIncomingCall(ub *ubot.Context, chatID int64) {
+	mode := config.Conf.IncomingCallMode
+	if mode == "off" {
+		return
+	}
+
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	_, _ = ub.App.SendMessage(chatID, lang.GetString(langCode, "incoming_call"))
+
+	if mode == "play" {
+		filePath, err := c.resolveIncomingCallMedia()
+		if err != nil {
+			gologging.InfoF("[OnIncomingCall] Failed to resolve the media: %v", err)
+			return
+		}
+
+		// PlayMedia only serves group voice chats, so the call is answered by the assistant that received it.
+		_, _ = ub.App.ResolvePeer(chatID)
+		if err := ub.Play(chatID, getMediaDescription(filePath, false, "", c.currentAudioParams(ub, chatID))); err != nil {
+			gologging.InfoF("[OnIncomingCall] Failed to play the media: %v", err)
+			return
+		}
+	}
+
+	c.logIncomingCall(ub, chatID, mode)
+}
+
+// resolveIncomingCallMedia returns a local path for the configured incoming-call media,
+// downloading it first when it is a t.me message link.
+func (c *TelegramCalls) resolveIncomingCallMedia() (string, error) {
+	media := config.Conf.IncomingCallMedia
+	if !tgMessageLinkRegex.MatchString(media) {
+		return media, nil
+	}
+
+	msg, err := dl.GetMessage(c.bot, media)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the message: %w", err)
+	}
+
+	filePath := filepath.Join(config.Conf.DownloadsDir, msg.File.Name)
+	if _, err := msg.Download(&tg.DownloadOptions{FileName: filePath}); err != nil {
+		return "", fmt.Errorf("failed to download the message: %w", err)
+	}
+	return filePath, nil
+}
+
+// logIncomingCall reports a handled incoming call to the logger chat, naming the assistant that received it.
+func (c *TelegramCalls) logIncomingCall(ub *ubot.Context, chatID int64, mode string) {
+	me := ub.App.Me()
+	text := fmt.Sprintf(
+		"<b>📞 Incoming call</b>\n\n‣ <b>Assistant:</b> %s (<code>%d</code>)\n‣ <b>Caller:</b> <code>%d</code>\n‣ <b>Mode:</b> %s",
+		me.FirstName,
+		me.ID,
+		chatID,
+		mode,
+	)
+
+	_, err := c.bot.SendMessage(config.Conf.LoggerId, text)
+	if err != nil {
+		gologging.WarnF("[logIncomingCall] Failed to send the message: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package vc
+
+import (
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"maps"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+// joinFlight is a check-and-join of the assistant to a chat that is in progress.
+type joinFlight struct {
+	done chan struct{}
+	err  error
+}
+
+// JoinStat sums up how long an assistant took to join chats it was not a member of.
+type JoinStat struct {
+	Count int
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Average returns the mean join time, or 0 if the assistant has not joined any chat.
+func (s JoinStat) Average() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// ensureJoined makes sure the assistant ubID is a member of a chat, like joinAssistant.
+// Joining can take several seconds for an assistant on a distant DC, so callers that arrive while a join of the
+// same chat is in progress wait for it and share its result instead of each starting another one.
+func (c *TelegramCalls) ensureJoined(chatID, ubID int64) error {
+	return c.coalesceJoin(chatID, func() error { return c.joinAssistant(chatID, ubID) })
+}
+
+// joinFunc joins an assistant to a chat, like TelegramCalls.ensureJoined.
+type joinFunc func(chatID, ubID int64) error
+
+// joinOnce makes sure the assistant ubID is in a chat before streaming there. Membership is checked only once per
+// session: a successful join is recorded on the chat's queue until InvalidateJoin or ClearChat drops it, or another
+// assistant takes over. It reports whether the assistant was already recorded as joined.
+func joinOnce(chatID, ubID int64, join joinFunc) (bool, error) {
+	if cache.ChatCache.GetJoinedAs(chatID) == ubID {
+		return true, nil
+	}
+	if err := join(chatID, ubID); err != nil {
+		return false, err
+	}
+	cache.ChatCache.SetJoinedAs(chatID, ubID)
+	return false, nil
+}
+
+// playJoined streams to a chat with play. If the assistant was recorded as joined but the stream fails because it is
+// no longer a member, the record is dropped, and the stream is retried once after a fresh join.
+func (c *TelegramCalls) playJoined(chatID, ubID int64, joined bool, join joinFunc, play func() error) error {
+	err := play()
+	if err == nil || !joined || !isNotMemberError(err) {
+		return err
+	}
+
+	gologging.InfoF("The assistant is no longer in chat %d (%v); joining again", chatID, err)
+	c.InvalidateJoin(chatID)
+	if _, err = joinOnce(chatID, ubID, join); err != nil {
+		return err
+	}
+	return play()
+}
+
+// coalesceJoin runs join unless a join of the chat is already in flight, in which case it waits for that one.
+func (c *TelegramCalls) coalesceJoin(chatID int64, join func() error) error {
+	c.mu.Lock()
+	if flight, ok := c.joins[chatID]; ok {
+		c.mu.Unlock()
+		c.notifyJoining(chatID)
+		<-flight.done
+		return flight.err
+	}
+	flight := &joinFlight{done: make(chan struct{})}
+	c.joins[chatID] = flight
+	c.mu.Unlock()
+
+	flight.err = join()
+
+	c.mu.Lock()
+	if c.joins[chatID] == flight {
+		delete(c.joins, chatID)
+	}
+	c.mu.Unlock()
+	close(flight.done)
+	return flight.err
+}
+
+// OnJoining registers a function called when the assistant has to join a chat before it can play there, so that the
+// play flow can tell users why the track is not starting yet. It returns a function that unregisters it.
+func (c *TelegramCalls) OnJoining(chatID int64, notify func()) func() {
+	c.mu.Lock()
+	c.joinNotify[chatID] = notify
+	c.mu.Unlock()
+
+	return func() {
+		c.mu.Lock()
+		delete(c.joinNotify, chatID)
+		c.mu.Unlock()
+	}
+}
+
+// notifyJoining calls the function registered with OnJoining for a chat, if any.
+func (c *TelegramCalls) notifyJoining(chatID int64) {
+	c.mu.RLock()
+	notify := c.joinNotify[chatID]
+	c.mu.RUnlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// recordJoin adds the time an assistant took to join a chat to its join statistics.
+func (c *TelegramCalls) recordJoin(chatID int64, took time.Duration) {
+	name, err := c.getClientName(chatID)
+	if err != nil {
+		return
+	}
+	gologging.InfoF("[TelegramCalls - joinUb] Assistant %s took %s to join chat %d", name, took.Round(time.Millisecond), chatID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stat := c.joinStats[name]
+	stat.Count++
+	stat.Total += took
+	stat.Max = max(stat.Max, took)
+	c.joinStats[name] = stat
+}
+
+// JoinStats returns the join statistics of each assistant that has joined a chat since the bot started.
+func (c *TelegramCalls) JoinStats() map[string]JoinStat {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.joinStats)
+}
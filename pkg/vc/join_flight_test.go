@@ -0,0 +1,196 @@
+package vc
+
+import (
+	"errors"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeJoiner counts joins, failing them while err is set.
+type fakeJoiner struct {
+	joins int
+	err   error
+}
+
+func (j *fakeJoiner) join(int64, int64) error {
+	j.joins++
+	return j.err
+}
+
+// newJoinTestChat queues a track in a chat, so that it has a session to record the join on.
+func newJoinTestChat(t *testing.T, chatID int64) {
+	t.Helper()
+	cache.ChatCache.AddSong(chatID, &cache.CachedTrack{TrackID: "a"})
+	t.Cleanup(func() { cache.ChatCache.ClearChat(chatID, false) })
+}
+
+func TestJoinOnce(t *testing.T) {
+	const chatID, ub, otherUb = -4001, 111, 222
+	newJoinTestChat(t, chatID)
+	j := &fakeJoiner{}
+
+	for track := 1; track <= 3; track++ {
+		joined, err := joinOnce(chatID, ub, j.join)
+		if err != nil || joined != (track > 1) {
+			t.Errorf("track %d: joinOnce() = %t, %v; want %t, nil", track, joined, err, track > 1)
+		}
+	}
+	if j.joins != 1 {
+		t.Errorf("%d joins for three tracks, want 1", j.joins)
+	}
+
+	// Another assistant taking over the chat joins for itself.
+	if joined, _ := joinOnce(chatID, otherUb, j.join); joined || j.joins != 2 {
+		t.Errorf("joinOnce() for another assistant = %t after %d joins, want false after 2", joined, j.joins)
+	}
+
+	Calls.InvalidateJoin(chatID)
+	if cache.ChatCache.GetJoinedAs(chatID) != 0 {
+		t.Error("InvalidateJoin() kept the record")
+	}
+	if joined, _ := joinOnce(chatID, otherUb, j.join); joined || j.joins != 3 {
+		t.Errorf("joinOnce() after InvalidateJoin = %t after %d joins, want false after 3", joined, j.joins)
+	}
+}
+
+func TestJoinOnceFailure(t *testing.T) {
+	const chatID, ub = -4002, 111
+	newJoinTestChat(t, chatID)
+	j := &fakeJoiner{err: errors.New("INVITE_HASH_EXPIRED")}
+
+	if _, err := joinOnce(chatID, ub, j.join); err == nil {
+		t.Fatal("joinOnce() with a failing join = nil error")
+	}
+	if cache.ChatCache.GetJoinedAs(chatID) != 0 {
+		t.Error("a failed join was recorded")
+	}
+
+	j.err = nil
+	if joined, err := joinOnce(chatID, ub, j.join); joined || err != nil || j.joins != 2 {
+		t.Errorf("joinOnce() after a failure = %t, %v after %d joins; want a fresh join", joined, err, j.joins)
+	}
+}
+
+func TestJoinRecordClearedWithChat(t *testing.T) {
+	const chatID, ub = -4003, 111
+	newJoinTestChat(t, chatID)
+	_, _ = joinOnce(chatID, ub, (&fakeJoiner{}).join)
+
+	cache.ChatCache.ClearChat(chatID, false)
+	cache.ChatCache.AddSong(chatID, &cache.CachedTrack{TrackID: "b"})
+	if got := cache.ChatCache.GetJoinedAs(chatID); got != 0 {
+		t.Errorf("a new session starts with the assistant recorded as %d", got)
+	}
+}
+
+func TestPlayJoined(t *testing.T) {
+	notMember := errors.New("rpc error code 400: USER_NOT_PARTICIPANT")
+	tests := []struct {
+		name       string
+		joined     bool
+		playErrs   []error // playErrs are the results of the successive plays; missing ones succeed.
+		joinErr    error
+		wantErr    bool
+		wantPlays  int
+		wantJoins  int
+		wantRecord bool
+	}{
+		{"plays", true, nil, nil, false, 1, 0, true},
+		{"assistant left", true, []error{notMember}, nil, false, 2, 1, true},
+		{"assistant left and cannot rejoin", true, []error{notMember}, errors.New("USER_BANNED_IN_CHANNEL"), true, 1, 1, false},
+		{"still not a member after rejoining", true, []error{notMember, notMember}, nil, true, 2, 1, true},
+		{"just joined", false, []error{notMember}, nil, true, 1, 0, true},
+		{"other error", true, []error{errors.New("GROUPCALL_INVALID")}, nil, true, 1, 0, true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatID := int64(-4100 - i)
+			const ub = 111
+			newJoinTestChat(t, chatID)
+			cache.ChatCache.SetJoinedAs(chatID, ub)
+
+			j := &fakeJoiner{err: tt.joinErr}
+			plays := 0
+			play := func() error {
+				plays++
+				if plays <= len(tt.playErrs) {
+					return tt.playErrs[plays-1]
+				}
+				return nil
+			}
+
+			err := Calls.playJoined(chatID, ub, tt.joined, j.join, play)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("playJoined() = %v, want error %t", err, tt.wantErr)
+			}
+			if plays != tt.wantPlays || j.joins != tt.wantJoins {
+				t.Errorf("%d plays and %d joins, want %d and %d", plays, j.joins, tt.wantPlays, tt.wantJoins)
+			}
+			if recorded := cache.ChatCache.GetJoinedAs(chatID) == ub; recorded != tt.wantRecord {
+				t.Errorf("assistant recorded as joined = %t, want %t", recorded, tt.wantRecord)
+			}
+		})
+	}
+}
+
+func TestCoalesceJoin(t *testing.T) {
+	const chatID, callers = -4401, 8
+	joinErr := errors.New("FLOOD_WAIT_5")
+
+	// Every caller but the one joining tells the play flow it is waiting; once all have, the slow join finishes.
+	var waiting atomic.Int32
+	allWaiting := make(chan struct{})
+	defer Calls.OnJoining(chatID, func() {
+		if waiting.Add(1) == callers-1 {
+			close(allWaiting)
+		}
+	})()
+
+	var joins atomic.Int32
+	slowJoin := func() error {
+		joins.Add(1)
+		select {
+		case <-allWaiting:
+		case <-time.After(5 * time.Second):
+			t.Error("the other callers did not wait for the join in progress")
+		}
+		return joinErr
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = Calls.coalesceJoin(chatID, slowJoin)
+		}()
+	}
+	wg.Wait()
+
+	if n := joins.Load(); n != 1 {
+		t.Errorf("%d joins for %d concurrent callers, want 1", n, callers)
+	}
+	for i, err := range errs {
+		if !errors.Is(err, joinErr) {
+			t.Errorf("caller %d got %v, want the shared join's error", i, err)
+		}
+	}
+
+	// Once the join is over, the next caller joins again.
+	if err := Calls.coalesceJoin(chatID, func() error { joins.Add(1); return nil }); err != nil || joins.Load() != 2 {
+		t.Errorf("coalesceJoin() after the flight = %v with %d joins, want a new join", err, joins.Load())
+	}
+}
+
+func TestJoinStatAverage(t *testing.T) {
+	if got := (JoinStat{}).Average(); got != 0 {
+		t.Errorf("Average() without joins = %s, want 0", got)
+	}
+	if got := (JoinStat{Count: 4, Total: 10 * time.Second}).Average(); got != 2500*time.Millisecond {
+		t.Errorf("Average() = %s, want 2.5s", got)
+	}
+}
@@ -3,6 +3,7 @@ package vc
 import (
 	"fmt"
 	"github.com/zuchzub/Go/pkg/config"
+"github.com/zuchzub/Go/pkg/core"
 "github.com/zuchzub/Go/pkg/core/cache"
 
 "github.com/Laky-64/gologging"
@@ -17,10 +18,9 @@ func sendLogger(client *tg.Client, chatID int64, song *cache.CachedTrack) {
 	}
 
 	text := fmt.Sprintf(
-		"<b>A song is playing</b> in <code>%d</code>\n\n‣ <b>Title:</b> <a href='%s'>%s</a>\n‣ <b>Duration:</b> %s\n‣ <b>Requested by:</b> %s\n‣ <b>Platform:</b> %s\n‣ <b>Is Video:</b> %t",
+		"<b>A song is playing</b> in <code>%d</code>\n\n‣ <b>Title:</b> %s\n‣ <b>Duration:</b> %s\n‣ <b>Requested by:</b> %s\n‣ <b>Platform:</b> %s\n‣ <b>Is Video:</b> %t",
 		chatID,
-		song.URL,
-		song.Name,
+		core.FormatTrackLine(song, core.TrackLineCompact),
 		cache.SecToMin(song.Duration),
 		song.User,
 		song.Platform,
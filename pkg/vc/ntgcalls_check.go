@@ -0,0 +1,40 @@
+package vc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/vc/ntgcalls"
+	"strconv"
+	"strings"
+)
+
+// minNtgCallsMajor is the oldest major version of the ntgcalls library that the bindings in pkg/vc/ntgcalls support.
+const minNtgCallsMajor = 2
+
+// ErrNtgCalls is returned by CheckNtgCalls when the ntgcalls library cannot be used.
+var ErrNtgCalls = errors.New("the ntgcalls library is missing or incompatible; run `go generate` (setup_ntgcalls.go) to fetch a matching build and rebuild the bot")
+
+// CheckNtgCalls verifies that the linked ntgcalls library answers and is a version the bindings support.
+// It returns the library version, or an error wrapping ErrNtgCalls that says how to fix the setup.
+func CheckNtgCalls() (version string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: loading it failed: %v", ErrNtgCalls, r)
+		}
+	}()
+
+	version = strings.TrimPrefix(strings.TrimSpace(ntgcalls.Version()), "v")
+	if version == "" {
+		return "", fmt.Errorf("%w: it did not report a version", ErrNtgCalls)
+	}
+
+	majorText, _, _ := strings.Cut(version, ".")
+	major, convErr := strconv.Atoi(majorText)
+	if convErr != nil {
+		return version, fmt.Errorf("%w: unrecognized version %q", ErrNtgCalls, version)
+	}
+	if major < minNtgCallsMajor {
+		return version, fmt.Errorf("%w: version %s is older than the supported %d.x", ErrNtgCalls, version, minNtgCallsMajor)
+	}
+	return version, nil
+}
@@ -0,0 +1,109 @@
+package vc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var errBroken = errors.New("broken track")
+
+// fakePlayer plays every track but those in broken, which fail the way a failed download does.
+type fakePlayer struct {
+	c      *TelegramCalls
+	broken map[string]bool
+	played []string
+	ended  int
+}
+
+func (p *fakePlayer) playSong(chatID int64, song *cache.CachedTrack) error {
+	p.played = append(p.played, song.TrackID)
+	if p.broken[song.TrackID] {
+		return p.c.songFailed(chatID, nil, errBroken)
+	}
+	return nil
+}
+
+func (p *fakePlayer) handleNoSong(int64) error {
+	p.ended++
+	return nil
+}
+
+// useTestDatabase replaces db.Instance with one whose chats are all cached, so that nothing reaches MongoDB.
+// The chats have neither an ident nor autoplay.
+func useTestDatabase(t *testing.T, chatIDs ...int64) {
+	t.Helper()
+	saved := db.Instance
+	db.Instance = &db.Database{
+		ChatCache: cache.NewCache[map[string]interface{}](time.Hour),
+		BotCache:  cache.NewCache[map[string]interface{}](time.Hour),
+		UserCache: cache.NewCache[map[string]interface{}](time.Hour),
+	}
+	for _, chatID := range chatIDs {
+		db.Instance.ChatCache.Set(fmt.Sprintf("%d", chatID), map[string]interface{}{"language": "en"})
+	}
+	t.Cleanup(func() { db.Instance = saved })
+}
+
+func TestPlayNext(t *testing.T) {
+	tests := []struct {
+		name        string
+		loop        int
+		queue       []string
+		broken      []string
+		wantPlayed  []string
+		wantEnded   int
+		wantLoop    int
+		wantPlaying string
+	}{
+		{"loop replays the current track", 2, []string{"a", "b"}, nil, []string{"a"}, 0, 1, "a"},
+		{"loop of a broken track moves on", 2, []string{"a", "b"}, []string{"a"}, []string{"a", "b"}, 0, 0, "b"},
+		{"loop of the only, broken track ends the queue", 1, []string{"a"}, []string{"a"}, []string{"a"}, 1, 0, ""},
+		{"no loop plays the next track", 0, []string{"a", "b", "c"}, nil, []string{"b"}, 0, 0, "b"},
+		{"no loop skips a broken next track", 0, []string{"a", "b", "c"}, []string{"b"}, []string{"b", "c"}, 0, 0, "c"},
+		{"no loop on the last track ends the queue", 0, []string{"a"}, nil, nil, 1, 0, ""},
+		{"every track broken ends the queue", 1, []string{"a", "b"}, []string{"a", "b"}, []string{"a", "b"}, 1, 0, ""},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatID := int64(-3000 - i)
+			useTestDatabase(t, chatID)
+			defer cache.ChatCache.ClearChat(chatID, false)
+			for _, id := range tt.queue {
+				cache.ChatCache.AddSong(chatID, &cache.CachedTrack{TrackID: id})
+			}
+			cache.ChatCache.SetLoopCount(chatID, tt.loop)
+
+			p := &fakePlayer{c: Calls, broken: map[string]bool{}}
+			for _, id := range tt.broken {
+				p.broken[id] = true
+			}
+			Calls.player = p
+			defer func() { Calls.player = Calls }()
+
+			if err := Calls.PlayNext(chatID); err != nil {
+				t.Fatalf("PlayNext() error = %v", err)
+			}
+			if !reflect.DeepEqual(p.played, tt.wantPlayed) {
+				t.Errorf("played %v, want %v", p.played, tt.wantPlayed)
+			}
+			if p.ended != tt.wantEnded {
+				t.Errorf("ended the queue %d times, want %d", p.ended, tt.wantEnded)
+			}
+			if got := cache.ChatCache.GetLoopCount(chatID); got != tt.wantLoop {
+				t.Errorf("loop count = %d, want %d", got, tt.wantLoop)
+			}
+			playing := ""
+			if track := cache.ChatCache.GetPlayingTrack(chatID); track != nil {
+				playing = track.TrackID
+			}
+			if playing != tt.wantPlaying {
+				t.Errorf("playing %q, want %q", playing, tt.wantPlaying)
+			}
+		})
+	}
+}
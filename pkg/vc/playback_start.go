@@ -0,0 +1,51 @@
+package vc
+
+import (
+	"github.com/zuchzub/Go/pkg/config"
+	"time"
+)
+
+// armFirstFrame starts waiting for the first frame of a new stream in a chat, replacing any earlier wait.
+func (c *TelegramCalls) armFirstFrame(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if started, ok := c.firstFrame[chatID]; ok {
+		close(started)
+	}
+	c.firstFrame[chatID] = make(chan struct{})
+}
+
+// markFirstFrame is called for every batch of frames and releases anyone waiting for the chat's stream to start.
+func (c *TelegramCalls) markFirstFrame(chatID int64) {
+	c.mu.RLock()
+	_, ok := c.firstFrame[chatID]
+	c.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if started, ok := c.firstFrame[chatID]; ok {
+		close(started)
+		delete(c.firstFrame, chatID)
+	}
+}
+
+// WaitForPlayback blocks until the stream started by the last PlayMedia call in a chat delivers its first frame,
+// so that "now playing" is only shown once audio flows. It gives up after PlaybackStartTimeout; a timeout of 0
+// returns at once.
+func (c *TelegramCalls) WaitForPlayback(chatID int64) {
+	timeout := config.Conf.PlaybackStartTimeout
+	c.mu.RLock()
+	started, ok := c.firstFrame[chatID]
+	c.mu.RUnlock()
+	if !ok || timeout <= 0 {
+		return
+	}
+
+	select {
+	case <-started:
+	case <-time.After(timeout):
+	}
+}
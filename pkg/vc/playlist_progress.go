@@ -0,0 +1,26 @@
+package vc
+
+import (
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+
+	"github.com/Laky-64/gologging"
+)
+
+// recordPlaylistProgress saves the position in its playlist of the track that just finished in a chat, if it was
+// queued from one, so that /continue can resume the playlist after it.
+func (c *TelegramCalls) recordPlaylistProgress(chatID int64) {
+	track := cache.ChatCache.GetPlayingTrack(chatID)
+	if track == nil || track.SourceURL == "" {
+		return
+	}
+
+	url, index := track.SourceURL, track.SourceIndex
+	go func() {
+		ctx, cancel := db.Ctx()
+		defer cancel()
+		if err := db.Instance.SavePlaylistProgress(ctx, chatID, url, index); err != nil {
+			gologging.WarnF("[PlaylistProgress] Failed to save the progress of chat %d: %v", chatID, err)
+		}
+	}()
+}
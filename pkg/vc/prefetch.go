@@ -0,0 +1,147 @@
+package vc
+
+import (
+	"context"
+	"errors"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"os"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+const (
+	// maxPrefetches is how many upcoming tracks are downloaded ahead of time at once, across all chats.
+	maxPrefetches = 3
+	// prefetchTimeout is how long the download of an upcoming track may take.
+	prefetchTimeout = 3 * time.Minute
+	// prefetchCheckInterval is how often a prefetch checks that its track is still queued.
+	prefetchCheckInterval = 5 * time.Second
+	// maxPrefetchFailures is how many failed downloads of a track stop it from being prefetched. The last attempt is
+	// left to playback, so that the chat is told the real error.
+	maxPrefetchFailures = 2
+)
+
+// prefetchSlots limits the prefetches that download at once to maxPrefetches.
+var prefetchSlots = make(chan struct{}, maxPrefetches)
+
+var (
+	// errTrackDequeued cancels the prefetch of a track that was removed from its queue.
+	errTrackDequeued = errors.New("the track was removed from the queue")
+	// errTrackPlaying cancels the prefetch of a track that is to play before a slot was free to download it.
+	errTrackPlaying = errors.New("the track is about to play")
+)
+
+// prefetchFlight is the download of an upcoming track that is in progress.
+type prefetchFlight struct {
+	chatID  int64
+	done    chan struct{}
+	cancel  context.CancelCauseFunc
+	started atomic.Bool // started is set once the download has a slot and has begun.
+}
+
+// PrefetchNext starts downloading the upcoming track of a chat in the background, so that it can start as soon as the
+// current one ends. It does nothing if there is no upcoming track, if it is already downloaded or being downloaded,
+// if it is a live stream, or if it already failed to download maxPrefetchFailures times.
+// The prefetch waits while maxPrefetches others are downloading, and is cancelled if the track leaves the queue.
+func (c *TelegramCalls) PrefetchNext(chatID int64) {
+	song := cache.ChatCache.GetUpcomingTrack(chatID)
+	if song == nil {
+		return
+	}
+	state := cache.ChatCache.TrackState(song)
+	if state.IsLive || state.FailCount >= maxPrefetchFailures {
+		return
+	}
+	if state.FilePath != "" {
+		if _, err := os.Stat(state.FilePath); err == nil || urlRegex.MatchString(state.FilePath) {
+			return
+		}
+	}
+
+	c.mu.Lock()
+	if _, ok := c.prefetches[song]; ok {
+		c.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	flight := &prefetchFlight{chatID: chatID, done: make(chan struct{}), cancel: cancel}
+	c.prefetches[song] = flight
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.prefetches, song)
+			c.mu.Unlock()
+			cancel(nil)
+			close(flight.done)
+		}()
+		go watchQueued(ctx, cancel, chatID, song)
+		c.prefetch(ctx, chatID, song, flight)
+	}()
+}
+
+// prefetch downloads an upcoming track once a slot is free, and records the download on the track if it is still
+// queued. A failed prefetch is recorded on the track, so that /queue shows it; the track is downloaded again when it
+// is played, and fails there if it must. A prefetch that was cancelled is not a failure.
+func (c *TelegramCalls) prefetch(ctx context.Context, chatID int64, song *cache.CachedTrack, flight *prefetchFlight) {
+	select {
+	case prefetchSlots <- struct{}{}:
+		defer func() { <-prefetchSlots }()
+	case <-ctx.Done():
+		return
+	}
+	flight.started.Store(true)
+
+	ctx, cancel := context.WithTimeout(ctx, prefetchTimeout)
+	defer cancel()
+	dlPath, trackInfo, err := DownloadSong(ctx, chatID, song, c.bot)
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			err = cause
+		}
+		gologging.InfoF("[Prefetch] Failed to prefetch %s in chat %d: %v", song.Name, chatID, err)
+		if !errors.Is(err, errTrackDequeued) && !errors.Is(err, errTrackPlaying) && !errors.Is(err, context.Canceled) {
+			cache.ChatCache.RecordFailure(chatID, song, err)
+		}
+		return
+	}
+	if dlPath != "" {
+		cache.ChatCache.SetDownload(chatID, song, dlPath, trackInfo)
+	}
+}
+
+// watchQueued cancels a prefetch when its track is no longer in the chat's queue, until ctx is done.
+func watchQueued(ctx context.Context, cancel context.CancelCauseFunc, chatID int64, song *cache.CachedTrack) {
+	ticker := time.NewTicker(prefetchCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !slices.Contains(cache.ChatCache.GetQueue(chatID), song) {
+				cancel(errTrackDequeued)
+				return
+			}
+		}
+	}
+}
+
+// waitForPrefetch waits for a prefetch of the song that is downloading to end, if there is one.
+// A prefetch still waiting for a slot is cancelled instead, as the song is to be downloaded for playback right away.
+func (c *TelegramCalls) waitForPrefetch(song *cache.CachedTrack) {
+	c.mu.RLock()
+	flight, ok := c.prefetches[song]
+	c.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if !flight.started.Load() {
+		flight.cancel(errTrackPlaying)
+	}
+	<-flight.done
+}
@@ -0,0 +1,33 @@
+package vc
+
+import (
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"testing"
+)
+
+func TestPrefetchNextSkips(t *testing.T) {
+	tests := []struct {
+		name  string
+		track *cache.CachedTrack
+	}{
+		{"failed twice", &cache.CachedTrack{TrackID: "failed", FailCount: maxPrefetchFailures, LastError: "boom"}},
+		{"live stream", &cache.CachedTrack{TrackID: "live", IsLive: true}},
+		{"already a stream URL", &cache.CachedTrack{TrackID: "url", FilePath: "https://example.com/a.mp3"}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatID := int64(-1000 - i)
+			defer cache.ChatCache.ClearChat(chatID, false)
+			cache.ChatCache.AddSong(chatID, &cache.CachedTrack{TrackID: "current"})
+			cache.ChatCache.AddSong(chatID, tt.track)
+
+			Calls.PrefetchNext(chatID)
+			Calls.mu.RLock()
+			_, started := Calls.prefetches[tt.track]
+			Calls.mu.RUnlock()
+			if started {
+				t.Error("PrefetchNext() started a prefetch")
+			}
+		})
+	}
+}
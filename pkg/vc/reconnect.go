@@ -0,0 +1,87 @@
+package vc
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+	"github.com/zuchzub/Go/pkg/vc/ntgcalls"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+const reconnectBackoff = 2 * time.Second
+
+// handleConnectionDrop is called when the call connection of a chat is closed, fails, or times out.
+// If the chat still has an active session, it tries to rejoin and resume the current track from where it stopped,
+// giving up after the configured number of attempts.
+func (c *TelegramCalls) handleConnectionDrop(chatID int64, state ntgcalls.ConnectionState) {
+	if !config.Conf.AutoReconnect || !cache.ChatCache.IsActive(chatID) || c.awaitingAssistant(chatID) {
+		return
+	}
+
+	c.mu.Lock()
+	if _, busy := c.reconnecting[chatID]; busy {
+		c.mu.Unlock()
+		return
+	}
+	c.reconnecting[chatID] = struct{}{}
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.reconnecting, chatID)
+		c.mu.Unlock()
+	}()
+
+	played, _ := c.PlayedTime(chatID)
+	gologging.WarnF("[Reconnect] The call connection dropped in chat %d (state=%v) at %ds", chatID, state, played)
+
+	var lastErr error
+	for attempt := 1; attempt <= config.Conf.ReconnectRetries; attempt++ {
+		time.Sleep(time.Duration(attempt) * reconnectBackoff)
+
+		track := cache.ChatCache.GetPlayingTrack(chatID)
+		if !cache.ChatCache.IsActive(chatID) || track == nil || c.awaitingAssistant(chatID) {
+			return
+		}
+
+		if lastErr = c.rejoin(chatID, track, int(played)); lastErr == nil {
+			gologging.InfoF("[Reconnect] Rejoined chat %d on attempt %d", chatID, attempt)
+			return
+		}
+		gologging.WarnF("[Reconnect] Attempt %d/%d for chat %d failed: %v", attempt, config.Conf.ReconnectRetries, chatID, lastErr)
+	}
+
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	langCode := db.Instance.GetLang(ctx, chatID)
+	_, _ = c.bot.SendMessage(chatID, fmt.Sprintf(lang.GetString(langCode, "reconnect_failed"), lastErr))
+	_ = c.Stop(chatID)
+}
+
+// rejoin drops the stale call state for a chat and starts the track again at the given offset.
+// Unlike PlayMedia, a failure leaves the queue untouched so another attempt can be made.
+func (c *TelegramCalls) rejoin(chatID int64, track *cache.CachedTrack, offset int) error {
+	call, err := c.GetGroupAssistant(chatID)
+	if err != nil {
+		return err
+	}
+
+	_ = call.StopBinding(chatID)
+	if chatID < 0 {
+		if err := c.ensureJoined(chatID, call.App.Me().ID); err != nil {
+			return err
+		}
+	}
+
+	var ffmpegParams string
+	if !track.IsLive && offset > 0 && offset < track.Duration {
+		ffmpegParams = seekParameters(track.FilePath, offset, track.Duration)
+	}
+
+	c.startGeneration(chatID)
+	return call.Play(chatID, getMediaDescription(track.FilePath, track.IsVideo, ffmpegParams, c.currentAudioParams(call, chatID)))
+}
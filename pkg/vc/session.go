@@ -0,0 +1,138 @@
+package vc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"os"
+	"time"
+
+	"github.com/Laky-64/gologging"
+)
+
+var (
+	// ErrSessionActive is returned by ResumeSession when the chat is already playing.
+	ErrSessionActive = errors.New("a session is already active in this chat")
+	// ErrNoSession is returned by ResumeSession when neither a session nor a queue was saved for the chat.
+	ErrNoSession = errors.New("no saved session for this chat")
+)
+
+// SessionReport describes what ResumeSession restored.
+type SessionReport struct {
+	Tracks     int // Tracks is the number of tracks put back in the queue.
+	Redownload int // Redownload is the number of tracks whose downloaded file no longer existed.
+	Position   int // Position is the offset in seconds the first track resumed from.
+}
+
+// SaveSessions snapshots the queue and playback position of every active chat so they can be resumed after a restart.
+func (c *TelegramCalls) SaveSessions() {
+	for _, chatID := range cache.ChatCache.GetActiveChats() {
+		queue := cache.ChatCache.GetQueue(chatID)
+		if len(queue) == 0 {
+			continue
+		}
+
+		position := 0
+		if !queue[0].IsLive {
+			played, _ := c.PlayedTime(chatID)
+			position = int(played)
+		}
+
+		ctx, cancel := db.Ctx()
+		err := db.Instance.SaveSession(ctx, chatID, db.Session{Queue: queue, Position: position, SavedAt: time.Now()})
+		cancel()
+		if err != nil {
+			gologging.WarnF("[SaveSessions] Failed to save the session of chat %d: %v", chatID, err)
+		}
+	}
+}
+
+// ResumeSession restores the queue saved for a chat and resumes playback of the first track from the saved position.
+// Tracks whose downloaded file is gone are downloaded again; the first one immediately, the rest when they are reached.
+// If no session was saved, as after a crash, the queue last persisted for the chat is used instead, from the start of
+// its first track.
+func (c *TelegramCalls) ResumeSession(chatID int64) (*SessionReport, error) {
+	if cache.ChatCache.IsActive(chatID) {
+		return nil, ErrSessionActive
+	}
+	session, err := loadSession(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SessionReport{Tracks: len(session.Queue)}
+	for _, track := range session.Queue {
+		if track.FilePath == "" {
+			continue
+		}
+		if _, err := os.Stat(track.FilePath); err != nil {
+			track.FilePath = ""
+			report.Redownload++
+		}
+	}
+
+	// Claiming the chat before the download makes a concurrent /play queue its track behind the session instead of
+	// starting playback a second time.
+	first := session.Queue[0]
+	if !cache.ChatCache.StartIfIdle(chatID, first) {
+		return nil, ErrSessionActive
+	}
+	for _, track := range session.Queue[1:] {
+		cache.ChatCache.AddSong(chatID, track)
+	}
+
+	if first.FilePath == "" {
+		dlCtx, dlCancel := context.WithTimeout(context.Background(), 3*time.Minute)
+		defer dlCancel()
+		filePath, _, err := DownloadSong(dlCtx, chatID, first, c.bot)
+		if err != nil {
+			cache.ChatCache.ClearChat(chatID, false)
+			return nil, fmt.Errorf("failed to download %s: %w", first.Name, err)
+		}
+		first.FilePath = filePath
+	}
+
+	if !first.IsLive && session.Position > 0 && session.Position < first.Duration {
+		report.Position = session.Position
+		err = c.SeekStream(chatID, first.FilePath, session.Position, first.Duration, first.IsVideo)
+	} else {
+		err = c.PlayMedia(chatID, first.FilePath, first.IsVideo, "")
+	}
+	if err != nil {
+		cache.ChatCache.ClearChat(chatID, false)
+		return nil, err
+	}
+
+	// The download may have taken minutes, so the session is cleared with a context of its own.
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	if err := db.Instance.ClearSession(ctx, chatID); err != nil {
+		gologging.WarnF("[ResumeSession] Failed to clear the session of chat %d: %v", chatID, err)
+	}
+	return report, nil
+}
+
+// loadSession returns the session saved for a chat or, if there is none, its last persisted queue as a session starting
+// at the beginning of its first track. It returns ErrNoSession if neither was saved.
+func loadSession(chatID int64) (*db.Session, error) {
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	session, err := db.Instance.GetSession(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the session: %w", err)
+	}
+	if session != nil && len(session.Queue) > 0 {
+		return session, nil
+	}
+
+	queue, err := db.Instance.LoadQueue(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the queue: %w", err)
+	}
+	if len(queue) == 0 {
+		return nil, ErrNoSession
+	}
+	return &db.Session{Queue: queue}, nil
+}
@@ -0,0 +1,65 @@
+package vc
+
+import (
+	"github.com/Laky-64/gologging"
+)
+
+// streamGen is a chat's playback generation. It changes every time a stream starts, so that the end of a stream is
+// acted on once: ntgcalls sometimes reports the same end twice, and each report would otherwise advance the queue.
+type streamGen struct {
+	id       uint64
+	consumed bool // consumed is set once the end of generation id has been handled.
+}
+
+// startGeneration begins a new playback generation for a chat. It is called right before a stream starts.
+func (c *TelegramCalls) startGeneration(chatID int64) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	gen := c.streamGens[chatID]
+	gen.id++
+	gen.consumed = false
+	c.streamGens[chatID] = gen
+	return gen.id
+}
+
+// currentGeneration returns the playback generation of a chat.
+func (c *TelegramCalls) currentGeneration(chatID int64) uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.streamGens[chatID].id
+}
+
+// consumeGeneration marks the end of generation gen as handled.
+// It reports false, so the stream end must be ignored, if gen is not the chat's current generation any more or its
+// end was already handled, such as by an earlier report of the same end or by a skip.
+func (c *TelegramCalls) consumeGeneration(chatID int64, gen uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := c.streamGens[chatID]
+	if current.id != gen {
+		gologging.InfoF("[OnStreamEnd] Ignoring a stale stream end in chat %d (generation %d, current %d)", chatID, gen, current.id)
+		return false
+	}
+	if current.consumed {
+		gologging.InfoF("[OnStreamEnd] Ignoring a duplicate stream end in chat %d (generation %d was already handled)", chatID, gen)
+		return false
+	}
+	current.consumed = true
+	c.streamGens[chatID] = current
+	return true
+}
+
+// Skip ends the current track of a chat and plays the next one.
+// The stream being skipped still reports its end when it stops; that report is ignored instead of skipping again.
+func (c *TelegramCalls) Skip(chatID int64) error {
+	c.mu.Lock()
+	gen := c.streamGens[chatID]
+	gen.id++
+	gen.consumed = true
+	c.streamGens[chatID] = gen
+	c.mu.Unlock()
+
+	return c.PlayNext(chatID)
+}
@@ -0,0 +1,81 @@
+package vc
+
+import (
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStreamGenerations(t *testing.T) {
+	// Each step is "start" for a stream starting, "skip" for /skip, or "end N" for ntgcalls reporting the end of the
+	// Nth stream started. want lists whether each end was acted on.
+	tests := []struct {
+		name  string
+		steps []string
+		want  []bool
+	}{
+		{"end", []string{"start", "end 1"}, []bool{true}},
+		{"end reported twice", []string{"start", "end 1", "end 1"}, []bool{true, false}},
+		{"skip then end", []string{"start", "skip", "end 1"}, []bool{false}},
+		{"skip then the next stream ends", []string{"start", "skip", "start", "end 1", "end 2"}, []bool{false, true}},
+		{"end during the next start", []string{"start", "end 1", "start", "end 1", "end 2"}, []bool{true, false, true}},
+		{"end of the previous stream after the next started", []string{"start", "start", "end 1", "end 2"}, []bool{false, true}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatID := int64(-4200 - i)
+			useTestDatabase(t, chatID)
+			cache.ChatCache.AddSong(chatID, &cache.CachedTrack{TrackID: "a"})
+			cache.ChatCache.AddSong(chatID, &cache.CachedTrack{TrackID: "b"})
+			Calls.player = &fakePlayer{c: Calls}
+			t.Cleanup(func() {
+				Calls.player = Calls
+				cache.ChatCache.ClearChat(chatID, false)
+				Calls.mu.Lock()
+				delete(Calls.streamGens, chatID)
+				Calls.mu.Unlock()
+			})
+
+			// gens holds the generation of every stream started, so that "end N" reports the Nth one's.
+			gens := []uint64{0}
+			var got []bool
+			for _, step := range tt.steps {
+				switch {
+				case step == "start":
+					gens = append(gens, Calls.startGeneration(chatID))
+				case step == "skip":
+					if err := Calls.Skip(chatID); err != nil {
+						t.Fatalf("Skip() error = %v", err)
+					}
+				case strings.HasPrefix(step, "end "):
+					n, _ := strconv.Atoi(strings.TrimPrefix(step, "end "))
+					got = append(got, Calls.consumeGeneration(chatID, gens[n]))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ends acted on = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamGenerationsPerChat(t *testing.T) {
+	const chatID, otherID = -4301, -4302
+	t.Cleanup(func() {
+		Calls.mu.Lock()
+		delete(Calls.streamGens, chatID)
+		delete(Calls.streamGens, otherID)
+		Calls.mu.Unlock()
+	})
+
+	gen := Calls.startGeneration(chatID)
+	other := Calls.startGeneration(otherID)
+	if !Calls.consumeGeneration(otherID, other) {
+		t.Fatal("the end of the other chat's stream was ignored")
+	}
+	if Calls.currentGeneration(chatID) != gen || !Calls.consumeGeneration(chatID, gen) {
+		t.Error("the end of a stream in one chat consumed the generation of another")
+	}
+}
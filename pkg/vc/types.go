@@ -19,6 +19,27 @@ type TelegramCalls struct {
 	bot              *tg.Client
 	statusCache      *cache.Cache[string]
 	inviteCache      *cache.Cache[string]
+	reconnecting     map[int64]struct{}
+	audioParams      map[int64]AudioParams
+	draining         map[int64]struct{}
+	autoplayed       map[int64][]string
+	assistantLeft    map[int64]*pendingLeave
+	firstFrame       map[int64]chan struct{}
+	identPending     map[int64]*cache.CachedTrack
+	streamGens       map[int64]streamGen
+	videoChats       map[int64]struct{}
+	joins            map[int64]*joinFlight
+	joinNotify       map[int64]func()
+	joinStats        map[string]JoinStat
+	prefetches       map[*cache.CachedTrack]*prefetchFlight
+	player           player
+}
+
+// player starts the tracks PlayNext picks, and ends the queue when there is none left.
+// It is the TelegramCalls itself; tests replace it to follow PlayNext's decisions without a voice chat.
+type player interface {
+	playSong(chatID int64, song *cache.CachedTrack) error
+	handleNoSong(chatID int64) error
 }
 
 var (
@@ -35,10 +56,29 @@ func GetCalls() *TelegramCalls {
 			clientCounter: 1,
 			statusCache:   cache.NewCache[string](2 * time.Hour),
 			inviteCache:   cache.NewCache[string](2 * time.Hour),
+			reconnecting:  make(map[int64]struct{}),
+			audioParams:   make(map[int64]AudioParams),
+			autoplayed:    make(map[int64][]string),
+			assistantLeft: make(map[int64]*pendingLeave),
+			firstFrame:    make(map[int64]chan struct{}),
+			identPending:  make(map[int64]*cache.CachedTrack),
+			streamGens:    make(map[int64]streamGen),
+			videoChats:    make(map[int64]struct{}),
+			joins:         make(map[int64]*joinFlight),
+			joinNotify:    make(map[int64]func()),
+			joinStats:     make(map[string]JoinStat),
+			prefetches:    make(map[*cache.CachedTrack]*prefetchFlight),
 		}
+		instance.player = instance
 	})
 	return instance
 }
 
 // Calls is the singleton instance of TelegramCalls, initialized lazily.
 var Calls = GetCalls()
+
+// ResetStep records the outcome of a single cleanup step performed by ForceReset.
+type ResetStep struct {
+	Name string
+	Err  error
+}
@@ -10,6 +10,25 @@ func (ctx *Context) Calls() map[int64]*ntgcalls.CallInfo {
 	return ctx.binding.Calls()
 }
 
+// JoinedCalls returns how many group calls the assistant is currently joined to.
+// It is safe to call from any goroutine.
+func (ctx *Context) JoinedCalls() int {
+	ctx.joinedCallsMutex.RLock()
+	defer ctx.joinedCallsMutex.RUnlock()
+	return len(ctx.joinedCalls)
+}
+
+// setJoined records whether the assistant is joined to the group call of a chat.
+func (ctx *Context) setJoined(chatId int64, joined bool) {
+	ctx.joinedCallsMutex.Lock()
+	defer ctx.joinedCallsMutex.Unlock()
+	if joined {
+		ctx.joinedCalls[chatId] = struct{}{}
+	} else {
+		delete(ctx.joinedCalls, chatId)
+	}
+}
+
 func (ctx *Context) InputGroupCall(chatId int64) tg.InputGroupCall {
 	return ctx.inputGroupCalls[chatId]
 }
@@ -163,6 +163,7 @@ func (ctx *Context) connectCall(chatId int64, mediaDescription ntgcalls2.MediaDe
 		if err != nil {
 			return err
 		}
+		ctx.setJoined(chatId, true)
 		callRes := callResRaw.(*tg.UpdatesObj)
 		for _, update := range callRes.Updates {
 			switch update.(type) {
@@ -17,6 +17,8 @@ type Context struct {
 	p2pConfigs            map[int64]*types.P2PConfig
 	inputCalls            map[int64]*tg.InputPhoneCall
 	inputGroupCalls       map[int64]tg.InputGroupCall
+	joinedCalls           map[int64]struct{}
+	joinedCallsMutex      sync.RWMutex
 	participantsMutex     sync.Mutex
 	callParticipants      map[int64]*types.CallParticipantsCache
 	pendingConnections    map[int64]*types.PendingConnection
@@ -26,6 +28,7 @@ type Context struct {
 	incomingCallCallbacks []func(client *Context, chatId int64)
 	streamEndCallbacks    []ntgcalls.StreamEndCallback
 	frameCallbacks        []ntgcalls.FrameCallback
+	dropCallbacks         []func(chatId int64, state ntgcalls.ConnectionState)
 }
 
 func NewInstance(app *tg.Client) (*Context, error) {
@@ -36,6 +39,7 @@ func NewInstance(app *tg.Client) (*Context, error) {
 		p2pConfigs:          make(map[int64]*types.P2PConfig),
 		inputCalls:          make(map[int64]*tg.InputPhoneCall),
 		inputGroupCalls:     make(map[int64]tg.InputGroupCall),
+		joinedCalls:         make(map[int64]struct{}),
 		pendingConnections:  make(map[int64]*types.PendingConnection),
 		callParticipants:    make(map[int64]*types.CallParticipantsCache),
 		callSources:         make(map[int64]*types.CallSources),
@@ -61,6 +65,10 @@ func (ctx *Context) OnStreamEnd(callback ntgcalls.StreamEndCallback) {
 	ctx.streamEndCallbacks = append(ctx.streamEndCallbacks, callback)
 }
 
+func (ctx *Context) OnConnectionDrop(callback func(chatId int64, state ntgcalls.ConnectionState)) {
+	ctx.dropCallbacks = append(ctx.dropCallbacks, callback)
+}
+
 func (ctx *Context) OnFrame(callback ntgcalls.FrameCallback) {
 	ctx.frameCallbacks = append(ctx.frameCallbacks, callback)
 }
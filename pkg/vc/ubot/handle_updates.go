@@ -215,6 +215,7 @@ func (ctx *Context) handleUpdates() {
 				return nil
 			case *tg.GroupCallDiscarded:
 				delete(ctx.inputGroupCalls, chatID)
+				ctx.setJoined(chatID, false)
 				_ = ctx.binding.Stop(chatID)
 				return nil
 			}
@@ -287,6 +288,17 @@ func (ctx *Context) handleUpdates() {
 				ctx.waitConnect[chatId] <- fmt.Errorf("connection timeout")
 			default:
 			}
+			return
+		}
+		if state.Kind != ntgcalls.NormalConnection {
+			return
+		}
+		switch state.State {
+		case ntgcalls.Closed, ntgcalls.Failed, ntgcalls.Timeout:
+			for _, callback := range ctx.dropCallbacks {
+				go callback(chatId, state.State)
+			}
+		default:
 		}
 	})
 
@@ -0,0 +1,23 @@
+package ubot
+
+// StopBinding forgets the presentation, mute and connection state kept for a chat and stops its ntgcalls call.
+// Unlike Stop, it does not leave the group call; it is meant for ForceReset, which leaves it separately.
+func (ctx *Context) StopBinding(chatId int64) error {
+	ctx.presentations = stdRemove(ctx.presentations, chatId)
+	ctx.mutedByAdmin = stdRemove(ctx.mutedByAdmin, chatId)
+	delete(ctx.pendingPresentation, chatId)
+	delete(ctx.pendingConnections, chatId)
+	delete(ctx.callSources, chatId)
+	return ctx.binding.Stop(chatId)
+}
+
+// LeaveCall leaves the group call of a chat and forgets that the assistant joined it, even if leaving fails.
+func (ctx *Context) LeaveCall(chatId int64) error {
+	inputGroupCall, err := ctx.getInputGroupCall(chatId)
+	if err != nil {
+		return err
+	}
+	_, err = ctx.App.PhoneLeaveGroupCall(inputGroupCall, 0)
+	ctx.setJoined(chatId, false)
+	return err
+}
@@ -1,5 +1,9 @@
 package ubot
 
+import tg "github.com/amarnathcjd/gogram/telegram"
+
+// Stop stops the stream of a chat and leaves its group call.
+// The group call is left even if stopping the stream fails.
 func (ctx *Context) Stop(chatId any) error {
 	parsedChatId, err := ctx.parseChatId(chatId)
 	if err != nil {
@@ -8,13 +12,26 @@ func (ctx *Context) Stop(chatId any) error {
 	ctx.presentations = stdRemove(ctx.presentations, parsedChatId)
 	delete(ctx.pendingPresentation, parsedChatId)
 	delete(ctx.callSources, parsedChatId)
-	err = ctx.binding.Stop(parsedChatId)
-	if err != nil {
+	stopErr := ctx.binding.Stop(parsedChatId)
+	if err := ctx.leaveGroupCall(parsedChatId); err != nil {
 		return err
 	}
-	_, err = ctx.App.PhoneLeaveGroupCall(ctx.inputGroupCalls[parsedChatId], 0)
-	if err != nil {
-		return err
+	return stopErr
+}
+
+// leaveGroupCall leaves the group call of a chat and forgets its input call, so the next join fetches it again.
+// Not being in the call, or the call having ended, is not an error.
+func (ctx *Context) leaveGroupCall(chatId int64) error {
+	inputGroupCall := ctx.inputGroupCalls[chatId]
+	delete(ctx.inputGroupCalls, chatId)
+	ctx.setJoined(chatId, false)
+	if inputGroupCall == nil {
+		return nil
+	}
+
+	_, err := ctx.App.PhoneLeaveGroupCall(inputGroupCall, 0)
+	if tg.MatchError(err, "GROUPCALL_JOIN_MISSING") || tg.MatchError(err, "GROUPCALL_INVALID") {
+		return nil
 	}
-	return nil
+	return err
 }
@@ -7,6 +7,7 @@ import (
 "github.com/zuchzub/Go/pkg/core/db"
 "github.com/zuchzub/Go/pkg/lang"
 "strings"
+"time"
 
 "github.com/Laky-64/gologging"
 tg "github.com/amarnathcjd/gogram/telegram"
@@ -104,6 +105,10 @@ func (c *TelegramCalls) checkUserStats(chatId int64) (string, error) {
 // joinUb handles the process of a userbot joining a chat via an invite link.
 // It returns an error if the userbot fails to join.
 func (c *TelegramCalls) joinUb(chatID int64) error {
+	c.notifyJoining(chatID)
+	start := time.Now()
+	defer func() { c.recordJoin(chatID, time.Since(start)) }()
+
 	ctx, cancel := db.Ctx()
 	defer cancel()
 	langCode := db.Instance.GetLang(ctx, chatID)
@@ -182,3 +187,24 @@ func (c *TelegramCalls) joinUb(chatID int64) error {
 	c.UpdateMembership(chatID, ub.Me().ID, tg.Member)
 	return nil
 }
+
+// InvalidateJoin forgets that the assistant of a chat was found to be in it, so that the next track checks its
+// membership again. It is called when the assistant leaves, or is banned or restricted.
+func (c *TelegramCalls) InvalidateJoin(chatID int64) {
+	cache.ChatCache.SetJoinedAs(chatID, 0)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, ctx := range c.uBContext {
+		if me := ctx.App.Me(); me != nil {
+			c.statusCache.Delete(fmt.Sprintf("%d:%d", chatID, me.ID))
+		}
+	}
+}
+
+// isNotMemberError reports whether err means that the assistant is not a member of the chat it tried to stream to.
+func isNotMemberError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "USER_NOT_PARTICIPANT") || strings.Contains(msg, "CHANNEL_PRIVATE") ||
+		strings.Contains(msg, "USER_BANNED_IN_CHANNEL")
+}
@@ -0,0 +1,102 @@
+package vc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zuchzub/Go/pkg/config"
+	"github.com/zuchzub/Go/pkg/core"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/lang"
+	"html"
+	"slices"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+// VideoSlotsFullError is returned by PlayMedia when starting a video stream would exceed MaxVideoSessions.
+type VideoSlotsFullError struct {
+	Used int
+	Max  int
+}
+
+func (e *VideoSlotsFullError) Error() string {
+	return fmt.Sprintf("video slots are full (%d/%d)", e.Used, e.Max)
+}
+
+// VideoSlotsFullReply builds the message shown when a track could not be played as video because every video slot
+// is taken, with buttons to play it as audio only or skip it.
+func VideoSlotsFullReply(langCode string, song *cache.CachedTrack, err *VideoSlotsFullError) (string, tg.SendOptions) {
+	text := fmt.Sprintf(lang.GetString(langCode, "video_slots_full"), err.Used, err.Max, html.EscapeString(song.Name))
+	return text, tg.SendOptions{ReplyMarkup: core.AudioOnlyButtons()}
+}
+
+// acquireVideoSlot takes a video slot for a chat, or returns a *VideoSlotsFullError if none is free.
+// A chat that already holds a slot keeps it. Slots of chats that are no longer active are freed first, so that a
+// queue cleared without going through Stop does not hold its slot forever.
+func (c *TelegramCalls) acquireVideoSlot(chatID int64) error {
+	limit := config.Conf.MaxVideoSessions
+	if limit <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pruneVideoSlots()
+	if _, ok := c.videoChats[chatID]; ok {
+		return nil
+	}
+	if len(c.videoChats) >= limit {
+		return &VideoSlotsFullError{Used: len(c.videoChats), Max: limit}
+	}
+	c.videoChats[chatID] = struct{}{}
+	return nil
+}
+
+// releaseVideoSlot frees the video slot of a chat, if it holds one.
+func (c *TelegramCalls) releaseVideoSlot(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.videoChats, chatID)
+}
+
+// pruneVideoSlots frees the slots of chats whose queue is no longer active. The caller must hold c.mu.
+func (c *TelegramCalls) pruneVideoSlots() {
+	for chatID := range c.videoChats {
+		if !cache.ChatCache.IsActive(chatID) {
+			delete(c.videoChats, chatID)
+		}
+	}
+}
+
+// VideoSlots returns the chats holding a video slot, sorted, and the number of slots; 0 slots means no limit.
+func (c *TelegramCalls) VideoSlots() ([]int64, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pruneVideoSlots()
+	chats := make([]int64, 0, len(c.videoChats))
+	for chatID := range c.videoChats {
+		chats = append(chats, chatID)
+	}
+	slices.Sort(chats)
+	return chats, config.Conf.MaxVideoSessions
+}
+
+// HasVideoSlot reports whether a chat holds a video slot.
+func (c *TelegramCalls) HasVideoSlot(chatID int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.videoChats[chatID]
+	return ok
+}
+
+// PlayAudioOnly plays the current track of a chat without its video, for a track that was refused a video slot.
+func (c *TelegramCalls) PlayAudioOnly(chatID int64) error {
+	song := cache.ChatCache.GetPlayingTrack(chatID)
+	if song == nil {
+		return errors.New("no track is playing")
+	}
+	song.IsVideo = false
+	return c.playSong(chatID, song)
+}
@@ -0,0 +1,67 @@
+package vc
+
+import (
+	"fmt"
+	"github.com/zuchzub/Go/pkg/core/cache"
+	"github.com/zuchzub/Go/pkg/core/db"
+	"github.com/zuchzub/Go/pkg/lang"
+)
+
+// MaxVolume is the highest playback volume, in percent, a chat can set.
+const MaxVolume = 200
+
+// VolumeNote returns the line the queue and now-playing messages show for a chat's playback volume, or an empty string
+// when it is the default.
+func VolumeNote(langCode string, volume int) string {
+	if volume == db.DefaultVolume {
+		return ""
+	}
+	return fmt.Sprintf(lang.GetString(langCode, "volume_note"), volume)
+}
+
+// SetVolume stores the playback volume of a chat, in percent, and applies it to the track playing there.
+// The track is restarted from its current position with the new volume in its filter chain; later tracks pick the
+// stored volume up when their audio parameters are selected.
+func (c *TelegramCalls) SetVolume(chatID int64, volume int) error {
+	if volume < 0 || volume > MaxVolume {
+		return fmt.Errorf("volume must be between 0 and %d", MaxVolume)
+	}
+
+	ctx, cancel := db.Ctx()
+	defer cancel()
+	if err := db.Instance.SetVolume(ctx, chatID, volume); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if params, ok := c.audioParams[chatID]; ok {
+		params.Volume = volume
+		c.audioParams[chatID] = params
+	}
+	c.mu.Unlock()
+	return c.restartWithFilters(chatID)
+}
+
+// restartWithFilters restarts the track playing in a chat so that a change to its audio parameters takes effect.
+// The track resumes from its current position; a track whose speed was changed with /speed keeps that speed, and
+// starts over from the beginning as it did when the speed was set, since ffmpeg is given either a seek or filters.
+func (c *TelegramCalls) restartWithFilters(chatID int64) error {
+	song := cache.ChatCache.GetPlayingTrack(chatID)
+	if !cache.ChatCache.IsActive(chatID) || song == nil || song.FilePath == "" {
+		return nil
+	}
+
+	c.mu.RLock()
+	speedFilters := c.audioParams[chatID].SpeedFilters
+	c.mu.RUnlock()
+	if speedFilters != "" {
+		return c.PlayMedia(chatID, song.FilePath, song.IsVideo, speedFilters)
+	}
+
+	if !song.IsLive {
+		if played, err := c.PlayedTime(chatID); err == nil && played > 0 && int(played) < song.Duration {
+			return c.SeekStream(chatID, song.FilePath, int(played), song.Duration, song.IsVideo)
+		}
+	}
+	return c.PlayMedia(chatID, song.FilePath, song.IsVideo, "")
+}